@@ -176,6 +176,7 @@ export interface GrafanaConfig {
   helpEnabled: boolean;
   profileEnabled: boolean;
   ldapEnabled: boolean;
+  totpEnabled: boolean;
   sigV4AuthEnabled: boolean;
   azureAuthEnabled: boolean;
   samlEnabled: boolean;
@@ -54,6 +54,7 @@ export class GrafanaBootConfig implements GrafanaConfig {
   helpEnabled = false;
   profileEnabled = false;
   ldapEnabled = false;
+  totpEnabled = false;
   jwtHeaderName = '';
   jwtUrlLogin = false;
   sigV4AuthEnabled = false;
@@ -71,6 +71,10 @@ func (hs *HTTPServer) registerRoutes() {
 	// not logged in views
 	r.Get("/logout", hs.Logout)
 	r.Post("/login", quota("session"), routing.Wrap(hs.LoginPost))
+	if hs.Cfg.TOTPEnabled {
+		r.Post("/login/totp", quota("session"), routing.Wrap(hs.LoginTOTPPost))
+		r.Post("/login/totp/enroll", routing.Wrap(hs.LoginTOTPEnroll))
+	}
 	r.Get("/login/:name", quota("session"), hs.OAuthLogin)
 	r.Get("/login", hs.LoginView)
 	r.Get("/invite/:code", hs.Index)
@@ -220,6 +224,14 @@ func (hs *HTTPServer) registerRoutes() {
 
 			userRoute.Get("/auth-tokens", routing.Wrap(hs.GetUserAuthTokens))
 			userRoute.Post("/revoke-auth-token", routing.Wrap(hs.RevokeUserAuthToken))
+
+			if hs.Cfg.TOTPEnabled {
+				userRoute.Get("/totp", routing.Wrap(hs.GetUserTOTP))
+				userRoute.Post("/totp/enroll", routing.Wrap(hs.EnrollUserTOTP))
+				userRoute.Post("/totp/confirm", routing.Wrap(hs.ConfirmUserTOTP))
+				userRoute.Post("/totp/recovery-codes", routing.Wrap(hs.RegenerateUserTOTPRecoveryCodes))
+				userRoute.Post("/totp/disable", routing.Wrap(hs.DisableUserTOTP))
+			}
 		}, reqSignedInNoAnonymous)
 
 		apiRoute.Group("/users", func(usersRoute routing.RouteRegister) {
@@ -288,6 +300,12 @@ func (hs *HTTPServer) registerRoutes() {
 			orgRoute.Get("/preferences", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsPreferencesRead)), routing.Wrap(hs.GetOrgPreferences))
 			orgRoute.Put("/preferences", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsPreferencesWrite)), routing.Wrap(hs.UpdateOrgPreferences))
 			orgRoute.Patch("/preferences", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsPreferencesWrite)), routing.Wrap(hs.PatchOrgPreferences))
+
+			// two-factor authentication
+			if hs.Cfg.TOTPEnabled {
+				orgRoute.Get("/totp", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsRead)), routing.Wrap(hs.GetOrgTOTP))
+				orgRoute.Put("/totp", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.UpdateOrgTOTP))
+			}
 		})
 
 		// current org without requirement of user to be org admin
@@ -353,6 +371,9 @@ func (hs *HTTPServer) registerRoutes() {
 			datasourceRoute.Post("/", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionCreate)), quota("data_source"), routing.Wrap(hs.AddDataSource))
 			datasourceRoute.Put("/:id", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, idScope)), routing.Wrap(hs.UpdateDataSourceByID))
 			datasourceRoute.Put("/uid/:uid", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(hs.UpdateDataSourceByUID))
+			datasourceRoute.Post("/uid/:uid/secrets/rotate", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(hs.RotateDataSourceSecretsByUID))
+			datasourceRoute.Get("/uid/:uid/secrets/rotation", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionRead, uidScope)), routing.Wrap(hs.GetDataSourceSecretRotationByUID))
+			datasourceRoute.Post("/uid/:uid/secrets/rotation/complete", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(hs.CompleteDataSourceSecretRotationByUID))
 			datasourceRoute.Delete("/:id", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionDelete, idScope)), routing.Wrap(hs.DeleteDataSourceById))
 			datasourceRoute.Delete("/uid/:uid", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionDelete, uidScope)), routing.Wrap(hs.DeleteDataSourceByUID))
 			datasourceRoute.Delete("/name/:name", authorize(reqOrgAdmin, ac.EvalPermission(datasources.ActionDelete, nameScope)), routing.Wrap(hs.DeleteDataSourceByName))
@@ -381,6 +402,8 @@ func (hs *HTTPServer) registerRoutes() {
 		}
 
 		apiRoute.Group("/plugins", func(pluginRoute routing.RouteRegister) {
+			pluginRoute.Post("/reload", authorize(reqGrafanaAdmin, ac.EvalPermission(plugins.ActionInstall)), routing.Wrap(hs.ReloadPlugins))
+			pluginRoute.Post("/:pluginId/reload", authorize(reqGrafanaAdmin, ac.EvalPermission(plugins.ActionInstall)), routing.Wrap(hs.ReloadPlugin))
 			pluginRoute.Get("/:pluginId/dashboards/", reqOrgAdmin, routing.Wrap(hs.GetPluginDashboards))
 			pluginRoute.Post("/:pluginId/settings", authorize(reqOrgAdmin, ac.EvalPermission(plugins.ActionWrite, pluginIDScope)), routing.Wrap(hs.UpdatePluginSetting))
 			pluginRoute.Get("/:pluginId/metrics", reqOrgAdmin, routing.Wrap(hs.CollectPluginMetrics))
@@ -639,6 +662,10 @@ func (hs *HTTPServer) registerRoutes() {
 		adminUserRoute.Post("/:id/logout", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersLogout, userIDScope)), routing.Wrap(hs.AdminLogoutUser))
 		adminUserRoute.Get("/:id/auth-tokens", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersAuthTokenList, userIDScope)), routing.Wrap(hs.AdminGetUserAuthTokens))
 		adminUserRoute.Post("/:id/revoke-auth-token", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersAuthTokenUpdate, userIDScope)), routing.Wrap(hs.AdminRevokeUserAuthToken))
+
+		if hs.Cfg.TOTPEnabled {
+			adminUserRoute.Delete("/:id/totp", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersTOTPDelete, userIDScope)), routing.Wrap(hs.AdminResetUserTOTP))
+		}
 	})
 
 	// rendering
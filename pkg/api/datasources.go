@@ -562,6 +562,107 @@ func (hs *HTTPServer) getRawDataSourceByUID(ctx context.Context, uid string, org
 	return query.Result, nil
 }
 
+// swagger:route POST /datasources/uid/{uid}/secrets/rotate datasources rotateDataSourceSecretsByUID
+//
+// Rotate the secrets of a data source.
+//
+// The new secure JSON data is used first, and the current secure JSON data is used when queries with the new
+// one fail, until the grace period ends and the new secure JSON data replaces the current one.
+//
+// Responses:
+// 200: dataSourceSecretRotationResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 500: internalServerError
+func (hs *HTTPServer) RotateDataSourceSecretsByUID(c *models.ReqContext) response.Response {
+	cmd := datasources.RotateDataSourceSecretsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.OrgID
+	cmd.UID = web.Params(c.Req)[":uid"]
+
+	if err := hs.DataSourcesService.RotateSecrets(c.Req.Context(), &cmd); err != nil {
+		switch {
+		case errors.Is(err, datasources.ErrDataSourceNotFound):
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		case errors.Is(err, datasources.ErrDatasourceIsReadOnly):
+			return response.Error(http.StatusForbidden, "Cannot rotate the secrets of a read-only data source", nil)
+		case errors.Is(err, datasources.ErrSecretRotationInProgress):
+			return response.Error(http.StatusConflict, err.Error(), nil)
+		case errors.Is(err, datasources.ErrSecretRotationInvalidGracePeriod):
+			return response.Error(http.StatusBadRequest, err.Error(), nil)
+		case errors.As(err, &secretsPluginError):
+			return response.Error(http.StatusInternalServerError, "Failed to rotate data source secrets: "+err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to rotate data source secrets", err)
+	}
+
+	return response.JSON(http.StatusOK, cmd.Result)
+}
+
+// swagger:route GET /datasources/uid/{uid}/secrets/rotation datasources getDataSourceSecretRotationByUID
+//
+// Get the secret rotation of a data source, including the secure JSON data in use.
+//
+// Responses:
+// 200: dataSourceSecretRotationResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetDataSourceSecretRotationByUID(c *models.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+
+	rotation, err := hs.DataSourcesService.GetSecretRotation(c.Req.Context(), ds)
+	if err != nil {
+		if errors.Is(err, datasources.ErrSecretRotationNotFound) {
+			return response.Error(http.StatusNotFound, err.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get data source secret rotation", err)
+	}
+
+	return response.JSON(http.StatusOK, rotation)
+}
+
+// swagger:route POST /datasources/uid/{uid}/secrets/rotation/complete datasources completeDataSourceSecretRotationByUID
+//
+// Replace the current secure JSON data of a data source with the rotated one, before the grace period ends.
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) CompleteDataSourceSecretRotationByUID(c *models.ReqContext) response.Response {
+	ds, err := hs.getRawDataSourceByUID(c.Req.Context(), web.Params(c.Req)[":uid"], c.OrgID)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query datasource", err)
+	}
+
+	if err := hs.DataSourcesService.CompleteSecretRotation(c.Req.Context(), ds); err != nil {
+		if errors.Is(err, datasources.ErrSecretRotationNotFound) {
+			return response.Error(http.StatusNotFound, err.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to complete data source secret rotation", err)
+	}
+
+	return response.Success("Data source secret rotation completed")
+}
+
 // swagger:route GET /datasources/name/{name} datasources getDataSourceByName
 //
 // Get a single data source by Name.
@@ -833,6 +934,11 @@ func (hs *HTTPServer) checkDatasourceHealth(c *models.ReqContext, ds *datasource
 	}
 
 	resp, err := hs.pluginClient.CheckHealth(c.Req.Context(), req)
+	if err != nil || resp.Status != backend.HealthStatusOk {
+		resp, err = hs.checkHealthWithCurrentSecrets(c.Req.Context(), ds, req, resp, err)
+	} else {
+		hs.setSecretRotationCredentialInUse(c.Req.Context(), ds, datasources.SecretRotationCredentialPending)
+	}
 	if err != nil {
 		return translatePluginRequestErrorToAPIError(err)
 	}
@@ -860,6 +966,38 @@ func (hs *HTTPServer) checkDatasourceHealth(c *models.ReqContext, ds *datasource
 	return response.JSON(http.StatusOK, payload)
 }
 
+// checkHealthWithCurrentSecrets checks the health of a data source again with its current secrets, when it is in the
+// grace period of a secret rotation and the check with the pending secrets failed. The secrets aren't changed.
+func (hs *HTTPServer) checkHealthWithCurrentSecrets(ctx context.Context, ds *datasources.DataSource, req *backend.CheckHealthRequest,
+	resp *backend.CheckHealthResult, err error) (*backend.CheckHealthResult, error) {
+	inGracePeriod, graceErr := hs.DataSourcesService.InSecretRotationGracePeriod(ctx, ds)
+	if graceErr != nil || !inGracePeriod {
+		return resp, err
+	}
+
+	instanceSettings, settingsErr := adapters.ModelToInstanceSettings(ds, hs.decryptSecureJsonDataFn(datasources.WithCurrentSecrets(ctx)))
+	if settingsErr != nil {
+		return resp, err
+	}
+	instanceSettings.ID = datasources.CurrentSecretsID(ds.Id)
+	retryReq := *req
+	retryReq.PluginContext.DataSourceInstanceSettings = instanceSettings
+
+	retryResp, retryErr := hs.pluginClient.CheckHealth(ctx, &retryReq)
+	if retryErr != nil || retryResp.Status != backend.HealthStatusOk {
+		return resp, err
+	}
+	hs.setSecretRotationCredentialInUse(ctx, ds, datasources.SecretRotationCredentialCurrent)
+	return retryResp, nil
+}
+
+// setSecretRotationCredentialInUse records the secrets of a successful request, for data sources in secret rotation.
+func (hs *HTTPServer) setSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) {
+	if err := hs.DataSourcesService.SetSecretRotationCredentialInUse(ctx, ds, credential); err != nil {
+		hs.log.Warn("Failed to record the secrets in use by the data source in secret rotation", "uid", ds.Uid, "error", err)
+	}
+}
+
 func (hs *HTTPServer) decryptSecureJsonDataFn(ctx context.Context) func(ds *datasources.DataSource) (map[string]string, error) {
 	return func(ds *datasources.DataSource) (map[string]string, error) {
 		return hs.DataSourcesService.DecryptedValues(ctx, ds)
@@ -987,6 +1125,36 @@ type UpdateDataSourceByUIDParams struct {
 	DatasourceUID string `json:"uid"`
 }
 
+// swagger:parameters rotateDataSourceSecretsByUID
+type RotateDataSourceSecretsByUIDParams struct {
+	// in:body
+	// required:true
+	Body datasources.RotateDataSourceSecretsCommand
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:parameters getDataSourceSecretRotationByUID
+type GetDataSourceSecretRotationByUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:parameters completeDataSourceSecretRotationByUID
+type CompleteDataSourceSecretRotationByUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:response dataSourceSecretRotationResponse
+type DataSourceSecretRotationResponse struct {
+	// in: body
+	Body datasources.SecretRotation `json:"body"`
+}
+
 // swagger:response getDataSourcesResponse
 type GetDataSourcesResponse struct {
 	// The response message
@@ -1,6 +1,8 @@
 package dtos
 
 import (
+	"time"
+
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 )
@@ -26,6 +28,19 @@ type PluginSetting struct {
 	Signature     plugins.SignatureStatus `json:"signature"`
 	SignatureType plugins.SignatureType   `json:"signatureType"`
 	SignatureOrg  string                  `json:"signatureOrg"`
+
+	Process *PluginProcess `json:"process,omitempty"`
+}
+
+// PluginProcess is the supervision state of the process of a backend plugin.
+type PluginProcess struct {
+	Running        bool       `json:"running"`
+	CircuitBreaker string     `json:"circuitBreaker"`
+	Restarts       int        `json:"restarts"`
+	RecentCrashes  int        `json:"recentCrashes"`
+	StartedAt      time.Time  `json:"startedAt"`
+	ExitedAt       *time.Time `json:"exitedAt,omitempty"`
+	NextRestartAt  *time.Time `json:"nextRestartAt,omitempty"`
 }
 
 type PluginListItem struct {
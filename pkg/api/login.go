@@ -233,6 +233,18 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 
 	usr = authQuery.User
 
+	if authModule == "grafana" && hs.Cfg.TOTPEnabled {
+		challenge, err := hs.startTOTPChallenge(c, usr)
+		if err != nil {
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+			return resp
+		}
+		if challenge != nil {
+			resp = response.JSON(http.StatusOK, challenge)
+			return resp
+		}
+	}
+
 	err = hs.loginUserWithUser(usr, c)
 	if err != nil {
 		var createTokenErr *models.CreateTokenErr
@@ -244,6 +256,13 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 		return resp
 	}
 
+	metrics.MApiLoginPost.Inc()
+	resp = response.JSON(http.StatusOK, hs.loginResult(c))
+	return resp
+}
+
+// loginResult returns the result of a successful login, with the URL of the redirect_to cookie.
+func (hs *HTTPServer) loginResult(c *models.ReqContext) map[string]interface{} {
 	result := map[string]interface{}{
 		"message": "Logged in",
 	}
@@ -256,10 +275,7 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 		}
 		cookies.DeleteCookie(c.Resp, "redirect_to", hs.CookieOptionsFromCfg)
 	}
-
-	metrics.MApiLoginPost.Inc()
-	resp = response.JSON(http.StatusOK, result)
-	return resp
+	return result
 }
 
 func (hs *HTTPServer) loginUserWithUser(user *user.User, c *models.ReqContext) error {
@@ -335,7 +351,7 @@ func (hs *HTTPServer) trySetEncryptedCookie(ctx *models.ReqContext, cookieName s
 		return err
 	}
 
-	cookies.WriteCookie(ctx.Resp, cookieName, hex.EncodeToString(encryptedError), 60, hs.CookieOptionsFromCfg)
+	cookies.WriteCookie(ctx.Resp, cookieName, hex.EncodeToString(encryptedError), maxAge, hs.CookieOptionsFromCfg)
 
 	return nil
 }
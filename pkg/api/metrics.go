@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/query"
 	"github.com/grafana/grafana/pkg/web"
 )
 
@@ -54,10 +55,14 @@ func (hs *HTTPServer) QueryMetricsV2(c *models.ReqContext) response.Response {
 
 	reqDTO.HTTPRequest = c.Req
 
-	resp, err := hs.queryDataService.QueryData(c.Req.Context(), c.SignedInUser, c.SkipCache, reqDTO, true)
+	ctx, cacheStatus := query.WithCacheStatus(c.Req.Context())
+	resp, err := hs.queryDataService.QueryData(ctx, c.SignedInUser, c.SkipCache, reqDTO, true)
 	if err != nil {
 		return hs.handleQueryMetricsError(err)
 	}
+	if status := cacheStatus.Get(); status != "" {
+		c.Resp.Header().Set(query.HeaderQueryCache, status)
+	}
 	return hs.toJsonStreamingResponse(resp)
 }
 
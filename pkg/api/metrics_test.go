@@ -75,6 +75,9 @@ func TestAPIEndpoint_Metrics_QueryMetricsV2(t *testing.T) {
 			},
 		},
 		&fakeOAuthTokenService{},
+		nil,
+		nil,
+		nil,
 	)
 	serverFeatureEnabled := SetupAPITestServer(t, func(hs *HTTPServer) {
 		hs.queryDataService = qds
@@ -124,6 +127,9 @@ func TestAPIEndpoint_Metrics_PluginDecryptionFailure(t *testing.T) {
 			},
 		},
 		&fakeOAuthTokenService{},
+		nil,
+		nil,
+		nil,
 	)
 	httpServer := SetupAPITestServer(t, func(hs *HTTPServer) {
 		hs.queryDataService = qds
@@ -278,6 +284,9 @@ func TestDataSourceQueryError(t *testing.T) {
 					&fakeDatasources.FakeDataSourceService{},
 					pluginClient.ProvideService(r),
 					&fakeOAuthTokenService{},
+					nil,
+					nil,
+					nil,
 				)
 				hs.QuotaService = quotatest.NewQuotaServiceFake()
 			})
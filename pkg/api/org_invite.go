@@ -262,7 +262,11 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 		return rsp
 	}
 
-	err = hs.loginUserWithUser(usr, c)
+	result := util.DynMap{
+		"message": "User created and logged in",
+		"id":      usr.ID,
+	}
+	err = hs.loginUserWithTOTPChallenge(c, usr, result)
 	if err != nil {
 		return response.Error(500, "failed to accept invite", err)
 	}
@@ -270,10 +274,7 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 	metrics.MApiUserSignUpCompleted.Inc()
 	metrics.MApiUserSignUpInvite.Inc()
 
-	return response.JSON(http.StatusOK, util.DynMap{
-		"message": "User created and logged in",
-		"id":      usr.ID,
-	})
+	return response.JSON(http.StatusOK, result)
 }
 
 func (hs *HTTPServer) updateTempUserStatus(ctx context.Context, code string, status models.TempUserStatus) (bool, response.Response) {
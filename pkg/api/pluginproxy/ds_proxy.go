@@ -2,10 +2,12 @@ package pluginproxy
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
 	"strconv"
 	"strings"
@@ -95,13 +97,98 @@ func (proxy *DataSourceProxy) HandleRequest() {
 		"referer", proxy.ctx.Req.Referer(),
 	)
 
-	transport, err := proxy.dataSourcesService.GetHTTPTransport(proxy.ctx.Req.Context(), proxy.ds, proxy.clientProvider)
+	// during the grace period of a secret rotation the data source may still only accept its current secrets, the
+	// request is buffered to send it again with them.
+	inGracePeriod, err := proxy.dataSourcesService.InSecretRotationGracePeriod(proxy.ctx.Req.Context(), proxy.ds)
+	if err != nil {
+		proxyErrorLogger.Warn("Failed to get the secret rotation of the data source", "error", err)
+	}
+	var body []byte
+	if inGracePeriod && proxy.ctx.Req.Body != nil {
+		body, err = io.ReadAll(proxy.ctx.Req.Body)
+		if err != nil {
+			proxy.ctx.JsonApiErr(500, "Failed to read the request body", err)
+			return
+		}
+		proxy.ctx.Req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	reverseProxy, err := proxy.newReverseProxy(proxy.ctx.Req.Context(), proxyErrorLogger, inGracePeriod)
 	if err != nil {
 		proxy.ctx.JsonApiErr(400, "Unable to load TLS certificate", err)
 		return
 	}
 
+	proxy.logRequest()
+	ctx, span := proxy.tracer.Start(proxy.ctx.Req.Context(), "datasource reverse proxy")
+	defer span.End()
+
+	proxy.ctx.Req = proxy.ctx.Req.WithContext(ctx)
+
+	span.SetAttributes("datasource_name", proxy.ds.Name, attribute.Key("datasource_name").String(proxy.ds.Name))
+	span.SetAttributes("datasource_type", proxy.ds.Type, attribute.Key("datasource_type").String(proxy.ds.Type))
+	span.SetAttributes("user", proxy.ctx.SignedInUser.Login, attribute.Key("user").String(proxy.ctx.SignedInUser.Login))
+	span.SetAttributes("org_id", proxy.ctx.SignedInUser.OrgID, attribute.Key("org_id").Int64(proxy.ctx.SignedInUser.OrgID))
+
+	proxy.addTraceFromHeaderValue(span, "X-Panel-Id", "panel_id")
+	proxy.addTraceFromHeaderValue(span, "X-Dashboard-Id", "dashboard_id")
+
+	proxy.tracer.Inject(ctx, proxy.ctx.Req.Header, span)
+
+	retry := false
+	defaultErrorHandler := reverseProxy.ErrorHandler
+	reverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(err, errRetryWithCurrentSecrets) {
+			retry = true
+			return
+		}
+		defaultErrorHandler(w, r, err)
+	}
+	reverseProxy.ServeHTTP(proxy.ctx.Resp, proxy.ctx.Req)
+	if !retry {
+		return
+	}
+
+	proxyErrorLogger.Debug("Retrying the data source request with the current secrets")
+	retryCtx := datasources.WithCurrentSecrets(ctx)
+	reverseProxy, err = proxy.newReverseProxy(retryCtx, proxyErrorLogger, false)
+	if err != nil {
+		proxy.ctx.JsonApiErr(400, "Unable to load TLS certificate", err)
+		return
+	}
+	retryReq := proxy.ctx.Req.WithContext(retryCtx)
+	retryReq.Body = io.NopCloser(bytes.NewReader(body))
+	reverseProxy.ServeHTTP(proxy.ctx.Resp, retryReq)
+}
+
+// errRetryWithCurrentSecrets aborts a proxied request rejected during the grace period of a secret rotation, to send it
+// again with the current secrets.
+var errRetryWithCurrentSecrets = errors.New("retry with the current secrets")
+
+// setSecretRotationCredentialInUse records the secrets of a request accepted by a data source in secret rotation.
+func (proxy *DataSourceProxy) setSecretRotationCredentialInUse(ctx context.Context, proxyErrorLogger glog.Logger, credential datasources.SecretRotationCredential) {
+	if err := proxy.dataSourcesService.SetSecretRotationCredentialInUse(ctx, proxy.ds, credential); err != nil {
+		proxyErrorLogger.Warn("Failed to record the secrets in use by the data source in secret rotation", "error", err)
+	}
+}
+
+func (proxy *DataSourceProxy) newReverseProxy(ctx context.Context, proxyErrorLogger glog.Logger, retryUnauthorized bool) (*httputil.ReverseProxy, error) {
+	transport, err := proxy.dataSourcesService.GetHTTPTransport(ctx, proxy.ds, proxy.clientProvider)
+	if err != nil {
+		return nil, err
+	}
+
 	modifyResponse := func(resp *http.Response) error {
+		unauthorized := resp.StatusCode == 401 || resp.StatusCode == 403
+		if retryUnauthorized && unauthorized {
+			_ = resp.Body.Close()
+			return errRetryWithCurrentSecrets
+		}
+		if retryUnauthorized && !unauthorized {
+			proxy.setSecretRotationCredentialInUse(resp.Request.Context(), proxyErrorLogger, datasources.SecretRotationCredentialPending)
+		} else if datasources.UsesCurrentSecrets(ctx) && !unauthorized {
+			proxy.setSecretRotationCredentialInUse(resp.Request.Context(), proxyErrorLogger, datasources.SecretRotationCredentialCurrent)
+		}
 		if resp.StatusCode == 401 {
 			// The data source rejected the request as unauthorized, convert to 400 (bad request)
 			body, err := io.ReadAll(resp.Body)
@@ -125,30 +212,12 @@ func (proxy *DataSourceProxy) HandleRequest() {
 		return nil
 	}
 
-	reverseProxy := proxyutil.NewReverseProxy(
+	return proxyutil.NewReverseProxy(
 		proxyErrorLogger,
 		proxy.director,
 		proxyutil.WithTransport(transport),
 		proxyutil.WithModifyResponse(modifyResponse),
-	)
-
-	proxy.logRequest()
-	ctx, span := proxy.tracer.Start(proxy.ctx.Req.Context(), "datasource reverse proxy")
-	defer span.End()
-
-	proxy.ctx.Req = proxy.ctx.Req.WithContext(ctx)
-
-	span.SetAttributes("datasource_name", proxy.ds.Name, attribute.Key("datasource_name").String(proxy.ds.Name))
-	span.SetAttributes("datasource_type", proxy.ds.Type, attribute.Key("datasource_type").String(proxy.ds.Type))
-	span.SetAttributes("user", proxy.ctx.SignedInUser.Login, attribute.Key("user").String(proxy.ctx.SignedInUser.Login))
-	span.SetAttributes("org_id", proxy.ctx.SignedInUser.OrgID, attribute.Key("org_id").Int64(proxy.ctx.SignedInUser.OrgID))
-
-	proxy.addTraceFromHeaderValue(span, "X-Panel-Id", "panel_id")
-	proxy.addTraceFromHeaderValue(span, "X-Dashboard-Id", "dashboard_id")
-
-	proxy.tracer.Inject(ctx, proxy.ctx.Req.Header, span)
-
-	reverseProxy.ServeHTTP(proxy.ctx.Resp, proxy.ctx.Req)
+	), nil
 }
 
 func (proxy *DataSourceProxy) addTraceFromHeaderValue(span tracing.Span, headerName string, tagName string) {
@@ -243,8 +312,12 @@ func (proxy *DataSourceProxy) director(req *http.Request) {
 			return
 		}
 
+		id := proxy.ds.Id
+		if datasources.UsesCurrentSecrets(req.Context()) {
+			id = datasources.CurrentSecretsID(id)
+		}
 		ApplyRoute(req.Context(), req, proxy.proxyPath, proxy.matchedRoute, DSInfo{
-			ID:                      proxy.ds.Id,
+			ID:                      id,
 			Updated:                 proxy.ds.Updated,
 			JSONData:                jsonData,
 			DecryptedSecureJSONData: decryptedValues,
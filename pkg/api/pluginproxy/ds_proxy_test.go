@@ -775,6 +775,64 @@ func TestDataSourceProxy_requestHandling(t *testing.T) {
 	})
 }
 
+func TestDataSourceProxy_secretRotation(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.DataSourceSecretRotationGracePeriod = time.Hour
+	tracer := tracing.InitializeTracerForTest()
+
+	var passwords, bodies []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		passwords = append(passwords, password)
+		bodies = append(bodies, string(body))
+		if password != "old" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	t.Cleanup(backend.Close)
+
+	sqlStore := sqlstore.InitTestDB(t)
+	secretsService := secretsmng.SetupTestService(t, fakes.NewFakeSecretsStore())
+	secretsStore := secretskvs.NewSQLSecretsKVStore(sqlStore, secretsService, log.New("test.logger"))
+	dsService := datasourceservice.ProvideService(sqlStore, secretsService, secretsStore, cfg, featuremgmt.WithFeatures(), acmock.New().WithDisabled(), acmock.NewMockedPermissionsService())
+	cmd := &datasources.AddDataSourceCommand{
+		OrgId:          1,
+		Name:           "graphite",
+		Type:           datasources.DS_GRAPHITE,
+		Access:         datasources.DS_ACCESS_PROXY,
+		Url:            backend.URL,
+		BasicAuth:      true,
+		BasicAuthUser:  "user",
+		SecureJsonData: map[string]string{"basicAuthPassword": "old"},
+	}
+	require.NoError(t, dsService.AddDataSource(context.Background(), cmd))
+	require.NoError(t, dsService.RotateSecrets(context.Background(), &datasources.RotateDataSourceSecretsCommand{
+		OrgID: 1, UID: cmd.Result.Uid, SecureJsonData: map[string]string{"basicAuthPassword": "new"},
+	}))
+	query := &datasources.GetDataSourceQuery{Id: cmd.Result.Id, OrgId: 1}
+	require.NoError(t, dsService.GetDataSource(context.Background(), query))
+
+	ctx := &models.ReqContext{
+		SignedInUser: &user.SignedInUser{},
+		Context: &web.Context{
+			Req:  httptest.NewRequest("POST", "/render", strings.NewReader("target=up")),
+			Resp: web.NewResponseWriter("POST", httptest.NewRecorder()),
+		},
+	}
+	proxy, err := NewDataSourceProxy(query.Result, nil, ctx, "/render", cfg, httpclient.NewProvider(), &oauthtoken.Service{}, dsService, tracer)
+	require.NoError(t, err)
+
+	proxy.HandleRequest()
+
+	assert.Equal(t, 200, proxy.ctx.Resp.Status())
+	assert.Equal(t, []string{"new", "old"}, passwords)
+	assert.Equal(t, []string{"target=up", "target=up"}, bodies)
+}
+
 func TestNewDataSourceProxy_InvalidURL(t *testing.T) {
 	ctx := models.ReqContext{
 		Context:      &web.Context{},
@@ -217,6 +217,20 @@ func (hs *HTTPServer) GetPluginSettingByID(c *models.ReqContext) response.Respon
 		dto.HasUpdate = true
 	}
 
+	if state, exists := hs.pluginProcesses.State(plugin.ID); exists {
+		dto.Process = &dtos.PluginProcess{
+			Running:        state.Running,
+			CircuitBreaker: string(state.CircuitBreaker),
+			Restarts:       state.Restarts,
+			RecentCrashes:  state.RecentCrashes,
+			StartedAt:      state.StartedAt,
+		}
+		if !state.Running {
+			dto.Process.ExitedAt = &state.ExitedAt
+			dto.Process.NextRestartAt = &state.NextRestartAt
+		}
+	}
+
 	return response.JSON(http.StatusOK, dto)
 }
 
@@ -465,6 +479,33 @@ func (hs *HTTPServer) UninstallPlugin(c *models.ReqContext) response.Response {
 	return response.JSON(http.StatusOK, []byte{})
 }
 
+// ReloadPlugins loads the external plugins which were added, and replaces or unloads the external plugins
+// which were changed or removed since they were loaded.
+func (hs *HTTPServer) ReloadPlugins(c *models.ReqContext) response.Response {
+	if err := hs.pluginReloader.ReloadAll(c.Req.Context()); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reload plugins", err)
+	}
+	return response.Success("Plugins reloaded")
+}
+
+// ReloadPlugin replaces an external plugin by the plugin found in its directory, after its in-flight requests completed.
+func (hs *HTTPServer) ReloadPlugin(c *models.ReqContext) response.Response {
+	pluginID := web.Params(c.Req)[":pluginId"]
+
+	err := hs.pluginReloader.Reload(c.Req.Context(), pluginID)
+	if err != nil {
+		if errors.Is(err, plugins.ErrPluginNotInstalled) {
+			return response.Error(http.StatusNotFound, "Plugin not installed", err)
+		}
+		if errors.Is(err, plugins.ErrReloadCorePlugin) {
+			return response.Error(http.StatusForbidden, "Cannot reload a Core plugin", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to reload plugin", err)
+	}
+	return response.Success("Plugin reloaded")
+}
+
 func translatePluginRequestErrorToAPIError(err error) response.Response {
 	if errors.Is(err, backendplugin.ErrPluginNotRegistered) {
 		return response.Error(404, "Plugin not found", err)
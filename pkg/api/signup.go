@@ -128,7 +128,7 @@ func (hs *HTTPServer) SignUpStep2(c *models.ReqContext) response.Response {
 		apiResponse["code"] = "redirect-to-select-org"
 	}
 
-	err = hs.loginUserWithUser(usr, c)
+	err = hs.loginUserWithTOTPChallenge(c, usr, apiResponse)
 	if err != nil {
 		return response.Error(500, "failed to login user", err)
 	}
@@ -0,0 +1,505 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/metrics"
+	"github.com/grafana/grafana/pkg/login"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	totpChallengeCookieName = "grafana_totp_challenge"
+
+	// totpStepVerify and totpStepEnroll are the second steps of a login with two-factor authentication
+	totpStepVerify = "verify"
+	totpStepEnroll = "enroll"
+)
+
+// startTOTPChallenge returns the second step of the login of a user with two-factor authentication, or of a user who
+// has to enroll because one of their organizations enforces two-factor authentication. It returns nil when the user can log
+// in with their password only. The user is kept in an encrypted cookie until the second step is completed.
+func (hs *HTTPServer) startTOTPChallenge(c *models.ReqContext, usr *user.User) (map[string]interface{}, error) {
+	ctx := c.Req.Context()
+
+	enabled, err := hs.totpService.IsEnabled(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	step := totpStepVerify
+	if !enabled {
+		enforced, err := hs.totpService.IsEnforcedForUser(ctx, usr.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !enforced {
+			return nil, nil
+		}
+		step = totpStepEnroll
+	}
+
+	lifetime := hs.Cfg.TOTPChallengeLifetime
+	value := fmt.Sprintf("%d:%d", usr.ID, time.Now().Add(lifetime).Unix())
+	if err := hs.trySetEncryptedCookie(c, totpChallengeCookieName, value, int(lifetime.Seconds())); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"message": "Two-factor authentication required",
+		"totp":    step,
+	}, nil
+}
+
+// loginUserWithTOTPChallenge logs in a user who just set their password, when they signed up or accepted an invite.
+// When one of their organizations enforces two-factor authentication the user isn't logged in: the second step of
+// the login is added to result instead.
+func (hs *HTTPServer) loginUserWithTOTPChallenge(c *models.ReqContext, usr *user.User, result util.DynMap) error {
+	if hs.Cfg.TOTPEnabled {
+		challenge, err := hs.startTOTPChallenge(c, usr)
+		if err != nil {
+			return err
+		}
+		if challenge != nil {
+			for k, v := range challenge {
+				result[k] = v
+			}
+			return nil
+		}
+	}
+	return hs.loginUserWithUser(usr, c)
+}
+
+// getTOTPChallengeUser returns the user of the login whose second step isn't completed yet.
+func (hs *HTTPServer) getTOTPChallengeUser(c *models.ReqContext) (*user.User, error) {
+	value, ok := hs.tryGetEncryptedCookie(c, totpChallengeCookieName)
+	if !ok {
+		return nil, totp.ErrChallengeNotFound
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil, totp.ErrChallengeNotFound
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, totp.ErrChallengeNotFound
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().After(time.Unix(expires, 0)) {
+		return nil, totp.ErrChallengeNotFound
+	}
+
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, totp.ErrChallengeNotFound
+		}
+		return nil, err
+	}
+	if usr.IsDisabled {
+		return nil, totp.ErrChallengeNotFound
+	}
+	return usr, nil
+}
+
+// LoginTOTPPost completes a login with a code of the authenticator app or a recovery code of the user. When the user
+// has to enroll, the code confirms the enrollment started with LoginTOTPEnroll, and the recovery codes of the user
+// are returned.
+func (hs *HTTPServer) LoginTOTPPost(c *models.ReqContext) response.Response {
+	cmd := totp.VerifyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad login data", err)
+	}
+	var usr *user.User
+	var resp *response.NormalResponse
+
+	defer func() {
+		err := resp.Err()
+		if err == nil && resp.ErrMessage() != "" {
+			err = errors.New(resp.ErrMessage())
+		}
+		info := &models.LoginInfo{
+			AuthModule: "grafana",
+			User:       usr,
+			HTTPStatus: resp.Status(),
+			Error:      err,
+		}
+		if usr != nil {
+			info.LoginUsername = usr.Login
+		}
+		hs.HooksService.RunLoginHook(info, c)
+	}()
+
+	if setting.DisableLoginForm {
+		resp = response.Error(http.StatusUnauthorized, "Login is disabled", nil)
+		return resp
+	}
+
+	ctx := c.Req.Context()
+	usr, err := hs.getTOTPChallengeUser(c)
+	if err != nil {
+		if errors.Is(err, totp.ErrChallengeNotFound) {
+			resp = response.Error(http.StatusUnauthorized, "Two-factor authentication expired, log in again", err)
+		} else {
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		}
+		return resp
+	}
+
+	loginQuery := &models.LoginUserQuery{
+		ReqContext: c,
+		Username:   usr.Login,
+		IpAddress:  c.Req.RemoteAddr,
+		Cfg:        hs.Cfg,
+	}
+	if err := login.ValidateLoginAttempts(ctx, loginQuery, hs.loginAttemptService); err != nil {
+		resp = response.Error(http.StatusUnauthorized, "Invalid two-factor authentication code", err)
+		return resp
+	}
+
+	enabled, err := hs.totpService.IsEnabled(ctx, usr.ID)
+	if err != nil {
+		resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		return resp
+	}
+	var recoveryCodes []string
+	if enabled {
+		err = hs.totpService.Verify(ctx, usr.ID, cmd.Code)
+	} else {
+		recoveryCodes, err = hs.totpService.ConfirmEnrollment(ctx, usr.ID, cmd.Code)
+	}
+	if err != nil {
+		if errors.Is(err, totp.ErrInvalidCode) || errors.Is(err, totp.ErrNoPendingEnrollment) {
+			if err := login.SaveInvalidLoginAttempt(ctx, loginQuery, hs.loginAttemptService); err != nil {
+				hs.log.Error("Failed to save invalid login attempt", "err", err)
+			}
+			resp = response.Error(http.StatusUnauthorized, "Invalid two-factor authentication code", err)
+		} else {
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		}
+		return resp
+	}
+
+	cookies.DeleteCookie(c.Resp, totpChallengeCookieName, hs.CookieOptionsFromCfg)
+
+	if err := hs.loginUserWithUser(usr, c); err != nil {
+		var createTokenErr *models.CreateTokenErr
+		if errors.As(err, &createTokenErr) {
+			resp = response.Error(createTokenErr.StatusCode, createTokenErr.ExternalErr, createTokenErr.InternalErr)
+		} else {
+			resp = response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+		}
+		return resp
+	}
+
+	result := hs.loginResult(c)
+	if recoveryCodes != nil {
+		result["recoveryCodes"] = recoveryCodes
+	}
+
+	metrics.MApiLoginPost.Inc()
+	resp = response.JSON(http.StatusOK, result)
+	return resp
+}
+
+// LoginTOTPEnroll starts the enrollment of a user who has to enroll to log in, because their organization enforces
+// two-factor authentication.
+func (hs *HTTPServer) LoginTOTPEnroll(c *models.ReqContext) response.Response {
+	if setting.DisableLoginForm {
+		return response.Error(http.StatusUnauthorized, "Login is disabled", nil)
+	}
+
+	usr, err := hs.getTOTPChallengeUser(c)
+	if err != nil {
+		if errors.Is(err, totp.ErrChallengeNotFound) {
+			return response.Error(http.StatusUnauthorized, "Two-factor authentication expired, log in again", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+	}
+
+	enrollment, err := hs.totpService.Enroll(c.Req.Context(), usr)
+	if err != nil {
+		if errors.Is(err, totp.ErrAlreadyEnabled) {
+			return response.Error(http.StatusBadRequest, "Two-factor authentication is already enabled", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// swagger:route GET /user/totp signed_in_user getUserTOTP
+//
+// Get the two-factor authentication status of the actual User.
+//
+// Responses:
+// 200: getUserTOTPResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetUserTOTP(c *models.ReqContext) response.Response {
+	ctx := c.Req.Context()
+
+	enabled, err := hs.totpService.IsEnabled(ctx, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication", err)
+	}
+	enforced, err := hs.totpService.IsEnforcedForUser(ctx, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, totp.Status{Enabled: enabled, Enforced: enforced})
+}
+
+// swagger:route POST /user/totp/enroll signed_in_user enrollUserTOTP
+//
+// Enroll two-factor authentication for the actual User.
+//
+// Returns a new secret for an authenticator app. Two-factor authentication is enabled once the enrollment is
+// confirmed with a code of the authenticator app.
+//
+// Responses:
+// 200: enrollUserTOTPResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) EnrollUserTOTP(c *models.ReqContext) response.Response {
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: c.UserID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	enrollment, err := hs.totpService.Enroll(c.Req.Context(), usr)
+	if err != nil {
+		if errors.Is(err, totp.ErrAlreadyEnabled) {
+			return response.Error(http.StatusBadRequest, "Two-factor authentication is already enabled", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// swagger:route POST /user/totp/confirm signed_in_user confirmUserTOTP
+//
+// Confirm the two-factor authentication enrollment of the actual User.
+//
+// Enables two-factor authentication, and returns the recovery codes of the user.
+//
+// Responses:
+// 200: userTOTPRecoveryCodesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) ConfirmUserTOTP(c *models.ReqContext) response.Response {
+	cmd := totp.VerifyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	codes, err := hs.totpService.ConfirmEnrollment(c.Req.Context(), c.UserID, cmd.Code)
+	if err != nil {
+		return totpErrorResponse(err)
+	}
+	return response.JSON(http.StatusOK, totp.RecoveryCodesResult{RecoveryCodes: codes})
+}
+
+// swagger:route POST /user/totp/recovery-codes signed_in_user regenerateUserTOTPRecoveryCodes
+//
+// Regenerate the recovery codes of the actual User.
+//
+// The previous recovery codes can't be used anymore.
+//
+// Responses:
+// 200: userTOTPRecoveryCodesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) RegenerateUserTOTPRecoveryCodes(c *models.ReqContext) response.Response {
+	cmd := totp.VerifyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := hs.totpService.Verify(c.Req.Context(), c.UserID, cmd.Code); err != nil {
+		return totpErrorResponse(err)
+	}
+	codes, err := hs.totpService.RegenerateRecoveryCodes(c.Req.Context(), c.UserID)
+	if err != nil {
+		return totpErrorResponse(err)
+	}
+	return response.JSON(http.StatusOK, totp.RecoveryCodesResult{RecoveryCodes: codes})
+}
+
+// swagger:route POST /user/totp/disable signed_in_user disableUserTOTP
+//
+// Disable the two-factor authentication of the actual User.
+//
+// Two-factor authentication can't be disabled when one of the organizations of the user enforces it.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) DisableUserTOTP(c *models.ReqContext) response.Response {
+	cmd := totp.VerifyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	ctx := c.Req.Context()
+
+	enforced, err := hs.totpService.IsEnforcedForUser(ctx, c.UserID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	if enforced {
+		return response.Error(http.StatusForbidden, "Two-factor authentication is required by one of your organizations", totp.ErrEnforcedByOrg)
+	}
+
+	if err := hs.totpService.Verify(ctx, c.UserID, cmd.Code); err != nil {
+		return totpErrorResponse(err)
+	}
+	if err := hs.totpService.Disable(ctx, c.UserID); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication disabled")
+}
+
+// swagger:route DELETE /admin/users/{user_id}/totp admin_users adminResetUserTOTP
+//
+// Reset the two-factor authentication of a user.
+//
+// Removes the secret and the recovery codes of the user, who can log in with their password only until they enroll
+// again.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminResetUserTOTP(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if err := hs.totpService.Disable(c.Req.Context(), userID); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication reset")
+}
+
+// swagger:route GET /org/totp org getOrgTOTP
+//
+// Get whether the current organization enforces two-factor authentication.
+//
+// Responses:
+// 200: getOrgTOTPResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgTOTP(c *models.ReqContext) response.Response {
+	enforced, err := hs.totpService.IsEnforced(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, totp.SetEnforcedCommand{Enforced: enforced})
+}
+
+// swagger:route PUT /org/totp org updateOrgTOTP
+//
+// Update whether the current organization enforces two-factor authentication.
+//
+// Users of the built-in login without two-factor authentication have to enroll when they log in.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateOrgTOTP(c *models.ReqContext) response.Response {
+	cmd := totp.SetEnforcedCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := hs.totpService.SetEnforced(c.Req.Context(), c.OrgID, cmd.Enforced); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication updated")
+}
+
+func totpErrorResponse(err error) response.Response {
+	switch {
+	case errors.Is(err, totp.ErrInvalidCode):
+		return response.Error(http.StatusBadRequest, "Invalid two-factor authentication code", err)
+	case errors.Is(err, totp.ErrNotEnabled), errors.Is(err, totp.ErrNoPendingEnrollment):
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, "Failed to verify two-factor authentication code", err)
+}
+
+// swagger:parameters confirmUserTOTP regenerateUserTOTPRecoveryCodes disableUserTOTP
+type UserTOTPCodeParams struct {
+	// in:body
+	// required:true
+	Body totp.VerifyCommand `json:"body"`
+}
+
+// swagger:parameters adminResetUserTOTP
+type AdminResetUserTOTPParams struct {
+	// in:path
+	// required:true
+	UserID int64 `json:"user_id"`
+}
+
+// swagger:parameters updateOrgTOTP
+type UpdateOrgTOTPParams struct {
+	// in:body
+	// required:true
+	Body totp.SetEnforcedCommand `json:"body"`
+}
+
+// swagger:response getUserTOTPResponse
+type GetUserTOTPResponse struct {
+	// in:body
+	Body totp.Status `json:"body"`
+}
+
+// swagger:response enrollUserTOTPResponse
+type EnrollUserTOTPResponse struct {
+	// in:body
+	Body totp.Enrollment `json:"body"`
+}
+
+// swagger:response userTOTPRecoveryCodesResponse
+type UserTOTPRecoveryCodesResponse struct {
+	// in:body
+	Body totp.RecoveryCodesResult `json:"body"`
+}
+
+// swagger:response getOrgTOTPResponse
+type GetOrgTOTPResponse struct {
+	// in:body
+	Body totp.SetEnforcedCommand `json:"body"`
+}
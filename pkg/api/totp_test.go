@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/hooks"
+	"github.com/grafana/grafana/pkg/services/licensing"
+	"github.com/grafana/grafana/pkg/services/login/loginservice"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupTOTPLoginServer(totpService *totptest.FakeService) *HTTPServer {
+	cfg := setting.NewCfg()
+	cfg.TOTPEnabled = true
+	cfg.TOTPChallengeLifetime = 5 * time.Minute
+	cfg.DisableBruteForceLoginProtection = true
+	cfg.LoginCookieName = "grafana_session"
+
+	return &HTTPServer{
+		log:              log.NewNopLogger(),
+		Cfg:              cfg,
+		HooksService:     &hooks.HooksService{},
+		License:          &licensing.OSSLicensingService{},
+		AuthTokenService: auth.NewFakeUserAuthTokenService(),
+		SecretsService:   fakes.NewFakeSecretsService(),
+		userService:      &usertest.FakeUserService{ExpectedUser: &user.User{ID: 42, Login: "admin"}},
+		totpService:      totpService,
+	}
+}
+
+func TestLoginPostTOTP(t *testing.T) {
+	testUser := &user.User{ID: 42, OrgID: 1, Login: "admin"}
+
+	testCases := []struct {
+		desc       string
+		enabled    bool
+		enforced   bool
+		authModule string
+		step       string
+	}{
+		{desc: "user without two-factor authentication logs in", authModule: "grafana"},
+		{desc: "user with two-factor authentication has to verify a code", enabled: true, authModule: "grafana", step: totpStepVerify},
+		{desc: "user has to enroll when enforced by the organization", enforced: true, authModule: "grafana", step: totpStepEnroll},
+		{desc: "LDAP user logs in", enabled: true, authModule: "ldap"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			hs := setupTOTPLoginServer(&totptest.FakeService{ExpectedEnabled: tc.enabled, ExpectedEnforced: tc.enforced})
+			hs.authenticator = &fakeAuthenticator{testUser, tc.authModule, nil}
+
+			sc := setupScenarioContext(t, "/login")
+			sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+				c.Req.Header.Set("Content-Type", "application/json")
+				c.Req.Body = io.NopCloser(bytes.NewBufferString(`{"user":"admin","password":"admin"}`))
+				return hs.LoginPost(c)
+			})
+			sc.m.Post(sc.url, sc.defaultHandler)
+			sc.fakeReqNoAssertions("POST", sc.url).exec()
+
+			require.Equal(t, http.StatusOK, sc.resp.Code)
+			var result map[string]interface{}
+			require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &result))
+
+			cookies := map[string]string{}
+			for _, cookie := range sc.resp.Result().Cookies() {
+				cookies[cookie.Name] = cookie.Value
+			}
+
+			if tc.step == "" {
+				assert.Nil(t, result["totp"])
+				assert.NotContains(t, cookies, totpChallengeCookieName)
+				assert.Contains(t, cookies, hs.Cfg.LoginCookieName)
+				return
+			}
+			assert.Equal(t, tc.step, result["totp"])
+			assert.Contains(t, cookies, totpChallengeCookieName)
+			assert.NotContains(t, cookies, hs.Cfg.LoginCookieName)
+		})
+	}
+}
+
+func TestLoginTOTPPost(t *testing.T) {
+	challengeCookie := func(userID int64, expires time.Time) *http.Cookie {
+		value := fmt.Sprintf("%d:%d", userID, expires.Unix())
+		return &http.Cookie{Name: totpChallengeCookieName, Value: hex.EncodeToString([]byte(value))}
+	}
+
+	testCases := []struct {
+		desc          string
+		cookie        *http.Cookie
+		service       *totptest.FakeService
+		code          string
+		status        int
+		recoveryCodes bool
+	}{
+		{
+			desc:    "without a challenge",
+			service: &totptest.FakeService{ExpectedEnabled: true, ValidCode: "123456"},
+			code:    "123456",
+			status:  http.StatusUnauthorized,
+		},
+		{
+			desc:    "with an expired challenge",
+			cookie:  challengeCookie(42, time.Now().Add(-time.Minute)),
+			service: &totptest.FakeService{ExpectedEnabled: true, ValidCode: "123456"},
+			code:    "123456",
+			status:  http.StatusUnauthorized,
+		},
+		{
+			desc:    "with an invalid code",
+			cookie:  challengeCookie(42, time.Now().Add(time.Minute)),
+			service: &totptest.FakeService{ExpectedEnabled: true, ValidCode: "123456"},
+			code:    "654321",
+			status:  http.StatusUnauthorized,
+		},
+		{
+			desc:    "with a valid code",
+			cookie:  challengeCookie(42, time.Now().Add(time.Minute)),
+			service: &totptest.FakeService{ExpectedEnabled: true, ValidCode: "123456"},
+			code:    "123456",
+			status:  http.StatusOK,
+		},
+		{
+			desc:          "with a valid code confirming the enrollment",
+			cookie:        challengeCookie(42, time.Now().Add(time.Minute)),
+			service:       &totptest.FakeService{ValidCode: "123456", ExpectedRecoveryCodes: []string{"abcde-fghij"}},
+			code:          "123456",
+			status:        http.StatusOK,
+			recoveryCodes: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			hs := setupTOTPLoginServer(tc.service)
+
+			sc := setupScenarioContext(t, "/login/totp")
+			sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+				c.Req.Body = io.NopCloser(bytes.NewBufferString(fmt.Sprintf(`{"code":%q}`, tc.code)))
+				return hs.LoginTOTPPost(c)
+			})
+			sc.m.Post(sc.url, sc.defaultHandler)
+			if tc.cookie != nil {
+				sc.fakeReqNoAssertionsWithCookie("POST", sc.url, *tc.cookie).exec()
+			} else {
+				sc.fakeReqNoAssertions("POST", sc.url).exec()
+			}
+
+			require.Equal(t, tc.status, sc.resp.Code)
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var result map[string]interface{}
+			require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &result))
+			assert.Equal(t, "Logged in", result["message"])
+			if tc.recoveryCodes {
+				assert.Equal(t, []interface{}{"abcde-fghij"}, result["recoveryCodes"])
+			} else {
+				assert.Nil(t, result["recoveryCodes"])
+			}
+		})
+	}
+}
+
+type fakeTempUserService struct {
+	tempuser.Service
+
+	invite *models.TempUserDTO
+}
+
+func (s *fakeTempUserService) GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error {
+	query.Result = s.invite
+	return nil
+}
+
+func (s *fakeTempUserService) GetTempUsersQuery(ctx context.Context, query *models.GetTempUsersQuery) error {
+	return nil
+}
+
+func (s *fakeTempUserService) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
+	return nil
+}
+
+func TestNewUserLoginTOTP(t *testing.T) {
+	setup := func(enforced bool) *HTTPServer {
+		hs := setupTOTPLoginServer(&totptest.FakeService{ExpectedEnforced: enforced})
+		hs.bus = bus.ProvideBus(tracing.InitializeTracerForTest())
+		hs.orgService = &orgtest.FakeOrgService{}
+		hs.tempUserService = &fakeTempUserService{invite: &models.TempUserDTO{
+			OrgId: 1, Email: "new@example.org", Role: org.RoleViewer, Code: "code", Status: models.TmpUserInvitePending,
+		}}
+		hs.Login = loginservice.LoginServiceMock{
+			ExpectedUserForm: dtos.AdminCreateUserForm{Login: "new", Email: "new@example.org", Password: "password"},
+			NoExistingOrgId:  -1,
+			GeneratedUserId:  42,
+		}
+		return hs
+	}
+
+	requests := []struct {
+		desc    string
+		url     string
+		body    string
+		handler func(hs *HTTPServer) func(c *models.ReqContext) response.Response
+	}{
+		{
+			desc: "completed invite",
+			url:  "/api/user/invite/complete",
+			body: `{"inviteCode":"code","username":"new","email":"new@example.org","password":"password"}`,
+			handler: func(hs *HTTPServer) func(c *models.ReqContext) response.Response {
+				return hs.CompleteInvite
+			},
+		},
+		{
+			desc: "sign up",
+			url:  "/api/user/signup/step2",
+			body: `{"code":"code","username":"new","email":"new@example.org","password":"password"}`,
+			handler: func(hs *HTTPServer) func(c *models.ReqContext) response.Response {
+				return hs.SignUpStep2
+			},
+		},
+	}
+
+	allowUserSignUp := setting.AllowUserSignUp
+	setting.AllowUserSignUp = true
+	t.Cleanup(func() { setting.AllowUserSignUp = allowUserSignUp })
+
+	for _, req := range requests {
+		for _, enforced := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s with two-factor authentication enforced: %t", req.desc, enforced), func(t *testing.T) {
+				hs := setup(enforced)
+				handler := req.handler(hs)
+
+				sc := setupScenarioContext(t, req.url)
+				sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+					c.Req.Header.Set("Content-Type", "application/json")
+					c.Req.Body = io.NopCloser(bytes.NewBufferString(req.body))
+					return handler(c)
+				})
+				sc.m.Post(sc.url, sc.defaultHandler)
+				sc.fakeReqNoAssertions("POST", sc.url).exec()
+
+				require.Equal(t, http.StatusOK, sc.resp.Code, sc.resp.Body.String())
+				var result map[string]interface{}
+				require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &result))
+
+				cookies := map[string]string{}
+				for _, cookie := range sc.resp.Result().Cookies() {
+					cookies[cookie.Name] = cookie.Value
+				}
+
+				if !enforced {
+					assert.Nil(t, result["totp"])
+					assert.NotContains(t, cookies, totpChallengeCookieName)
+					assert.Contains(t, cookies, hs.Cfg.LoginCookieName)
+					return
+				}
+				// the user has to enroll before they are logged in
+				assert.Equal(t, totpStepEnroll, result["totp"])
+				assert.Contains(t, cookies, totpChallengeCookieName)
+				assert.NotContains(t, cookies, hs.Cfg.LoginCookieName)
+			})
+		}
+	}
+}
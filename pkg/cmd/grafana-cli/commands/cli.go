@@ -34,7 +34,7 @@ func RunCLI(version string) int {
 			},
 			&cli.StringFlag{
 				Name:    "repo",
-				Usage:   "URL to the plugin repository",
+				Usage:   "URL to the plugin repository, or path to a local plugin repository directory",
 				Value:   "https://grafana.com/api/plugins",
 				EnvVars: []string{"GF_PLUGIN_REPO"},
 			},
@@ -160,6 +160,11 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	{
+		Name:   "reset-user-totp",
+		Usage:  "reset-user-totp <login or email>",
+		Action: runRunnerCommand(resetUserTOTPCommand),
+	},
 	{
 		Name:  "data-migration",
 		Usage: "Runs a script that migrates or cleanups data in your database",
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/models"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/services"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/signature"
 	"github.com/grafana/grafana/pkg/plugins/repo"
 	"github.com/grafana/grafana/pkg/plugins/storage"
 )
@@ -52,8 +55,8 @@ func (cmd Command) installCommand(c utils.CommandLine) error {
 	return installPlugin(context.Background(), pluginID, version, c)
 }
 
-// installPlugin downloads the plugin code as a zip file from the Grafana.com API
-// and then extracts the zip into the plugin's directory.
+// installPlugin downloads the plugin code as a zip file from the plugin repository (Grafana.com API by default)
+// and then extracts the zip into the plugin's directory, along with the plugins it depends on.
 func installPlugin(ctx context.Context, pluginID, version string, c utils.CommandLine) error {
 	skipTLSVerify := c.Bool("insecure")
 	repository := repo.New(skipTLSVerify, c.PluginRepoURL(), services.Logger)
@@ -74,22 +77,56 @@ func installPlugin(ctx context.Context, pluginID, version string, c utils.Comman
 	}
 
 	pluginFs := storage.FileSystem(services.Logger, c.PluginDirectory())
-	extractedArchive, err := pluginFs.Add(ctx, pluginID, archive.File)
+	extractedArchive, err := addPluginArchive(ctx, pluginFs, pluginID, archive)
 	if err != nil {
 		return err
 	}
 
-	for _, dep := range extractedArchive.Dependencies {
+	return installDependencies(ctx, repository, pluginFs, extractedArchive, compatOpts, map[string]struct{}{pluginID: {}})
+}
+
+// addPluginArchive verifies the signature of the plugin archive and extracts it into the plugin's directory.
+func addPluginArchive(ctx context.Context, pluginFs storage.Manager, pluginID string, archive *repo.PluginArchive) (*storage.ExtractedPluginArchive, error) {
+	sig, err := signature.VerifyArchive(&archive.File.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", fmt.Sprintf("failed to verify signature of plugin %s", pluginID), err)
+	}
+	if sig.Status == plugins.SignatureUnsigned {
+		services.Logger.Warnf("Plugin %s is unsigned, it is only loaded if it's allowed by allow_loading_unsigned_plugins", pluginID)
+	}
+
+	return pluginFs.Add(ctx, pluginID, archive.File)
+}
+
+// installDependencies installs the dependencies of the plugin archive, and their dependencies. Dependencies which
+// are already installed in the plugin directory are skipped.
+func installDependencies(ctx context.Context, repository repo.Service, pluginFs storage.Manager,
+	archive *storage.ExtractedPluginArchive, compatOpts repo.CompatOpts, visited map[string]struct{}) error {
+	for _, dep := range archive.Dependencies {
+		if _, exists := visited[dep.ID]; exists {
+			continue
+		}
+		visited[dep.ID] = struct{}{}
+
+		if _, err := os.Stat(filepath.Join(filepath.Dir(archive.Path), dep.ID)); err == nil {
+			services.Logger.Debugf("Dependency %s is already installed", dep.ID)
+			continue
+		}
+
 		services.Logger.Infof("Fetching %s dependency...", dep.ID)
 		d, err := repository.GetPluginArchive(ctx, dep.ID, dep.Version, compatOpts)
 		if err != nil {
 			return fmt.Errorf("%v: %w", fmt.Sprintf("failed to download plugin %s from repository", dep.ID), err)
 		}
 
-		_, err = pluginFs.Add(ctx, dep.ID, d.File)
+		depArchive, err := addPluginArchive(ctx, pluginFs, dep.ID, d)
 		if err != nil {
 			return err
 		}
+
+		if err := installDependencies(ctx, repository, pluginFs, depArchive, compatOpts, visited); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/runner"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func resetUserTOTPCommand(c utils.CommandLine, runner runner.Runner) error {
+	login := c.Args().First()
+	if login == "" {
+		return fmt.Errorf("missing login or email of the user")
+	}
+
+	usr, err := runner.UserService.GetByLogin(context.Background(), &user.GetUserByLoginQuery{LoginOrEmail: login})
+	if err != nil {
+		return fmt.Errorf("could not read user from database. Error: %v", err)
+	}
+
+	if err := runner.TOTPService.Disable(context.Background(), usr.ID); err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication: %w", err)
+	}
+
+	logger.Infof("\n")
+	logger.Infof("Two-factor authentication of %s reset successfully %s", usr.Login, color.GreenString("✔"))
+
+	return nil
+}
@@ -3,6 +3,7 @@ package expr
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp"
@@ -11,6 +12,13 @@ import (
 	"gonum.org/v1/gonum/graph/topo"
 )
 
+var (
+	// ErrUnknownDependency is returned when a node needs a reference ID which is not the reference ID of a node.
+	ErrUnknownDependency = errors.New("unknown dependency")
+	// ErrDependencyCycle is returned when a node needs itself, or nodes need each other in a cycle.
+	ErrDependencyCycle = errors.New("dependency cycle")
+)
+
 // NodeType is the type of a DPNode. Currently either a expression command or datasource query.
 type NodeType int
 
@@ -106,6 +114,48 @@ func buildExecutionOrder(graph *simple.DirectedGraph) ([]Node, error) {
 	return nodes, nil
 }
 
+// DependencyNode is a node of a dependency graph which needs the results of the nodes of other reference IDs.
+type DependencyNode interface {
+	ID() int64 // ID() allows the gonum graph node interface to be fulfilled
+	RefID() string
+	NeedsVars() []string
+}
+
+// OrderByDependency returns the nodes ordered by dependency like the nodes of a data pipeline: the nodes needed by
+// a node come before it. The IDs and reference IDs of the nodes must be unique.
+func OrderByDependency(nodes []DependencyNode) ([]DependencyNode, error) {
+	graph := simple.NewDirectedGraph()
+	registry := make(map[string]DependencyNode, len(nodes))
+	for _, node := range nodes {
+		graph.AddNode(node)
+		registry[node.RefID()] = node
+	}
+
+	for _, node := range nodes {
+		for _, neededVar := range node.NeedsVars() {
+			neededNode, ok := registry[neededVar]
+			if !ok {
+				return nil, fmt.Errorf("%w: '%v' needs '%v'", ErrUnknownDependency, node.RefID(), neededVar)
+			}
+			if neededNode.ID() == node.ID() {
+				return nil, fmt.Errorf("%w: '%v' needs itself", ErrDependencyCycle, node.RefID())
+			}
+			graph.SetEdge(graph.NewEdge(neededNode, node))
+		}
+	}
+
+	sortedNodes, err := topo.Sort(graph)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDependencyCycle, err)
+	}
+
+	res := make([]DependencyNode, len(sortedNodes))
+	for i, v := range sortedNodes {
+		res[i] = v.(DependencyNode)
+	}
+	return res, nil
+}
+
 // buildNodeRegistry returns a lookup table for reference IDs to respective node.
 func buildNodeRegistry(g *simple.DirectedGraph) map[string]Node {
 	res := make(map[string]Node)
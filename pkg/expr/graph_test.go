@@ -242,6 +242,49 @@ func TestServicebuildPipeLine(t *testing.T) {
 	}
 }
 
+type dependencyNode struct {
+	id    int64
+	refID string
+	needs []string
+}
+
+func (n *dependencyNode) ID() int64           { return n.id }
+func (n *dependencyNode) RefID() string       { return n.refID }
+func (n *dependencyNode) NeedsVars() []string { return n.needs }
+
+func TestOrderByDependency(t *testing.T) {
+	refIDs := func(nodes []DependencyNode) []string {
+		ids := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			ids = append(ids, n.RefID())
+		}
+		return ids
+	}
+
+	t.Run("nodes come after the nodes they need", func(t *testing.T) {
+		nodes, err := OrderByDependency([]DependencyNode{
+			&dependencyNode{id: 0, refID: "A", needs: []string{"B"}},
+			&dependencyNode{id: 1, refID: "B"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"B", "A"}, refIDs(nodes))
+	})
+
+	t.Run("unknown dependencies, self references and cycles are rejected", func(t *testing.T) {
+		_, err := OrderByDependency([]DependencyNode{&dependencyNode{id: 0, refID: "A", needs: []string{"B"}}})
+		require.ErrorIs(t, err, ErrUnknownDependency)
+
+		_, err = OrderByDependency([]DependencyNode{&dependencyNode{id: 0, refID: "A", needs: []string{"A"}}})
+		require.ErrorIs(t, err, ErrDependencyCycle)
+
+		_, err = OrderByDependency([]DependencyNode{
+			&dependencyNode{id: 0, refID: "A", needs: []string{"B"}},
+			&dependencyNode{id: 1, refID: "B", needs: []string{"A"}},
+		})
+		require.ErrorIs(t, err, ErrDependencyCycle)
+	})
+}
+
 func getRefIDOrder(nodes []Node) []string {
 	ids := make([]string, 0, len(nodes))
 	for _, n := range nodes {
@@ -46,3 +46,14 @@ var saveInvalidLoginAttempt = func(ctx context.Context, query *models.LoginUserQ
 
 	return loginAttemptService.CreateLoginAttempt(ctx, &loginAttemptCommand)
 }
+
+// ValidateLoginAttempts returns ErrTooManyLoginAttempts when there were too many invalid login attempts of the user
+// recently. The invalid codes of the two-factor authentication of a login are counted as invalid login attempts too.
+func ValidateLoginAttempts(ctx context.Context, query *models.LoginUserQuery, loginAttemptService loginattempt.Service) error {
+	return validateLoginAttempts(ctx, query, loginAttemptService)
+}
+
+// SaveInvalidLoginAttempt records an invalid login attempt of the user.
+func SaveInvalidLoginAttempt(ctx context.Context, query *models.LoginUserQuery, loginAttemptService loginattempt.Service) error {
+	return saveInvalidLoginAttempt(ctx, query, loginAttemptService)
+}
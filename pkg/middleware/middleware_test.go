@@ -34,6 +34,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
 	"github.com/grafana/grafana/pkg/setting"
@@ -213,6 +214,25 @@ func TestMiddlewareContext(t *testing.T) {
 		assert.Equal(t, "Expired API key", sc.respJson["message"])
 	})
 
+	middlewareScenario(t, "Valid service account token with permissions", func(t *testing.T, sc *scenarioContext) {
+		const orgID int64 = 12
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		serviceAccountID := int64(3)
+		permissions := `[{"action":"dashboards:read","scope":"folders:uid:a"},{"action":"users:read"}]`
+		sc.apiKeyService.ExpectedAPIKey = &apikey.APIKey{OrgId: orgID, Key: keyhash, ServiceAccountId: &serviceAccountID, Permissions: &permissions}
+		sc.userService.ExpectedSignedInUser = &user.SignedInUser{UserID: serviceAccountID, OrgID: orgID, OrgRole: org.RoleAdmin}
+
+		sc.fakeReq("GET", "/").withValidApiKey().exec()
+
+		require.Equal(t, 200, sc.resp.Code)
+		assert.True(t, sc.context.IsSignedIn)
+		// the role of the service account is kept to load its permissions
+		assert.Equal(t, org.RoleAdmin, sc.context.OrgRole)
+		assert.Equal(t, map[string][]string{"dashboards:read": {"folders:uid:a"}, "users:read": {""}}, sc.context.TokenPermissions)
+	})
+
 	middlewareScenario(t, "Non-expired auth token in cookie which is not being rotated", func(
 		t *testing.T, sc *scenarioContext) {
 		const userID int64 = 12
@@ -704,7 +724,7 @@ func getContextHandler(t *testing.T, cfg *setting.Cfg, mockSQLStore *mockstore.S
 	tracer := tracing.InitializeTracerForTest()
 	authProxy := authproxy.ProvideAuthProxy(cfg, remoteCacheSvc, loginService, userService, mockSQLStore)
 	authenticator := &logintest.AuthenticatorFake{ExpectedUser: &user.User{}}
-	return contexthandler.ProvideService(cfg, userAuthTokenSvc, authJWTSvc, remoteCacheSvc, renderSvc, mockSQLStore, tracer, authProxy, loginService, apiKeyService, authenticator, userService)
+	return contexthandler.ProvideService(cfg, userAuthTokenSvc, authJWTSvc, remoteCacheSvc, renderSvc, mockSQLStore, tracer, authProxy, loginService, apiKeyService, authenticator, userService, &totptest.FakeService{})
 }
 
 type fakeRenderService struct {
@@ -28,6 +28,9 @@ type ReqContext struct {
 
 	PerfmonTimer   prometheus.Summary
 	LookupTokenErr error
+	// TokenPermissions are the permissions, grouped by action, the service account token of the request is
+	// restricted to, nil when the token isn't restricted
+	TokenPermissions map[string][]string
 }
 
 // Handle handles and logs error by given status.
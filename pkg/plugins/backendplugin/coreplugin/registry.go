@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/grafana/pkg/tsdb/opentsdb"
 	"github.com/grafana/grafana/pkg/tsdb/postgres"
 	"github.com/grafana/grafana/pkg/tsdb/prometheus"
+	"github.com/grafana/grafana/pkg/tsdb/sqlite"
 	"github.com/grafana/grafana/pkg/tsdb/tempo"
 	"github.com/grafana/grafana/pkg/tsdb/testdatasource"
 )
@@ -40,6 +41,7 @@ const (
 	PostgreSQL      = "postgres"
 	MySQL           = "mysql"
 	MSSQL           = "mssql"
+	SQLite          = "sqlite"
 	Grafana         = "grafana"
 )
 
@@ -62,7 +64,7 @@ func NewRegistry(store map[string]backendplugin.PluginFactoryFunc) *Registry {
 func ProvideCoreRegistry(am *azuremonitor.Service, cw *cloudwatch.CloudWatchService, cm *cloudmonitoring.Service,
 	es *elasticsearch.Service, grap *graphite.Service, idb *influxdb.Service, lk *loki.Service, otsdb *opentsdb.Service,
 	pr *prometheus.Service, t *tempo.Service, td *testdatasource.Service, pg *postgres.Service, my *mysql.Service,
-	ms *mssql.Service, graf *grafanads.Service) *Registry {
+	ms *mssql.Service, sl *sqlite.Service, graf *grafanads.Service) *Registry {
 	return NewRegistry(map[string]backendplugin.PluginFactoryFunc{
 		CloudWatch:      asBackendPlugin(cw.Executor),
 		CloudMonitoring: asBackendPlugin(cm),
@@ -78,6 +80,7 @@ func ProvideCoreRegistry(am *azuremonitor.Service, cw *cloudwatch.CloudWatchServ
 		PostgreSQL:      asBackendPlugin(pg),
 		MySQL:           asBackendPlugin(my),
 		MSSQL:           asBackendPlugin(ms),
+		SQLite:          asBackendPlugin(sl),
 		Grafana:         asBackendPlugin(graf),
 	})
 }
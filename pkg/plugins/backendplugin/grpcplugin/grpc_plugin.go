@@ -107,6 +107,19 @@ func (p *grpcPlugin) Exited() bool {
 	return true
 }
 
+func (p *grpcPlugin) PID() (int, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.client == nil || p.client.Exited() {
+		return 0, false
+	}
+	rc := p.client.ReattachConfig()
+	if rc == nil {
+		return 0, false
+	}
+	return rc.Pid, true
+}
+
 func (p *grpcPlugin) Decommission() error {
 	p.mutex.RLock()
 	defer p.mutex.RUnlock()
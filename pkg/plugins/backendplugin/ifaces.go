@@ -23,3 +23,9 @@ type Plugin interface {
 	backend.CallResourceHandler
 	backend.StreamHandler
 }
+
+// ProcessPlugin is implemented by backend plugins running in a separate process.
+type ProcessPlugin interface {
+	// PID returns the ID of the plugin process, if it is running.
+	PID() (int, bool)
+}
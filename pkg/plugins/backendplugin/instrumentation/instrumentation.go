@@ -21,6 +21,24 @@ var (
 		Help:      "Plugin request duration",
 		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 25, 50, 100},
 	}, []string{"plugin_id", "endpoint"})
+
+	pluginRestartCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "plugin_process_restarts_total",
+		Help:      "The total amount of backend plugin process restarts",
+	}, []string{"plugin_id"})
+
+	pluginUptime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Name:      "plugin_process_uptime_seconds",
+		Help:      "Uptime of the backend plugin process, 0 when the process is not running",
+	}, []string{"plugin_id"})
+
+	pluginCircuitBreakerOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Name:      "plugin_process_circuit_breaker_open",
+		Help:      "1 when the backend plugin process is not restarted because it is crash looping",
+	}, []string{"plugin_id"})
 )
 
 // instrumentPluginRequest instruments success rate and latency of `fn`
@@ -60,3 +78,22 @@ func InstrumentCallResourceRequest(pluginID string, fn func() error) error {
 func InstrumentQueryDataRequest(pluginID string, fn func() error) error {
 	return instrumentPluginRequest(pluginID, "queryData", fn)
 }
+
+// InstrumentPluginRestart counts a restart of a backend plugin process.
+func InstrumentPluginRestart(pluginID string) {
+	pluginRestartCounter.WithLabelValues(pluginID).Inc()
+}
+
+// InstrumentPluginUptime sets the uptime of a backend plugin process.
+func InstrumentPluginUptime(pluginID string, uptime time.Duration) {
+	pluginUptime.WithLabelValues(pluginID).Set(uptime.Seconds())
+}
+
+// InstrumentPluginCircuitBreaker sets whether the circuit breaker of a backend plugin process is open.
+func InstrumentPluginCircuitBreaker(pluginID string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	pluginCircuitBreakerOpen.WithLabelValues(pluginID).Set(value)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-azure-sdk-go/azsettings"
 
@@ -18,6 +19,17 @@ type Cfg struct {
 
 	PluginSettings       setting.PluginSettings
 	PluginsAllowUnsigned []string
+	PluginRepositoryURL  string
+
+	// Backend plugin process supervision
+	PluginSupervision          PluginSupervision
+	PluginSupervisionOverrides map[string]PluginSupervision
+	PluginsCgroupPath          string
+
+	// Plugin hot reload
+	PluginsHotReload         bool
+	PluginsHotReloadInterval time.Duration
+	PluginsDrainTimeout      time.Duration
 
 	EnterpriseLicensePath string
 
@@ -52,16 +64,30 @@ func NewCfg(settingProvider setting.Provider, grafanaCfg *setting.Cfg) *Cfg {
 		allowedUnsigned = strings.Split(settingProvider.KeyValue("plugins", "allow_loading_unsigned_plugins").Value(), ",")
 	}
 
+	pluginSettings := extractPluginSettings(settingProvider)
+	supervision, supervisionOverrides := extractPluginSupervision(logger, plugins, pluginSettings)
+	cgroupPath := plugins.KeyValue("backend_cgroup_path").MustString("")
+	if cgroupPath == "" && hasResourceLimits(supervision, supervisionOverrides) {
+		logger.Warn("The resource limits of backend plugins are ignored since backend_cgroup_path is not configured")
+	}
+
 	return &Cfg{
-		log:                     logger,
-		PluginsPath:             grafanaCfg.PluginsPath,
-		BuildVersion:            grafanaCfg.BuildVersion,
-		DevMode:                 settingProvider.KeyValue("", "app_mode").MustBool(grafanaCfg.Env == setting.Dev),
-		EnterpriseLicensePath:   settingProvider.KeyValue("enterprise", "license_path").MustString(grafanaCfg.EnterpriseLicensePath),
-		PluginSettings:          extractPluginSettings(settingProvider),
-		PluginsAllowUnsigned:    allowedUnsigned,
-		AWSAllowedAuthProviders: allowedAuth,
-		AWSAssumeRoleEnabled:    aws.KeyValue("assume_role_enabled").MustBool(grafanaCfg.AWSAssumeRoleEnabled),
+		log:                        logger,
+		PluginsPath:                grafanaCfg.PluginsPath,
+		BuildVersion:               grafanaCfg.BuildVersion,
+		DevMode:                    settingProvider.KeyValue("", "app_mode").MustBool(grafanaCfg.Env == setting.Dev),
+		EnterpriseLicensePath:      settingProvider.KeyValue("enterprise", "license_path").MustString(grafanaCfg.EnterpriseLicensePath),
+		PluginSettings:             pluginSettings,
+		PluginsAllowUnsigned:       allowedUnsigned,
+		PluginRepositoryURL:        grafanaCfg.PluginRepositoryURL,
+		PluginSupervision:          supervision,
+		PluginSupervisionOverrides: supervisionOverrides,
+		PluginsCgroupPath:          cgroupPath,
+		PluginsHotReload:           plugins.KeyValue("hot_reload").MustBool(false),
+		PluginsHotReloadInterval:   plugins.KeyValue("hot_reload_interval").MustDuration(10 * time.Second),
+		PluginsDrainTimeout:        plugins.KeyValue("drain_timeout").MustDuration(30 * time.Second),
+		AWSAllowedAuthProviders:    allowedAuth,
+		AWSAssumeRoleEnabled:       aws.KeyValue("assume_role_enabled").MustBool(grafanaCfg.AWSAssumeRoleEnabled),
 		Azure: &azsettings.AzureSettings{
 			Cloud:                   azure.KeyValue("cloud").MustString(grafanaCfg.Azure.Cloud),
 			ManagedIdentityEnabled:  azure.KeyValue("managed_identity_enabled").MustBool(grafanaCfg.Azure.ManagedIdentityEnabled),
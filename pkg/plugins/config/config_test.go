@@ -2,9 +2,11 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/ini.v1"
 
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/setting"
 
 	"github.com/stretchr/testify/require"
@@ -36,3 +38,44 @@ func TestPluginSettings(t *testing.T) {
 	require.Equal(t, ps["secret-plugin"]["secret_key"], "secret")
 	require.Equal(t, ps["secret-plugin"]["normal_key"], "not a secret")
 }
+
+func TestPluginSupervision(t *testing.T) {
+	raw, err := ini.Load([]byte(`
+		[plugins]
+		backend_max_restarts = 3
+		backend_restart_backoff = 2s
+
+		[plugin.test-datasource]
+		backend_restart_window = 1m
+		backend_memory_limit_mb = 512
+		backend_cpu_limit = 0.5
+		backend_max_restart_backoff = invalid
+		foo = bar`))
+	require.NoError(t, err)
+
+	settings := &setting.OSSImpl{Cfg: &setting.Cfg{Raw: raw}}
+	defaults, overrides := extractPluginSupervision(log.NewNopLogger(), settings.Section("plugins"), extractPluginSettings(settings))
+
+	require.Equal(t, PluginSupervision{
+		RestartBackoff:        2 * time.Second,
+		MaxRestartBackoff:     time.Minute,
+		MaxRestarts:           3,
+		RestartWindow:         5 * time.Minute,
+		CircuitBreakerTimeout: 5 * time.Minute,
+	}, defaults)
+	require.Len(t, overrides, 1)
+	require.Equal(t, PluginSupervision{
+		RestartBackoff:        2 * time.Second,
+		MaxRestartBackoff:     time.Minute,
+		MaxRestarts:           3,
+		RestartWindow:         time.Minute,
+		CircuitBreakerTimeout: 5 * time.Minute,
+		MemoryLimitMB:         512,
+		CPULimit:              0.5,
+	}, overrides["test-datasource"])
+
+	cfg := &Cfg{PluginSupervision: defaults, PluginSupervisionOverrides: overrides}
+	require.Equal(t, defaults, cfg.Supervision("other-datasource"))
+	require.Equal(t, overrides["test-datasource"], cfg.Supervision("test-datasource"))
+	require.Equal(t, defaultPluginSupervision, (&Cfg{}).Supervision("test-datasource"))
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// PluginSupervision is the policy used to supervise the process of a backend plugin.
+type PluginSupervision struct {
+	// RestartBackoff is the delay before restarting a crashed plugin process, it doubles
+	// with every crash within RestartWindow up to MaxRestartBackoff.
+	RestartBackoff    time.Duration
+	MaxRestartBackoff time.Duration
+	// MaxRestarts is the number of crashes within RestartWindow after which the circuit breaker
+	// opens, and the plugin process is not restarted before CircuitBreakerTimeout.
+	MaxRestarts           int
+	RestartWindow         time.Duration
+	CircuitBreakerTimeout time.Duration
+
+	// MemoryLimitMB is the maximum memory of the plugin process, 0 means unlimited.
+	MemoryLimitMB int64
+	// CPULimit is the maximum number of CPUs used by the plugin process, 0 means unlimited.
+	CPULimit float64
+}
+
+var defaultPluginSupervision = PluginSupervision{
+	RestartBackoff:        time.Second,
+	MaxRestartBackoff:     time.Minute,
+	MaxRestarts:           5,
+	RestartWindow:         5 * time.Minute,
+	CircuitBreakerTimeout: 5 * time.Minute,
+}
+
+const (
+	restartBackoffKey        = "backend_restart_backoff"
+	maxRestartBackoffKey     = "backend_max_restart_backoff"
+	maxRestartsKey           = "backend_max_restarts"
+	restartWindowKey         = "backend_restart_window"
+	circuitBreakerTimeoutKey = "backend_circuit_breaker_timeout"
+	memoryLimitKey           = "backend_memory_limit_mb"
+	cpuLimitKey              = "backend_cpu_limit"
+)
+
+var supervisionKeys = map[string]struct{}{
+	restartBackoffKey:        {},
+	maxRestartBackoffKey:     {},
+	maxRestartsKey:           {},
+	restartWindowKey:         {},
+	circuitBreakerTimeoutKey: {},
+	memoryLimitKey:           {},
+	cpuLimitKey:              {},
+}
+
+// IsSupervisionSetting returns true if `key` of a [plugin.<plugin id>] section is part of the supervision
+// policy of the plugin, rather than a setting of the plugin itself.
+func IsSupervisionSetting(key string) bool {
+	_, exists := supervisionKeys[key]
+	return exists
+}
+
+// Supervision returns the supervision policy of the backend plugin process of `pluginID`.
+func (c *Cfg) Supervision(pluginID string) PluginSupervision {
+	if s, exists := c.PluginSupervisionOverrides[pluginID]; exists {
+		return s
+	}
+	if c.PluginSupervision == (PluginSupervision{}) {
+		return defaultPluginSupervision
+	}
+	return c.PluginSupervision
+}
+
+// extractPluginSupervision reads the default supervision policy from the [plugins] section, and the
+// supervision policies of specific plugins from their [plugin.<plugin id>] section.
+func extractPluginSupervision(logger log.Logger, plugins setting.Section, ps setting.PluginSettings) (
+	PluginSupervision, map[string]PluginSupervision) {
+	values := map[string]string{}
+	for key := range supervisionKeys {
+		values[key] = plugins.KeyValue(key).Value()
+	}
+	defaults := parsePluginSupervision(logger, "plugins", defaultPluginSupervision, values)
+
+	overrides := map[string]PluginSupervision{}
+	for pluginID, settings := range ps {
+		values := map[string]string{}
+		for key, value := range settings {
+			if IsSupervisionSetting(key) {
+				values[key] = value
+			}
+		}
+		if len(values) > 0 {
+			overrides[pluginID] = parsePluginSupervision(logger, "plugin."+pluginID, defaults, values)
+		}
+	}
+
+	return defaults, overrides
+}
+
+func parsePluginSupervision(logger log.Logger, section string, defaults PluginSupervision, values map[string]string) PluginSupervision {
+	s := defaults
+	parseDuration := func(key string, dst *time.Duration) {
+		if values[key] == "" {
+			return
+		}
+		d, err := time.ParseDuration(values[key])
+		if err != nil || d <= 0 {
+			logger.Warn("Invalid plugin supervision setting, using default", "section", section, "key", key, "value", values[key])
+			return
+		}
+		*dst = d
+	}
+	parseDuration(restartBackoffKey, &s.RestartBackoff)
+	parseDuration(maxRestartBackoffKey, &s.MaxRestartBackoff)
+	parseDuration(restartWindowKey, &s.RestartWindow)
+	parseDuration(circuitBreakerTimeoutKey, &s.CircuitBreakerTimeout)
+
+	if v := values[maxRestartsKey]; v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			s.MaxRestarts = n
+		} else {
+			logger.Warn("Invalid plugin supervision setting, using default", "section", section, "key", maxRestartsKey, "value", v)
+		}
+	}
+	if v := values[memoryLimitKey]; v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			s.MemoryLimitMB = n
+		} else {
+			logger.Warn("Invalid plugin supervision setting, using default", "section", section, "key", memoryLimitKey, "value", v)
+		}
+	}
+	if v := values[cpuLimitKey]; v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			s.CPULimit = n
+		} else {
+			logger.Warn("Invalid plugin supervision setting, using default", "section", section, "key", cpuLimitKey, "value", v)
+		}
+	}
+
+	if s.MaxRestartBackoff < s.RestartBackoff {
+		s.MaxRestartBackoff = s.RestartBackoff
+	}
+	return s
+}
+
+// hasResourceLimits returns true if the default or a plugin specific supervision policy limits the memory or CPU.
+func hasResourceLimits(defaults PluginSupervision, overrides map[string]PluginSupervision) bool {
+	if defaults.MemoryLimitMB > 0 || defaults.CPULimit > 0 {
+		return true
+	}
+	for _, s := range overrides {
+		if s.MemoryLimitMB > 0 || s.CPULimit > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package plugins
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightRequests tracks the requests handled by a plugin, so that the plugin can be drained
+// before it is stopped or replaced.
+type inflightRequests struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	drained  chan struct{}
+}
+
+// start registers a new request, and returns false if the plugin is draining.
+func (r *inflightRequests) start() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return false
+	}
+	r.count++
+	return true
+}
+
+func (r *inflightRequests) done() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count--
+	if r.count == 0 && r.drained != nil {
+		close(r.drained)
+		r.drained = nil
+	}
+}
+
+// drain rejects new requests, and waits until the in-flight requests are completed or ctx is done.
+func (r *inflightRequests) drain(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	if r.count == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.drained == nil {
+		r.drained = make(chan struct{})
+	}
+	drained := r.drained
+	r.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -23,6 +23,15 @@ type Installer interface {
 	Remove(ctx context.Context, pluginID string) error
 }
 
+// Reloader is responsible for reloading external plugins without restarting Grafana.
+type Reloader interface {
+	// Reload replaces an external plugin by the plugin found in its directory.
+	Reload(ctx context.Context, pluginID string) error
+	// ReloadAll loads the external plugins which were added, and replaces or unloads the external plugins
+	// which were changed or removed since they were loaded.
+	ReloadAll(ctx context.Context) error
+}
+
 type PluginSource struct {
 	Class Class
 	Paths []string
@@ -36,6 +36,7 @@ func (i *FakePluginInstaller) Remove(ctx context.Context, pluginID string) error
 
 type FakeLoader struct {
 	LoadFunc   func(_ context.Context, _ plugins.Class, paths []string) ([]*plugins.Plugin, error)
+	ReloadFunc func(_ context.Context, _ plugins.Class, paths []string) ([]*plugins.Plugin, error)
 	UnloadFunc func(_ context.Context, _ string) error
 }
 
@@ -46,6 +47,13 @@ func (l *FakeLoader) Load(ctx context.Context, class plugins.Class, paths []stri
 	return nil, nil
 }
 
+func (l *FakeLoader) Reload(ctx context.Context, class plugins.Class, paths []string) ([]*plugins.Plugin, error) {
+	if l.ReloadFunc != nil {
+		return l.ReloadFunc(ctx, class, paths)
+	}
+	return nil, nil
+}
+
 func (l *FakeLoader) Unload(ctx context.Context, pluginID string) error {
 	if l.UnloadFunc != nil {
 		return l.UnloadFunc(ctx, pluginID)
@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/logger"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader"
 	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/plugins/manager/signature"
 	"github.com/grafana/grafana/pkg/plugins/repo"
 	"github.com/grafana/grafana/pkg/plugins/storage"
 )
@@ -99,27 +100,18 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 		}
 	}
 
-	extractedArchive, err := m.pluginStorage.Add(ctx, pluginID, pluginArchive.File)
+	extractedArchive, err := m.addArchive(ctx, pluginID, pluginArchive)
 	if err != nil {
 		return err
 	}
 
 	// download dependency plugins
 	pathsToScan := []string{extractedArchive.Path}
-	for _, dep := range extractedArchive.Dependencies {
-		m.log.Info("Fetching %s dependencies...", dep.ID)
-		d, err := m.pluginRepo.GetPluginArchive(ctx, dep.ID, dep.Version, compatOpts)
-		if err != nil {
-			return fmt.Errorf("%v: %w", fmt.Sprintf("failed to download plugin %s from repository", dep.ID), err)
-		}
-
-		depArchive, err := m.pluginStorage.Add(ctx, dep.ID, d.File)
-		if err != nil {
-			return err
-		}
-
-		pathsToScan = append(pathsToScan, depArchive.Path)
+	depPaths, err := m.addDependencies(ctx, extractedArchive, compatOpts, map[string]struct{}{pluginID: {}})
+	if err != nil {
+		return err
 	}
+	pathsToScan = append(pathsToScan, depPaths...)
 
 	_, err = m.pluginLoader.Load(ctx, plugins.External, pathsToScan)
 	if err != nil {
@@ -130,6 +122,58 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 	return nil
 }
 
+// addArchive verifies the signature of the plugin archive and extracts it to the plugin storage.
+func (m *PluginInstaller) addArchive(ctx context.Context, pluginID string, archive *repo.PluginArchive) (*storage.ExtractedPluginArchive, error) {
+	if archive.File != nil {
+		sig, err := signature.VerifyArchive(&archive.File.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", fmt.Sprintf("failed to verify signature of plugin %s", pluginID), err)
+		}
+		if sig.Status == plugins.SignatureUnsigned {
+			m.log.Warn("Installing unsigned plugin", "pluginID", pluginID)
+		}
+	}
+
+	return m.pluginStorage.Add(ctx, pluginID, archive.File)
+}
+
+// addDependencies installs the dependencies of an extracted plugin archive, and their dependencies, from the
+// plugin repository. Dependencies which are already installed or visited are skipped.
+func (m *PluginInstaller) addDependencies(ctx context.Context, archive *storage.ExtractedPluginArchive,
+	compatOpts repo.CompatOpts, visited map[string]struct{}) ([]string, error) {
+	var paths []string
+	for _, dep := range archive.Dependencies {
+		if _, exists := visited[dep.ID]; exists {
+			continue
+		}
+		visited[dep.ID] = struct{}{}
+
+		if _, exists := m.plugin(ctx, dep.ID); exists {
+			m.log.Debug("Dependency is already installed", "pluginID", dep.ID)
+			continue
+		}
+
+		m.log.Info("Fetching dependency", "pluginID", dep.ID, "version", dep.Version)
+		d, err := m.pluginRepo.GetPluginArchive(ctx, dep.ID, dep.Version, compatOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", fmt.Sprintf("failed to download plugin %s from repository", dep.ID), err)
+		}
+
+		depArchive, err := m.addArchive(ctx, dep.ID, d)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, depArchive.Path)
+
+		depPaths, err := m.addDependencies(ctx, depArchive, compatOpts, visited)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, depPaths...)
+	}
+	return paths, nil
+}
+
 func (m *PluginInstaller) Remove(ctx context.Context, pluginID string) error {
 	plugin, exists := m.plugin(ctx, pluginID)
 	if !exists {
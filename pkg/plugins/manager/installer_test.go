@@ -163,6 +163,49 @@ func TestPluginManager_Add_Remove(t *testing.T) {
 		})
 	})
 
+	t.Run("Adding a plugin installs its dependencies recursively", func(t *testing.T) {
+		deps := map[string][]*storage.Dependency{
+			"test-app":        {{ID: "test-datasource", Version: "1.0.0"}, {ID: "installed-panel"}},
+			"test-datasource": {{ID: "test-panel", Version: "2.0.0"}},
+			"test-panel":      {{ID: "test-app"}},
+		}
+
+		var fetched []string
+		pluginRepo := &fakes.FakePluginRepo{
+			GetPluginArchiveFunc: func(_ context.Context, id, version string, _ repo.CompatOpts) (*repo.PluginArchive, error) {
+				fetched = append(fetched, id+"@"+version)
+				return &repo.PluginArchive{File: &zip.ReadCloser{Reader: zip.Reader{File: []*zip.File{{
+					FileHeader: zip.FileHeader{Name: id},
+				}}}}}, nil
+			},
+		}
+		fs := &fakes.FakePluginStorage{
+			AddFunc: func(_ context.Context, id string, _ *zip.ReadCloser) (*storage.ExtractedPluginArchive, error) {
+				return &storage.ExtractedPluginArchive{ID: id, Path: "/data/plugin/" + id, Dependencies: deps[id]}, nil
+			},
+			Store: map[string]struct{}{},
+		}
+		var loadedPaths []string
+		loader := &fakes.FakeLoader{
+			LoadFunc: func(_ context.Context, _ plugins.Class, paths []string) ([]*plugins.Plugin, error) {
+				loadedPaths = paths
+				return nil, nil
+			},
+		}
+		reg := &fakes.FakePluginRegistry{
+			Store: map[string]*plugins.Plugin{
+				"installed-panel": createPlugin(t, "installed-panel", plugins.External, true, false),
+			},
+		}
+
+		inst := New(reg, loader, pluginRepo, fs)
+		err := inst.Add(context.Background(), "test-app", "", plugins.CompatOpts{})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"test-app@", "test-datasource@1.0.0", "test-panel@2.0.0"}, fetched)
+		require.Equal(t, []string{"/data/plugin/test-app", "/data/plugin/test-datasource", "/data/plugin/test-panel"}, loadedPaths)
+	})
+
 	t.Run("Can't update core or bundled plugin", func(t *testing.T) {
 		tcs := []struct {
 			class plugins.Class
@@ -105,7 +105,7 @@ func (ps pluginSettings) asEnvVar(prefix string, hostEnv []string) []string {
 func getPluginSettings(pluginID string, cfg *config.Cfg) pluginSettings {
 	ps := pluginSettings{}
 	for k, v := range cfg.PluginSettings[pluginID] {
-		if k == "path" || strings.ToLower(k) == "id" {
+		if k == "path" || strings.ToLower(k) == "id" || config.IsSupervisionSetting(k) {
 			continue
 		}
 		ps[k] = v
@@ -152,7 +152,8 @@ func TestInitializer_envVars(t *testing.T) {
 				EnterpriseLicensePath: "/path/to/ent/license",
 				PluginSettings: map[string]map[string]string{
 					"test": {
-						"custom_env_var": "customVal",
+						"custom_env_var":       "customVal",
+						"backend_max_restarts": "3",
 					},
 				},
 			},
@@ -51,8 +51,8 @@ type Loader struct {
 }
 
 func ProvideService(cfg *config.Cfg, license models.Licensing, authorizer plugins.PluginLoaderAuthorizer,
-	pluginRegistry registry.Service, backendProvider plugins.BackendFactoryProvider) *Loader {
-	return New(cfg, license, authorizer, pluginRegistry, backendProvider, process.NewManager(pluginRegistry),
+	pluginRegistry registry.Service, backendProvider plugins.BackendFactoryProvider, processManager process.Service) *Loader {
+	return New(cfg, license, authorizer, pluginRegistry, backendProvider, processManager,
 		storage.FileSystem(logger.NewLogger("loader.fs"), cfg.PluginsPath))
 }
 
@@ -77,10 +77,55 @@ func (l *Loader) Load(ctx context.Context, class plugins.Class, paths []string)
 		return nil, err
 	}
 
-	return l.loadPlugins(ctx, class, pluginJSONPaths)
+	return l.loadPlugins(ctx, class, pluginJSONPaths, nil)
 }
 
-func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSONPaths []string) ([]*plugins.Plugin, error) {
+// Reload loads the plugins found in the provided file system paths like Load, but registered plugins of `class`
+// located in the paths are replaced by the plugin found in their directory, or unloaded if their directory was
+// removed. A plugin is only replaced once the new version passed the signature validation. Unlike Unload,
+// the files of unloaded plugins are not removed from the plugin storage.
+func (l *Loader) Reload(ctx context.Context, class plugins.Class, paths []string) ([]*plugins.Plugin, error) {
+	var existingPaths []string
+	for _, path := range paths {
+		if exists, err := fs.Exists(path); err == nil && exists {
+			existingPaths = append(existingPaths, path)
+		}
+	}
+
+	pluginJSONPaths, err := l.pluginFinder.Find(existingPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	foundDirs := make(map[string]struct{})
+	for _, pluginJSONPath := range pluginJSONPaths {
+		if pluginJSONAbsPath, err := filepath.Abs(pluginJSONPath); err == nil {
+			foundDirs[filepath.Dir(pluginJSONAbsPath)] = struct{}{}
+		}
+	}
+
+	replaceable := make(map[string]*plugins.Plugin)
+	for _, p := range l.pluginRegistry.Plugins(ctx) {
+		if p.Class != class || !withinPaths(p.PluginDir, paths) {
+			continue
+		}
+		if _, exists := foundDirs[p.PluginDir]; !exists {
+			l.log.Info("Unloading plugin as its directory was removed", "pluginID", p.ID, "path", p.PluginDir)
+			if err := l.stop(ctx, p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		replaceable[p.ID] = p
+	}
+
+	return l.loadPlugins(ctx, class, pluginJSONPaths, replaceable)
+}
+
+// loadPlugins loads the plugins of the provided plugin.json paths. Registered plugins are skipped,
+// unless they are part of `replaceable`, in which case they are stopped and replaced by the new plugin.
+func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSONPaths []string,
+	replaceable map[string]*plugins.Plugin) ([]*plugins.Plugin, error) {
 	var foundPlugins = foundPlugins{}
 
 	// load plugin.json files and map directory to JSON data
@@ -107,6 +152,9 @@ func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSO
 	// get all registered plugins
 	registeredPlugins := make(map[string]struct{})
 	for _, p := range l.pluginRegistry.Plugins(ctx) {
+		if _, exists := replaceable[p.ID]; exists {
+			continue
+		}
 		registeredPlugins[p.ID] = struct{}{}
 	}
 
@@ -198,6 +246,13 @@ func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSO
 	}
 
 	for _, p := range verifiedPlugins {
+		if old, exists := replaceable[p.ID]; exists {
+			l.log.Info("Replacing plugin", "pluginID", p.ID, "version", p.Info.Version, "previousVersion", old.Info.Version)
+			if err := l.stop(ctx, old); err != nil {
+				l.log.Error("Could not stop plugin", "pluginId", p.ID, "err", err)
+				continue
+			}
+		}
 		if err := l.load(ctx, p); err != nil {
 			l.log.Error("Could not start plugin", "pluginId", p.ID, "err", err)
 		}
@@ -241,9 +296,20 @@ func (l *Loader) load(ctx context.Context, p *plugins.Plugin) error {
 }
 
 func (l *Loader) unload(ctx context.Context, p *plugins.Plugin) error {
+	if err := l.stop(ctx, p); err != nil {
+		return err
+	}
+
+	if err := l.pluginStorage.Remove(ctx, p.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stop drains and stops the plugin process, and unregisters the plugin.
+func (l *Loader) stop(ctx context.Context, p *plugins.Plugin) error {
 	l.log.Debug("Stopping plugin process", "pluginId", p.ID)
 
-	// TODO confirm the sequence of events is safe
 	if err := l.processManager.Stop(ctx, p.ID); err != nil {
 		return err
 	}
@@ -252,10 +318,6 @@ func (l *Loader) unload(ctx context.Context, p *plugins.Plugin) error {
 		return err
 	}
 	l.log.Debug("Plugin unregistered", "pluginId", p.ID)
-
-	if err := l.pluginStorage.Remove(ctx, p.ID); err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -436,6 +498,20 @@ func validatePluginJSON(data plugins.JSONData) error {
 	return nil
 }
 
+// withinPaths returns true if `dir` is one of the paths, or is located in one of them.
+func withinPaths(dir string, paths []string) bool {
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(absPath, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
 type foundPlugins map[string]plugins.JSONData
 
 // stripDuplicates will strip duplicate plugins or plugins that already exist
@@ -3,6 +3,8 @@ package loader
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 	"testing"
@@ -21,7 +23,7 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
-var compareOpts = cmpopts.IgnoreFields(plugins.Plugin{}, "client", "log")
+var compareOpts = cmpopts.IgnoreFields(plugins.Plugin{}, "client", "log", "inflight")
 
 func TestLoader_Load(t *testing.T) {
 	corePluginDir, err := filepath.Abs("./../../../../public")
@@ -747,6 +749,89 @@ func TestLoader_Load_DuplicatePlugins(t *testing.T) {
 	})
 }
 
+func TestLoader_Reload(t *testing.T) {
+	writePlugin := func(t *testing.T, dir, version string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(dir, 0750))
+		pluginJSON := fmt.Sprintf(`{"id": "test-panel", "type": "panel", "name": "Test Panel", "info": {"version": "%s"}}`, version)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(pluginJSON), 0600))
+	}
+
+	setup := func(t *testing.T) (*Loader, string, *fakes.FakePluginRegistry, *fakes.FakePluginStorage, *fakes.FakeProcessManager) {
+		t.Helper()
+		pluginsDir := t.TempDir()
+		writePlugin(t, filepath.Join(pluginsDir, "test-panel"), "1.0.0")
+
+		cfg := &config.Cfg{PluginsAllowUnsigned: []string{"test-panel"}}
+		reg := fakes.NewFakePluginRegistry()
+		storage := fakes.NewFakePluginStorage()
+		procMgr := fakes.NewFakeProcessManager()
+		l := newLoader(cfg, func(l *Loader) {
+			l.pluginRegistry = reg
+			l.pluginStorage = storage
+			l.processManager = procMgr
+		})
+
+		_, err := l.Load(context.Background(), plugins.External, []string{pluginsDir})
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", reg.Store["test-panel"].Info.Version)
+		return l, pluginsDir, reg, storage, procMgr
+	}
+
+	t.Run("Upgraded plugins are replaced", func(t *testing.T) {
+		l, pluginsDir, reg, storage, procMgr := setup(t)
+		writePlugin(t, filepath.Join(pluginsDir, "test-panel"), "2.0.0")
+
+		got, err := l.Reload(context.Background(), plugins.External, []string{pluginsDir})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, "2.0.0", reg.Store["test-panel"].Info.Version)
+		require.Equal(t, 2, procMgr.Started["test-panel"])
+		require.Equal(t, 1, procMgr.Stopped["test-panel"])
+		require.Contains(t, storage.Store, "test-panel")
+	})
+
+	t.Run("New plugins are loaded", func(t *testing.T) {
+		l, pluginsDir, reg, _, procMgr := setup(t)
+		pluginDir := filepath.Join(pluginsDir, "test-app")
+		require.NoError(t, os.MkdirAll(pluginDir, 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "plugin.json"),
+			[]byte(`{"id": "test-app", "type": "app", "name": "Test App", "info": {"version": "1.0.0"}}`), 0600))
+		l.signatureValidator = signature.NewValidator(signature.NewUnsignedAuthorizer(&config.Cfg{
+			PluginsAllowUnsigned: []string{"test-panel", "test-app"},
+		}))
+
+		_, err := l.Reload(context.Background(), plugins.External, []string{pluginDir})
+		require.NoError(t, err)
+		require.Contains(t, reg.Store, "test-app")
+		require.Equal(t, 1, procMgr.Started["test-panel"])
+		require.Zero(t, procMgr.Stopped["test-panel"])
+	})
+
+	t.Run("Plugins with an invalid signature are not replaced", func(t *testing.T) {
+		l, pluginsDir, reg, _, procMgr := setup(t)
+		writePlugin(t, filepath.Join(pluginsDir, "test-panel"), "2.0.0")
+		l.signatureValidator = signature.NewValidator(signature.NewUnsignedAuthorizer(&config.Cfg{}))
+
+		_, err := l.Reload(context.Background(), plugins.External, []string{pluginsDir})
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", reg.Store["test-panel"].Info.Version)
+		require.Zero(t, procMgr.Stopped["test-panel"])
+	})
+
+	t.Run("Removed plugins are unloaded without removing their files", func(t *testing.T) {
+		l, pluginsDir, reg, storage, procMgr := setup(t)
+		pluginDir := filepath.Join(pluginsDir, "test-panel")
+		require.NoError(t, os.RemoveAll(pluginDir))
+
+		_, err := l.Reload(context.Background(), plugins.External, []string{pluginDir})
+		require.NoError(t, err)
+		require.NotContains(t, reg.Store, "test-panel")
+		require.Equal(t, 1, procMgr.Stopped["test-panel"])
+		require.Contains(t, storage.Store, "test-panel")
+	})
+}
+
 func TestLoader_Load_NestedPlugins(t *testing.T) {
 	rootDir, err := filepath.Abs("../")
 	if err != nil {
@@ -1026,7 +1111,7 @@ func TestLoader_Load_NestedPlugins(t *testing.T) {
 				l.processManager = procMgr
 				l.pluginInitializer = initializer.New(&config.Cfg{}, procPrvdr, fakes.NewFakeLicensingService())
 			})
-			got, err = l.loadPlugins(context.Background(), plugins.External, []string{parentPluginJSON, childPluginJSON})
+			got, err = l.loadPlugins(context.Background(), plugins.External, []string{parentPluginJSON, childPluginJSON}, nil)
 			require.NoError(t, err)
 
 			// to ensure we can compare with expected
@@ -1050,7 +1135,7 @@ func TestLoader_Load_NestedPlugins(t *testing.T) {
 				l.processManager = procMgr
 				l.pluginInitializer = initializer.New(&config.Cfg{}, procPrvdr, fakes.NewFakeLicensingService())
 			})
-			got, err = l.loadPlugins(context.Background(), plugins.External, []string{childPluginJSON, parentPluginJSON})
+			got, err = l.loadPlugins(context.Background(), plugins.External, []string{childPluginJSON, parentPluginJSON}, nil)
 			require.NoError(t, err)
 
 			// to ensure we can compare with expected
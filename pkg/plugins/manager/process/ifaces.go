@@ -8,3 +8,9 @@ type Service interface {
 	// Stop terminates a backend plugin process.
 	Stop(ctx context.Context, pluginID string) error
 }
+
+// StateProvider provides the supervision state of backend plugin processes.
+type StateProvider interface {
+	// State returns the supervision state of the process of a backend plugin.
+	State(pluginID string) (State, bool)
+}
@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+)
+
+// cpuPeriod is the period of the CPU bandwidth limit of a cgroup, in microseconds.
+const cpuPeriod = 100000
+
+// applyResourceLimits limits the memory and CPU of a plugin process by moving it to a cgroup (v2) of the plugin
+// within the configured cgroup path.
+func applyResourceLimits(cgroupPath, pluginID string, pid int, policy config.PluginSupervision) error {
+	if cgroupPath == "" {
+		return errors.New("resource limits require backend_cgroup_path to be configured")
+	}
+	return applyCgroupLimits(cgroupPath, pluginID, pid, policy)
+}
+
+func applyCgroupLimits(cgroupPath, pluginID string, pid int, policy config.PluginSupervision) error {
+	dir := filepath.Join(cgroupPath, "grafana-plugin-"+pluginID)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	if policy.MemoryLimitMB > 0 {
+		limit := policy.MemoryLimitMB * 1024 * 1024
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(limit, 10)); err != nil {
+			return err
+		}
+	}
+	if policy.CPULimit > 0 {
+		quota := int64(policy.CPULimit * cpuPeriod)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return err
+		}
+	}
+	return writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(pid))
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0600); err != nil {
+		return fmt.Errorf("failed to write %s of cgroup: %w", name, err)
+	}
+	return nil
+}
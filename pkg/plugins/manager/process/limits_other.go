@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+)
+
+func applyResourceLimits(_, _ string, _ int, _ config.PluginSupervision) error {
+	return errors.New("resource limits of plugin processes are only supported on Linux")
+}
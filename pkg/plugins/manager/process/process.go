@@ -9,25 +9,36 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/grafana/grafana/pkg/plugins/backendplugin/instrumentation"
+	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/manager/registry"
 )
 
+const defaultDrainTimeout = 30 * time.Second
+
 var _ Service = (*Manager)(nil)
+var _ StateProvider = (*Manager)(nil)
 
 type Manager struct {
+	cfg            *config.Cfg
 	pluginRegistry registry.Service
 
+	supervisorsMu sync.RWMutex
+	supervisors   map[string]*supervisor
+
 	mu  sync.Mutex
 	log log.Logger
 }
 
-func ProvideService(pluginRegistry registry.Service) *Manager {
-	return NewManager(pluginRegistry)
+func ProvideService(cfg *config.Cfg, pluginRegistry registry.Service) *Manager {
+	return NewManager(cfg, pluginRegistry)
 }
 
-func NewManager(pluginRegistry registry.Service) *Manager {
+func NewManager(cfg *config.Cfg, pluginRegistry registry.Service) *Manager {
 	return &Manager{
+		cfg:            cfg,
 		pluginRegistry: pluginRegistry,
+		supervisors:    map[string]*supervisor{},
 		log:            log.New("plugin.process.manager"),
 	}
 }
@@ -51,7 +62,7 @@ func (m *Manager) Start(ctx context.Context, pluginID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if err := startPluginAndRestartKilledProcesses(ctx, p); err != nil {
+	if err := m.startPluginAndRestartKilledProcesses(ctx, p); err != nil {
 		return err
 	}
 
@@ -65,20 +76,52 @@ func (m *Manager) Stop(ctx context.Context, pluginID string) error {
 		return backendplugin.ErrPluginNotRegistered
 	}
 	m.log.Debug("Stopping plugin process", "pluginID", p.ID)
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if err := p.Decommission(); err != nil {
 		return err
 	}
+	m.drain(ctx, p)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if err := p.Stop(ctx); err != nil {
 		return err
 	}
 
+	m.supervisorsMu.Lock()
+	delete(m.supervisors, p.ID)
+	m.supervisorsMu.Unlock()
+	instrumentation.InstrumentPluginUptime(p.ID, 0)
+
 	return nil
 }
 
+// drain waits for the in-flight requests of the plugin to complete, up to the drain timeout.
+func (m *Manager) drain(ctx context.Context, p *plugins.Plugin) {
+	timeout := m.cfg.PluginsDrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := p.Drain(ctx); err != nil {
+		p.Logger().Warn("Stopping plugin before its in-flight requests completed", "timeout", timeout, "error", err)
+	}
+}
+
+// State returns the supervision state of the process of a backend plugin.
+func (m *Manager) State(pluginID string) (State, bool) {
+	m.supervisorsMu.RLock()
+	defer m.supervisorsMu.RUnlock()
+
+	s, exists := m.supervisors[pluginID]
+	if !exists {
+		return State{}, false
+	}
+	return s.state(), true
+}
+
 // shutdown stops all backend plugin processes
 func (m *Manager) shutdown(ctx context.Context) {
 	var wg sync.WaitGroup
@@ -96,7 +139,7 @@ func (m *Manager) shutdown(ctx context.Context) {
 	wg.Wait()
 }
 
-func startPluginAndRestartKilledProcesses(ctx context.Context, p *plugins.Plugin) error {
+func (m *Manager) startPluginAndRestartKilledProcesses(ctx context.Context, p *plugins.Plugin) error {
 	if err := p.Start(ctx); err != nil {
 		return err
 	}
@@ -105,8 +148,16 @@ func startPluginAndRestartKilledProcesses(ctx context.Context, p *plugins.Plugin
 		return nil
 	}
 
+	policy := m.cfg.Supervision(p.ID)
+	m.applyResourceLimits(p, policy)
+
+	s := newSupervisor(p.ID, policy, p.Logger(), time.Now())
+	m.supervisorsMu.Lock()
+	m.supervisors[p.ID] = s
+	m.supervisorsMu.Unlock()
+
 	go func(ctx context.Context, p *plugins.Plugin) {
-		if err := restartKilledProcess(ctx, p); err != nil {
+		if err := m.restartKilledProcess(ctx, p, s); err != nil {
 			p.Logger().Error("Attempt to restart killed plugin process failed", "error", err)
 		}
 	}(ctx, p)
@@ -114,8 +165,14 @@ func startPluginAndRestartKilledProcesses(ctx context.Context, p *plugins.Plugin
 	return nil
 }
 
-func restartKilledProcess(ctx context.Context, p *plugins.Plugin) error {
-	ticker := time.NewTicker(time.Second * 1)
+// restartKilledProcess restarts the plugin process when it exits, according to the supervision policy of the plugin.
+func (m *Manager) restartKilledProcess(ctx context.Context, p *plugins.Plugin, s *supervisor) error {
+	interval := time.Second
+	if s.policy.RestartBackoff < interval {
+		interval = s.policy.RestartBackoff
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -130,16 +187,40 @@ func restartKilledProcess(ctx context.Context, p *plugins.Plugin) error {
 				return nil
 			}
 
+			now := time.Now()
 			if !p.Exited() {
+				s.running(now)
+				continue
+			}
+
+			if !s.exited(now) {
 				continue
 			}
 
 			p.Logger().Debug("Restarting plugin")
 			if err := p.Start(ctx); err != nil {
 				p.Logger().Error("Failed to restart plugin", "error", err)
+				s.restartFailed(time.Now())
 				continue
 			}
+			s.restarted(time.Now())
+			m.applyResourceLimits(p, s.policy)
 			p.Logger().Debug("Plugin restarted")
 		}
 	}
 }
+
+// applyResourceLimits limits the memory and CPU of the plugin process, if the policy of the plugin has limits.
+func (m *Manager) applyResourceLimits(p *plugins.Plugin, policy config.PluginSupervision) {
+	if policy.MemoryLimitMB == 0 && policy.CPULimit == 0 {
+		return
+	}
+
+	pid, ok := p.PID()
+	if !ok {
+		return
+	}
+	if err := applyResourceLimits(m.cfg.PluginsCgroupPath, p.ID, pid, policy); err != nil {
+		p.Logger().Warn("Failed to apply resource limits to plugin process", "error", err)
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/stretchr/testify/require"
 )
 
 func TestProcessManager_Start(t *testing.T) {
 	t.Run("Plugin not found in registry", func(t *testing.T) {
-		m := NewManager(newFakePluginRegistry(map[string]*plugins.Plugin{}))
+		m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{}))
 		err := m.Start(context.Background(), "non-existing-datasource")
 		require.ErrorIs(t, err, backendplugin.ErrPluginNotRegistered)
 	})
@@ -62,7 +66,7 @@ func TestProcessManager_Start(t *testing.T) {
 					plugin.SignatureError = tc.signatureError
 				})
 
-				m := NewManager(newFakePluginRegistry(map[string]*plugins.Plugin{
+				m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{
 					p.ID: p,
 				}))
 
@@ -82,7 +86,7 @@ func TestProcessManager_Start(t *testing.T) {
 
 func TestProcessManager_Stop(t *testing.T) {
 	t.Run("Plugin not found in registry", func(t *testing.T) {
-		m := NewManager(newFakePluginRegistry(map[string]*plugins.Plugin{}))
+		m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{}))
 		err := m.Stop(context.Background(), "non-existing-datasource")
 		require.ErrorIs(t, err, backendplugin.ErrPluginNotRegistered)
 	})
@@ -96,7 +100,7 @@ func TestProcessManager_Stop(t *testing.T) {
 			plugin.Backend = true
 		})
 
-		m := NewManager(newFakePluginRegistry(map[string]*plugins.Plugin{
+		m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{
 			pluginID: p,
 		}))
 		err := m.Stop(context.Background(), pluginID)
@@ -107,6 +111,64 @@ func TestProcessManager_Stop(t *testing.T) {
 		require.True(t, p.Exited())
 		require.Equal(t, 1, bp.stopCount)
 	})
+
+	t.Run("Waits for in-flight requests before stopping a plugin", func(t *testing.T) {
+		bp := newFakeBackendPlugin(true)
+		started, release := make(chan struct{}), make(chan struct{})
+		bp.queryData = func() {
+			close(started)
+			<-release
+		}
+		p := createPlugin(t, bp, func(plugin *plugins.Plugin) {
+			plugin.Backend = true
+		})
+		m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{
+			p.ID: p,
+		}))
+
+		go func() {
+			_, _ = p.QueryData(context.Background(), &backend.QueryDataRequest{})
+		}()
+		<-started
+
+		stopped := make(chan error)
+		go func() {
+			stopped <- m.Stop(context.Background(), p.ID)
+		}()
+
+		require.Eventually(t, p.IsDecommissioned, time.Second, 5*time.Millisecond)
+		_, err := p.QueryData(context.Background(), &backend.QueryDataRequest{})
+		require.ErrorIs(t, err, backendplugin.ErrPluginUnavailable)
+		require.Zero(t, bp.stops())
+
+		close(release)
+		require.NoError(t, <-stopped)
+		require.Equal(t, 1, bp.stops())
+	})
+
+	t.Run("Stops a plugin when its in-flight requests exceed the drain timeout", func(t *testing.T) {
+		bp := newFakeBackendPlugin(true)
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		bp.queryData = func() {
+			close(started)
+			<-release
+		}
+		p := createPlugin(t, bp, func(plugin *plugins.Plugin) {
+			plugin.Backend = true
+		})
+		m := NewManager(&config.Cfg{PluginsDrainTimeout: 10 * time.Millisecond}, newFakePluginRegistry(map[string]*plugins.Plugin{
+			p.ID: p,
+		}))
+
+		go func() {
+			_, _ = p.QueryData(context.Background(), &backend.QueryDataRequest{})
+		}()
+		<-started
+
+		require.NoError(t, m.Stop(context.Background(), p.ID))
+		require.Equal(t, 1, bp.stops())
+	})
 }
 
 func TestProcessManager_ManagedBackendPluginLifecycle(t *testing.T) {
@@ -115,7 +177,7 @@ func TestProcessManager_ManagedBackendPluginLifecycle(t *testing.T) {
 		plugin.Backend = true
 	})
 
-	m := NewManager(newFakePluginRegistry(map[string]*plugins.Plugin{
+	m := NewManager(&config.Cfg{}, newFakePluginRegistry(map[string]*plugins.Plugin{
 		p.ID: p,
 	}))
 
@@ -123,6 +185,11 @@ func TestProcessManager_ManagedBackendPluginLifecycle(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, bp.startCount)
 
+	state, exists := m.State(p.ID)
+	require.True(t, exists)
+	require.True(t, state.Running)
+	require.Equal(t, CircuitBreakerClosed, state.CircuitBreaker)
+
 	t.Run("When plugin process is killed, the process is restarted", func(t *testing.T) {
 		pCtx := context.Background()
 		cCtx, cancel := context.WithCancel(pCtx)
@@ -149,6 +216,10 @@ func TestProcessManager_ManagedBackendPluginLifecycle(t *testing.T) {
 		require.True(t, !p.Exited())
 		require.Equal(t, 2, bp.startCount)
 		require.Equal(t, 0, bp.stopCount)
+		require.Eventually(t, func() bool {
+			state, _ := m.State(p.ID)
+			return state.Restarts == 1 && state.Running
+		}, time.Second, 10*time.Millisecond)
 
 		t.Run("When context is cancelled the plugin is stopped", func(t *testing.T) {
 			cancel()
@@ -202,6 +273,7 @@ type fakeBackendPlugin struct {
 	stopCount      int
 	decommissioned bool
 	running        bool
+	queryData      func()
 
 	mutex sync.RWMutex
 	backendplugin.Plugin
@@ -229,6 +301,19 @@ func (p *fakeBackendPlugin) Stop(_ context.Context) error {
 	return nil
 }
 
+func (p *fakeBackendPlugin) stops() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.stopCount
+}
+
+func (p *fakeBackendPlugin) QueryData(_ context.Context, _ *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	if p.queryData != nil {
+		p.queryData()
+	}
+	return &backend.QueryDataResponse{}, nil
+}
+
 func (p *fakeBackendPlugin) Decommission() error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
@@ -0,0 +1,174 @@
+package process
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins/backendplugin/instrumentation"
+	"github.com/grafana/grafana/pkg/plugins/config"
+)
+
+type CircuitBreakerState string
+
+const (
+	// CircuitBreakerClosed means the plugin process is restarted when it crashes.
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	// CircuitBreakerOpen means the plugin process crashed too many times and is not restarted
+	// until the circuit breaker timeout ends.
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	// CircuitBreakerHalfOpen means the plugin process was restarted after the circuit breaker timeout,
+	// the circuit breaker closes if it keeps running for the restart window and opens again if it crashes.
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+// State is the supervision state of a backend plugin process.
+type State struct {
+	Running        bool
+	CircuitBreaker CircuitBreakerState
+	// Restarts is the number of times the plugin process was restarted.
+	Restarts int
+	// RecentCrashes is the number of times the plugin process crashed within the restart window.
+	RecentCrashes int
+	StartedAt     time.Time
+	ExitedAt      time.Time
+	NextRestartAt time.Time
+}
+
+// supervisor tracks the crashes of a plugin process, and decides when the process is restarted
+// according to the supervision policy of the plugin.
+type supervisor struct {
+	pluginID string
+	policy   config.PluginSupervision
+	log      log.Logger
+
+	mu             sync.RWMutex
+	circuitBreaker CircuitBreakerState
+	crashes        []time.Time
+	restarts       int
+	startedAt      time.Time
+	exitedAt       time.Time
+	nextRestartAt  time.Time
+}
+
+func newSupervisor(pluginID string, policy config.PluginSupervision, logger log.Logger, now time.Time) *supervisor {
+	instrumentation.InstrumentPluginCircuitBreaker(pluginID, false)
+	return &supervisor{
+		pluginID:       pluginID,
+		policy:         policy,
+		log:            logger,
+		circuitBreaker: CircuitBreakerClosed,
+		startedAt:      now,
+	}
+}
+
+// running is called while the plugin process is running.
+func (s *supervisor) running(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.circuitBreaker == CircuitBreakerHalfOpen && now.Sub(s.startedAt) >= s.policy.RestartWindow {
+		s.log.Info("Plugin process recovered, closing circuit breaker")
+		s.circuitBreaker = CircuitBreakerClosed
+		s.crashes = nil
+	}
+	instrumentation.InstrumentPluginUptime(s.pluginID, now.Sub(s.startedAt))
+}
+
+// exited is called while the plugin process is not running, and returns true when the process should be restarted.
+func (s *supervisor) exited(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exitedAt.IsZero() {
+		s.crash(now)
+	}
+	return !now.Before(s.nextRestartAt)
+}
+
+// restarted is called when the plugin process was restarted.
+func (s *supervisor) restarted(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restarts++
+	s.startedAt = now
+	s.exitedAt = time.Time{}
+	if s.circuitBreaker == CircuitBreakerOpen {
+		s.circuitBreaker = CircuitBreakerHalfOpen
+		instrumentation.InstrumentPluginCircuitBreaker(s.pluginID, false)
+	}
+	instrumentation.InstrumentPluginRestart(s.pluginID)
+}
+
+// restartFailed is called when the plugin process could not be restarted, which counts as a crash.
+func (s *supervisor) restartFailed(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.crash(now)
+}
+
+// crash records a crash of the plugin process and schedules the next restart, with an exponential backoff.
+// The circuit breaker opens when the process crashes too often within the restart window.
+func (s *supervisor) crash(now time.Time) {
+	s.exitedAt = now
+	instrumentation.InstrumentPluginUptime(s.pluginID, 0)
+
+	if s.circuitBreaker == CircuitBreakerHalfOpen {
+		s.open(now)
+		return
+	}
+
+	crashes := s.crashes[:0]
+	for _, t := range s.crashes {
+		if now.Sub(t) < s.policy.RestartWindow {
+			crashes = append(crashes, t)
+		}
+	}
+	s.crashes = append(crashes, now)
+
+	if len(s.crashes) > s.policy.MaxRestarts {
+		s.open(now)
+		return
+	}
+
+	backoff := s.policy.RestartBackoff
+	for i := 1; i < len(s.crashes) && backoff < s.policy.MaxRestartBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.policy.MaxRestartBackoff {
+		backoff = s.policy.MaxRestartBackoff
+	}
+	s.nextRestartAt = now.Add(backoff)
+}
+
+func (s *supervisor) open(now time.Time) {
+	s.circuitBreaker = CircuitBreakerOpen
+	s.nextRestartAt = now.Add(s.policy.CircuitBreakerTimeout)
+	s.log.Warn("Plugin process is crash looping, opening circuit breaker", "crashes", len(s.crashes),
+		"window", s.policy.RestartWindow, "nextRestart", s.nextRestartAt)
+	instrumentation.InstrumentPluginCircuitBreaker(s.pluginID, true)
+}
+
+func (s *supervisor) state() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recentCrashes := 0
+	for _, t := range s.crashes {
+		if time.Since(t) < s.policy.RestartWindow {
+			recentCrashes++
+		}
+	}
+
+	return State{
+		Running:        s.exitedAt.IsZero(),
+		CircuitBreaker: s.circuitBreaker,
+		Restarts:       s.restarts,
+		RecentCrashes:  recentCrashes,
+		StartedAt:      s.startedAt,
+		ExitedAt:       s.exitedAt,
+		NextRestartAt:  s.nextRestartAt,
+	}
+}
@@ -0,0 +1,111 @@
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins/config"
+)
+
+func TestSupervisor(t *testing.T) {
+	policy := config.PluginSupervision{
+		RestartBackoff:        time.Second,
+		MaxRestartBackoff:     3 * time.Second,
+		MaxRestarts:           3,
+		RestartWindow:         time.Minute,
+		CircuitBreakerTimeout: 10 * time.Minute,
+	}
+	start := time.Now()
+
+	t.Run("restarts are delayed with an exponential backoff", func(t *testing.T) {
+		s := newSupervisor("test-datasource", policy, log.NewNopLogger(), start)
+
+		now := start
+		for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+			require.False(t, s.exited(now))
+			require.False(t, s.exited(now.Add(backoff-time.Millisecond)))
+			now = now.Add(backoff)
+			require.True(t, s.exited(now))
+			s.restarted(now)
+		}
+
+		state := s.state()
+		require.Equal(t, CircuitBreakerClosed, state.CircuitBreaker)
+		require.Equal(t, 3, state.Restarts)
+		require.True(t, state.Running)
+	})
+
+	t.Run("crashes outside of the restart window are forgotten", func(t *testing.T) {
+		s := newSupervisor("test-datasource", policy, log.NewNopLogger(), start)
+
+		now := start
+		for i := 0; i < 10; i++ {
+			require.False(t, s.exited(now))
+			now = now.Add(time.Second)
+			require.True(t, s.exited(now))
+			s.restarted(now)
+			now = now.Add(policy.RestartWindow)
+		}
+		require.Equal(t, CircuitBreakerClosed, s.state().CircuitBreaker)
+	})
+
+	// crashLoop crashes the plugin until the circuit breaker opens, and returns the time it opened
+	crashLoop := func(t *testing.T, s *supervisor) time.Time {
+		t.Helper()
+		now := start
+		for i := 0; i < policy.MaxRestarts; i++ {
+			s.exited(now)
+			now = now.Add(policy.MaxRestartBackoff)
+			require.True(t, s.exited(now))
+			s.restarted(now)
+		}
+		require.False(t, s.exited(now))
+		return now
+	}
+
+	t.Run("circuit breaker opens when crash looping and closes once the plugin recovers", func(t *testing.T) {
+		s := newSupervisor("test-datasource", policy, log.NewNopLogger(), start)
+		now := crashLoop(t, s)
+
+		state := s.state()
+		require.Equal(t, CircuitBreakerOpen, state.CircuitBreaker)
+		require.False(t, state.Running)
+		require.Equal(t, now.Add(policy.CircuitBreakerTimeout), state.NextRestartAt)
+
+		now = now.Add(policy.CircuitBreakerTimeout)
+		require.True(t, s.exited(now))
+		s.restarted(now)
+		require.Equal(t, CircuitBreakerHalfOpen, s.state().CircuitBreaker)
+
+		s.running(now.Add(policy.RestartWindow - time.Second))
+		require.Equal(t, CircuitBreakerHalfOpen, s.state().CircuitBreaker)
+		s.running(now.Add(policy.RestartWindow))
+		require.Equal(t, CircuitBreakerClosed, s.state().CircuitBreaker)
+		require.Zero(t, s.state().RecentCrashes)
+	})
+
+	t.Run("circuit breaker opens again when the plugin crashes while half-open", func(t *testing.T) {
+		s := newSupervisor("test-datasource", policy, log.NewNopLogger(), start)
+		now := crashLoop(t, s).Add(policy.CircuitBreakerTimeout)
+		require.True(t, s.exited(now))
+		s.restarted(now)
+
+		now = now.Add(time.Second)
+		require.False(t, s.exited(now))
+		require.Equal(t, CircuitBreakerOpen, s.state().CircuitBreaker)
+		require.Equal(t, now.Add(policy.CircuitBreakerTimeout), s.state().NextRestartAt)
+	})
+
+	t.Run("failed restarts count as crashes", func(t *testing.T) {
+		s := newSupervisor("test-datasource", policy, log.NewNopLogger(), start)
+
+		require.False(t, s.exited(start))
+		require.True(t, s.exited(start.Add(time.Second)))
+		s.restartFailed(start.Add(time.Second))
+		require.False(t, s.exited(start.Add(2*time.Second)))
+		require.True(t, s.exited(start.Add(3*time.Second)))
+	})
+}
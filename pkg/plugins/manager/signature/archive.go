@@ -0,0 +1,149 @@
+package signature
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// ErrInvalidArchiveSignature is returned when a plugin archive has a manifest which is invalid
+// or does not match the files of the archive.
+var ErrInvalidArchiveSignature = errors.New("plugin archive signature is invalid")
+
+// VerifyArchive verifies the signature of a plugin archive before it is extracted, by checking the files
+// of the archive against its MANIFEST.txt. Archives without a manifest are reported as unsigned, the
+// plugin loader decides whether unsigned plugins can be loaded.
+func VerifyArchive(r *zip.Reader) (plugins.Signature, error) {
+	files := make(map[string]*zip.File, len(r.File))
+	var manifestPath string
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := strings.TrimPrefix(f.Name, "./")
+		files[name] = f
+
+		// the plugin root is the directory of the top-most manifest, nested plugins are signed by it
+		if path.Base(name) == "MANIFEST.txt" &&
+			(manifestPath == "" || strings.Count(name, "/") < strings.Count(manifestPath, "/")) {
+			manifestPath = name
+		}
+	}
+
+	if manifestPath == "" {
+		return plugins.Signature{Status: plugins.SignatureUnsigned}, nil
+	}
+
+	body, err := readArchiveFile(files[manifestPath])
+	if err != nil {
+		return plugins.Signature{}, err
+	}
+	manifest, err := readPluginManifest(body)
+	if err != nil {
+		return plugins.Signature{Status: plugins.SignatureInvalid}, fmt.Errorf("%w: %v", ErrInvalidArchiveSignature, err)
+	}
+
+	rootDir := path.Dir(manifestPath)
+	if err := verifyArchivePluginJSON(files, rootDir, manifest); err != nil {
+		return plugins.Signature{Status: plugins.SignatureModified}, err
+	}
+
+	// Verify the manifest contents
+	for p, hash := range manifest.Files {
+		f, exists := files[path.Join(rootDir, p)]
+		if !exists {
+			return plugins.Signature{Status: plugins.SignatureModified},
+				fmt.Errorf("%w: file %s listed in the manifest was not found", ErrInvalidArchiveSignature, p)
+		}
+		sum, err := archiveFileHash(f)
+		if err != nil {
+			return plugins.Signature{}, err
+		}
+		if sum != hash {
+			return plugins.Signature{Status: plugins.SignatureModified},
+				fmt.Errorf("%w: checksum of file %s does not match the manifest", ErrInvalidArchiveSignature, p)
+		}
+	}
+
+	if manifest.isV2() {
+		// Track files missing from the manifest
+		for name := range files {
+			if name == manifestPath {
+				continue
+			}
+			rel := name
+			if rootDir != "." {
+				if !strings.HasPrefix(name, rootDir+"/") {
+					continue
+				}
+				rel = strings.TrimPrefix(name, rootDir+"/")
+			}
+			if _, exists := manifest.Files[rel]; !exists {
+				return plugins.Signature{Status: plugins.SignatureModified},
+					fmt.Errorf("%w: file %s was not included in the signature", ErrInvalidArchiveSignature, rel)
+			}
+		}
+	}
+
+	return plugins.Signature{
+		Status:     plugins.SignatureValid,
+		Type:       manifest.SignatureType,
+		SigningOrg: manifest.SignedByOrgName,
+	}, nil
+}
+
+// verifyArchivePluginJSON makes sure the manifest was created for the plugin and version of the archive.
+func verifyArchivePluginJSON(files map[string]*zip.File, rootDir string, manifest *pluginManifest) error {
+	f, exists := files[path.Join(rootDir, "plugin.json")]
+	if !exists {
+		return nil
+	}
+	body, err := readArchiveFile(f)
+	if err != nil {
+		return err
+	}
+
+	var plugin plugins.JSONData
+	if err := json.Unmarshal(body, &plugin); err != nil {
+		return fmt.Errorf("%v: %w", "failed to parse plugin.json", err)
+	}
+	if manifest.Plugin != plugin.ID || manifest.Version != plugin.Info.Version {
+		return fmt.Errorf("%w: manifest was created for %s v%s", ErrInvalidArchiveSignature, manifest.Plugin, manifest.Version)
+	}
+	return nil
+}
+
+func readArchiveFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	return io.ReadAll(rc)
+}
+
+func archiveFileHash(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("could not calculate plugin file checksum")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,82 @@
+package signature
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestVerifyArchive(t *testing.T) {
+	t.Run("valid signature", func(t *testing.T) {
+		sig, err := VerifyArchive(zipPluginDir(t, "valid-v2-signature", nil))
+		require.NoError(t, err)
+		require.Equal(t, plugins.Signature{
+			Status:     plugins.SignatureValid,
+			Type:       plugins.GrafanaSignature,
+			SigningOrg: "Grafana Labs",
+		}, sig)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		sig, err := VerifyArchive(zipPluginDir(t, "unsigned-panel", nil))
+		require.NoError(t, err)
+		require.Equal(t, plugins.SignatureUnsigned, sig.Status)
+	})
+
+	t.Run("file not included in the signature", func(t *testing.T) {
+		sig, err := VerifyArchive(zipPluginDir(t, "invalid-v2-extra-file", nil))
+		require.ErrorIs(t, err, ErrInvalidArchiveSignature)
+		require.Equal(t, plugins.SignatureModified, sig.Status)
+	})
+
+	t.Run("modified file", func(t *testing.T) {
+		sig, err := VerifyArchive(zipPluginDir(t, "valid-v2-signature", map[string]string{
+			"plugin.json": `{"id": "test-datasource", "info": {"version": "1.0.0"}}`,
+		}))
+		require.ErrorIs(t, err, ErrInvalidArchiveSignature)
+		require.Equal(t, plugins.SignatureModified, sig.Status)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		sig, err := VerifyArchive(zipPluginDir(t, "valid-v2-signature", map[string]string{
+			"MANIFEST.txt": "not a manifest",
+		}))
+		require.ErrorIs(t, err, ErrInvalidArchiveSignature)
+		require.Equal(t, plugins.SignatureInvalid, sig.Status)
+	})
+}
+
+// zipPluginDir creates a plugin archive from a plugin directory of the manager testdata,
+// with the contents of some of the files replaced by `overrides`.
+func zipPluginDir(t *testing.T, name string, overrides map[string]string) *zip.Reader {
+	t.Helper()
+
+	dir := filepath.Join("..", "testdata", name, "plugin")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for _, e := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		if override, exists := overrides[e.Name()]; exists {
+			content = []byte(override)
+		}
+		f, err := w.Create(name + "/" + e.Name())
+		require.NoError(t, err)
+		_, err = f.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return r
+}
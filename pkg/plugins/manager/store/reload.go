@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	infrafs "github.com/grafana/grafana/pkg/infra/fs"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/finder"
+)
+
+var _ plugins.Reloader = (*Service)(nil)
+
+// IsDisabled returns true if the plugin directories are not watched for hot reload.
+func (s *Service) IsDisabled() bool {
+	return s.cfg == nil || !s.cfg.PluginsHotReload
+}
+
+// Run watches the external plugin directories, and reloads the plugins which were added, changed or removed.
+// The directories are scanned periodically, and a plugin is only reloaded once its files did not change
+// between two scans, so that plugins are not loaded while they are being extracted.
+func (s *Service) Run(ctx context.Context) error {
+	interval := s.cfg.PluginsHotReloadInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := s.sync(ctx, true); err != nil {
+				s.log.Error("Failed to reload plugins", "error", err)
+			}
+		}
+	}
+}
+
+// Reload replaces an external plugin by the plugin found in its directory.
+func (s *Service) Reload(ctx context.Context, pluginID string) error {
+	p, exists := s.pluginRegistry.Plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+	if !p.IsExternalPlugin() {
+		return plugins.ErrReloadCorePlugin
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.pluginLoader.Reload(ctx, plugins.External, []string{p.PluginDir}); err != nil {
+		return err
+	}
+	if _, exists := s.fingerprints[p.PluginDir]; exists {
+		s.fingerprints[p.PluginDir] = fingerprint(p.PluginDir)
+	}
+	return nil
+}
+
+// ReloadAll loads the external plugins which were added, and replaces or unloads the external plugins
+// which were changed or removed since they were loaded.
+func (s *Service) ReloadAll(ctx context.Context) error {
+	return s.sync(ctx, false)
+}
+
+// sync reloads the plugin directories which were added, changed or removed since the previous reload.
+// When `stable` is true, added or changed directories are only reloaded if they did not change since the previous scan.
+func (s *Service) sync(ctx context.Context, stable bool) error {
+	current, err := s.scan()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	for dir, fp := range current {
+		if s.fingerprints[dir] == fp {
+			continue
+		}
+		if stable && s.pending[dir] != fp {
+			continue
+		}
+		changed = append(changed, dir)
+	}
+	for dir := range s.fingerprints {
+		if _, exists := current[dir]; !exists {
+			changed = append(changed, dir)
+		}
+	}
+	s.pending = current
+
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	s.log.Info("Reloading plugins", "paths", changed)
+	for _, dir := range changed {
+		if fp, exists := current[dir]; exists {
+			s.fingerprints[dir] = fp
+		} else {
+			delete(s.fingerprints, dir)
+		}
+	}
+	if _, err := s.pluginLoader.Reload(ctx, plugins.External, changed); err != nil {
+		return fmt.Errorf("%v: %w", "failed to reload plugins", err)
+	}
+	return nil
+}
+
+// scan returns the fingerprints of the top-level plugin directories found in the external plugin paths.
+func (s *Service) scan() (map[string]string, error) {
+	var paths []string
+	for _, path := range s.externalPaths {
+		if exists, err := infrafs.Exists(path); err == nil && exists {
+			paths = append(paths, path)
+		}
+	}
+
+	f := finder.New()
+	pluginJSONPaths, err := f.Find(paths)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", "failed to scan plugin directories", err)
+	}
+
+	var dirs []string
+	for _, pluginJSONPath := range pluginJSONPaths {
+		dirs = append(dirs, filepath.Dir(pluginJSONPath))
+	}
+	sort.Strings(dirs)
+
+	res := make(map[string]string)
+	for i, dir := range dirs {
+		// nested plugins are part of the fingerprint of their parent
+		if i > 0 && strings.HasPrefix(dir, dirs[i-1]+string(filepath.Separator)) {
+			dirs[i] = dirs[i-1]
+			continue
+		}
+		res[dir] = fingerprint(dir)
+	}
+	return res, nil
+}
+
+// fingerprint returns a hash of the names, sizes and modification times of the files of a plugin directory.
+func fingerprint(dir string) string {
+	h := sha256.New()
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		_, _ = fmt.Fprintf(h, "%s:%d:%d\n", rel, fi.Size(), fi.ModTime().UnixNano())
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/manager/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestStore_ReloadAll(t *testing.T) {
+	writePlugin := func(t *testing.T, dir, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(dir, 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(content), 0600))
+	}
+
+	setup := func(t *testing.T) (*Service, string, *[][]string) {
+		t.Helper()
+		pluginsDir := t.TempDir()
+		writePlugin(t, filepath.Join(pluginsDir, "test-panel"), `{"id": "test-panel"}`)
+		writePlugin(t, filepath.Join(pluginsDir, "test-app", "datasource"), `{"id": "test-datasource"}`)
+		writePlugin(t, filepath.Join(pluginsDir, "test-app"), `{"id": "test-app"}`)
+
+		var reloaded [][]string
+		l := &fakes.FakeLoader{
+			ReloadFunc: func(_ context.Context, class plugins.Class, paths []string) ([]*plugins.Plugin, error) {
+				require.Equal(t, plugins.External, class)
+				reloaded = append(reloaded, paths)
+				return nil, nil
+			},
+		}
+		s, err := ProvideService(&setting.Cfg{}, &config.Cfg{PluginsPath: pluginsDir}, fakes.NewFakePluginRegistry(), l)
+		require.NoError(t, err)
+		require.Len(t, s.fingerprints, 2)
+		return s, pluginsDir, &reloaded
+	}
+
+	t.Run("Nothing is reloaded when the plugin directories did not change", func(t *testing.T) {
+		s, _, reloaded := setup(t)
+
+		require.NoError(t, s.ReloadAll(context.Background()))
+		require.Empty(t, *reloaded)
+	})
+
+	t.Run("Added, changed and removed plugins are reloaded", func(t *testing.T) {
+		s, pluginsDir, reloaded := setup(t)
+		writePlugin(t, filepath.Join(pluginsDir, "test-app", "datasource"), `{"id": "test-datasource", "type": "datasource"}`)
+		writePlugin(t, filepath.Join(pluginsDir, "new-panel"), `{"id": "new-panel"}`)
+		require.NoError(t, os.RemoveAll(filepath.Join(pluginsDir, "test-panel")))
+
+		require.NoError(t, s.ReloadAll(context.Background()))
+		require.Equal(t, [][]string{{
+			filepath.Join(pluginsDir, "new-panel"),
+			filepath.Join(pluginsDir, "test-app"),
+			filepath.Join(pluginsDir, "test-panel"),
+		}}, *reloaded)
+
+		require.NoError(t, s.ReloadAll(context.Background()))
+		require.Len(t, *reloaded, 1)
+	})
+
+	t.Run("Plugins are reloaded by the watcher once they stopped changing", func(t *testing.T) {
+		s, pluginsDir, reloaded := setup(t)
+		writePlugin(t, filepath.Join(pluginsDir, "test-panel"), `{"id": "test-panel", "type": "panel"}`)
+
+		require.NoError(t, s.sync(context.Background(), true))
+		require.Empty(t, *reloaded)
+
+		require.NoError(t, s.sync(context.Background(), true))
+		require.Equal(t, [][]string{{filepath.Join(pluginsDir, "test-panel")}}, *reloaded)
+	})
+}
+
+func TestStore_Reload(t *testing.T) {
+	t.Run("Only external plugins can be reloaded", func(t *testing.T) {
+		p := &plugins.Plugin{JSONData: plugins.JSONData{ID: "test-datasource"}, Class: plugins.Core}
+		s := New(newFakePluginRegistry(map[string]*plugins.Plugin{p.ID: p}))
+
+		require.ErrorIs(t, s.Reload(context.Background(), p.ID), plugins.ErrReloadCorePlugin)
+		require.ErrorIs(t, s.Reload(context.Background(), "test-panel"), plugins.ErrPluginNotInstalled)
+	})
+
+	t.Run("External plugins are reloaded from their directory", func(t *testing.T) {
+		p := &plugins.Plugin{JSONData: plugins.JSONData{ID: "test-datasource"}, Class: plugins.External, PluginDir: t.TempDir()}
+		var reloaded []string
+		s := New(newFakePluginRegistry(map[string]*plugins.Plugin{p.ID: p}))
+		s.pluginLoader = &fakes.FakeLoader{
+			ReloadFunc: func(_ context.Context, _ plugins.Class, paths []string) ([]*plugins.Plugin, error) {
+				reloaded = append(reloaded, paths...)
+				return nil, nil
+			},
+		}
+
+		require.NoError(t, s.Reload(context.Background(), p.ID))
+		require.Equal(t, []string{p.PluginDir}, reloaded)
+	})
+}
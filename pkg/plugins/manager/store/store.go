@@ -4,7 +4,9 @@ import (
 	"context"
 	"path/filepath"
 	"sort"
+	"sync"
 
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader"
@@ -16,21 +18,47 @@ var _ plugins.Store = (*Service)(nil)
 
 type Service struct {
 	pluginRegistry registry.Service
+	pluginLoader   loader.Service
+	cfg            *config.Cfg
+	log            log.Logger
+
+	// external plugin directories watched for hot reload
+	externalPaths []string
+	mu            sync.Mutex
+	fingerprints  map[string]string
+	pending       map[string]string
 }
 
 func ProvideService(gCfg *setting.Cfg, cfg *config.Cfg, pluginRegistry registry.Service,
 	pluginLoader loader.Service) (*Service, error) {
+	s := New(pluginRegistry)
+	s.pluginLoader = pluginLoader
+	s.cfg = cfg
+
 	for _, ps := range pluginSources(gCfg, cfg) {
 		if _, err := pluginLoader.Load(context.Background(), ps.Class, ps.Paths); err != nil {
 			return nil, err
 		}
+		if ps.Class == plugins.External {
+			s.externalPaths = ps.Paths
+		}
+	}
+
+	fingerprints, err := s.scan()
+	if err != nil {
+		s.log.Warn("Plugins will not be reloaded", "error", err)
+	} else {
+		s.fingerprints, s.pending = fingerprints, fingerprints
 	}
-	return New(pluginRegistry), nil
+	return s, nil
 }
 
 func New(pluginRegistry registry.Service) *Service {
 	return &Service{
 		pluginRegistry: pluginRegistry,
+		log:            log.New("plugin.store"),
+		fingerprints:   map[string]string{},
+		pending:        map[string]string{},
 	}
 }
 
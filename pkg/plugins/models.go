@@ -15,6 +15,7 @@ var (
 	ErrInstallCorePlugin   = errors.New("cannot install a Core plugin")
 	ErrUninstallCorePlugin = errors.New("cannot uninstall a Core plugin")
 	ErrPluginNotInstalled  = errors.New("plugin is not installed")
+	ErrReloadCorePlugin    = errors.New("cannot reload a Core plugin")
 )
 
 type NotFoundError struct {
@@ -41,6 +41,7 @@ type Plugin struct {
 	SecretsManager secretsmanagerplugin.SecretsManagerPlugin
 	client         backendplugin.Plugin
 	log            log.Logger
+	inflight       inflightRequests
 }
 
 type PluginDTO struct {
@@ -248,7 +249,26 @@ func (p *Plugin) Exited() bool {
 	return false
 }
 
+// PID returns the ID of the backend plugin process, if the plugin runs in a separate process.
+func (p *Plugin) PID() (int, bool) {
+	if pp, ok := p.client.(backendplugin.ProcessPlugin); ok {
+		return pp.PID()
+	}
+	return 0, false
+}
+
+// Drain rejects new requests to the plugin, and waits until its in-flight requests are completed or ctx is done.
+// Streams started with RunStream are not tracked, as they last until the plugin is stopped.
+func (p *Plugin) Drain(ctx context.Context) error {
+	return p.inflight.drain(ctx)
+}
+
 func (p *Plugin) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	if !p.inflight.start() {
+		return nil, backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return nil, backendplugin.ErrPluginUnavailable
@@ -257,6 +277,11 @@ func (p *Plugin) QueryData(ctx context.Context, req *backend.QueryDataRequest) (
 }
 
 func (p *Plugin) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if !p.inflight.start() {
+		return backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return backendplugin.ErrPluginUnavailable
@@ -265,6 +290,11 @@ func (p *Plugin) CallResource(ctx context.Context, req *backend.CallResourceRequ
 }
 
 func (p *Plugin) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	if !p.inflight.start() {
+		return nil, backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return nil, backendplugin.ErrPluginUnavailable
@@ -273,6 +303,11 @@ func (p *Plugin) CheckHealth(ctx context.Context, req *backend.CheckHealthReques
 }
 
 func (p *Plugin) CollectMetrics(ctx context.Context, req *backend.CollectMetricsRequest) (*backend.CollectMetricsResult, error) {
+	if !p.inflight.start() {
+		return nil, backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return nil, backendplugin.ErrPluginUnavailable
@@ -281,6 +316,11 @@ func (p *Plugin) CollectMetrics(ctx context.Context, req *backend.CollectMetrics
 }
 
 func (p *Plugin) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	if !p.inflight.start() {
+		return nil, backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return nil, backendplugin.ErrPluginUnavailable
@@ -289,6 +329,11 @@ func (p *Plugin) SubscribeStream(ctx context.Context, req *backend.SubscribeStre
 }
 
 func (p *Plugin) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	if !p.inflight.start() {
+		return nil, backendplugin.ErrPluginUnavailable
+	}
+	defer p.inflight.done()
+
 	pluginClient, ok := p.Client()
 	if !ok {
 		return nil, backendplugin.ErrPluginUnavailable
@@ -69,9 +69,9 @@ func (c *Client) download(_ context.Context, pluginZipURL, checksum string, comp
 func (c *Client) downloadFile(tmpFile *os.File, pluginURL, checksum string, compatOpts CompatOpts) (err error) {
 	// Try handling URL as a local file path first
 	if _, err := os.Stat(pluginURL); err == nil {
-		// TODO re-verify
-		// We can ignore this gosec G304 warning since `pluginURL` stems from command line flag "pluginUrl". If the
-		// user shouldn't be able to read the file, it should be handled through filesystem permissions.
+		// We can ignore this gosec G304 warning since `pluginURL` stems from command line flag "pluginUrl" or a local
+		// plugin repository. If the user shouldn't be able to read the file, it should be handled through filesystem
+		// permissions.
 		// nolint:gosec
 		f, err := os.Open(pluginURL)
 		if err != nil {
@@ -82,10 +82,14 @@ func (c *Client) downloadFile(tmpFile *os.File, pluginURL, checksum string, comp
 				c.log.Warn("Failed to close file", "err", err)
 			}
 		}()
-		_, err = io.Copy(tmpFile, f)
+		h := sha256.New()
+		_, err = io.Copy(tmpFile, io.TeeReader(f, h))
 		if err != nil {
 			return fmt.Errorf("%v: %w", "Failed to copy plugin archive", err)
 		}
+		if len(checksum) > 0 && checksum != fmt.Sprintf("%x", h.Sum(nil)) {
+			return fmt.Errorf("expected SHA256 checksum does not match the plugin archive %q", pluginURL)
+		}
 		return nil
 	}
 
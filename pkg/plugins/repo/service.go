@@ -3,32 +3,63 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/logger"
 )
 
+const defaultBaseURL = "https://grafana.com/api/plugins"
+
 type Manager struct {
 	client  *Client
 	baseURL string
+	// localPath is the directory of the repository when it is served from the local filesystem
+	localPath string
 
 	log logger.Logger
 }
 
-func ProvideService() *Manager {
-	defaultBaseURL := "https://grafana.com/api/plugins"
-	return New(false, defaultBaseURL, logger.NewLogger("plugin.repository"))
+func ProvideService(cfg *config.Cfg) *Manager {
+	baseURL := cfg.PluginRepositoryURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return New(false, baseURL, logger.NewLogger("plugin.repository"))
 }
 
+// New returns a repository which fetches plugins from `baseURL`. The `baseURL` is either the URL of a repository with
+// the same API as grafana.com, or the path (or file:// URL) of a local repository directory.
 func New(skipTLSVerify bool, baseURL string, logger logger.Logger) *Manager {
 	return &Manager{
-		client:  newClient(skipTLSVerify, logger),
-		baseURL: baseURL,
-		log:     logger,
+		client:    newClient(skipTLSVerify, logger),
+		baseURL:   baseURL,
+		localPath: localRepoPath(baseURL),
+		log:       logger,
+	}
+}
+
+// localRepoPath returns the directory of a repository served from the local filesystem,
+// or an empty string if the repository is served over HTTP.
+func localRepoPath(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
 	}
+	switch u.Scheme {
+	case "http", "https":
+		return ""
+	case "file":
+		return filepath.FromSlash(u.Path)
+	}
+	return baseURL
 }
 
 // GetPluginArchive fetches the requested plugin archive
@@ -38,6 +69,12 @@ func (m *Manager) GetPluginArchive(ctx context.Context, pluginID, version string
 		return nil, err
 	}
 
+	if m.localPath != "" {
+		if _, err := os.Stat(dlOpts.PluginZipURL); err != nil {
+			return nil, fmt.Errorf("%v: %w", fmt.Sprintf("failed to find archive of %s v%s in local repository", pluginID, dlOpts.Version), err)
+		}
+	}
+
 	return m.client.download(ctx, dlOpts.PluginZipURL, dlOpts.Checksum, compatOpts)
 }
 
@@ -60,31 +97,43 @@ func (m *Manager) GetPluginDownloadOptions(_ context.Context, pluginID, version
 
 	// Plugins which are downloaded just as sourcecode zipball from GitHub do not have checksum
 	var checksum string
+	arch := "any"
 	if v.Arch != nil {
-		archMeta, exists := v.Arch[compatOpts.OSAndArch()]
-		if !exists {
-			archMeta = v.Arch["any"]
+		if _, exists := v.Arch[compatOpts.OSAndArch()]; exists {
+			arch = compatOpts.OSAndArch()
 		}
-		checksum = archMeta.SHA256
+		checksum = v.Arch[arch].SHA256
+	}
+
+	pluginZipURL := fmt.Sprintf("%s/%s/versions/%s/download", m.baseURL, pluginID, v.Version)
+	if m.localPath != "" {
+		pluginZipURL = filepath.Join(m.localPath, pluginID, "versions", v.Version, arch+".zip")
 	}
 
 	return &PluginDownloadOptions{
 		Version:      v.Version,
 		Checksum:     checksum,
-		PluginZipURL: fmt.Sprintf("%s/%s/versions/%s/download", m.baseURL, pluginID, v.Version),
+		PluginZipURL: pluginZipURL,
 	}, nil
 }
 
 func (m *Manager) pluginMetadata(pluginID string, compatOpts CompatOpts) (Plugin, error) {
 	m.log.Debugf("Fetching metadata for plugin \"%s\" from repo %s", pluginID, m.baseURL)
 
-	u, err := url.Parse(m.baseURL)
-	if err != nil {
-		return Plugin{}, err
-	}
-	u.Path = path.Join(u.Path, "repo", pluginID)
+	var body []byte
+	var err error
+	if m.localPath != "" {
+		body, err = m.localPluginMetadata(pluginID)
+	} else {
+		var u *url.URL
+		u, err = url.Parse(m.baseURL)
+		if err != nil {
+			return Plugin{}, err
+		}
+		u.Path = path.Join(u.Path, "repo", pluginID)
 
-	body, err := m.client.sendReq(u, compatOpts)
+		body, err = m.client.sendReq(u, compatOpts)
+	}
 	if err != nil {
 		return Plugin{}, err
 	}
@@ -99,6 +148,25 @@ func (m *Manager) pluginMetadata(pluginID string, compatOpts CompatOpts) (Plugin
 	return data, nil
 }
 
+// localPluginMetadata reads the metadata of a plugin from `repo/<plugin id>.json` in the local repository directory.
+func (m *Manager) localPluginMetadata(pluginID string) ([]byte, error) {
+	if pluginID == "" || pluginID != filepath.Base(pluginID) {
+		return nil, fmt.Errorf("invalid plugin ID %q", pluginID)
+	}
+
+	// We can ignore the gosec G304 warning since the path is within the configured repository directory,
+	// and `pluginID` can't contain path separators.
+	// nolint:gosec
+	body, err := os.ReadFile(filepath.Join(m.localPath, "repo", pluginID+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, Response4xxError{StatusCode: http.StatusNotFound, Message: "Plugin not found"}
+		}
+		return nil, fmt.Errorf("%v: %w", "failed to read plugin metadata", err)
+	}
+	return body, nil
+}
+
 // selectVersion selects the most appropriate plugin version
 // returns the specified version if supported.
 // returns the latest version if no specific version is specified.
@@ -1,7 +1,13 @@
 package repo
 
 import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -50,6 +56,94 @@ func TestSelectVersion(t *testing.T) {
 	})
 }
 
+func TestLocalRepository(t *testing.T) {
+	dir := t.TempDir()
+	compatOpts := NewCompatOpts("9.2.0", "linux", "amd64")
+
+	writeFile := func(t *testing.T, content []byte, elem ...string) {
+		t.Helper()
+		p := filepath.Join(append([]string{dir}, elem...)...)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0750))
+		require.NoError(t, os.WriteFile(p, content, 0600))
+	}
+
+	archive := zipArchive(t, "test-panel/plugin.json", `{"id": "test-panel"}`)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(archive))
+	writeFile(t, archive, "test-panel", "versions", "2.0.0", "linux-amd64.zip")
+	writeFile(t, archive, "test-panel", "versions", "1.0.0", "any.zip")
+
+	metadata, err := json.Marshal(Plugin{
+		ID: "test-panel",
+		Versions: []Version{
+			{Version: "2.0.0", Arch: map[string]ArchMeta{"linux-amd64": {SHA256: checksum}, "darwin-arm64": {SHA256: "other"}}},
+			{Version: "1.0.0", Arch: map[string]ArchMeta{"any": {SHA256: "invalid"}}},
+		},
+	})
+	require.NoError(t, err)
+	writeFile(t, metadata, "repo", "test-panel.json")
+
+	for _, baseURL := range []string{dir, "file://" + filepath.ToSlash(dir)} {
+		m := New(false, baseURL, &fakeLogger{})
+
+		t.Run("Should return download options of the local archive for the current arch", func(t *testing.T) {
+			opts, err := m.GetPluginDownloadOptions(context.Background(), "test-panel", "", compatOpts)
+			require.NoError(t, err)
+			require.Equal(t, &PluginDownloadOptions{
+				Version:      "2.0.0",
+				Checksum:     checksum,
+				PluginZipURL: filepath.Join(dir, "test-panel", "versions", "2.0.0", "linux-amd64.zip"),
+			}, opts)
+		})
+
+		t.Run("Should return the plugin archive", func(t *testing.T) {
+			a, err := m.GetPluginArchive(context.Background(), "test-panel", "2.0.0", compatOpts)
+			require.NoError(t, err)
+			require.Len(t, a.File.File, 1)
+			require.Equal(t, "test-panel/plugin.json", a.File.File[0].Name)
+			require.NoError(t, a.File.Close())
+		})
+
+		t.Run("Should return error when the checksum does not match", func(t *testing.T) {
+			_, err := m.GetPluginArchive(context.Background(), "test-panel", "1.0.0", compatOpts)
+			require.Error(t, err)
+		})
+
+		t.Run("Should return error when the archive does not exist", func(t *testing.T) {
+			_, err := m.GetPluginArchive(context.Background(), "test-panel", "2.0.0", NewCompatOpts("9.2.0", "darwin", "arm64"))
+			require.Error(t, err)
+		})
+
+		t.Run("Should return not found error when the plugin does not exist", func(t *testing.T) {
+			_, err := m.GetPluginArchive(context.Background(), "unknown-panel", "", compatOpts)
+			var notFound Response4xxError
+			require.ErrorAs(t, err, &notFound)
+			require.Equal(t, 404, notFound.StatusCode)
+		})
+
+		t.Run("Should return error when the plugin ID is a path", func(t *testing.T) {
+			_, err := m.GetPluginArchive(context.Background(), "../repo/test-panel", "", compatOpts)
+			require.Error(t, err)
+		})
+	}
+}
+
+func zipArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "*.zip")
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	fw, err := w.Create(name)
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	return b
+}
+
 type versionArg struct {
 	version string
 	arch    []string
@@ -102,7 +102,7 @@ func HasGlobalAccess(ac AccessControl, service Service, c *models.ReqContext) fu
 			if err != nil {
 				c.Logger.Error("failed fetching permissions for user", "userID", userCopy.UserID, "error", err)
 			}
-			userCopy.Permissions[GlobalOrgID] = GroupScopesByAction(permissions)
+			userCopy.Permissions[GlobalOrgID] = restrictToToken(c, GroupScopesByAction(permissions))
 		}
 
 		hasAccess, err := ac.Evaluate(c.Req.Context(), &userCopy, evaluator)
@@ -179,6 +179,49 @@ func GroupScopesByAction(permissions []Permission) map[string][]string {
 	return m
 }
 
+// RestrictPermissions returns the permissions, grouped by action, which are granted both by permissions and by
+// restrictions. A restriction without scope keeps all the scopes of its action, a restriction with a scope keeps the
+// scope when it is covered by permissions, and the scopes of permissions it covers.
+func RestrictPermissions(permissions map[string][]string, restrictions map[string][]string) map[string][]string {
+	restricted := make(map[string][]string)
+	seen := make(map[string]map[string]bool)
+	add := func(action, scope string) {
+		if seen[action] == nil {
+			seen[action] = make(map[string]bool)
+		}
+		if !seen[action][scope] {
+			seen[action][scope] = true
+			restricted[action] = append(restricted[action], scope)
+		}
+	}
+
+	for action, restrictedScopes := range restrictions {
+		scopes, ok := permissions[action]
+		if !ok {
+			continue
+		}
+		for _, restrictedScope := range restrictedScopes {
+			if restrictedScope == "" {
+				for _, scope := range scopes {
+					add(action, scope)
+				}
+				continue
+			}
+			if EvalPermission(action, restrictedScope).Evaluate(permissions) {
+				add(action, restrictedScope)
+				continue
+			}
+			restriction := map[string][]string{action: {restrictedScope}}
+			for _, scope := range scopes {
+				if scope != "" && EvalPermission(action, scope).Evaluate(restriction) {
+					add(action, scope)
+				}
+			}
+		}
+	}
+	return restricted
+}
+
 func ValidateScope(scope string) bool {
 	prefix, last := scope[:len(scope)-1], scope[len(scope)-1]
 	// verify that last char is either ':' or '/' if last character of scope is '*'
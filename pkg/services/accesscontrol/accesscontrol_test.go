@@ -0,0 +1,63 @@
+package accesscontrol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestrictPermissions(t *testing.T) {
+	permissions := map[string][]string{
+		"dashboards:read":  {"folders:uid:a", "folders:uid:b"},
+		"dashboards:write": {"dashboards:*"},
+		"teams:read":       {"teams:id:1"},
+	}
+
+	tests := []struct {
+		name         string
+		restrictions map[string][]string
+		want         map[string][]string
+	}{
+		{
+			name:         "should keep nothing without restrictions",
+			restrictions: nil,
+			want:         map[string][]string{},
+		},
+		{
+			name:         "should keep all the scopes of an action without scope",
+			restrictions: map[string][]string{"dashboards:read": {""}},
+			want:         map[string][]string{"dashboards:read": {"folders:uid:a", "folders:uid:b"}},
+		},
+		{
+			name:         "should keep a scope covered by the permissions",
+			restrictions: map[string][]string{"dashboards:write": {"dashboards:uid:1"}},
+			want:         map[string][]string{"dashboards:write": {"dashboards:uid:1"}},
+		},
+		{
+			name:         "should keep the scopes of the permissions covered by a wildcard",
+			restrictions: map[string][]string{"dashboards:read": {"folders:*"}},
+			want:         map[string][]string{"dashboards:read": {"folders:uid:a", "folders:uid:b"}},
+		},
+		{
+			name:         "should drop a scope which isn't granted",
+			restrictions: map[string][]string{"teams:read": {"teams:id:2"}},
+			want:         map[string][]string{},
+		},
+		{
+			name:         "should drop an action which isn't granted",
+			restrictions: map[string][]string{"users:read": {""}, "dashboards:read": {"folders:uid:a"}},
+			want:         map[string][]string{"dashboards:read": {"folders:uid:a"}},
+		},
+		{
+			name:         "should not duplicate scopes",
+			restrictions: map[string][]string{"dashboards:read": {"", "folders:uid:a"}},
+			want:         map[string][]string{"dashboards:read": {"folders:uid:a", "folders:uid:b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RestrictPermissions(permissions, tt.restrictions))
+		})
+	}
+}
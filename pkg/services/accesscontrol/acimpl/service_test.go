@@ -2,6 +2,7 @@ package acimpl
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,8 +14,11 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func setupTestEnv(t testing.TB) *Service {
@@ -239,3 +243,39 @@ func TestService_RegisterFixedRoles(t *testing.T) {
 		})
 	}
 }
+
+func TestService_LoadPermissionsOfRestrictedTokens(t *testing.T) {
+	ac := setupTestEnv(t)
+	ac.cfg.RBACPermissionCache = true
+	ac.cache = localcache.ProvideService()
+	require.NoError(t, ac.DeclareFixedRoles(accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        "fixed:dashboards:writer",
+			Permissions: []accesscontrol.Permission{{Action: "dashboards:write", Scope: "dashboards:*"}},
+		},
+		Grants: []string{string(org.RoleEditor)},
+	}))
+	require.NoError(t, ac.RegisterFixedRoles(context.Background()))
+
+	// an Editor service account, with a restricted and an unrestricted token
+	loadPermissions := func(tokenPermissions map[string][]string) *models.ReqContext {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		c := &models.ReqContext{
+			Context:          &web.Context{Req: req},
+			SignedInUser:     &user.SignedInUser{UserID: 2, OrgID: 1, OrgRole: org.RoleEditor},
+			TokenPermissions: tokenPermissions,
+		}
+		accesscontrol.LoadPermissionsMiddleware(ac).(func(c *models.ReqContext))(c)
+		return c
+	}
+
+	for _, tokenPermissions := range []map[string][]string{{"dashboards:write": {""}}, nil, {"dashboards:write": {""}}} {
+		c := loadPermissions(tokenPermissions)
+		assert.Equal(t, []string{"dashboards:*"}, c.SignedInUser.Permissions[1]["dashboards:write"])
+		if tokenPermissions != nil {
+			assert.Equal(t, map[string][]string{"dashboards:write": {"dashboards:*"}}, c.SignedInUser.Permissions[1])
+			assert.Equal(t, org.RoleViewer, c.SignedInUser.OrgRole)
+		}
+	}
+}
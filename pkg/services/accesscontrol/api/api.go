@@ -30,6 +30,16 @@ func (api *AccessControlAPI) RegisterAPIEndpoints() {
 
 // GET /api/access-control/user/permissions
 func (api *AccessControlAPI) getUsersPermissions(c *models.ReqContext) response.Response {
+	// service account tokens with permissions only have the permissions of their service account they are restricted
+	// to, which are loaded with the role of the service account by the access control middleware
+	if c.TokenPermissions != nil {
+		permissionsMap := make(map[string]bool)
+		for action := range c.SignedInUser.Permissions[c.OrgID] {
+			permissionsMap[action] = true
+		}
+		return response.JSON(http.StatusOK, permissionsMap)
+	}
+
 	reloadCache := c.QueryBool("reloadcache")
 	permissions, err := api.Service.GetUserPermissions(c.Req.Context(),
 		c.SignedInUser, ac.Options{ReloadCache: reloadCache})
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -132,7 +133,7 @@ func AuthorizeInOrgMiddleware(ac AccessControl, service Service, cache userCache
 				if err != nil {
 					deny(c, nil, fmt.Errorf("failed to authenticate user in target org: %w", err))
 				}
-				userCopy.Permissions[userCopy.OrgID] = GroupScopesByAction(permissions)
+				userCopy.Permissions[userCopy.OrgID] = restrictToToken(c, GroupScopesByAction(permissions))
 			}
 
 			authorize(c, ac, &userCopy, evaluator)
@@ -161,6 +162,9 @@ func UseGlobalOrg(c *models.ReqContext) (int64, error) {
 func LoadPermissionsMiddleware(service Service) web.Handler {
 	return func(c *models.ReqContext) {
 		if service.IsDisabled() {
+			if c.TokenPermissions != nil {
+				c.JsonApiErr(http.StatusUnauthorized, "Tokens with permissions require role-based access control", nil)
+			}
 			return
 		}
 
@@ -174,8 +178,22 @@ func LoadPermissionsMiddleware(service Service) web.Handler {
 		if c.SignedInUser.Permissions == nil {
 			c.SignedInUser.Permissions = make(map[int64]map[string][]string)
 		}
-		c.SignedInUser.Permissions[c.OrgID] = GroupScopesByAction(permissions)
+		c.SignedInUser.Permissions[c.OrgID] = restrictToToken(c, GroupScopesByAction(permissions))
+
+		// the permissions are loaded with the role of the service account, so they are the same for all its tokens,
+		// routes which only check the role of the user are limited to the Viewer role for restricted tokens
+		if c.TokenPermissions != nil {
+			c.SignedInUser.OrgRole = org.RoleViewer
+		}
+	}
+}
+
+// restrictToToken restricts permissions to the permissions of the service account token of the request.
+func restrictToToken(c *models.ReqContext, permissions map[string][]string) map[string][]string {
+	if c.TokenPermissions == nil {
+		return permissions
 	}
+	return RestrictPermissions(permissions, c.TokenPermissions)
 }
 
 // scopeParams holds the parameters used to fill in scope templates
@@ -83,7 +83,67 @@ func TestMiddleware(t *testing.T) {
 	}
 }
 
-func contextProvider() web.Handler {
+func TestLoadPermissionsMiddleware(t *testing.T) {
+	tests := []struct {
+		desc                string
+		service             *mock.Mock
+		tokenPermissions    map[string][]string
+		expectedCode        int
+		expectedPermissions map[string][]string
+	}{
+		{
+			desc:                "should load the permissions of the user",
+			service:             mock.New().WithPermissions([]accesscontrol.Permission{{Action: "dashboards:read", Scope: "dashboards:*"}}),
+			expectedCode:        http.StatusOK,
+			expectedPermissions: map[string][]string{"dashboards:read": {"dashboards:*"}},
+		},
+		{
+			desc: "should restrict the permissions of the user to the permissions of the token",
+			service: mock.New().WithPermissions([]accesscontrol.Permission{
+				{Action: "dashboards:read", Scope: "dashboards:*"},
+				{Action: "dashboards:write", Scope: "dashboards:*"},
+			}),
+			tokenPermissions:    map[string][]string{"dashboards:read": {"dashboards:uid:1"}},
+			expectedCode:        http.StatusOK,
+			expectedPermissions: map[string][]string{"dashboards:read": {"dashboards:uid:1"}},
+		},
+		{
+			desc:             "should reject tokens with permissions if access control is disabled",
+			service:          mock.New().WithDisabled(),
+			tokenPermissions: map[string][]string{"dashboards:read": {""}},
+			expectedCode:     http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			server := web.New()
+			server.UseMiddleware(web.Renderer("../../public/views", "[[", "]]"))
+
+			server.Use(contextProvider(func(c *models.ReqContext) {
+				c.TokenPermissions = test.tokenPermissions
+			}))
+			server.Use(accesscontrol.LoadPermissionsMiddleware(test.service))
+
+			var permissions map[string][]string
+			server.Get("/", func(c *models.ReqContext) {
+				permissions = c.SignedInUser.Permissions[c.OrgID]
+				c.Resp.WriteHeader(http.StatusOK)
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedPermissions, permissions)
+		})
+	}
+}
+
+func contextProvider(modifiers ...func(c *models.ReqContext)) web.Handler {
 	return func(c *web.Context) {
 		reqCtx := &models.ReqContext{
 			Context:      c,
@@ -92,6 +152,9 @@ func contextProvider() web.Handler {
 			IsSignedIn:   true,
 			SkipCache:    true,
 		}
+		for _, modifier := range modifiers {
+			modifier(reqCtx)
+		}
 		c.Req = c.Req.WithContext(ctxkey.Set(c.Req.Context(), reqCtx))
 	}
 }
@@ -304,6 +304,7 @@ const (
 	ActionUsersLogout            = "users:logout"
 	ActionUsersQuotasList        = "users.quotas:read"
 	ActionUsersQuotasUpdate      = "users.quotas:write"
+	ActionUsersTOTPDelete        = "users.totp:delete"
 
 	// Org actions
 	ActionOrgsRead             = "orgs:read"
@@ -12,5 +12,7 @@ type Service interface {
 	GetApiKeyById(ctx context.Context, query *GetByIDQuery) error
 	GetApiKeyByName(ctx context.Context, query *GetByNameQuery) error
 	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
-	UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error
+	UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error
+	GetExpiringOrStaleAPIKeys(ctx context.Context, query *GetExpiringOrStaleQuery) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error
 }
@@ -45,6 +45,12 @@ func (s *Service) DeleteApiKey(ctx context.Context, cmd *apikey.DeleteCommand) e
 func (s *Service) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) error {
 	return s.store.AddAPIKey(ctx, cmd)
 }
-func (s *Service) UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error {
-	return s.store.UpdateAPIKeyLastUsedDate(ctx, tokenID)
+func (s *Service) UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error {
+	return s.store.UpdateAPIKeyLastUsed(ctx, tokenID, clientIP)
+}
+func (s *Service) GetExpiringOrStaleAPIKeys(ctx context.Context, query *apikey.GetExpiringOrStaleQuery) ([]*apikey.APIKey, error) {
+	return s.store.GetExpiringOrStaleAPIKeys(ctx, query)
+}
+func (s *Service) RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error {
+	return s.store.RevokeAPIKey(ctx, orgID, tokenID)
 }
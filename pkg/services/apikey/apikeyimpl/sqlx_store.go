@@ -89,6 +89,11 @@ func (ss *sqlxStore) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) erro
 	if !errors.Is(err, apikey.ErrInvalid) {
 		return apikey.ErrDuplicate
 	}
+	permissions, err := apikey.EncodePermissions(cmd.Permissions)
+	if err != nil {
+		return err
+	}
+
 	isRevoked := false
 	t := apikey.APIKey{
 		OrgId:            cmd.OrgId,
@@ -100,10 +105,11 @@ func (ss *sqlxStore) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) erro
 		Expires:          expires,
 		ServiceAccountId: nil,
 		IsRevoked:        &isRevoked,
+		Permissions:      permissions,
 	}
 
 	t.Id, err = ss.sess.ExecWithReturningId(ctx,
-		`INSERT INTO api_key (org_id, name, role, "key", created, updated, expires, service_account_id, is_revoked) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, t.OrgId, t.Name, t.Role, t.Key, t.Created, t.Updated, t.Expires, t.ServiceAccountId, t.IsRevoked)
+		`INSERT INTO api_key (org_id, name, role, "key", created, updated, expires, service_account_id, is_revoked, permissions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, t.OrgId, t.Name, t.Role, t.Key, t.Created, t.Updated, t.Expires, t.ServiceAccountId, t.IsRevoked, t.Permissions)
 	cmd.Result = &t
 	return err
 }
@@ -137,8 +143,38 @@ func (ss *sqlxStore) GetAPIKeyByHash(ctx context.Context, hash string) (*apikey.
 	return &key, err
 }
 
-func (ss *sqlxStore) UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error {
+func (ss *sqlxStore) UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error {
 	now := timeNow()
-	_, err := ss.sess.Exec(ctx, `UPDATE api_key SET last_used_at=? WHERE id=?`, &now, tokenID)
+	_, err := ss.sess.Exec(ctx, `UPDATE api_key SET last_used_at=?, last_used_ip=? WHERE id=?`, &now, clientIP, tokenID)
+	return err
+}
+
+func (ss *sqlxStore) GetExpiringOrStaleAPIKeys(ctx context.Context, query *apikey.GetExpiringOrStaleQuery) ([]*apikey.APIKey, error) {
+	where := []string{
+		"(is_revoked IS NULL OR is_revoked = ?)",
+		"(expires IS NULL OR expires > ?)",
+		"((expires IS NOT NULL AND expires <= ?) OR last_used_at < ? OR (last_used_at IS NULL AND created < ?))",
+	}
+	args := []interface{}{false, query.ExpiredSince.Unix(), query.ExpiresBefore.Unix(), query.UnusedSince, query.UnusedSince}
+	if query.OrgID != -1 {
+		where = append(where, "org_id = ?")
+		args = append(args, query.OrgID)
+	}
+
+	result := make([]*apikey.APIKey, 0)
+	qr := fmt.Sprintf(`SELECT * FROM api_key WHERE %s ORDER BY org_id ASC, name ASC`, strings.Join(where, " AND "))
+	err := ss.sess.Select(ctx, &result, qr, args...)
+	return result, err
+}
+
+func (ss *sqlxStore) RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error {
+	res, err := ss.sess.Exec(ctx, "UPDATE api_key SET is_revoked=?, updated=? WHERE id=? AND org_id=?", true, timeNow(), tokenID, orgID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		return apikey.ErrNotFound
+	}
 	return err
 }
@@ -14,5 +14,7 @@ type store interface {
 	GetApiKeyById(ctx context.Context, query *apikey.GetByIDQuery) error
 	GetApiKeyByName(ctx context.Context, query *apikey.GetByNameQuery) error
 	GetAPIKeyByHash(ctx context.Context, hash string) (*apikey.APIKey, error)
-	UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error
+	UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error
+	GetExpiringOrStaleAPIKeys(ctx context.Context, query *apikey.GetExpiringOrStaleQuery) ([]*apikey.APIKey, error)
+	RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error
 }
@@ -120,21 +120,41 @@ func testIntegrationApiKeyDataAccess(t *testing.T, fn getStore) {
 			assert.Equal(t, *query.Result.Expires, expected)
 		})
 
-		t.Run("Last Used At datetime update", func(t *testing.T) {
+		t.Run("Last Used At datetime and IP update", func(t *testing.T) {
 			// expires in one hour
 			cmd := apikey.AddCommand{OrgId: 1, Name: "last-update-at", Key: "asd3", SecondsToLive: 3600}
 			err := ss.AddAPIKey(context.Background(), &cmd)
 			require.NoError(t, err)
 
 			assert.Nil(t, cmd.Result.LastUsedAt)
+			assert.Nil(t, cmd.Result.LastUsedIP)
 
-			err = ss.UpdateAPIKeyLastUsedDate(context.Background(), cmd.Result.Id)
+			err = ss.UpdateAPIKeyLastUsed(context.Background(), cmd.Result.Id, "192.168.1.1")
 			require.NoError(t, err)
 
 			query := apikey.GetByNameQuery{KeyName: "last-update-at", OrgId: 1}
 			err = ss.GetApiKeyByName(context.Background(), &query)
 			assert.Nil(t, err)
 			assert.NotNil(t, query.Result.LastUsedAt)
+			require.NotNil(t, query.Result.LastUsedIP)
+			assert.Equal(t, "192.168.1.1", *query.Result.LastUsedIP)
+		})
+
+		t.Run("Add a key with permissions", func(t *testing.T) {
+			permissions := []apikey.Permission{
+				{Action: "dashboards:read", Scope: "folders:uid:general"},
+				{Action: "datasources:query"},
+			}
+			cmd := apikey.AddCommand{OrgId: 1, Name: "key-with-permissions", Key: "asd4", Permissions: permissions}
+			err := ss.AddAPIKey(context.Background(), &cmd)
+			require.NoError(t, err)
+
+			query := apikey.GetByNameQuery{KeyName: "key-with-permissions", OrgId: 1}
+			err = ss.GetApiKeyByName(context.Background(), &query)
+			require.NoError(t, err)
+			result, err := query.Result.GetPermissions()
+			require.NoError(t, err)
+			assert.Equal(t, permissions, result)
 		})
 
 		t.Run("Add a key with negative lifespan", func(t *testing.T) {
@@ -268,4 +288,52 @@ func testIntegrationApiKeyDataAccess(t *testing.T, fn getStore) {
 			})
 		}
 	})
+
+	t.Run("Testing expiring or stale API keys", func(t *testing.T) {
+		db := sqlstore.InitTestDB(t)
+		ss := fn(db)
+		ctx := context.Background()
+
+		now := time.Now()
+		addKey := func(name string, created time.Time, secondsToLive int64) *apikey.APIKey {
+			timeNow = func() time.Time { return created }
+			cmd := apikey.AddCommand{OrgId: 1, Name: name, Key: name, SecondsToLive: secondsToLive}
+			require.NoError(t, ss.AddAPIKey(ctx, &cmd))
+			return cmd.Result
+		}
+
+		longAgo := now.Add(-100 * 24 * time.Hour)
+		addKey("stale", longAgo, 0)
+		usedRecently := addKey("used-recently", longAgo, 0)
+		revoked := addKey("revoked", longAgo, 0)
+		addKey("expired", longAgo, 3600)
+		addKey("expired-recently", now.Add(-2*24*time.Hour), 3600)
+		addKey("expiring-soon", now, 3*24*3600)
+		addKey("expiring-later", now, 30*24*3600)
+		timeNow = func() time.Time { return now }
+
+		require.NoError(t, ss.UpdateAPIKeyLastUsed(ctx, usedRecently.Id, "192.168.1.1"))
+		require.NoError(t, ss.RevokeAPIKey(ctx, 1, revoked.Id))
+
+		t.Run("Should not revoke a key of another organization", func(t *testing.T) {
+			err := ss.RevokeAPIKey(ctx, 2, usedRecently.Id)
+			assert.ErrorIs(t, err, apikey.ErrNotFound)
+		})
+
+		t.Run("Should find the keys which expire soon or weren't used", func(t *testing.T) {
+			keys, err := ss.GetExpiringOrStaleAPIKeys(ctx, &apikey.GetExpiringOrStaleQuery{
+				OrgID:         -1,
+				ExpiresBefore: now.Add(7 * 24 * time.Hour),
+				UnusedSince:   now.Add(-90 * 24 * time.Hour),
+				ExpiredSince:  now.Add(-7 * 24 * time.Hour),
+			})
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(keys))
+			for _, key := range keys {
+				names = append(names, key.Name)
+			}
+			assert.Equal(t, []string{"expired-recently", "expiring-soon", "stale"}, names)
+		})
+	})
 }
@@ -97,6 +97,11 @@ func (ss *sqlStore) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) error
 			return apikey.ErrInvalidExpiration
 		}
 
+		permissions, err := apikey.EncodePermissions(cmd.Permissions)
+		if err != nil {
+			return err
+		}
+
 		isRevoked := false
 		t := apikey.APIKey{
 			OrgId:            cmd.OrgId,
@@ -108,6 +113,7 @@ func (ss *sqlStore) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) error
 			Expires:          expires,
 			ServiceAccountId: cmd.ServiceAccountID,
 			IsRevoked:        &isRevoked,
+			Permissions:      permissions,
 		}
 
 		if _, err := sess.Insert(&t); err != nil {
@@ -164,13 +170,44 @@ func (ss *sqlStore) GetAPIKeyByHash(ctx context.Context, hash string) (*apikey.A
 	return &key, err
 }
 
-func (ss *sqlStore) UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error {
+func (ss *sqlStore) UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error {
 	now := timeNow()
 	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		if _, err := sess.Table("api_key").ID(tokenID).Cols("last_used_at").Update(&apikey.APIKey{LastUsedAt: &now}); err != nil {
+		key := &apikey.APIKey{LastUsedAt: &now, LastUsedIP: &clientIP}
+		if _, err := sess.Table("api_key").ID(tokenID).Cols("last_used_at", "last_used_ip").Update(key); err != nil {
 			return err
 		}
 
 		return nil
 	})
 }
+
+func (ss *sqlStore) GetExpiringOrStaleAPIKeys(ctx context.Context, query *apikey.GetExpiringOrStaleQuery) ([]*apikey.APIKey, error) {
+	result := make([]*apikey.APIKey, 0)
+	err := ss.db.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		sess := dbSession.Where("(is_revoked IS NULL OR is_revoked = ?)", false).
+			Where("(expires IS NULL OR expires > ?)", query.ExpiredSince.Unix()).
+			Where("((expires IS NOT NULL AND expires <= ?) OR last_used_at < ? OR (last_used_at IS NULL AND created < ?))",
+				query.ExpiresBefore.Unix(), query.UnusedSince, query.UnusedSince).
+			Asc("org_id", "name")
+		if query.OrgID != -1 {
+			sess = sess.Where("org_id=?", query.OrgID)
+		}
+		return sess.Find(&result)
+	})
+	return result, err
+}
+
+func (ss *sqlStore) RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		isRevoked := true
+		key := &apikey.APIKey{IsRevoked: &isRevoked, Updated: timeNow()}
+		n, err := sess.Table("api_key").Where("id=? AND org_id=?", tokenID, orgID).Cols("is_revoked", "updated").Update(key)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return apikey.ErrNotFound
+		}
+		return nil
+	})
+}
@@ -37,6 +37,12 @@ func (s *Service) AddAPIKey(ctx context.Context, cmd *apikey.AddCommand) error {
 	cmd.Result = s.ExpectedAPIKey
 	return s.ExpectedError
 }
-func (s *Service) UpdateAPIKeyLastUsedDate(ctx context.Context, tokenID int64) error {
+func (s *Service) UpdateAPIKeyLastUsed(ctx context.Context, tokenID int64, clientIP string) error {
+	return s.ExpectedError
+}
+func (s *Service) GetExpiringOrStaleAPIKeys(ctx context.Context, query *apikey.GetExpiringOrStaleQuery) ([]*apikey.APIKey, error) {
+	return s.ExpectedAPIKeys, s.ExpectedError
+}
+func (s *Service) RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error {
 	return s.ExpectedError
 }
@@ -1,6 +1,7 @@
 package apikey
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -27,10 +28,46 @@ type APIKey struct {
 	Expires          *int64       `db:"expires"`
 	ServiceAccountId *int64       `db:"service_account_id"`
 	IsRevoked        *bool        `xorm:"is_revoked" db:"is_revoked"`
+	LastUsedIP       *string      `xorm:"last_used_ip" db:"last_used_ip"`
+	// Permissions are the JSON encoded permissions of a service account token, tokens without permissions have all
+	// the permissions of their service account
+	Permissions *string `xorm:"permissions" db:"permissions"`
 }
 
 func (k APIKey) TableName() string { return "api_key" }
 
+// Permission is an action, and optionally a scope, a service account token is restricted to.
+type Permission struct {
+	Action string `json:"action"`
+	Scope  string `json:"scope,omitempty"`
+}
+
+// GetPermissions returns the permissions a service account token is restricted to, or nil when the token isn't
+// restricted.
+func (k *APIKey) GetPermissions() ([]Permission, error) {
+	if k.Permissions == nil || *k.Permissions == "" {
+		return nil, nil
+	}
+	var permissions []Permission
+	if err := json.Unmarshal([]byte(*k.Permissions), &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// EncodePermissions returns the column value of the permissions of a service account token.
+func EncodePermissions(permissions []Permission) (*string, error) {
+	if len(permissions) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(permissions)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(data)
+	return &encoded, nil
+}
+
 // swagger:model
 type AddCommand struct {
 	Name             string       `json:"name" binding:"Required"`
@@ -39,6 +76,7 @@ type AddCommand struct {
 	Key              string       `json:"-"`
 	SecondsToLive    int64        `json:"secondsToLive"`
 	ServiceAccountID *int64       `json:"-"`
+	Permissions      []Permission `json:"-"`
 
 	Result *APIKey `json:"-"`
 }
@@ -60,6 +98,16 @@ type GetByNameQuery struct {
 	Result  *APIKey
 }
 
+// GetExpiringOrStaleQuery finds the API keys and service account tokens which expire before ExpiresBefore, or which
+// haven't been used since UnusedSince. Revoked keys and keys which expired before ExpiredSince aren't returned.
+type GetExpiringOrStaleQuery struct {
+	// OrgID is the organization of the keys, -1 for all organizations
+	OrgID         int64
+	ExpiresBefore time.Time
+	UnusedSince   time.Time
+	ExpiredSince  time.Time
+}
+
 type GetByIDQuery struct {
 	ApiKeyId int64
 	Result   *APIKey
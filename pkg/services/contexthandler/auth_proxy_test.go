@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/login/loginservice"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
 	"github.com/grafana/grafana/pkg/setting"
@@ -101,7 +102,7 @@ func getContextHandler(t *testing.T) *ContextHandler {
 	authenticator := &fakeAuthenticator{}
 
 	return ProvideService(cfg, userAuthTokenSvc, authJWTSvc, remoteCacheSvc,
-		renderSvc, sqlStore, tracer, authProxy, loginService, nil, authenticator, &userService)
+		renderSvc, sqlStore, tracer, authProxy, loginService, nil, authenticator, &userService, &totptest.FakeService{})
 }
 
 type FakeGetSignUserStore struct {
@@ -26,6 +26,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -44,6 +45,7 @@ func ProvideService(cfg *setting.Cfg, tokenService models.UserTokenService, jwtS
 	remoteCache *remotecache.RemoteCache, renderService rendering.Service, sqlStore sqlstore.Store,
 	tracer tracing.Tracer, authProxy *authproxy.AuthProxy, loginService login.Service,
 	apiKeyService apikey.Service, authenticator loginpkg.Authenticator, userService user.Service,
+	totpService totp.Service,
 ) *ContextHandler {
 	return &ContextHandler{
 		Cfg:              cfg,
@@ -58,6 +60,7 @@ func ProvideService(cfg *setting.Cfg, tokenService models.UserTokenService, jwtS
 		loginService:     loginService,
 		apiKeyService:    apiKeyService,
 		userService:      userService,
+		totpService:      totpService,
 	}
 }
 
@@ -75,6 +78,7 @@ type ContextHandler struct {
 	loginService     login.Service
 	apiKeyService    apikey.Service
 	userService      user.Service
+	totpService      totp.Service
 	// GetTime returns the current time.
 	// Stubbable by tests.
 	GetTime func() time.Time
@@ -293,8 +297,15 @@ func (h *ContextHandler) initContextWithAPIKey(reqContext *models.ReqContext) bo
 		return true
 	}
 
-	// update api_key last used date
-	if err := h.apiKeyService.UpdateAPIKeyLastUsedDate(reqContext.Req.Context(), apikey.Id); err != nil {
+	// update api_key last used date and IP
+	clientIP := ""
+	addr := reqContext.RemoteAddr()
+	if ip, err := network.GetIPFromAddress(addr); err == nil {
+		clientIP = ip.String()
+	} else {
+		reqContext.Logger.Debug("Failed to get client IP address", "addr", addr, "err", err)
+	}
+	if err := h.apiKeyService.UpdateAPIKeyLastUsed(reqContext.Req.Context(), apikey.Id, clientIP); err != nil {
 		reqContext.JsonApiErr(http.StatusInternalServerError, InvalidAPIKey, errKey)
 		return true
 	}
@@ -331,6 +342,20 @@ func (h *ContextHandler) initContextWithAPIKey(reqContext *models.ReqContext) bo
 		return true
 	}
 
+	restrictions, err := apikey.GetPermissions()
+	if err != nil {
+		reqContext.JsonApiErr(http.StatusInternalServerError, "Failed to read the permissions of the token", err)
+		return true
+	}
+	if restrictions != nil {
+		// the permissions of the service account, loaded with its role, are restricted by the access control
+		// middleware
+		reqContext.TokenPermissions = make(map[string][]string, len(restrictions))
+		for _, p := range restrictions {
+			reqContext.TokenPermissions[p.Action] = append(reqContext.TokenPermissions[p.Action], p.Scope)
+		}
+	}
+
 	reqContext.IsSignedIn = true
 	reqContext.SignedInUser = querySignedInUserResult
 
@@ -377,6 +402,19 @@ func (h *ContextHandler) initContextWithBasicAuth(reqContext *models.ReqContext,
 
 	usr := authQuery.User
 
+	// The password is only the first factor of these users, so basic authentication would bypass the second one
+	if h.Cfg.TOTPEnabled && authQuery.AuthModule == "grafana" {
+		required, err := h.requiresTOTP(ctx, usr.ID)
+		if err != nil {
+			reqContext.JsonApiErr(500, "Failed to check two-factor authentication", err)
+			return true
+		}
+		if required {
+			reqContext.JsonApiErr(401, "Basic authentication is not allowed for users with two-factor authentication, use a service account token instead", totp.ErrBasicAuthNotAllowed)
+			return true
+		}
+	}
+
 	query := user.GetSignedInUserQuery{UserID: usr.ID, OrgID: orgID}
 	queryResult, err := h.userService.GetSignedInUserWithCacheCtx(ctx, &query)
 	if err != nil {
@@ -394,6 +432,16 @@ func (h *ContextHandler) initContextWithBasicAuth(reqContext *models.ReqContext,
 	return true
 }
 
+// requiresTOTP returns whether the user has enabled two-factor authentication, or has to enroll because one of their
+// organizations enforces it.
+func (h *ContextHandler) requiresTOTP(ctx context.Context, userID int64) (bool, error) {
+	enabled, err := h.totpService.IsEnabled(ctx, userID)
+	if err != nil || enabled {
+		return enabled, err
+	}
+	return h.totpService.IsEnforcedForUser(ctx, userID)
+}
+
 func (h *ContextHandler) initContextWithToken(reqContext *models.ReqContext, orgID int64) bool {
 	if h.Cfg.LoginCookieName == "" {
 		return false
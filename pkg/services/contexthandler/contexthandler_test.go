@@ -11,6 +11,8 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
 	"github.com/stretchr/testify/assert"
@@ -79,6 +81,78 @@ func TestTokenRotationAtEndOfRequest(t *testing.T) {
 	assert.True(t, foundLoginCookie, "Could not find cookie")
 }
 
+func TestInitContextWithBasicAuth_TOTP(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		totpEnabled    bool
+		totpService    *totptest.FakeService
+		expectedStatus int
+		expectSignedIn bool
+	}{
+		{
+			desc:           "user without two-factor authentication is signed in",
+			totpEnabled:    true,
+			totpService:    &totptest.FakeService{},
+			expectSignedIn: true,
+		},
+		{
+			desc:           "user with two-factor authentication is rejected",
+			totpEnabled:    true,
+			totpService:    &totptest.FakeService{ExpectedEnabled: true},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "user of an organization which enforces two-factor authentication is rejected",
+			totpEnabled:    true,
+			totpService:    &totptest.FakeService{ExpectedEnforced: true},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "user with two-factor authentication is signed in when the feature is disabled",
+			totpEnabled:    false,
+			totpService:    &totptest.FakeService{ExpectedEnabled: true},
+			expectSignedIn: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctxHdlr := getContextHandler(t)
+			ctxHdlr.Cfg.BasicAuthEnabled = true
+			ctxHdlr.Cfg.TOTPEnabled = tc.totpEnabled
+			ctxHdlr.authenticator = &basicAuthenticator{user: &user.User{ID: userID}}
+			ctxHdlr.totpService = tc.totpService
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+			req.SetBasicAuth("admin", "password")
+			reqContext := &models.ReqContext{
+				Context: &web.Context{Req: req, Resp: web.NewResponseWriter("GET", rr)},
+				Logger:  log.New("Test"),
+			}
+
+			handled := ctxHdlr.initContextWithBasicAuth(reqContext, orgID)
+			require.True(t, handled)
+			assert.Equal(t, tc.expectSignedIn, reqContext.IsSignedIn)
+			if tc.expectedStatus != 0 {
+				assert.Equal(t, tc.expectedStatus, rr.Code)
+				assert.Contains(t, rr.Body.String(), "service account token")
+			}
+		})
+	}
+}
+
+type basicAuthenticator struct {
+	user *user.User
+}
+
+func (a *basicAuthenticator) AuthenticateUser(c context.Context, query *models.LoginUserQuery) error {
+	query.User = a.user
+	query.AuthModule = "grafana"
+	return nil
+}
+
 func initTokenRotationScenario(ctx context.Context, t *testing.T, ctxHdlr *ContextHandler) (
 	*models.ReqContext, *httptest.ResponseRecorder, error) {
 	t.Helper()
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/datasource"
 	"github.com/grafana/grafana/pkg/api/pluginproxy"
@@ -15,7 +16,9 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/concurrency"
 	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -25,7 +28,8 @@ import (
 func ProvideService(dataSourceCache datasources.CacheService, plugReqValidator models.PluginRequestValidator,
 	pluginStore plugins.Store, cfg *setting.Cfg, httpClientProvider httpclient.Provider,
 	oauthTokenService *oauthtoken.Service, dsService datasources.DataSourceService,
-	tracer tracing.Tracer, secretsService secrets.Service) *DataSourceProxyService {
+	tracer tracing.Tracer, secretsService secrets.Service, limiter *concurrency.Limiter,
+	auditService *queryaudit.Service) *DataSourceProxyService {
 	return &DataSourceProxyService{
 		DataSourceCache:        dataSourceCache,
 		PluginRequestValidator: plugReqValidator,
@@ -36,6 +40,8 @@ func ProvideService(dataSourceCache datasources.CacheService, plugReqValidator m
 		DataSourcesService:     dsService,
 		tracer:                 tracer,
 		secretsService:         secretsService,
+		limiter:                limiter,
+		auditService:           auditService,
 	}
 }
 
@@ -49,6 +55,8 @@ type DataSourceProxyService struct {
 	DataSourcesService     datasources.DataSourceService
 	tracer                 tracing.Tracer
 	secretsService         secrets.Service
+	limiter                *concurrency.Limiter
+	auditService           *queryaudit.Service
 }
 
 func (p *DataSourceProxyService) ProxyDataSourceRequest(c *models.ReqContext) {
@@ -128,7 +136,53 @@ func (p *DataSourceProxyService) proxyDatasourceRequest(c *models.ReqContext, ds
 		}
 		return
 	}
+
+	ctx, release, err := p.limiter.Acquire(c.Req.Context(), ds)
+	if err != nil {
+		if errors.Is(err, concurrency.ErrTooManyConcurrentQueries) || errors.Is(err, concurrency.ErrQueueTimeout) {
+			c.JsonApiErr(http.StatusTooManyRequests, "Too many concurrent queries for the data source", err)
+		} else {
+			c.JsonApiErr(http.StatusInternalServerError, "Failed waiting for the data source", err)
+		}
+		return
+	}
+	defer release()
+	c.Req = c.Req.WithContext(ctx)
+
+	start := time.Now()
 	proxy.HandleRequest()
+	p.recordAudit(c, ds, proxyPath, time.Since(start))
+}
+
+// recordAudit records a query audit event for the proxied request.
+func (p *DataSourceProxyService) recordAudit(c *models.ReqContext, ds *datasources.DataSource, proxyPath string, duration time.Duration) {
+	if p.auditService.IsDisabled() {
+		return
+	}
+
+	dashboardUID, panelID := queryaudit.DashboardContext(c.Req)
+	e := queryaudit.Event{
+		OrgID:          c.SignedInUser.OrgID,
+		UserID:         c.SignedInUser.UserID,
+		UserLogin:      c.SignedInUser.Login,
+		Source:         queryaudit.SourceProxy,
+		DataSourceUID:  ds.Uid,
+		DataSourceType: ds.Type,
+		DashboardUID:   dashboardUID,
+		PanelID:        panelID,
+		DurationMs:     duration.Milliseconds(),
+		Status:         queryaudit.StatusSuccess,
+		StatusCode:     c.Resp.Status(),
+		Query:          c.Req.Method + " " + proxyPath,
+	}
+	if c.Req.URL.RawQuery != "" {
+		e.Query += "?" + c.Req.URL.RawQuery
+	}
+	if e.StatusCode >= http.StatusBadRequest {
+		e.Status = queryaudit.StatusError
+		e.Error = http.StatusText(e.StatusCode)
+	}
+	p.auditService.Record(e)
 }
 
 var proxyPathRegexp = regexp.MustCompile(`^\/api\/datasources\/proxy\/([\d]+|uid\/[\w]+)\/?`)
@@ -0,0 +1,268 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+var (
+	ErrTooManyConcurrentQueries = errutil.NewBase(errutil.StatusTooManyRequests, "datasource.tooManyConcurrentQueries",
+		errutil.WithPublicMessage("Too many concurrent queries for the data source, try again later")).Errorf("too many concurrent queries for the data source")
+	ErrQueueTimeout = errutil.NewBase(errutil.StatusTooManyRequests, "datasource.queryQueueTimeout",
+		errutil.WithPublicMessage("Timed out waiting for a query slot of the data source, try again later")).Errorf("timed out waiting for a query slot of the data source")
+
+	queueDepthGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Name:      "datasource_query_queue_depth",
+			Help:      "A gauge of data source queries waiting for a concurrency slot",
+		},
+		[]string{"org_id", "datasource_uid"},
+	)
+	inFlightGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Name:      "datasource_query_limited_in_flight",
+			Help:      "A gauge of data source queries holding a concurrency slot",
+		},
+		[]string{"org_id", "datasource_uid"},
+	)
+	rejectedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "grafana",
+			Name:      "datasource_query_rejected_total",
+			Help:      "A counter for data source queries rejected because of the concurrency limits",
+		},
+		[]string{"org_id", "datasource_uid", "reason"},
+	)
+)
+
+func ProvideService(cfg *setting.Cfg) *Limiter {
+	return NewLimiter(cfg.Query)
+}
+
+// NewLimiter returns a limiter that enforces the concurrency limits of the settings.
+func NewLimiter(cfg setting.QuerySettings) *Limiter {
+	return &Limiter{
+		cfg:          cfg,
+		active:       map[dsKey]int{},
+		activePerOrg: map[int64]int{},
+		waitingPerDS: map[dsKey]int{},
+		log:          log.New("datasources.concurrency"),
+	}
+}
+
+type dsKey struct {
+	orgID int64
+	uid   string
+}
+
+type waiter struct {
+	ds       dsKey
+	dsLimit  int
+	orgLimit int
+	granted  bool
+	ready    chan struct{}
+}
+
+// Limiter bounds the number of queries that are sent at the same time to a data source
+// and to all data sources of an organization. Queries over the limit wait in a bounded
+// queue, in the order they arrived, until a slot is released or the wait times out.
+type Limiter struct {
+	cfg setting.QuerySettings
+
+	mu           sync.Mutex
+	active       map[dsKey]int
+	activePerOrg map[int64]int
+	waiting      []*waiter
+	waitingPerDS map[dsKey]int
+
+	log log.Logger
+}
+
+// Acquire waits until the query can be sent to the data source and returns the context the query must be
+// sent with, and a function that must be called once the query is done. It fails with
+// ErrTooManyConcurrentQueries when the queue of the data source is full, and with ErrQueueTimeout when the
+// wait times out. The context of a limited query expires after the execution timeout, and its slot is then
+// released even if the query doesn't stop.
+func (l *Limiter) Acquire(ctx context.Context, ds *datasources.DataSource) (context.Context, func(), error) {
+	if l == nil {
+		return ctx, func() {}, nil
+	}
+
+	dsLimit, orgLimit := l.limits(ds)
+	if dsLimit <= 0 && orgLimit <= 0 {
+		return ctx, func() {}, nil
+	}
+
+	key := dsKey{orgID: ds.OrgId, uid: ds.Uid}
+	orgLabel, uidLabel := strconv.FormatInt(ds.OrgId, 10), ds.Uid
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.active[key]--
+		if l.active[key] <= 0 {
+			delete(l.active, key)
+		}
+		l.activePerOrg[key.orgID]--
+		if l.activePerOrg[key.orgID] <= 0 {
+			delete(l.activePerOrg, key.orgID)
+		}
+		inFlightGauge.WithLabelValues(orgLabel, uidLabel).Dec()
+		l.dispatch()
+	}
+
+	l.mu.Lock()
+	if l.waitingPerDS[key] == 0 && l.canRun(key, dsLimit, orgLimit) {
+		l.take(key)
+		l.mu.Unlock()
+		inFlightGauge.WithLabelValues(orgLabel, uidLabel).Inc()
+		ctx, release := l.withExecutionTimeout(ctx, ds, release)
+		return ctx, release, nil
+	}
+	if l.waitingPerDS[key] >= l.cfg.ConcurrentQueryQueueSize {
+		l.mu.Unlock()
+		rejectedCounter.WithLabelValues(orgLabel, uidLabel, "queue_full").Inc()
+		return nil, nil, ErrTooManyConcurrentQueries
+	}
+	w := &waiter{ds: key, dsLimit: dsLimit, orgLimit: orgLimit, ready: make(chan struct{})}
+	l.waiting = append(l.waiting, w)
+	l.waitingPerDS[key]++
+	l.mu.Unlock()
+	queueDepthGauge.WithLabelValues(orgLabel, uidLabel).Inc()
+	defer queueDepthGauge.WithLabelValues(orgLabel, uidLabel).Dec()
+
+	var timeout <-chan time.Time
+	if l.cfg.ConcurrentQueryQueueTimeout > 0 {
+		timer := time.NewTimer(l.cfg.ConcurrentQueryQueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		inFlightGauge.WithLabelValues(orgLabel, uidLabel).Inc()
+		ctx, release := l.withExecutionTimeout(ctx, ds, release)
+		return ctx, release, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+
+	l.mu.Lock()
+	if w.granted {
+		// the slot was granted while the wait was being abandoned.
+		l.mu.Unlock()
+		inFlightGauge.WithLabelValues(orgLabel, uidLabel).Inc()
+		release()
+		return nil, nil, err
+	}
+	l.remove(w)
+	l.dispatch()
+	l.mu.Unlock()
+	if errors.Is(err, ErrQueueTimeout) {
+		rejectedCounter.WithLabelValues(orgLabel, uidLabel, "timeout").Inc()
+		l.log.Debug("Query timed out waiting for a slot of the data source", "datasource", ds.Uid, "orgId", ds.OrgId)
+	}
+	return nil, nil, err
+}
+
+// withExecutionTimeout returns the context of a query holding a slot, which expires after the execution timeout,
+// and the function releasing the slot. The slot is released when the timeout expires, so that a query which
+// doesn't stop when its context expires doesn't hold the slot forever.
+func (l *Limiter) withExecutionTimeout(ctx context.Context, ds *datasources.DataSource, release func()) (context.Context, func()) {
+	var once sync.Once
+	releaseOnce := func() { once.Do(release) }
+
+	timeout := l.cfg.ConcurrentQueryExecutionTimeout
+	if timeout <= 0 {
+		return ctx, releaseOnce
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	timer := time.AfterFunc(timeout, func() {
+		l.log.Warn("Query exceeded the execution timeout of the data source, releasing its slot", "datasource", ds.Uid, "orgId", ds.OrgId, "timeout", timeout)
+		releaseOnce()
+	})
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+		releaseOnce()
+	}
+}
+
+// limits returns the limit of concurrent queries for the data source and for its organization.
+// A data source can override the limit with `concurrentQueryLimit` in its JSON data.
+func (l *Limiter) limits(ds *datasources.DataSource) (int, int) {
+	dsLimit := l.cfg.ConcurrentQueryLimitPerDataSource
+	if ds.JsonData != nil {
+		if limit := ds.JsonData.Get("concurrentQueryLimit").MustInt(0); limit > 0 {
+			dsLimit = limit
+		}
+	}
+	return dsLimit, l.cfg.ConcurrentQueryLimitPerOrg
+}
+
+func (l *Limiter) canRun(key dsKey, dsLimit, orgLimit int) bool {
+	if dsLimit > 0 && l.active[key] >= dsLimit {
+		return false
+	}
+	if orgLimit > 0 && l.activePerOrg[key.orgID] >= orgLimit {
+		return false
+	}
+	return true
+}
+
+func (l *Limiter) take(key dsKey) {
+	l.active[key]++
+	l.activePerOrg[key.orgID]++
+}
+
+// dispatch grants slots to the waiting queries that can run now, in the order they arrived.
+// A query of a data source is not granted a slot before the earlier queries of the same data source.
+func (l *Limiter) dispatch() {
+	blocked := map[dsKey]bool{}
+	for i := 0; i < len(l.waiting); {
+		w := l.waiting[i]
+		if blocked[w.ds] || !l.canRun(w.ds, w.dsLimit, w.orgLimit) {
+			blocked[w.ds] = true
+			i++
+			continue
+		}
+		l.take(w.ds)
+		w.granted = true
+		close(w.ready)
+		l.removeAt(i)
+	}
+}
+
+func (l *Limiter) remove(w *waiter) {
+	for i := range l.waiting {
+		if l.waiting[i] == w {
+			l.removeAt(i)
+			return
+		}
+	}
+}
+
+func (l *Limiter) removeAt(i int) {
+	w := l.waiting[i]
+	l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+	l.waitingPerDS[w.ds]--
+	if l.waitingPerDS[w.ds] <= 0 {
+		delete(l.waitingPerDS, w.ds)
+	}
+}
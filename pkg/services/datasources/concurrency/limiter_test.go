@@ -0,0 +1,162 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestLimiter(t *testing.T) {
+	ds1 := &datasources.DataSource{OrgId: 1, Uid: "ds1", Name: "ds1"}
+	ds2 := &datasources.DataSource{OrgId: 1, Uid: "ds2", Name: "ds2"}
+
+	acquireAsync := func(l *Limiter, ds *datasources.DataSource) chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			_, release, err := l.Acquire(context.Background(), ds)
+			if err == nil {
+				release()
+			}
+			errCh <- err
+		}()
+		return errCh
+	}
+
+	waiting := func(l *Limiter) int {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.waiting)
+	}
+
+	t.Run("does not limit without limits", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{})
+		for i := 0; i < 10; i++ {
+			_, _, err := l.Acquire(context.Background(), ds1)
+			require.NoError(t, err)
+		}
+		require.Empty(t, l.active)
+	})
+
+	t.Run("nil limiter does not limit", func(t *testing.T) {
+		var l *Limiter
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("queued query runs when a slot is released", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: time.Minute})
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+
+		errCh := acquireAsync(l, ds1)
+		require.Eventually(t, func() bool { return waiting(l) == 1 }, time.Second, 10*time.Millisecond)
+
+		release()
+		require.NoError(t, <-errCh)
+		require.Empty(t, l.active)
+		require.Empty(t, l.waitingPerDS)
+	})
+
+	t.Run("rejects queries when the queue is full", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: time.Minute})
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+
+		errCh := acquireAsync(l, ds1)
+		require.Eventually(t, func() bool { return waiting(l) == 1 }, time.Second, 10*time.Millisecond)
+
+		_, _, err = l.Acquire(context.Background(), ds1)
+		require.ErrorIs(t, err, ErrTooManyConcurrentQueries)
+
+		// other data sources are not affected
+		_, release2, err := l.Acquire(context.Background(), ds2)
+		require.NoError(t, err)
+		release2()
+
+		release()
+		require.NoError(t, <-errCh)
+	})
+
+	t.Run("rejects queries that wait too long", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: 10 * time.Millisecond})
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+		defer release()
+
+		_, _, err = l.Acquire(context.Background(), ds1)
+		require.ErrorIs(t, err, ErrQueueTimeout)
+		require.Empty(t, l.waiting)
+	})
+
+	t.Run("stops waiting when the context is canceled", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: time.Minute})
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err = l.Acquire(ctx, ds1)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, l.waiting)
+	})
+
+	t.Run("limits the queries of all data sources of an organization", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerOrg: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: time.Minute})
+		_, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+
+		errCh := acquireAsync(l, ds2)
+		require.Eventually(t, func() bool { return waiting(l) == 1 }, time.Second, 10*time.Millisecond)
+
+		// other organizations are not affected
+		_, release2, err := l.Acquire(context.Background(), &datasources.DataSource{OrgId: 2, Uid: "ds3"})
+		require.NoError(t, err)
+		release2()
+
+		release()
+		require.NoError(t, <-errCh)
+	})
+
+	t.Run("releases the slot when the execution timeout expires", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: time.Minute, ConcurrentQueryExecutionTimeout: 10 * time.Millisecond})
+		ctx, release, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+		defer release()
+
+		// the query doesn't stop when its context expires, the next query gets the slot anyway
+		errCh := acquireAsync(l, ds1)
+		<-ctx.Done()
+		require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+		require.NoError(t, <-errCh)
+
+		// releasing the expired query afterwards doesn't release another slot
+		release()
+		require.Empty(t, l.active)
+		_, release2, err := l.Acquire(context.Background(), ds1)
+		require.NoError(t, err)
+		release()
+		require.Equal(t, 1, l.active[dsKey{orgID: ds1.OrgId, uid: ds1.Uid}])
+		release2()
+	})
+
+	t.Run("data source can override the limit", func(t *testing.T) {
+		l := NewLimiter(setting.QuerySettings{ConcurrentQueryLimitPerDataSource: 1, ConcurrentQueryQueueSize: 1, ConcurrentQueryQueueTimeout: 10 * time.Millisecond})
+		ds := &datasources.DataSource{OrgId: 1, Uid: "ds4", JsonData: simplejson.NewFromAny(map[string]interface{}{"concurrentQueryLimit": 2})}
+		_, release1, err := l.Acquire(context.Background(), ds)
+		require.NoError(t, err)
+		_, release2, err := l.Acquire(context.Background(), ds)
+		require.NoError(t, err)
+		_, _, err = l.Acquire(context.Background(), ds)
+		require.ErrorIs(t, err, ErrQueueTimeout)
+		release1()
+		release2()
+	})
+}
@@ -54,6 +54,23 @@ type DataSourceService interface {
 	// DecryptedPassword decrypts the encrypted datasource password and returns the
 	// decrypted value.
 	DecryptedPassword(ctx context.Context, ds *DataSource) (string, error)
+
+	// RotateSecrets stores new secureJSONData values next to the current ones until the grace period ends.
+	RotateSecrets(ctx context.Context, cmd *RotateDataSourceSecretsCommand) error
+
+	// GetSecretRotation returns the secret rotation of the datasource, or ErrSecretRotationNotFound.
+	GetSecretRotation(ctx context.Context, ds *DataSource) (*SecretRotation, error)
+
+	// InSecretRotationGracePeriod returns true if the datasource is in the grace period of a secret rotation, so that
+	// a request which failed with the pending secureJSONData can be retried with the current one, see WithCurrentSecrets.
+	InSecretRotationGracePeriod(ctx context.Context, ds *DataSource) (bool, error)
+
+	// SetSecretRotationCredentialInUse records the secureJSONData of the last request of the datasource which
+	// succeeded during the grace period of its secret rotation.
+	SetSecretRotationCredentialInUse(ctx context.Context, ds *DataSource, credential SecretRotationCredential) error
+
+	// CompleteSecretRotation replaces the current secureJSONData of the datasource with the pending values.
+	CompleteSecretRotation(ctx context.Context, ds *DataSource) error
 }
 
 // CacheService interface for retrieving a cached datasource.
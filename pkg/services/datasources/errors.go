@@ -11,4 +11,7 @@ var (
 	ErrDataSourceFailedGenerateUniqueUid = errors.New("failed to generate unique datasource ID")
 	ErrDataSourceIdentifierNotSet        = errors.New("unique identifier and org id are needed to be able to get or delete a datasource")
 	ErrDatasourceIsReadOnly              = errors.New("data source is readonly, can only be updated from configuration")
+	ErrSecretRotationNotFound            = errors.New("data source has no secret rotation in progress")
+	ErrSecretRotationInProgress          = errors.New("data source already has a secret rotation in progress")
+	ErrSecretRotationInvalidGracePeriod  = errors.New("invalid secret rotation grace period")
 )
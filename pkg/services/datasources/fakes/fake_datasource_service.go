@@ -134,3 +134,23 @@ func (s *FakeDataSourceService) DecryptedBasicAuthPassword(ctx context.Context,
 func (s *FakeDataSourceService) DecryptedPassword(ctx context.Context, ds *datasources.DataSource) (string, error) {
 	return "", nil
 }
+
+func (s *FakeDataSourceService) RotateSecrets(ctx context.Context, cmd *datasources.RotateDataSourceSecretsCommand) error {
+	return nil
+}
+
+func (s *FakeDataSourceService) GetSecretRotation(ctx context.Context, ds *datasources.DataSource) (*datasources.SecretRotation, error) {
+	return nil, datasources.ErrSecretRotationNotFound
+}
+
+func (s *FakeDataSourceService) InSecretRotationGracePeriod(ctx context.Context, ds *datasources.DataSource) (bool, error) {
+	return false, nil
+}
+
+func (s *FakeDataSourceService) SetSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) error {
+	return nil
+}
+
+func (s *FakeDataSourceService) CompleteSecretRotation(ctx context.Context, ds *datasources.DataSource) error {
+	return datasources.ErrSecretRotationNotFound
+}
@@ -0,0 +1,65 @@
+package healthcheck
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+// UnhealthyAlertName is the name of the alert sent while a data source fails its health check.
+const UnhealthyAlertName = "DatasourceUnhealthy"
+
+type alertSender interface {
+	PutAlerts(orgID int64, alert *models.PostableAlert) error
+}
+
+// ngalertSender sends the alerts to the Grafana Alertmanager of the organization.
+type ngalertSender struct {
+	ng *ngalert.AlertNG
+}
+
+func (s *ngalertSender) PutAlerts(orgID int64, alert *models.PostableAlert) error {
+	if s.ng == nil || s.ng.MultiOrgAlertmanager == nil {
+		return errors.New("unified alerting is not enabled")
+	}
+	am, err := s.ng.MultiOrgAlertmanager.AlertmanagerFor(orgID)
+	if err != nil {
+		return err
+	}
+	return am.PutAlerts(apimodels.PostableAlerts{PostableAlerts: []models.PostableAlert{*alert}})
+}
+
+func unhealthyAlert(ds *datasources.DataSource, result Result, appURL string, startsAt, endsAt time.Time) *models.PostableAlert {
+	var generatorURL string
+	if u, err := url.Parse(appURL); err == nil {
+		u.Path = path.Join(u.Path, "datasources/edit", ds.Uid)
+		generatorURL = u.String()
+	}
+
+	return &models.PostableAlert{
+		Annotations: models.LabelSet{
+			"message": result.Message,
+			"status":  result.Status,
+		},
+		StartsAt: strfmt.DateTime(startsAt),
+		EndsAt:   strfmt.DateTime(endsAt),
+		Alert: models.Alert{
+			Labels: models.LabelSet{
+				model.AlertNameLabel: UnhealthyAlertName,
+				"datasource_uid":     ds.Uid,
+				"datasource_name":    ds.Name,
+				"datasource_type":    ds.Type,
+			},
+			GeneratorURL: strfmt.URI(generatorURL),
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *Service) registerAPIEndpoints() {
+	auth := ac.Middleware(s.accessControl)
+	uidScope := datasources.ScopeProvider.GetResourceScopeUID(ac.Parameter(":uid"))
+
+	s.routeRegister.Group("/api/datasources/uid/:uid/health", func(route routing.RouteRegister) {
+		route.Get("/history", auth(middleware.ReqSignedIn, ac.EvalPermission(datasources.ActionQuery, uidScope)), routing.Wrap(s.historyHandler))
+	})
+}
+
+// swagger:route GET /datasources/uid/{uid}/health/history datasources getDatasourceHealthHistory
+//
+// Get the results of the recent periodic health checks of the data source, the most recent first.
+// The results are kept in memory by the Grafana instance which serves the request, and are lost on restart.
+//
+// Responses:
+// 200: getDatasourceHealthHistoryResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *Service) historyHandler(c *models.ReqContext) response.Response {
+	dsUID := web.Params(c.Req)[":uid"]
+	if !util.IsValidShortUID(dsUID) {
+		return response.Error(http.StatusBadRequest, "UID is invalid", nil)
+	}
+
+	ds, err := s.dataSourceCache.GetDatasourceByUID(c.Req.Context(), dsUID, c.SignedInUser, c.SkipCache)
+	if err != nil {
+		if errors.Is(err, datasources.ErrDataSourceNotFound) {
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		}
+		if errors.Is(err, datasources.ErrDataSourceAccessDenied) {
+			return response.Error(http.StatusForbidden, "Access denied to datasource", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Unable to load datasource metadata", err)
+	}
+
+	return response.JSON(http.StatusOK, s.History(ds.OrgId, ds.Uid))
+}
+
+// swagger:parameters getDatasourceHealthHistory
+type GetDatasourceHealthHistoryParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"uid"`
+}
+
+// swagger:response getDatasourceHealthHistoryResponse
+type GetDatasourceHealthHistoryResponse struct {
+	// in: body
+	Body []Result `json:"body"`
+}
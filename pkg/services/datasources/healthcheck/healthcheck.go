@@ -0,0 +1,287 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/adapters"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// maxConcurrentChecks is the number of data sources that are checked at the same time.
+const maxConcurrentChecks = 10
+
+var (
+	healthyGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Name:      "datasource_health_check_healthy",
+			Help:      "A gauge that is 1 if the last health check of the data source succeeded and 0 otherwise",
+		},
+		[]string{"org_id", "datasource_uid", "type"},
+	)
+	checkDurationHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "grafana",
+			Name:      "datasource_health_check_duration_seconds",
+			Help:      "Histogram of durations of data source health checks",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 25},
+		},
+		[]string{"org_id", "datasource_uid", "type"},
+	)
+)
+
+type dsKey struct {
+	orgID int64
+	uid   string
+}
+
+// Service periodically runs the health check of the plugin of every data source,
+// and keeps the most recent results of each data source.
+type Service struct {
+	cfg               *setting.Cfg
+	dataSourceService datasources.DataSourceService
+	dataSourceCache   datasources.CacheService
+	pluginStore       plugins.Store
+	pluginClient      plugins.Client
+	oAuthTokenService oauthtoken.OAuthTokenService
+	alerts            alertSender
+	routeRegister     routing.RouteRegister
+	accessControl     accesscontrol.AccessControl
+	log               log.Logger
+
+	mu sync.RWMutex
+	// history is kept in memory, so each instance only has the results of its own checks since it started.
+	history map[dsKey]*history
+}
+
+func ProvideService(cfg *setting.Cfg, dataSourceService datasources.DataSourceService, dataSourceCache datasources.CacheService,
+	pluginStore plugins.Store, pluginClient plugins.Client, oAuthTokenService oauthtoken.OAuthTokenService, ng *ngalert.AlertNG,
+	routeRegister routing.RouteRegister, accessControl accesscontrol.AccessControl) *Service {
+	s := &Service{
+		cfg:               cfg,
+		dataSourceService: dataSourceService,
+		dataSourceCache:   dataSourceCache,
+		pluginStore:       pluginStore,
+		pluginClient:      pluginClient,
+		oAuthTokenService: oAuthTokenService,
+		routeRegister:     routeRegister,
+		accessControl:     accessControl,
+		log:               log.New("datasources.healthcheck"),
+		history:           map[dsKey]*history{},
+	}
+	if cfg.DataSourceHealthCheckAlerts {
+		s.alerts = &ngalertSender{ng: ng}
+	}
+
+	if !s.IsDisabled() {
+		s.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+// IsDisabled returns true if the periodic health checks are not configured.
+func (s *Service) IsDisabled() bool {
+	return s.cfg.DataSourceHealthCheckInterval <= 0
+}
+
+// Run checks the health of all data sources at the configured interval until the context is canceled.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.DataSourceHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.checkAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// History returns the health check results of the data source, the most recent first.
+func (s *Service) History(orgID int64, uid string) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.history[dsKey{orgID: orgID, uid: uid}]
+	if !ok {
+		return []Result{}
+	}
+	return h.results()
+}
+
+func (s *Service) checkAll(ctx context.Context) {
+	query := &datasources.GetAllDataSourcesQuery{}
+	if err := s.dataSourceService.GetAllDataSources(ctx, query); err != nil {
+		s.log.Error("Failed to list data sources for health checks", "error", err)
+		return
+	}
+
+	seen := make(map[dsKey]struct{}, len(query.Result))
+	sem := make(chan struct{}, maxConcurrentChecks)
+	var wg sync.WaitGroup
+	for _, ds := range query.Result {
+		seen[dsKey{orgID: ds.OrgId, uid: ds.Uid}] = struct{}{}
+		if !s.canCheck(ctx, ds) {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(ds *datasources.DataSource) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.record(ds, s.check(ctx, ds))
+		}(ds)
+	}
+	wg.Wait()
+
+	// forget the data sources that were deleted
+	s.mu.Lock()
+	for key := range s.history {
+		if _, ok := seen[key]; !ok {
+			delete(s.history, key)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// canCheck returns true if the health of the data source can be checked without a user.
+func (s *Service) canCheck(ctx context.Context, ds *datasources.DataSource) bool {
+	plugin, exists := s.pluginStore.Plugin(ctx, ds.Type)
+	if !exists || !plugin.Backend {
+		return false
+	}
+	// the credentials of these data sources belong to the signed in user.
+	return !s.oAuthTokenService.IsOAuthPassThruEnabled(ds)
+}
+
+func (s *Service) check(ctx context.Context, ds *datasources.DataSource) Result {
+	start := time.Now()
+	result := Result{Timestamp: start, Status: backend.HealthStatusUnknown.String()}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.DataSourceHealthCheckTimeout)
+	defer cancel()
+
+	resp, err := s.checkHealth(ctx, ctx, ds, ds.Id)
+	if err != nil || resp.Status != backend.HealthStatusOk {
+		// during the grace period of a secret rotation the data source may still only accept its current secrets.
+		if inGracePeriod, graceErr := s.dataSourceService.InSecretRotationGracePeriod(ctx, ds); graceErr == nil && inGracePeriod {
+			retryResp, retryErr := s.checkHealth(ctx, datasources.WithCurrentSecrets(ctx), ds, datasources.CurrentSecretsID(ds.Id))
+			if retryErr == nil && retryResp.Status == backend.HealthStatusOk {
+				resp, err = retryResp, nil
+				s.setSecretRotationCredentialInUse(ctx, ds, datasources.SecretRotationCredentialCurrent)
+			}
+		}
+	} else {
+		s.setSecretRotationCredentialInUse(ctx, ds, datasources.SecretRotationCredentialPending)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Status = backend.HealthStatusError.String()
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Status = resp.Status.String()
+	result.Message = resp.Message
+	return result
+}
+
+// setSecretRotationCredentialInUse records the secrets of a successful check, for data sources in secret rotation.
+func (s *Service) setSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) {
+	if err := s.dataSourceService.SetSecretRotationCredentialInUse(ctx, ds, credential); err != nil {
+		s.log.Warn("Failed to record the secrets in use by the data source in secret rotation", "uid", ds.Uid, "error", err)
+	}
+}
+
+// checkHealth checks the health of the data source with the secrets decrypted with secretsCtx, using id as the
+// identifier of the plugin instance.
+func (s *Service) checkHealth(ctx, secretsCtx context.Context, ds *datasources.DataSource, id int64) (*backend.CheckHealthResult, error) {
+	instanceSettings, err := adapters.ModelToInstanceSettings(ds, func(ds *datasources.DataSource) (map[string]string, error) {
+		return s.dataSourceService.DecryptedValues(secretsCtx, ds)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get data source settings: %w", err)
+	}
+	instanceSettings.ID = id
+
+	return s.pluginClient.CheckHealth(ctx, &backend.CheckHealthRequest{
+		PluginContext: backend.PluginContext{
+			OrgID:                      ds.OrgId,
+			PluginID:                   ds.Type,
+			DataSourceInstanceSettings: instanceSettings,
+		},
+		Headers: map[string]string{},
+	})
+}
+
+func (s *Service) record(ds *datasources.DataSource, result Result) {
+	key := dsKey{orgID: ds.OrgId, uid: ds.Uid}
+	s.mu.Lock()
+	h, ok := s.history[key]
+	if !ok {
+		h = newHistory(s.cfg.DataSourceHealthCheckHistorySize)
+		s.history[key] = h
+	}
+	previous, hadPrevious := h.latest()
+	unhealthySince := h.unhealthySince()
+	h.add(result)
+	if !result.Healthy() {
+		unhealthySince = h.unhealthySince()
+	}
+	s.mu.Unlock()
+
+	orgLabel := strconv.FormatInt(ds.OrgId, 10)
+	checkDurationHistogram.WithLabelValues(orgLabel, ds.Uid, ds.Type).Observe(float64(result.LatencyMs) / 1000)
+	if result.Healthy() {
+		healthyGauge.WithLabelValues(orgLabel, ds.Uid, ds.Type).Set(1)
+	} else {
+		healthyGauge.WithLabelValues(orgLabel, ds.Uid, ds.Type).Set(0)
+	}
+
+	if !result.Healthy() && (!hadPrevious || previous.Healthy()) {
+		s.log.Warn("Data source became unhealthy", "datasource", ds.Uid, "orgId", ds.OrgId, "message", result.Message)
+	}
+
+	if s.alerts == nil {
+		return
+	}
+	// the alert is sent on every check while the data source is unhealthy, so that it does not expire,
+	// and resolved on the first successful check.
+	if !result.Healthy() {
+		s.sendAlert(ds, result, unhealthySince, false)
+	} else if hadPrevious && !previous.Healthy() {
+		s.sendAlert(ds, previous, unhealthySince, true)
+	}
+}
+
+func (s *Service) sendAlert(ds *datasources.DataSource, result Result, startsAt time.Time, resolved bool) {
+	// keep the alert firing until a few checks were missed.
+	endsAt := result.Timestamp.Add(4 * s.cfg.DataSourceHealthCheckInterval)
+	if resolved {
+		endsAt = time.Now()
+	}
+	if err := s.alerts.PutAlerts(ds.OrgId, unhealthyAlert(ds, result, s.cfg.AppURL, startsAt, endsAt)); err != nil {
+		s.log.Warn("Failed to send data source health alert", "datasource", ds.Uid, "orgId", ds.OrgId, "error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService(t *testing.T) {
+	backendDS := &datasources.DataSource{OrgId: 1, Uid: "backend", Name: "backend", Type: "backend-ds"}
+	frontendDS := &datasources.DataSource{OrgId: 1, Uid: "frontend", Name: "frontend", Type: "frontend-ds"}
+	passThruDS := &datasources.DataSource{OrgId: 1, Uid: "passthru", Name: "passthru", Type: "backend-ds"}
+
+	setup := func(client *fakePluginClient, dataSources ...*datasources.DataSource) (*Service, *fakeAlertSender) {
+		cfg := setting.NewCfg()
+		cfg.DataSourceHealthCheckInterval = time.Minute
+		cfg.DataSourceHealthCheckTimeout = time.Second
+		cfg.DataSourceHealthCheckHistorySize = 3
+		cfg.AppURL = "http://localhost:3000/"
+		alerts := &fakeAlertSender{}
+		return &Service{
+			cfg:               cfg,
+			dataSourceService: &fakeDatasources.FakeDataSourceService{DataSources: dataSources},
+			pluginStore: plugins.FakePluginStore{PluginList: []plugins.PluginDTO{
+				{JSONData: plugins.JSONData{ID: "backend-ds", Backend: true}},
+				{JSONData: plugins.JSONData{ID: "frontend-ds"}},
+			}},
+			pluginClient:      client,
+			oAuthTokenService: &fakeOAuthTokenService{passThru: map[string]bool{passThruDS.Uid: true}},
+			alerts:            alerts,
+			log:               log.New("test"),
+			history:           map[dsKey]*history{},
+		}, alerts
+	}
+
+	t.Run("checks only data sources with a backend plugin and no OAuth pass-thru", func(t *testing.T) {
+		client := &fakePluginClient{status: backend.HealthStatusOk}
+		s, _ := setup(client, backendDS, frontendDS, passThruDS)
+
+		s.checkAll(context.Background())
+
+		require.Equal(t, []string{"backend"}, client.checked)
+		history := s.History(1, "backend")
+		require.Len(t, history, 1)
+		require.Equal(t, "OK", history[0].Status)
+		require.Empty(t, s.History(1, "frontend"))
+		require.Empty(t, s.History(1, "passthru"))
+	})
+
+	t.Run("keeps the most recent results, the most recent first", func(t *testing.T) {
+		client := &fakePluginClient{status: backend.HealthStatusOk}
+		s, _ := setup(client, backendDS)
+
+		for _, msg := range []string{"1", "2", "3", "4"} {
+			client.message = msg
+			s.checkAll(context.Background())
+		}
+
+		history := s.History(1, "backend")
+		require.Len(t, history, 3)
+		require.Equal(t, "4", history[0].Message)
+		require.Equal(t, "2", history[2].Message)
+	})
+
+	t.Run("plugin errors are recorded as unhealthy", func(t *testing.T) {
+		client := &fakePluginClient{err: errors.New("plugin unavailable")}
+		s, _ := setup(client, backendDS)
+
+		s.checkAll(context.Background())
+
+		history := s.History(1, "backend")
+		require.Len(t, history, 1)
+		require.Equal(t, "ERROR", history[0].Status)
+		require.Equal(t, "plugin unavailable", history[0].Message)
+	})
+
+	t.Run("forgets deleted data sources", func(t *testing.T) {
+		client := &fakePluginClient{status: backend.HealthStatusOk}
+		s, _ := setup(client, backendDS)
+		s.checkAll(context.Background())
+		require.Len(t, s.History(1, "backend"), 1)
+
+		s.dataSourceService = &fakeDatasources.FakeDataSourceService{}
+		s.checkAll(context.Background())
+		require.Empty(t, s.History(1, "backend"))
+	})
+
+	t.Run("alerts while the data source is unhealthy and resolves on recovery", func(t *testing.T) {
+		client := &fakePluginClient{status: backend.HealthStatusOk}
+		s, alerts := setup(client, backendDS)
+
+		s.checkAll(context.Background())
+		require.Empty(t, alerts.sent)
+
+		client.status = backend.HealthStatusError
+		s.checkAll(context.Background())
+		s.checkAll(context.Background())
+		require.Len(t, alerts.sent, 2)
+		require.Equal(t, UnhealthyAlertName, alerts.sent[0].Labels["alertname"])
+		require.Equal(t, "backend", alerts.sent[0].Labels["datasource_uid"])
+		require.Equal(t, alerts.sent[0].StartsAt, alerts.sent[1].StartsAt)
+		require.True(t, time.Time(alerts.sent[1].EndsAt).After(time.Now()))
+
+		client.status = backend.HealthStatusOk
+		s.checkAll(context.Background())
+		require.Len(t, alerts.sent, 3)
+		require.Equal(t, alerts.sent[0].StartsAt, alerts.sent[2].StartsAt)
+		require.False(t, time.Time(alerts.sent[2].EndsAt).After(time.Now()))
+
+		s.checkAll(context.Background())
+		require.Len(t, alerts.sent, 3)
+	})
+}
+
+type fakePluginClient struct {
+	plugins.Client
+
+	mu      sync.Mutex
+	status  backend.HealthStatus
+	message string
+	err     error
+	checked []string
+}
+
+func (c *fakePluginClient) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = append(c.checked, req.PluginContext.DataSourceInstanceSettings.UID)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &backend.CheckHealthResult{Status: c.status, Message: c.message}, nil
+}
+
+type fakeOAuthTokenService struct {
+	oauthtoken.OAuthTokenService
+
+	passThru map[string]bool
+}
+
+func (s *fakeOAuthTokenService) IsOAuthPassThruEnabled(ds *datasources.DataSource) bool {
+	return s.passThru[ds.Uid]
+}
+
+type fakeAlertSender struct {
+	mu   sync.Mutex
+	sent []*models.PostableAlert
+}
+
+func (s *fakeAlertSender) PutAlerts(_ int64, alert *models.PostableAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, alert)
+	return nil
+}
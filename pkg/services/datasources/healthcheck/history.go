@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Result is the outcome of one health check of a data source.
+type Result struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	LatencyMs int64     `json:"latencyMs"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Healthy returns true if the plugin reported the data source as working.
+func (r Result) Healthy() bool {
+	return r.Status == backend.HealthStatusOk.String()
+}
+
+// history is a fixed size ring of the most recent results of a data source.
+type history struct {
+	entries []Result
+	next    int
+	size    int
+	// since is the time of the first result of the current outage, it outlives the entries.
+	since time.Time
+}
+
+func newHistory(capacity int) *history {
+	return &history{entries: make([]Result, capacity)}
+}
+
+func (h *history) add(r Result) {
+	h.entries[h.next] = r
+	h.next = (h.next + 1) % len(h.entries)
+	if h.size < len(h.entries) {
+		h.size++
+	}
+	if r.Healthy() {
+		h.since = time.Time{}
+	} else if h.since.IsZero() {
+		h.since = r.Timestamp
+	}
+}
+
+// latest returns the most recent result, if any.
+func (h *history) latest() (Result, bool) {
+	if h.size == 0 {
+		return Result{}, false
+	}
+	return h.entries[(h.next-1+len(h.entries))%len(h.entries)], true
+}
+
+// results returns the results, the most recent first.
+func (h *history) results() []Result {
+	out := make([]Result, 0, h.size)
+	for i := 1; i <= h.size; i++ {
+		out = append(out, h.entries[(h.next-i+len(h.entries))%len(h.entries)])
+	}
+	return out
+}
+
+// unhealthySince returns the time the data source became unhealthy,
+// or the zero time if the latest result is healthy.
+func (h *history) unhealthySince() time.Time {
+	return h.since
+}
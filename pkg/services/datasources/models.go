@@ -1,6 +1,7 @@
 package datasources
 
 import (
+	"context"
 	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
@@ -150,6 +151,62 @@ type DeleteDataSourceCommand struct {
 // Function for updating secrets along with datasources, to ensure atomicity
 type UpdateSecretFn func() error
 
+// RotateDataSourceSecretsCommand starts a rotation of the secureJsonData of a data source. The new values are
+// pending until the grace period ends: they are used first, and the current values are used if they fail.
+type RotateDataSourceSecretsCommand struct {
+	SecureJsonData map[string]string `json:"secureJsonData" binding:"Required"`
+	// GracePeriod is a duration like `1h`, defaults to the `secret_rotation_grace_period` setting.
+	GracePeriod string `json:"gracePeriod"`
+
+	OrgID int64  `json:"-"`
+	UID   string `json:"-"`
+
+	Result *SecretRotation `json:"-"`
+}
+
+// SecretRotationCredential identifies the secureJsonData used by a data source during a secret rotation.
+type SecretRotationCredential string
+
+const (
+	SecretRotationCredentialPending SecretRotationCredential = "pending"
+	SecretRotationCredentialCurrent SecretRotationCredential = "current"
+)
+
+// SecretRotation is the state of the secret rotation of a data source.
+type SecretRotation struct {
+	StartedAt  time.Time `json:"startedAt"`
+	GraceUntil time.Time `json:"graceUntil"`
+	// InUse is the secureJsonData of the last request which succeeded.
+	InUse SecretRotationCredential `json:"inUse"`
+}
+
+// InGracePeriod returns true if the current secureJsonData can still be used.
+func (r *SecretRotation) InGracePeriod(now time.Time) bool {
+	return now.Before(r.GraceUntil)
+}
+
+type currentSecretsKey struct{}
+
+// WithCurrentSecrets returns a context in which the secureJsonData of data sources are the current values, instead
+// of the pending values of their secret rotation. It is used to retry a single request during the grace period.
+func WithCurrentSecrets(ctx context.Context) context.Context {
+	return context.WithValue(ctx, currentSecretsKey{}, true)
+}
+
+// UsesCurrentSecrets returns true if the context was created with WithCurrentSecrets.
+func UsesCurrentSecrets(ctx context.Context) bool {
+	v, _ := ctx.Value(currentSecretsKey{}).(bool)
+	return v
+}
+
+// CurrentSecretsID returns the identifier given to the plugin instance settings built with the current secrets of a
+// secret rotation. Plugins and token providers cache instances by data source identifier, and replace them when the
+// update time changes, so the current secrets get their own identifier to not replace the instance built with the
+// pending secrets. Data source identifiers are positive, so it never identifies another data source.
+func CurrentSecretsID(id int64) int64 {
+	return -id
+}
+
 // ---------------------
 // QUERIES
 
@@ -199,6 +199,9 @@ func (s *Service) AddDataSource(ctx context.Context, cmd *datasources.AddDataSou
 func (s *Service) DeleteDataSource(ctx context.Context, cmd *datasources.DeleteDataSourceCommand) error {
 	return s.db.InTransaction(ctx, func(ctx context.Context) error {
 		cmd.UpdateSecretFn = func() error {
+			if err := s.SecretsStore.Del(ctx, cmd.OrgID, cmd.Name, kvstore.DataSourcePendingSecretType); err != nil {
+				return err
+			}
 			return s.SecretsStore.Del(ctx, cmd.OrgID, cmd.Name, kvstore.DataSourceSecretType)
 		}
 
@@ -219,7 +222,10 @@ func (s *Service) UpdateDataSource(ctx context.Context, cmd *datasources.UpdateD
 			return err
 		}
 
-		err = s.fillWithSecureJSONData(ctx, cmd, query.Result)
+		// changing the secure fields ends a secret rotation, the other changes keep it going.
+		secureFieldsChanged := len(cmd.SecureJsonData) > 0
+
+		err = s.fillWithSecureJSONData(ctx, cmd, query.Result, secureFieldsChanged)
 		if err != nil {
 			return err
 		}
@@ -231,11 +237,23 @@ func (s *Service) UpdateDataSource(ctx context.Context, cmd *datasources.UpdateD
 					return err
 				}
 
+				if secureFieldsChanged {
+					if err := s.SecretsStore.Del(ctx, cmd.OrgId, query.Result.Name, kvstore.DataSourcePendingSecretType); err != nil {
+						return err
+					}
+				}
+
 				if query.Result.Name != cmd.Name {
 					err := s.SecretsStore.Rename(ctx, cmd.OrgId, query.Result.Name, kvstore.DataSourceSecretType, cmd.Name)
 					if err != nil {
 						return err
 					}
+					if !secureFieldsChanged {
+						err := s.SecretsStore.Rename(ctx, cmd.OrgId, query.Result.Name, kvstore.DataSourcePendingSecretType, cmd.Name)
+						if err != nil {
+							return err
+						}
+					}
 				}
 
 				return s.SecretsStore.Set(ctx, cmd.OrgId, cmd.Name, kvstore.DataSourceSecretType, string(secret))
@@ -264,6 +282,16 @@ func (s *Service) GetHTTPClient(ctx context.Context, ds *datasources.DataSource,
 
 func (s *Service) GetHTTPTransport(ctx context.Context, ds *datasources.DataSource, provider httpclient.Provider,
 	customMiddlewares ...sdkhttpclient.Middleware) (http.RoundTripper, error) {
+	// the transports built with the current secrets of a secret rotation serve a single retried request.
+	if datasources.UsesCurrentSecrets(ctx) {
+		opts, err := s.httpClientOptions(ctx, ds)
+		if err != nil {
+			return nil, err
+		}
+		opts.Middlewares = append(opts.Middlewares, customMiddlewares...)
+		return provider.GetTransport(*opts)
+	}
+
 	s.ptc.Lock()
 	defer s.ptc.Unlock()
 
@@ -299,7 +327,17 @@ func (s *Service) GetTLSConfig(ctx context.Context, ds *datasources.DataSource,
 	return httpClientProvider.GetTLSConfig(*opts)
 }
 
+// DecryptedValues returns the secureJsonData in use by the datasource. During a secret rotation these are
+// the pending values, unless the context was created with WithCurrentSecrets in the grace period.
 func (s *Service) DecryptedValues(ctx context.Context, ds *datasources.DataSource) (map[string]string, error) {
+	current, err := s.decryptedCurrentValues(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptedValuesInUse(ctx, ds, current)
+}
+
+func (s *Service) decryptedCurrentValues(ctx context.Context, ds *datasources.DataSource) (map[string]string, error) {
 	decryptedValues := make(map[string]string)
 	secret, exist, err := s.SecretsStore.Get(ctx, ds.OrgId, ds.Name, kvstore.DataSourceSecretType)
 	if err != nil {
@@ -565,11 +603,19 @@ func awsServiceNamespace(dsType string) string {
 	}
 }
 
-func (s *Service) fillWithSecureJSONData(ctx context.Context, cmd *datasources.UpdateDataSourceCommand, ds *datasources.DataSource) error {
-	decrypted, err := s.DecryptedValues(ctx, ds)
+// fillWithSecureJSONData fills the secure fields missing from the command. They are filled with the values in use
+// when the command ends a secret rotation, and with the current values when the rotation goes on.
+func (s *Service) fillWithSecureJSONData(ctx context.Context, cmd *datasources.UpdateDataSourceCommand, ds *datasources.DataSource, endsRotation bool) error {
+	decrypted, err := s.decryptedCurrentValues(ctx, ds)
 	if err != nil {
 		return err
 	}
+	if endsRotation {
+		decrypted, err = s.decryptedValuesInUse(ctx, ds, decrypted)
+		if err != nil {
+			return err
+		}
+	}
 
 	if cmd.SecureJsonData == nil {
 		cmd.SecureJsonData = make(map[string]string)
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/secrets/kvstore"
+)
+
+// pendingSecrets is the secret rotation of a datasource, stored in the secrets store
+// next to the current secureJsonData of the datasource.
+type pendingSecrets struct {
+	SecureJsonData map[string]string                    `json:"secureJsonData"`
+	StartedAt      time.Time                            `json:"startedAt"`
+	GraceUntil     time.Time                            `json:"graceUntil"`
+	InUse          datasources.SecretRotationCredential `json:"inUse"`
+}
+
+func (p *pendingSecrets) rotation() *datasources.SecretRotation {
+	return &datasources.SecretRotation{StartedAt: p.StartedAt, GraceUntil: p.GraceUntil, InUse: p.InUse}
+}
+
+func (p *pendingSecrets) inGracePeriod() bool {
+	return p.rotation().InGracePeriod(time.Now())
+}
+
+// RotateSecrets stores the new secureJsonData of the datasource as pending. The pending values are used
+// from now on, and the current values are kept as a fallback until the grace period ends. The fallback is
+// decided for each request, with WithCurrentSecrets, and never changes the stored secrets.
+func (s *Service) RotateSecrets(ctx context.Context, cmd *datasources.RotateDataSourceSecretsCommand) error {
+	gracePeriod := s.cfg.DataSourceSecretRotationGracePeriod
+	if cmd.GracePeriod != "" {
+		var err error
+		gracePeriod, err = time.ParseDuration(cmd.GracePeriod)
+		if err != nil || gracePeriod <= 0 {
+			return fmt.Errorf("%w: %q", datasources.ErrSecretRotationInvalidGracePeriod, cmd.GracePeriod)
+		}
+	}
+
+	return s.db.InTransaction(ctx, func(ctx context.Context) error {
+		query := &datasources.GetDataSourceQuery{Uid: cmd.UID, OrgId: cmd.OrgID}
+		if err := s.SQLStore.GetDataSource(ctx, query); err != nil {
+			return err
+		}
+		ds := query.Result
+		if ds.ReadOnly {
+			return datasources.ErrDatasourceIsReadOnly
+		}
+
+		existing, err := s.getPendingSecrets(ctx, ds)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.inGracePeriod() {
+			return datasources.ErrSecretRotationInProgress
+		}
+		if existing != nil {
+			// the grace period of the previous rotation has ended, its values are the current ones now.
+			if err := s.completeSecretRotation(ctx, ds, existing); err != nil {
+				return err
+			}
+			if err := s.SQLStore.GetDataSource(ctx, query); err != nil {
+				return err
+			}
+			ds = query.Result
+		}
+
+		now := time.Now()
+		pending := &pendingSecrets{
+			SecureJsonData: cmd.SecureJsonData,
+			StartedAt:      now,
+			GraceUntil:     now.Add(gracePeriod),
+			InUse:          datasources.SecretRotationCredentialPending,
+		}
+		if err := s.setPendingSecrets(ctx, ds, pending); err != nil {
+			return err
+		}
+		if err := s.SQLStore.TouchDataSource(ctx, ds.OrgId, ds.Id); err != nil {
+			return err
+		}
+
+		s.logger.Info("Started datasource secret rotation", "uid", ds.Uid, "orgId", ds.OrgId, "graceUntil", pending.GraceUntil)
+		cmd.Result = pending.rotation()
+		return nil
+	})
+}
+
+// GetSecretRotation returns the secret rotation of the datasource.
+func (s *Service) GetSecretRotation(ctx context.Context, ds *datasources.DataSource) (*datasources.SecretRotation, error) {
+	pending, err := s.getPendingSecrets(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, datasources.ErrSecretRotationNotFound
+	}
+	rotation := pending.rotation()
+	if !pending.inGracePeriod() || rotation.InUse == "" {
+		rotation.InUse = datasources.SecretRotationCredentialPending
+	}
+	return rotation, nil
+}
+
+// InSecretRotationGracePeriod returns true if the datasource is in the grace period of a secret rotation.
+func (s *Service) InSecretRotationGracePeriod(ctx context.Context, ds *datasources.DataSource) (bool, error) {
+	pending, err := s.getPendingSecrets(ctx, ds)
+	if err != nil {
+		return false, err
+	}
+	return pending != nil && pending.inGracePeriod(), nil
+}
+
+// SetSecretRotationCredentialInUse records the secureJsonData of the last request of the datasource which succeeded
+// during the grace period of its secret rotation. The secrets store is only written when the credential changes.
+func (s *Service) SetSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) error {
+	pending, err := s.getPendingSecrets(ctx, ds)
+	if err != nil {
+		return err
+	}
+	if pending == nil || !pending.inGracePeriod() || pending.InUse == credential {
+		return nil
+	}
+
+	pending.InUse = credential
+	if err := s.setPendingSecrets(ctx, ds, pending); err != nil {
+		return err
+	}
+	s.logger.Info("Datasource secret rotation credential in use changed", "uid", ds.Uid, "orgId", ds.OrgId, "inUse", credential)
+	return nil
+}
+
+// CompleteSecretRotation replaces the current secureJsonData of the datasource with the pending values.
+func (s *Service) CompleteSecretRotation(ctx context.Context, ds *datasources.DataSource) error {
+	return s.db.InTransaction(ctx, func(ctx context.Context) error {
+		pending, err := s.getPendingSecrets(ctx, ds)
+		if err != nil {
+			return err
+		}
+		if pending == nil {
+			return datasources.ErrSecretRotationNotFound
+		}
+		return s.completeSecretRotation(ctx, ds, pending)
+	})
+}
+
+// completeSecretRotation updates the datasource with the pending values, which also removes the pending values.
+func (s *Service) completeSecretRotation(ctx context.Context, ds *datasources.DataSource, pending *pendingSecrets) error {
+	cmd := &datasources.UpdateDataSourceCommand{
+		Id:              ds.Id,
+		OrgId:           ds.OrgId,
+		Uid:             ds.Uid,
+		Name:            ds.Name,
+		Type:            ds.Type,
+		Access:          ds.Access,
+		Url:             ds.Url,
+		User:            ds.User,
+		Database:        ds.Database,
+		BasicAuth:       ds.BasicAuth,
+		BasicAuthUser:   ds.BasicAuthUser,
+		WithCredentials: ds.WithCredentials,
+		IsDefault:       ds.IsDefault,
+		JsonData:        ds.JsonData,
+		SecureJsonData:  pending.SecureJsonData,
+		ReadOnly:        ds.ReadOnly,
+	}
+	if err := s.UpdateDataSource(ctx, cmd); err != nil {
+		return err
+	}
+	s.logger.Info("Completed datasource secret rotation", "uid", ds.Uid, "orgId", ds.OrgId)
+	return nil
+}
+
+// decryptedValuesInUse overlays the pending values of a secret rotation on the current values, unless the
+// context asks for the current values of a datasource in the grace period.
+func (s *Service) decryptedValuesInUse(ctx context.Context, ds *datasources.DataSource, current map[string]string) (map[string]string, error) {
+	pending, err := s.getPendingSecrets(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || (pending.inGracePeriod() && datasources.UsesCurrentSecrets(ctx)) {
+		return current, nil
+	}
+
+	values := make(map[string]string, len(current)+len(pending.SecureJsonData))
+	for k, v := range current {
+		values[k] = v
+	}
+	for k, v := range pending.SecureJsonData {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func (s *Service) getPendingSecrets(ctx context.Context, ds *datasources.DataSource) (*pendingSecrets, error) {
+	secret, exist, err := s.SecretsStore.Get(ctx, ds.OrgId, ds.Name, kvstore.DataSourcePendingSecretType)
+	if err != nil || !exist {
+		return nil, err
+	}
+	pending := &pendingSecrets{}
+	if err := json.Unmarshal([]byte(secret), pending); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending datasource secrets: %w", err)
+	}
+	return pending, nil
+}
+
+func (s *Service) setPendingSecrets(ctx context.Context, ds *datasources.DataSource, pending *pendingSecrets) error {
+	secret, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return s.SecretsStore.Set(ctx, ds.OrgId, ds.Name, kvstore.DataSourcePendingSecretType, string(secret))
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	secretskvs "github.com/grafana/grafana/pkg/services/secrets/kvstore"
+	secretsmng "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService_SecretRotation(t *testing.T) {
+	setup := func(t *testing.T) (*Service, *datasources.DataSource) {
+		t.Helper()
+		cfg := setting.NewCfg()
+		cfg.DataSourceSecretRotationGracePeriod = time.Hour
+		sqlStore := sqlstore.InitTestDB(t)
+		secretsService := secretsmng.SetupTestService(t, fakes.NewFakeSecretsStore())
+		secretsStore := secretskvs.NewSQLSecretsKVStore(sqlStore, secretsService, log.New("test.logger"))
+		dsService := ProvideService(sqlStore, secretsService, secretsStore, cfg, featuremgmt.WithFeatures(), acmock.New().WithDisabled(), acmock.NewMockedPermissionsService())
+
+		cmd := &datasources.AddDataSourceCommand{
+			OrgId:          1,
+			Name:           "postgres",
+			Type:           datasources.DS_POSTGRES,
+			Access:         datasources.DS_ACCESS_PROXY,
+			SecureJsonData: map[string]string{"password": "old", "tlsClientKey": "key"},
+		}
+		require.NoError(t, dsService.AddDataSource(context.Background(), cmd))
+		return dsService, cmd.Result
+	}
+
+	get := func(t *testing.T, s *Service, ds *datasources.DataSource) *datasources.DataSource {
+		t.Helper()
+		query := &datasources.GetDataSourceQuery{Id: ds.Id, OrgId: ds.OrgId}
+		require.NoError(t, s.GetDataSource(context.Background(), query))
+		return query.Result
+	}
+
+	rotate := func(t *testing.T, s *Service, ds *datasources.DataSource, gracePeriod string) {
+		t.Helper()
+		cmd := &datasources.RotateDataSourceSecretsCommand{
+			OrgID:          ds.OrgId,
+			UID:            ds.Uid,
+			SecureJsonData: map[string]string{"password": "new"},
+			GracePeriod:    gracePeriod,
+		}
+		require.NoError(t, s.RotateSecrets(context.Background(), cmd))
+	}
+
+	t.Run("pending secrets are used first and overlay the current ones", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+
+		updated := get(t, s, ds)
+		require.Greater(t, updated.Version, ds.Version)
+
+		values, err := s.DecryptedValues(context.Background(), updated)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"password": "new", "tlsClientKey": "key"}, values)
+
+		rotation, err := s.GetSecretRotation(context.Background(), updated)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(time.Hour), rotation.GraceUntil, time.Minute)
+	})
+
+	t.Run("a second rotation is rejected during the grace period", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+
+		err := s.RotateSecrets(context.Background(), &datasources.RotateDataSourceSecretsCommand{
+			OrgID: ds.OrgId, UID: ds.Uid, SecureJsonData: map[string]string{"password": "newer"},
+		})
+		require.ErrorIs(t, err, datasources.ErrSecretRotationInProgress)
+	})
+
+	t.Run("invalid grace periods are rejected", func(t *testing.T) {
+		s, ds := setup(t)
+		err := s.RotateSecrets(context.Background(), &datasources.RotateDataSourceSecretsCommand{
+			OrgID: ds.OrgId, UID: ds.Uid, SecureJsonData: map[string]string{"password": "new"}, GracePeriod: "soon",
+		})
+		require.ErrorIs(t, err, datasources.ErrSecretRotationInvalidGracePeriod)
+	})
+
+	t.Run("the current secrets are used on request during the grace period", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+		ds = get(t, s, ds)
+
+		inGracePeriod, err := s.InSecretRotationGracePeriod(context.Background(), ds)
+		require.NoError(t, err)
+		require.True(t, inGracePeriod)
+
+		values, err := s.DecryptedValues(datasources.WithCurrentSecrets(context.Background()), ds)
+		require.NoError(t, err)
+		require.Equal(t, "old", values["password"])
+
+		// the fallback doesn't change the data source for the other requests
+		require.Equal(t, ds.Version, get(t, s, ds).Version)
+		values, err = s.DecryptedValues(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, "new", values["password"])
+	})
+
+	t.Run("the secrets of the last successful request are reported", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+		ds = get(t, s, ds)
+
+		rotation, err := s.GetSecretRotation(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, datasources.SecretRotationCredentialPending, rotation.InUse)
+
+		require.NoError(t, s.SetSecretRotationCredentialInUse(context.Background(), ds, datasources.SecretRotationCredentialCurrent))
+		rotation, err = s.GetSecretRotation(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, datasources.SecretRotationCredentialCurrent, rotation.InUse)
+
+		// reporting the credential doesn't change the data source or the secrets used by the requests
+		require.Equal(t, ds.Version, get(t, s, ds).Version)
+		values, err := s.DecryptedValues(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, "new", values["password"])
+	})
+
+	t.Run("pending secrets are used once the grace period ends", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "1ms")
+		ds = get(t, s, ds)
+		time.Sleep(5 * time.Millisecond)
+
+		inGracePeriod, err := s.InSecretRotationGracePeriod(context.Background(), ds)
+		require.NoError(t, err)
+		require.False(t, inGracePeriod)
+
+		values, err := s.DecryptedValues(datasources.WithCurrentSecrets(context.Background()), ds)
+		require.NoError(t, err)
+		require.Equal(t, "new", values["password"])
+
+		require.NoError(t, s.SetSecretRotationCredentialInUse(context.Background(), ds, datasources.SecretRotationCredentialCurrent))
+		rotation, err := s.GetSecretRotation(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, datasources.SecretRotationCredentialPending, rotation.InUse)
+	})
+
+	t.Run("updating other fields keeps the rotation", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+		ds = get(t, s, ds)
+
+		require.NoError(t, s.UpdateDataSource(context.Background(), &datasources.UpdateDataSourceCommand{
+			Id: ds.Id, OrgId: ds.OrgId, Uid: ds.Uid, Name: ds.Name, Type: ds.Type, Access: ds.Access, Url: "localhost:5433",
+		}))
+		ds = get(t, s, ds)
+
+		_, err := s.GetSecretRotation(context.Background(), ds)
+		require.NoError(t, err)
+		values, err := s.DecryptedValues(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"password": "new", "tlsClientKey": "key"}, values)
+		values, err = s.DecryptedValues(datasources.WithCurrentSecrets(context.Background()), ds)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"password": "old", "tlsClientKey": "key"}, values)
+	})
+
+	t.Run("updating the secrets ends the rotation", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+		ds = get(t, s, ds)
+
+		require.NoError(t, s.UpdateDataSource(context.Background(), &datasources.UpdateDataSourceCommand{
+			Id: ds.Id, OrgId: ds.OrgId, Uid: ds.Uid, Name: ds.Name, Type: ds.Type, Access: ds.Access,
+			SecureJsonData: map[string]string{"password": "newer"},
+		}))
+		ds = get(t, s, ds)
+
+		_, err := s.GetSecretRotation(context.Background(), ds)
+		require.ErrorIs(t, err, datasources.ErrSecretRotationNotFound)
+		values, err := s.DecryptedValues(context.Background(), ds)
+		require.NoError(t, err)
+		require.Equal(t, "newer", values["password"])
+	})
+
+	t.Run("completing the rotation replaces the current secrets", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+		ds = get(t, s, ds)
+
+		require.NoError(t, s.CompleteSecretRotation(context.Background(), ds))
+		_, err := s.GetSecretRotation(context.Background(), ds)
+		require.ErrorIs(t, err, datasources.ErrSecretRotationNotFound)
+
+		values, err := s.DecryptedValues(context.Background(), get(t, s, ds))
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"password": "new", "tlsClientKey": "key"}, values)
+	})
+
+	t.Run("deleting the data source removes the pending secrets", func(t *testing.T) {
+		s, ds := setup(t)
+		rotate(t, s, ds, "")
+
+		require.NoError(t, s.DeleteDataSource(context.Background(), &datasources.DeleteDataSourceCommand{ID: ds.Id, Name: ds.Name, OrgID: ds.OrgId}))
+		_, exists, err := s.SecretsStore.Get(context.Background(), ds.OrgId, ds.Name, secretskvs.DataSourcePendingSecretType)
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+}
@@ -29,6 +29,7 @@ type Store interface {
 	AddDataSource(context.Context, *datasources.AddDataSourceCommand) error
 	UpdateDataSource(context.Context, *datasources.UpdateDataSourceCommand) error
 	GetAllDataSources(ctx context.Context, query *datasources.GetAllDataSourcesQuery) error
+	TouchDataSource(ctx context.Context, orgID int64, id int64) error
 }
 
 type SqlStore struct {
@@ -327,6 +328,26 @@ func (ss *SqlStore) UpdateDataSource(ctx context.Context, cmd *datasources.Updat
 	})
 }
 
+// TouchDataSource increments the version of the datasource and sets its update time, so that the
+// HTTP transports and plugin instances of the datasource, which are cached by update time, are recreated.
+func (ss *SqlStore) TouchDataSource(ctx context.Context, orgID int64, id int64) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := "UPDATE data_source SET version=version+1, updated=? WHERE org_id=? AND id=?"
+		res, err := sess.Exec(rawSQL, time.Now(), orgID, id)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return datasources.ErrDataSourceNotFound
+		}
+		return nil
+	})
+}
+
 func generateNewDatasourceUid(sess *sqlstore.DBSession, orgId int64) (string, error) {
 	for i := 0; i < 3; i++ {
 		uid := generateNewUid()
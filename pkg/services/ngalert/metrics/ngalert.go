@@ -54,6 +54,7 @@ type Scheduler struct {
 	SchedulePeriodicDuration            prometheus.Histogram
 	SchedulableAlertRules               prometheus.Gauge
 	SchedulableAlertRulesHash           prometheus.Gauge
+	OwnedAlertRules                     prometheus.Gauge
 	UpdateSchedulableAlertRulesDuration prometheus.Histogram
 	Ticker                              *ticker.Metrics
 	EvaluationMissed                    *prometheus.CounterVec
@@ -190,6 +191,13 @@ func newSchedulerMetrics(r prometheus.Registerer) *Scheduler {
 				Name:      "schedule_alert_rules_hash",
 				Help:      "A hash of the alert rules that could be considered for evaluation at the next tick.",
 			}),
+		OwnedAlertRules: promauto.With(r).NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "schedule_owned_alert_rules",
+				Help:      "The number of alert rules that are evaluated by this instance at the current tick.",
+			}),
 		UpdateSchedulableAlertRulesDuration: promauto.With(r).NewHistogram(
 			prometheus.HistogramOpts{
 				Namespace: Namespace,
@@ -6,6 +6,7 @@ import (
 	"net/url"
 
 	"github.com/benbjohnson/clock"
+	"github.com/prometheus/alertmanager/cluster"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/grafana/pkg/api/routing"
@@ -167,6 +168,14 @@ func (ng *AlertNG) init() error {
 		AlertSender: alertsRouter,
 	}
 
+	if ng.Cfg.UnifiedAlerting.HAEvaluationSharding {
+		if peer, ok := ng.MultiOrgAlertmanager.Peer().(*cluster.Peer); ok {
+			schedCfg.Peer = peer
+		} else {
+			ng.Log.Warn("Evaluation sharding is enabled but high availability is not configured. All alert rules will be evaluated by this instance")
+		}
+	}
+
 	stateManager := state.NewManager(ng.Log, ng.Metrics.GetStateMetrics(), appUrl, store, store, ng.dashboardService, ng.imageService, clk, ng.annotationsRepo)
 	scheduler := schedule.NewScheduler(schedCfg, appUrl, stateManager)
 
@@ -244,7 +253,10 @@ func subscribeToFolderChanges(logger log.Logger, bus bus.Bus, dbStore api.RuleSt
 // Run starts the scheduler and Alertmanager.
 func (ng *AlertNG) Run(ctx context.Context) error {
 	ng.Log.Debug("ngalert starting")
-	ng.stateManager.Warm(ctx)
+	// with evaluation sharding, the state of a rule is loaded by the instance that evaluates it.
+	if !ng.Cfg.UnifiedAlerting.HAEvaluationSharding || len(ng.Cfg.UnifiedAlerting.HAPeers) == 0 {
+		ng.stateManager.Warm(ctx)
+	}
 
 	children, subCtx := errgroup.WithContext(ctx)
 
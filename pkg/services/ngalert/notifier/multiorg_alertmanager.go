@@ -309,6 +309,11 @@ func (moa *MultiOrgAlertmanager) StopAndWait() {
 	}
 }
 
+// Peer returns the clustering peer shared by the Alertmanagers of all organizations.
+func (moa *MultiOrgAlertmanager) Peer() ClusterPeer {
+	return moa.peer
+}
+
 // AlertmanagerFor returns the Alertmanager instance for the organization provided.
 // When the organization does not have an active Alertmanager, it returns a ErrNoAlertmanagerForOrg.
 // When the Alertmanager of the organization is not ready, it returns a ErrAlertmanagerNotReady.
@@ -100,6 +100,10 @@ type schedule struct {
 	// current tick depends on its evaluation interval and when it was
 	// last evaluated.
 	schedulableAlertRules alertRulesRegistry
+
+	// shard is used to evaluate only the alert rules that belong to this instance
+	// when Grafana runs in high-availability mode. It is nil if sharding is disabled.
+	shard *evaluationShard
 }
 
 // SchedulerCfg is the scheduler configuration.
@@ -113,6 +117,9 @@ type SchedulerCfg struct {
 	RuleStore       RulesStore
 	Metrics         *metrics.Scheduler
 	AlertSender     AlertsSender
+	// Peer is the gossip peer used to shard the evaluation of alert rules between instances.
+	// If nil, all alert rules are evaluated by this instance.
+	Peer ClusterPeer
 }
 
 // NewScheduler returns a new schedule.
@@ -139,6 +146,10 @@ func NewScheduler(cfg SchedulerCfg, appURL *url.URL, stateManager *state.Manager
 		alertsSender:          cfg.AlertSender,
 	}
 
+	if cfg.Peer != nil {
+		sch.shard = &evaluationShard{peer: cfg.Peer}
+	}
+
 	return &sch
 }
 
@@ -219,10 +230,29 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 				evaluation
 			}
 
+			var members []string
+			if sch.shard != nil {
+				members = sch.shard.members()
+			}
+
 			readyToRun := make([]readyToRunItem, 0)
 			missingFolder := make(map[string][]string)
+			ownedRules := 0
 			for _, item := range alertRules {
 				key := item.GetKey()
+
+				if sch.shard != nil && !sch.shard.owns(key, members) {
+					// the rule is evaluated by another instance. If it was evaluated by this one, stop the routine
+					// but keep the state in the database so the new owner can continue from it.
+					delete(registeredDefinitions, key)
+					if ruleInfo, ok := sch.registry.del(key); ok {
+						sch.log.Info("alert rule is reassigned to another instance", "uid", key.UID, "org_id", key.OrgID)
+						ruleInfo.stop(errRuleReassigned)
+					}
+					continue
+				}
+				ownedRules++
+
 				ruleInfo, newRoutine := sch.registry.getOrCreateInfo(ctx, key)
 
 				// enforce minimum evaluation interval
@@ -268,6 +298,8 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 				delete(registeredDefinitions, key)
 			}
 
+			sch.metrics.OwnedAlertRules.Set(float64(ownedRules))
+
 			if len(missingFolder) > 0 { // if this happens then there can be problems with fetching folders from the database.
 				sch.log.Warn("unable to find obtain folder titles for some rules", "folder_to_rule_map", missingFolder)
 			}
@@ -421,6 +453,9 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertR
 						if currentRuleVersion > 0 { // do not clean up state if the eval loop has just started.
 							logger.Debug("got a new version of alert rule. Clear up the state and refresh extra labels", "version", currentRuleVersion, "new_version", newVersion)
 							clearState()
+						} else if sch.shard != nil {
+							// the rule could have been evaluated by another instance, continue from the state it persisted.
+							sch.stateManager.WarmRule(grafanaCtx, ctx.rule)
 						}
 						currentRuleVersion = newVersion
 					}
@@ -436,6 +471,10 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertR
 			if errors.Is(grafanaCtx.Err(), errRuleDeleted) {
 				clearState()
 			}
+			// the rule is evaluated by another instance now, drop the cached state but keep it in the database.
+			if errors.Is(grafanaCtx.Err(), errRuleReassigned) {
+				sch.stateManager.ForgetRule(key)
+			}
 			logger.Debug("stopping alert rule routine")
 			return nil
 		}
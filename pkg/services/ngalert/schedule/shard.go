@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/prometheus/alertmanager/cluster"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+var errRuleReassigned = errors.New("rule reassigned to another instance")
+
+// ClusterPeer is the subset of the Alertmanager gossip peer that is required to
+// shard the evaluation of alert rules between Grafana instances.
+type ClusterPeer interface {
+	// Name returns the unique name of the current instance in the cluster.
+	Name() string
+	// Peers returns all members of the cluster, including the current instance.
+	Peers() []cluster.ClusterMember
+}
+
+// evaluationShard decides which instance of the cluster evaluates an alert rule.
+// It uses rendezvous hashing so that when the membership changes only the rules
+// that belonged to the instances that joined or left are moved.
+type evaluationShard struct {
+	peer ClusterPeer
+}
+
+// members returns the names of the instances that are currently part of the cluster.
+func (s *evaluationShard) members() []string {
+	peers := s.peer.Peers()
+	members := make([]string, 0, len(peers))
+	for _, p := range peers {
+		members = append(members, p.Name())
+	}
+	if len(members) == 0 {
+		// the peer has not joined the cluster yet, behave as a single instance.
+		members = append(members, s.peer.Name())
+	}
+	return members
+}
+
+// owns returns true if the current instance is responsible for the evaluation of the alert rule.
+func (s *evaluationShard) owns(key ngmodels.AlertRuleKey, members []string) bool {
+	return ruleOwner(key, members) == s.peer.Name()
+}
+
+// ruleOwner returns the member with the highest weight for the alert rule.
+func ruleOwner(key ngmodels.AlertRuleKey, members []string) string {
+	var owner string
+	var maxWeight uint64
+	for _, m := range members {
+		w := ruleWeight(key, m)
+		if owner == "" || w > maxWeight || (w == maxWeight && m < owner) {
+			owner = m
+			maxWeight = w
+		}
+	}
+	return owner
+}
+
+func ruleWeight(key ngmodels.AlertRuleKey, member string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(key.OrgID, 10)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key.UID))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(member))
+	return h.Sum64()
+}
@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type fakeClusterMember struct {
+	name string
+}
+
+func (m fakeClusterMember) Name() string    { return m.name }
+func (m fakeClusterMember) Address() string { return m.name }
+
+type fakeClusterPeer struct {
+	name    string
+	members []string
+}
+
+func (p *fakeClusterPeer) Name() string { return p.name }
+
+func (p *fakeClusterPeer) Peers() []cluster.ClusterMember {
+	result := make([]cluster.ClusterMember, 0, len(p.members))
+	for _, m := range p.members {
+		result = append(result, fakeClusterMember{name: m})
+	}
+	return result
+}
+
+func TestEvaluationShard(t *testing.T) {
+	keys := make([]models.AlertRuleKey, 0, 1000)
+	for i := 0; i < cap(keys); i++ {
+		keys = append(keys, models.AlertRuleKey{OrgID: int64(i%3 + 1), UID: util.GenerateShortUID()})
+	}
+
+	t.Run("each rule is owned by exactly one member", func(t *testing.T) {
+		members := []string{"a", "b", "c"}
+		owned := map[string]int{}
+		for _, key := range keys {
+			owners := 0
+			for _, m := range members {
+				shard := &evaluationShard{peer: &fakeClusterPeer{name: m, members: members}}
+				if shard.owns(key, shard.members()) {
+					owners++
+					owned[m]++
+				}
+			}
+			require.Equal(t, 1, owners, "rule %v", key)
+		}
+		for _, m := range members {
+			require.Greater(t, owned[m], len(keys)/len(members)/2, "member %s owns too few rules", m)
+		}
+	})
+
+	t.Run("only rules of the member that left are moved", func(t *testing.T) {
+		before := []string{"a", "b", "c"}
+		after := []string{"a", "c"}
+		for _, key := range keys {
+			ownerBefore := ruleOwner(key, before)
+			ownerAfter := ruleOwner(key, after)
+			if ownerBefore != "b" {
+				require.Equal(t, ownerBefore, ownerAfter, "rule %v", key)
+			}
+		}
+	})
+
+	t.Run("owner does not depend on the order of members", func(t *testing.T) {
+		for _, key := range keys {
+			require.Equal(t, ruleOwner(key, []string{"a", "b", "c"}), ruleOwner(key, []string{"c", "a", "b"}))
+		}
+	})
+
+	t.Run("peer that has not joined the cluster owns all rules", func(t *testing.T) {
+		shard := &evaluationShard{peer: &fakeClusterPeer{name: "a"}}
+		for _, key := range keys {
+			require.True(t, shard.owns(key, shard.members()), fmt.Sprintf("rule %v", key))
+		}
+	})
+}
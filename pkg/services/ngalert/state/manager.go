@@ -110,26 +110,7 @@ func (st *Manager) Warm(ctx context.Context) {
 				st.log.Error("rule not found for instance, ignoring", "rule", entry.RuleUID)
 				continue
 			}
-
-			lbs := map[string]string(entry.Labels)
-			cacheId, err := entry.Labels.StringKey()
-			if err != nil {
-				st.log.Error("error getting cacheId for entry", "msg", err.Error())
-			}
-			stateForEntry := &State{
-				AlertRuleUID:         entry.RuleUID,
-				OrgID:                entry.RuleOrgID,
-				CacheId:              cacheId,
-				Labels:               lbs,
-				State:                translateInstanceState(entry.CurrentState),
-				StateReason:          entry.CurrentReason,
-				LastEvaluationString: "",
-				StartsAt:             entry.CurrentStateSince,
-				EndsAt:               entry.CurrentStateEnd,
-				LastEvaluationTime:   entry.LastEvalTime,
-				Annotations:          ruleForEntry.Annotations,
-			}
-			states = append(states, stateForEntry)
+			states = append(states, st.stateFromInstance(entry, ruleForEntry))
 		}
 	}
 
@@ -138,6 +119,51 @@ func (st *Manager) Warm(ctx context.Context) {
 	}
 }
 
+// WarmRule replaces the cached states of the alert rule with the ones stored in the database.
+// It is used when the evaluation of the rule is taken over from another instance.
+func (st *Manager) WarmRule(ctx context.Context, alertRule *ngModels.AlertRule) {
+	logger := st.log.New(alertRule.GetKey().LogContext()...)
+	cmd := ngModels.ListAlertInstancesQuery{
+		RuleOrgID: alertRule.OrgID,
+		RuleUID:   alertRule.UID,
+	}
+	if err := st.instanceStore.ListAlertInstances(ctx, &cmd); err != nil {
+		logger.Error("unable to fetch previous state of the rule", "msg", err.Error())
+		return
+	}
+	st.cache.removeByRuleUID(alertRule.OrgID, alertRule.UID)
+	for _, entry := range cmd.Result {
+		st.set(st.stateFromInstance(entry, alertRule))
+	}
+	logger.Debug("rule state was loaded from the database", "states", len(cmd.Result))
+}
+
+// ForgetRule removes the states of the alert rule from the cache but keeps them in the database,
+// so that another instance can continue the evaluation of the rule from where this one stopped.
+func (st *Manager) ForgetRule(ruleKey ngModels.AlertRuleKey) []*State {
+	return st.cache.removeByRuleUID(ruleKey.OrgID, ruleKey.UID)
+}
+
+func (st *Manager) stateFromInstance(entry *ngModels.AlertInstance, alertRule *ngModels.AlertRule) *State {
+	cacheId, err := entry.Labels.StringKey()
+	if err != nil {
+		st.log.Error("error getting cacheId for entry", "msg", err.Error())
+	}
+	return &State{
+		AlertRuleUID:         entry.RuleUID,
+		OrgID:                entry.RuleOrgID,
+		CacheId:              cacheId,
+		Labels:               map[string]string(entry.Labels),
+		State:                translateInstanceState(entry.CurrentState),
+		StateReason:          entry.CurrentReason,
+		LastEvaluationString: "",
+		StartsAt:             entry.CurrentStateSince,
+		EndsAt:               entry.CurrentStateEnd,
+		LastEvaluationTime:   entry.LastEvalTime,
+		Annotations:          alertRule.Annotations,
+	}
+}
+
 func (st *Manager) getOrCreate(ctx context.Context, alertRule *ngModels.AlertRule, result eval.Result, extraLabels data.Labels) *State {
 	return st.cache.getOrCreate(ctx, alertRule, result, extraLabels)
 }
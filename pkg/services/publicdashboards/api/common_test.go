@@ -139,6 +139,9 @@ func buildQueryDataService(t *testing.T, cs datasources.CacheService, fpc *fakeP
 		&fakeDatasources.FakeDataSourceService{},
 		fpc,
 		&fakeOAuthTokenService{},
+		nil,
+		nil,
+		nil,
 	)
 }
 
@@ -79,6 +79,9 @@ func mockAccessTokenExistsResponse(returnArguments ...interface{}) *publicdashbo
 			},
 		},
 		&fakeOAuthTokenService{},
+		nil,
+		nil,
+		nil,
 	)
 
 	return publicdashboardsService.ProvideService(setting.NewCfg(), fakeStore, qds)
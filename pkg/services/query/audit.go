@@ -0,0 +1,68 @@
+package query
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// recordAudit records one query audit event for each data source queried by the request.
+func (s *Service) recordAudit(user *user.SignedInUser, parsedReq *parsedRequest, duration time.Duration, resp *backend.QueryDataResponse, err error) {
+	if s.auditService.IsDisabled() {
+		return
+	}
+
+	dashboardUID, panelID := queryaudit.DashboardContext(parsedReq.httpRequest)
+
+	var order []string
+	byDataSource := map[string][]parsedQuery{}
+	for _, pq := range parsedReq.parsedQueries {
+		if pq.datasource == nil || expr.IsDataSource(pq.datasource.Uid) {
+			continue
+		}
+		if _, exists := byDataSource[pq.datasource.Uid]; !exists {
+			order = append(order, pq.datasource.Uid)
+		}
+		byDataSource[pq.datasource.Uid] = append(byDataSource[pq.datasource.Uid], pq)
+	}
+
+	for _, uid := range order {
+		queries := byDataSource[uid]
+		e := queryaudit.Event{
+			OrgID:          user.OrgID,
+			UserID:         user.UserID,
+			UserLogin:      user.Login,
+			Source:         queryaudit.SourceQuery,
+			DataSourceUID:  uid,
+			DataSourceType: queries[0].datasource.Type,
+			DashboardUID:   dashboardUID,
+			PanelID:        panelID,
+			DurationMs:     duration.Milliseconds(),
+			Status:         queryaudit.StatusSuccess,
+		}
+
+		models := make([]json.RawMessage, 0, len(queries))
+		for _, pq := range queries {
+			models = append(models, pq.query.JSON)
+			if err == nil && resp != nil && resp.Responses[pq.query.RefID].Error != nil && e.Error == "" {
+				e.Error = resp.Responses[pq.query.RefID].Error.Error()
+			}
+		}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		if e.Error != "" {
+			e.Status = queryaudit.StatusError
+		}
+		if text, err := json.Marshal(models); err == nil {
+			e.Query = string(text)
+		}
+
+		s.auditService.Record(e)
+	}
+}
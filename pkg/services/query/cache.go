@@ -0,0 +1,251 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// HeaderQueryCache is set on query responses to report whether the result was served from the query cache.
+	HeaderQueryCache = "X-Cache"
+
+	QueryCacheHit    = "HIT"
+	QueryCacheMiss   = "MISS"
+	QueryCacheBypass = "BYPASS"
+
+	queryCacheKeyPrefix = "query-cache-"
+)
+
+var queryCacheRequestsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query_cache",
+		Name:      "requests_total",
+		Help:      "A counter for data source queries that could be served from the query cache, partitioned by the cache status",
+	},
+	[]string{"datasource_type", "status"},
+)
+
+// volatileQueryFields are the fields of a query model that change on every request
+// without changing the result, or that are part of the cache key in a normalized form.
+var volatileQueryFields = []string{"requestId", "intervalMs", "maxDataPoints", "datasourceId", "datasource"}
+
+// queryCache stores the responses of data source queries in the remote cache. Data sources
+// opt in to caching by setting `queryCachingEnabled` in their JSON data, and can override
+// the default time to live with `queryCachingTTL`.
+type queryCache struct {
+	cfg            *setting.Cfg
+	cache          remotecache.CacheStorage
+	isUserSpecific func(ds *datasources.DataSource) bool
+	log            log.Logger
+}
+
+// ttl returns the time to live of the cached results of the data source,
+// and false if the results of the data source must not be cached.
+func (c *queryCache) ttl(ds *datasources.DataSource) (time.Duration, bool) {
+	if c.cache == nil || c.cfg == nil || !c.cfg.QueryCaching.Enabled || ds.JsonData == nil {
+		return 0, false
+	}
+	if !ds.JsonData.Get("queryCachingEnabled").MustBool(false) {
+		return 0, false
+	}
+
+	ttl := c.cfg.QueryCaching.TTL
+	if raw := ds.JsonData.Get("queryCachingTTL").MustString(""); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			c.log.Warn("Invalid query caching TTL of data source, using the default", "datasource", ds.Uid, "ttl", raw, "error", err)
+		} else {
+			ttl = d
+		}
+	}
+	if ttl > c.cfg.QueryCaching.MaxTTL {
+		ttl = c.cfg.QueryCaching.MaxTTL
+	}
+	return ttl, ttl > 0
+}
+
+// key returns the cache key of the request. The result is shared between the users with the same
+// org, role, teams and permissions, unless the data source receives the identity of the user, in
+// which case the user becomes part of the key.
+func (c *queryCache) key(u *user.SignedInUser, ds *datasources.DataSource, req *backend.QueryDataRequest) (string, error) {
+	key, err := requestKey(u, ds, req, c.isUserSpecific(ds), true)
+	if err != nil {
+		return "", err
+	}
+	return queryCacheKeyPrefix + key, nil
+}
+
+// requestKey returns a hash that identifies the result of the request. When alignToInterval is set,
+// the time range of the queries is truncated to their interval.
+func requestKey(u *user.SignedInUser, ds *datasources.DataSource, req *backend.QueryDataRequest, userSpecific bool, alignToInterval bool) (string, error) {
+	h := sha256.New()
+	write := func(values ...string) {
+		for _, v := range values {
+			_, _ = h.Write([]byte(v))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+
+	write(strconv.FormatInt(ds.OrgId, 10), ds.Uid, strconv.Itoa(ds.Version), ds.Updated.String())
+	if u != nil {
+		write(accessFingerprint(u)...)
+		if userSpecific {
+			write(strconv.FormatInt(u.UserID, 10), u.Login)
+		}
+	}
+
+	for _, q := range req.Queries {
+		model, err := normalizeQueryModel(q.JSON)
+		if err != nil {
+			return "", err
+		}
+		from, to := q.TimeRange.From, q.TimeRange.To
+		if alignToInterval {
+			from, to = alignTimeRange(q.TimeRange, q.Interval)
+		}
+		write(q.RefID, q.QueryType, q.Interval.String(), strconv.FormatInt(q.MaxDataPoints, 10),
+			strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli(), 10), string(model))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// accessFingerprint returns the org, role, teams and permissions of the user, so that a result is
+// only shared between users with the same access.
+func accessFingerprint(u *user.SignedInUser) []string {
+	values := []string{strconv.FormatInt(u.OrgID, 10), string(u.OrgRole), strconv.FormatBool(u.IsGrafanaAdmin)}
+
+	teams := make([]int64, len(u.Teams))
+	copy(teams, u.Teams)
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+	for _, t := range teams {
+		values = append(values, "team:"+strconv.FormatInt(t, 10))
+	}
+
+	permissions := make([]string, 0)
+	for action, scopes := range u.Permissions[u.OrgID] {
+		for _, scope := range scopes {
+			permissions = append(permissions, action+"|"+scope)
+		}
+	}
+	sort.Strings(permissions)
+	return append(values, permissions...)
+}
+
+// get returns the cached response of the request, or nil if there is none.
+func (c *queryCache) get(ctx context.Context, key string) *backend.QueryDataResponse {
+	value, err := c.cache.Get(ctx, key)
+	if err != nil {
+		if err != remotecache.ErrCacheItemNotFound {
+			c.log.Warn("Failed to read query result from cache", "error", err)
+		}
+		return nil
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	resp := &backend.QueryDataResponse{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		c.log.Warn("Failed to decode cached query result", "error", err)
+		return nil
+	}
+	return resp
+}
+
+// set caches the response unless any of its queries failed.
+func (c *queryCache) set(ctx context.Context, key string, resp *backend.QueryDataResponse, ttl time.Duration) {
+	for _, r := range resp.Responses {
+		if r.Error != nil {
+			return
+		}
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		c.log.Warn("Failed to encode query result for caching", "error", err)
+		return
+	}
+	if err := c.cache.Set(ctx, key, b, ttl); err != nil {
+		c.log.Warn("Failed to write query result to cache", "error", err)
+	}
+}
+
+// normalizeQueryModel removes the volatile fields from the query model.
+// The fields of the result are sorted, so equal queries have equal models.
+func normalizeQueryModel(raw json.RawMessage) ([]byte, error) {
+	model := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &model); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range volatileQueryFields {
+		delete(model, f)
+	}
+	return json.Marshal(model)
+}
+
+// alignTimeRange truncates the time range to the interval of the query,
+// so that requests issued within the same step share the cache entry.
+func alignTimeRange(tr backend.TimeRange, interval time.Duration) (time.Time, time.Time) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return tr.From.Truncate(interval), tr.To.Truncate(interval)
+}
+
+type cacheStatusKey struct{}
+
+// CacheStatus collects the query cache status of the queries made with a context.
+type CacheStatus struct {
+	mu     sync.Mutex
+	status string
+}
+
+// WithCacheStatus returns a context that records the query cache status of the
+// queries made with it, so that it can be reported back to the client.
+func WithCacheStatus(ctx context.Context) (context.Context, *CacheStatus) {
+	s := &CacheStatus{}
+	return context.WithValue(ctx, cacheStatusKey{}, s), s
+}
+
+// Get returns HIT if all queries were served from the cache, the status of the first query
+// that was not otherwise, or an empty string if caching was not enabled for any of them.
+func (s *CacheStatus) Get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func recordCacheStatus(ctx context.Context, dsType string, status string) {
+	queryCacheRequestsCounter.WithLabelValues(dsType, status).Inc()
+
+	s, ok := ctx.Value(cacheStatusKey{}).(*CacheStatus)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status == "" || s.status == QueryCacheHit {
+		s.status = status
+	}
+}
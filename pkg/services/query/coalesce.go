@@ -0,0 +1,145 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var coalescedRequestsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "coalesced_requests_total",
+		Help:      "A counter for data source requests that were served by an identical request already in flight",
+	},
+	[]string{"datasource_type"},
+)
+
+// inflightRequest is a data source request that is shared by all callers that issued it.
+type inflightRequest struct {
+	done    chan struct{}
+	resp    *backend.QueryDataResponse
+	err     error
+	callers int
+	cancel  context.CancelFunc
+}
+
+// requestCoalescer makes sure that identical data source requests that are in flight at the same
+// time are sent to the plugin only once. The request is canceled only when all callers are gone.
+type requestCoalescer struct {
+	mu       sync.Mutex
+	inflight map[string]*inflightRequest
+}
+
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{inflight: map[string]*inflightRequest{}}
+}
+
+// do executes fn unless a request with the same key is in flight, in which case it waits for its result.
+// Every caller receives its own copy of the response, so that callers can modify it.
+func (c *requestCoalescer) do(ctx context.Context, key string, dsType string, fn func(ctx context.Context) (*backend.QueryDataResponse, error)) (*backend.QueryDataResponse, error) {
+	c.mu.Lock()
+	req, ok := c.inflight[key]
+	if ok {
+		req.callers++
+		coalescedRequestsCounter.WithLabelValues(dsType).Inc()
+	} else {
+		// the request must outlive the caller that started it, as long as there are other callers waiting for it.
+		reqCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		req = &inflightRequest{done: make(chan struct{}), callers: 1, cancel: cancel}
+		c.inflight[key] = req
+		go c.execute(reqCtx, key, req, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-req.done:
+		if req.err != nil {
+			return nil, req.err
+		}
+		return copyQueryDataResponse(req.resp), nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		req.callers--
+		if req.callers == 0 {
+			req.cancel()
+			if c.inflight[key] == req {
+				delete(c.inflight, key)
+			}
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *requestCoalescer) execute(ctx context.Context, key string, req *inflightRequest, fn func(ctx context.Context) (*backend.QueryDataResponse, error)) {
+	defer req.cancel()
+	req.resp, req.err = fn(ctx)
+
+	c.mu.Lock()
+	if c.inflight[key] == req {
+		delete(c.inflight, key)
+	}
+	c.mu.Unlock()
+	close(req.done)
+}
+
+// copyQueryDataResponse returns a deep copy of the frames of the response, so that a caller modifying its frames,
+// their metadata or the values of their fields doesn't modify the responses of the other callers.
+func copyQueryDataResponse(resp *backend.QueryDataResponse) *backend.QueryDataResponse {
+	if resp == nil {
+		return nil
+	}
+	result := backend.NewQueryDataResponse()
+	for refID, r := range resp.Responses {
+		frames := make(data.Frames, 0, len(r.Frames))
+		for _, f := range r.Frames {
+			frames = append(frames, copyFrame(f))
+		}
+		r.Frames = frames
+		result.Responses[refID] = r
+	}
+	return result
+}
+
+// copyFrame returns a copy of the frame with copies of its metadata, fields, field configs and values.
+func copyFrame(f *data.Frame) *data.Frame {
+	if f == nil {
+		return nil
+	}
+	frame := f.EmptyCopy()
+	if f.Meta != nil {
+		meta := *f.Meta
+		meta.Notices = append([]data.Notice(nil), f.Meta.Notices...)
+		meta.Stats = append([]data.QueryStat(nil), f.Meta.Stats...)
+		frame.Meta = &meta
+	}
+	for i, field := range f.Fields {
+		fieldCopy := frame.Fields[i]
+		if field.Config != nil {
+			config := *field.Config
+			fieldCopy.Config = &config
+		}
+		fieldCopy.Extend(field.Len())
+		for j := 0; j < field.Len(); j++ {
+			fieldCopy.Set(j, field.CopyAt(j))
+		}
+	}
+	return frame
+}
+
+// detachedContext keeps the values of its parent, but is not canceled with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
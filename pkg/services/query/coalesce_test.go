@@ -0,0 +1,149 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCoalescer(t *testing.T) {
+	response := func() *backend.QueryDataResponse {
+		resp := backend.NewQueryDataResponse()
+		frame := data.NewFrame("A", data.NewField("value", nil, []int64{1}).SetConfig(&data.FieldConfig{Unit: "ms"}))
+		frame.Meta = &data.FrameMeta{ExecutedQueryString: "up"}
+		resp.Responses["A"] = backend.DataResponse{Frames: data.Frames{frame}}
+		return resp
+	}
+
+	t.Run("concurrent identical requests are executed once", func(t *testing.T) {
+		c := newRequestCoalescer()
+		release := make(chan struct{})
+		calls := 0
+		fn := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+			calls++
+			<-release
+			return response(), nil
+		}
+
+		const callers = 5
+		results := make(chan *backend.QueryDataResponse, callers)
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := c.do(context.Background(), "key", "test", fn)
+				require.NoError(t, err)
+				results <- resp
+			}()
+		}
+
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.inflight["key"] != nil && c.inflight["key"].callers == callers
+		}, time.Second, 10*time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(results)
+
+		require.Equal(t, 1, calls)
+		var previous *backend.QueryDataResponse
+		for resp := range results {
+			frame := resp.Responses["A"].Frames[0]
+			require.Equal(t, "up", frame.Meta.ExecutedQueryString)
+			require.Equal(t, int64(1), frame.Fields[0].At(0))
+			require.Equal(t, "ms", frame.Fields[0].Config.Unit)
+			require.Empty(t, frame.Meta.Notices)
+			if previous != nil {
+				previousFrame := previous.Responses["A"].Frames[0]
+				require.NotSame(t, previousFrame.Meta, frame.Meta)
+				require.NotSame(t, previousFrame.Fields[0], frame.Fields[0])
+				require.NotSame(t, previousFrame.Fields[0].Config, frame.Fields[0].Config)
+			}
+			// modifying the values of a response doesn't modify the other responses
+			frame.Fields[0].Set(0, int64(2))
+			frame.Fields[0].Config.Unit = "s"
+			frame.AppendNotices(data.Notice{Text: "modified"})
+			previous = resp
+		}
+		require.Empty(t, c.inflight)
+	})
+
+	t.Run("request is not canceled while there are callers waiting", func(t *testing.T) {
+		c := newRequestCoalescer()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		fn := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+			close(started)
+			select {
+			case <-release:
+				return response(), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		firstCtx, cancelFirst := context.WithCancel(context.Background())
+		firstErr := make(chan error)
+		go func() {
+			_, err := c.do(firstCtx, "key", "test", fn)
+			firstErr <- err
+		}()
+		<-started
+
+		secondResp := make(chan *backend.QueryDataResponse)
+		go func() {
+			resp, err := c.do(context.Background(), "key", "test", fn)
+			require.NoError(t, err)
+			secondResp <- resp
+		}()
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.inflight["key"].callers == 2
+		}, time.Second, 10*time.Millisecond)
+
+		cancelFirst()
+		require.ErrorIs(t, <-firstErr, context.Canceled)
+
+		close(release)
+		require.NotNil(t, <-secondResp)
+	})
+
+	t.Run("request is canceled when all callers are gone", func(t *testing.T) {
+		c := newRequestCoalescer()
+		canceled := make(chan struct{})
+		fn := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error)
+		go func() {
+			_, err := c.do(ctx, "key", "test", fn)
+			errCh <- err
+		}()
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.inflight["key"] != nil
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-errCh, context.Canceled)
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("request was not canceled")
+		}
+		require.Empty(t, c.inflight)
+	})
+}
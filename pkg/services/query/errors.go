@@ -9,4 +9,9 @@ var (
 	ErrInvalidDatasourceID   = errutil.NewBase(errutil.StatusBadRequest, "query.invalidDatasourceId", errutil.WithPublicMessage("Query does not contain a valid data source identifier")).Errorf("invalid data source identifier")
 	ErrMultipleDatasources   = errutil.NewBase(errutil.StatusBadRequest, "query.differentDatasources", errutil.WithPublicMessage("All queries must use the same datasource")).Errorf("all queries must use the same datasource")
 	ErrMissingDataSourceInfo = errutil.NewBase(errutil.StatusBadRequest, "query.missingDataSourceInfo").MustTemplate("query missing datasource info: {{ .Public.RefId }}", errutil.WithPublic("Query {{ .Public.RefId }} is missing datasource information"))
+
+	ErrQueryReferenceNotFound       = errutil.NewBase(errutil.StatusBadRequest, "query.referenceNotFound").MustTemplate("query {{ .Public.RefId }} references unknown query {{ .Public.Reference }}", errutil.WithPublic("Query {{ .Public.RefId }} references unknown query {{ .Public.Reference }}"))
+	ErrQueryDuplicateRefID          = errutil.NewBase(errutil.StatusBadRequest, "query.duplicateRefId").MustTemplate("duplicate query refId {{ .Public.RefId }}", errutil.WithPublic("Several queries have the refId {{ .Public.RefId }}"))
+	ErrQueryReferenceCycle          = errutil.NewBase(errutil.StatusBadRequest, "query.referenceCycle", errutil.WithPublicMessage("Queries cannot reference themselves or each other in a cycle")).Errorf("queries reference each other in a cycle")
+	ErrQueryReferenceWithExpression = errutil.NewBase(errutil.StatusBadRequest, "query.referenceWithExpression", errutil.WithPublicMessage("Queries referencing other queries cannot be combined with expressions")).Errorf("query references cannot be combined with expressions")
 )
@@ -0,0 +1,276 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/expr"
+	publicDashboards "github.com/grafana/grafana/pkg/services/publicdashboards/queries"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// queryReferenceRegexp matches the references to the results of other queries of a request, in the form
+// `${__ref.A}`, `${__ref.A.field}` or `${__ref.A.field:format}`. Without a field, the values of the first
+// field which is not a time field are used.
+var queryReferenceRegexp = regexp.MustCompile(`\$\{__ref\.([\w-]+)(?:\.([^}:]+))?(?::(\w+))?\}`)
+
+// queryNode is a query of a request with queries of several data sources, in the dependency graph of the request.
+type queryNode struct {
+	id    int64
+	refID string
+	dsUID string
+	query *simplejson.Json
+	// needs are the reference IDs of the queries whose results are used by the query.
+	needs []string
+}
+
+// ID allows the gonum graph node interface to be fulfilled.
+func (n *queryNode) ID() int64 {
+	return n.id
+}
+
+func (n *queryNode) RefID() string {
+	return n.refID
+}
+
+func (n *queryNode) NeedsVars() []string {
+	return n.needs
+}
+
+// queryPlan is the order of execution of the queries of a request, grouped in stages. The queries of a stage
+// only depend on queries of previous stages.
+type queryPlan struct {
+	stages [][]*queryNode
+	// hasReferences is true if a query references the results of other queries.
+	hasReferences bool
+}
+
+// hasQueryReferences returns true if a query references the results of other queries.
+func hasQueryReferences(queries []*simplejson.Json) bool {
+	for _, q := range queries {
+		raw, err := q.MarshalJSON()
+		if err == nil && queryReferenceRegexp.Match(raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildQueryPlan orders the queries by dependency with the dependency graph of the expressions.
+func buildQueryPlan(queries []*simplejson.Json) (*queryPlan, error) {
+	registry := make(map[string]*queryNode, len(queries))
+	nodes := make([]*queryNode, 0, len(queries))
+	plan := &queryPlan{}
+
+	for i, q := range queries {
+		raw, err := q.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		n := &queryNode{
+			id:    int64(i),
+			refID: q.Get("refId").MustString(),
+			dsUID: publicDashboards.GetDataSourceUidFromJson(q),
+			query: q,
+		}
+		if _, exists := registry[n.refID]; exists {
+			return nil, ErrQueryDuplicateRefID.Build(errutil.TemplateData{
+				Public: map[string]interface{}{"RefId": n.refID},
+			})
+		}
+		seen := map[string]bool{}
+		for _, m := range queryReferenceRegexp.FindAllStringSubmatch(string(raw), -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				n.needs = append(n.needs, m[1])
+			}
+		}
+		if len(n.needs) > 0 {
+			plan.hasReferences = true
+		}
+
+		registry[n.refID] = n
+		nodes = append(nodes, n)
+	}
+
+	if !plan.hasReferences {
+		plan.stages = [][]*queryNode{nodes}
+		return plan, nil
+	}
+
+	dependencyNodes := make([]expr.DependencyNode, 0, len(nodes))
+	for _, n := range nodes {
+		for _, refID := range n.needs {
+			if _, ok := registry[refID]; !ok {
+				return nil, ErrQueryReferenceNotFound.Build(errutil.TemplateData{
+					Public: map[string]interface{}{
+						"RefId":     n.refID,
+						"Reference": refID,
+					},
+				})
+			}
+		}
+		dependencyNodes = append(dependencyNodes, n)
+	}
+
+	sorted, err := expr.OrderByDependency(dependencyNodes)
+	if err != nil {
+		if errors.Is(err, expr.ErrDependencyCycle) {
+			return nil, ErrQueryReferenceCycle
+		}
+		return nil, err
+	}
+
+	// the stage of a query is the length of the longest chain of queries it depends on
+	stageOf := make(map[int64]int, len(sorted))
+	for _, dn := range sorted {
+		n := dn.(*queryNode)
+		stage := 0
+		for _, refID := range n.needs {
+			if s := stageOf[registry[refID].id] + 1; s > stage {
+				stage = s
+			}
+		}
+		stageOf[n.id] = stage
+		for len(plan.stages) <= stage {
+			plan.stages = append(plan.stages, nil)
+		}
+		plan.stages[stage] = append(plan.stages[stage], n)
+	}
+	for _, stage := range plan.stages {
+		sort.Slice(stage, func(i, j int) bool { return stage[i].id < stage[j].id })
+	}
+	return plan, nil
+}
+
+// failedDependency returns the reference ID of a query needed by the node which failed, if any.
+func (n *queryNode) failedDependency(responses backend.Responses) (string, bool) {
+	for _, refID := range n.needs {
+		if dr, ok := responses[refID]; !ok || dr.Error != nil {
+			return refID, true
+		}
+	}
+	return "", false
+}
+
+// interpolate returns a copy of the query where the references to other queries are replaced by their results.
+func (n *queryNode) interpolate(responses backend.Responses) (*simplejson.Json, error) {
+	if len(n.needs) == 0 {
+		return n.query, nil
+	}
+
+	var interpolateErr error
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			return queryReferenceRegexp.ReplaceAllStringFunc(v, func(ref string) string {
+				m := queryReferenceRegexp.FindStringSubmatch(ref)
+				res, err := formatValues(referenceValues(responses[m[1]], m[2]), m[3])
+				if err != nil && interpolateErr == nil {
+					interpolateErr = fmt.Errorf("query %s: %w", n.refID, err)
+				}
+				return res
+			})
+		case map[string]interface{}:
+			res := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				res[key] = walk(value)
+			}
+			return res
+		case []interface{}:
+			res := make([]interface{}, len(v))
+			for i, value := range v {
+				res[i] = walk(value)
+			}
+			return res
+		default:
+			return v
+		}
+	}
+
+	res := simplejson.NewFromAny(walk(n.query.Interface()))
+	if interpolateErr != nil {
+		return nil, interpolateErr
+	}
+	return res, nil
+}
+
+// referenceValues returns the distinct values of a field of the frames of a query result.
+func referenceValues(dr backend.DataResponse, fieldName string) []string {
+	var values []string
+	seen := map[string]bool{}
+	for _, frame := range dr.Frames {
+		field := referenceField(frame, fieldName)
+		if field == nil {
+			continue
+		}
+		for i := 0; i < field.Len(); i++ {
+			v, ok := field.ConcreteAt(i)
+			if !ok {
+				continue
+			}
+			s := fmt.Sprint(v)
+			if !seen[s] {
+				seen[s] = true
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+func referenceField(frame *data.Frame, name string) *data.Field {
+	for _, field := range frame.Fields {
+		if name == "" && field.Type() != data.FieldTypeTime && field.Type() != data.FieldTypeNullableTime {
+			return field
+		}
+		if name != "" && (field.Name == name || field.Config != nil && field.Config.DisplayNameFromDS == name) {
+			return field
+		}
+	}
+	return nil
+}
+
+// formatValues formats the values of a reference like the template variable formats of dashboards.
+func formatValues(values []string, format string) (string, error) {
+	quote := func(values []string, q string, escaped string) []string {
+		res := make([]string, len(values))
+		for i, v := range values {
+			res[i] = q + strings.ReplaceAll(v, q, escaped) + q
+		}
+		return res
+	}
+
+	switch format {
+	case "", "csv":
+		return strings.Join(values, ","), nil
+	case "pipe":
+		return strings.Join(values, "|"), nil
+	case "regex":
+		escaped := make([]string, len(values))
+		for i, v := range values {
+			escaped[i] = regexp.QuoteMeta(v)
+		}
+		if len(escaped) == 1 {
+			return escaped[0], nil
+		}
+		return "(" + strings.Join(escaped, "|") + ")", nil
+	case "singlequote":
+		return strings.Join(quote(values, "'", `\'`), ","), nil
+	case "doublequote":
+		return strings.Join(quote(values, `"`, `\"`), ","), nil
+	case "sqlstring":
+		return strings.Join(quote(values, "'", "''"), ","), nil
+	default:
+		return "", fmt.Errorf("unknown query reference format %q", format)
+	}
+}
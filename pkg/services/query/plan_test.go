@@ -0,0 +1,135 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestBuildQueryPlan(t *testing.T) {
+	newQuery := func(refID, dsUID, expr string) *simplejson.Json {
+		return simplejson.NewFromAny(map[string]interface{}{
+			"refId":      refID,
+			"datasource": map[string]interface{}{"uid": dsUID},
+			"expr":       expr,
+		})
+	}
+	refIDs := func(plan *queryPlan) [][]string {
+		var res [][]string
+		for _, stage := range plan.stages {
+			var ids []string
+			for _, n := range stage {
+				ids = append(ids, n.refID)
+			}
+			res = append(res, ids)
+		}
+		return res
+	}
+
+	t.Run("Queries without references are executed together", func(t *testing.T) {
+		plan, err := buildQueryPlan([]*simplejson.Json{newQuery("A", "ds1", "up"), newQuery("B", "ds2", "down")})
+		require.NoError(t, err)
+		require.False(t, plan.hasReferences)
+		require.Equal(t, [][]string{{"A", "B"}}, refIDs(plan))
+	})
+
+	t.Run("Queries are executed after the queries they reference", func(t *testing.T) {
+		plan, err := buildQueryPlan([]*simplejson.Json{
+			newQuery("C", "ds2", `up{host=~"${__ref.B.host:regex}", env="${__ref.A}"}`),
+			newQuery("B", "ds1", "SELECT host FROM hosts WHERE env = '${__ref.A}'"),
+			newQuery("A", "ds1", "SELECT env FROM envs"),
+			newQuery("D", "ds2", "down"),
+		})
+		require.NoError(t, err)
+		require.True(t, plan.hasReferences)
+		require.Equal(t, [][]string{{"A", "D"}, {"B"}, {"C"}}, refIDs(plan))
+	})
+
+	t.Run("References to unknown queries are rejected", func(t *testing.T) {
+		_, err := buildQueryPlan([]*simplejson.Json{newQuery("A", "ds1", "${__ref.B}")})
+		require.ErrorIs(t, err, ErrQueryReferenceNotFound.Base)
+	})
+
+	t.Run("Cycles and references to the query itself are rejected", func(t *testing.T) {
+		_, err := buildQueryPlan([]*simplejson.Json{newQuery("A", "ds1", "${__ref.B}"), newQuery("B", "ds1", "${__ref.A}")})
+		require.True(t, errors.Is(err, ErrQueryReferenceCycle))
+
+		_, err = buildQueryPlan([]*simplejson.Json{newQuery("A", "ds1", "${__ref.A}")})
+		require.True(t, errors.Is(err, ErrQueryReferenceCycle))
+	})
+
+	t.Run("Duplicate refIds are rejected", func(t *testing.T) {
+		_, err := buildQueryPlan([]*simplejson.Json{newQuery("A", "ds1", "up"), newQuery("A", "ds2", "${__ref.A}")})
+		require.ErrorIs(t, err, ErrQueryDuplicateRefID.Base)
+	})
+}
+
+func TestQueryNode_Interpolate(t *testing.T) {
+	responses := backend.Responses{
+		"A": backend.DataResponse{Frames: data.Frames{
+			data.NewFrame("",
+				data.NewField("time", nil, []time.Time{time.Unix(1, 0), time.Unix(2, 0), time.Unix(3, 0)}),
+				data.NewField("host", nil, []string{"a", "b.example", "a"}),
+				data.NewField("value", nil, []*float64{nil, float64Ptr(1.5), float64Ptr(2)}),
+			),
+		}},
+	}
+	newNode := func(q map[string]interface{}) *queryNode {
+		plan, err := buildQueryPlan([]*simplejson.Json{
+			simplejson.NewFromAny(map[string]interface{}{"refId": "A"}),
+			simplejson.NewFromAny(q),
+		})
+		require.NoError(t, err)
+		return plan.stages[1][0]
+	}
+
+	t.Run("References are replaced by the values of the referenced field", func(t *testing.T) {
+		n := newNode(map[string]interface{}{
+			"refId": "B",
+			"expr":  `up{host=~"${__ref.A.host:regex}"} > ${__ref.A.value}`,
+			"nested": []interface{}{
+				map[string]interface{}{"sql": "WHERE host IN (${__ref.A:sqlstring})", "limit": 10},
+			},
+		})
+
+		q, err := n.interpolate(responses)
+		require.NoError(t, err)
+		require.Equal(t, `up{host=~"(a|b\.example)"} > 1.5,2`, q.Get("expr").MustString())
+		require.Equal(t, "WHERE host IN ('a','b.example')", q.Get("nested").GetIndex(0).Get("sql").MustString())
+		require.Equal(t, 10, q.Get("nested").GetIndex(0).Get("limit").MustInt())
+		require.Contains(t, n.query.Get("expr").MustString(), "${__ref.A.host:regex}")
+	})
+
+	t.Run("Unknown formats are rejected", func(t *testing.T) {
+		n := newNode(map[string]interface{}{"refId": "B", "expr": "${__ref.A:glob}"})
+		_, err := n.interpolate(responses)
+		require.Error(t, err)
+	})
+}
+
+func TestFormatValues(t *testing.T) {
+	values := []string{"a", "it's"}
+	for format, expected := range map[string]string{
+		"":            "a,it's",
+		"csv":         "a,it's",
+		"pipe":        "a|it's",
+		"regex":       "(a|it's)",
+		"singlequote": `'a','it\'s'`,
+		"doublequote": `"a","it's"`,
+		"sqlstring":   `'a','it''s'`,
+	} {
+		res, err := formatValues(values, format)
+		require.NoError(t, err)
+		require.Equal(t, expected, res, format)
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
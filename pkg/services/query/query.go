@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
@@ -11,12 +12,15 @@ import (
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/httpclient/httpclientprovider"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/adapters"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/concurrency"
 	"github.com/grafana/grafana/pkg/services/oauthtoken"
 	publicDashboards "github.com/grafana/grafana/pkg/services/publicdashboards/queries"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/grafanads"
@@ -36,6 +40,9 @@ func ProvideService(
 	dataSourceService datasources.DataSourceService,
 	pluginClient plugins.Client,
 	oAuthTokenService oauthtoken.OAuthTokenService,
+	remoteCache *remotecache.RemoteCache,
+	limiter *concurrency.Limiter,
+	auditService *queryaudit.Service,
 ) *Service {
 	g := &Service{
 		cfg:                    cfg,
@@ -45,8 +52,19 @@ func ProvideService(
 		dataSourceService:      dataSourceService,
 		pluginClient:           pluginClient,
 		oAuthTokenService:      oAuthTokenService,
+		coalescer:              newRequestCoalescer(),
+		limiter:                limiter,
+		auditService:           auditService,
 		log:                    log.New("query_data"),
 	}
+	g.queryCache = &queryCache{
+		cfg:            cfg,
+		isUserSpecific: g.isUserSpecific,
+		log:            log.New("query_data.cache"),
+	}
+	if remoteCache != nil {
+		g.queryCache.cache = remoteCache
+	}
 	g.log.Info("Query Service initialization")
 	return g
 }
@@ -59,6 +77,10 @@ type Service struct {
 	dataSourceService      datasources.DataSourceService
 	pluginClient           plugins.Client
 	oAuthTokenService      oauthtoken.OAuthTokenService
+	queryCache             *queryCache
+	coalescer              *requestCoalescer
+	limiter                *concurrency.Limiter
+	auditService           *queryaudit.Service
 	log                    log.Logger
 }
 
@@ -69,19 +91,37 @@ func (s *Service) Run(ctx context.Context) error {
 }
 
 // QueryData can process queries and return query responses.
+// Queries can use the results of other queries with `${__ref.<refId>}`, and are then executed in dependency order.
 func (s *Service) QueryData(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest, handleExpressions bool) (*backend.QueryDataResponse, error) {
+	if hasQueryReferences(reqDTO.Queries) {
+		return s.queryDataWithReferences(ctx, user, skipCache, reqDTO)
+	}
+	return s.queryData(ctx, user, skipCache, reqDTO, handleExpressions)
+}
+
+func (s *Service) queryData(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest, handleExpressions bool) (*backend.QueryDataResponse, error) {
 	parsedReq, err := s.parseMetricRequest(ctx, user, skipCache, reqDTO)
 	if err != nil {
 		return nil, err
 	}
+
+	var resp *backend.QueryDataResponse
+	start := time.Now()
 	if handleExpressions && parsedReq.hasExpression {
-		return s.handleExpressions(ctx, user, parsedReq)
+		resp, err = s.handleExpressions(ctx, user, parsedReq)
+	} else {
+		resp, err = s.handleQueryData(ctx, user, parsedReq)
 	}
-	return s.handleQueryData(ctx, user, parsedReq)
+	s.recordAudit(user, parsedReq, time.Since(start), resp, err)
+	return resp, err
 }
 
-// QueryData can process queries and return query responses.
+// QueryDataMultipleSources can process queries of several data sources and return query responses.
 func (s *Service) QueryDataMultipleSources(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest, handleExpressions bool) (*backend.QueryDataResponse, error) {
+	if hasQueryReferences(reqDTO.Queries) {
+		return s.queryDataWithReferences(ctx, user, skipCache, reqDTO)
+	}
+
 	byDataSource := publicDashboards.GroupQueriesByDataSource(reqDTO.Queries)
 
 	// The expression service will handle mixed datasources, so we don't need to group them when an expression is present.
@@ -109,6 +149,63 @@ func (s *Service) QueryDataMultipleSources(ctx context.Context, user *user.Signe
 	}
 }
 
+// queryDataWithReferences executes queries of one or several data sources which use the results of other queries.
+func (s *Service) queryDataWithReferences(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest) (*backend.QueryDataResponse, error) {
+	if publicDashboards.HasExpressionQuery(reqDTO.Queries) {
+		return nil, ErrQueryReferenceWithExpression
+	}
+	plan, err := buildQueryPlan(reqDTO.Queries)
+	if err != nil {
+		return nil, err
+	}
+	return s.executeQueryPlan(ctx, user, skipCache, reqDTO, plan)
+}
+
+// executeQueryPlan executes the stages of the plan in order. The queries of a stage are grouped by data source,
+// and their references to queries of previous stages are replaced by the results of those queries.
+// A query is not executed when a query it depends on failed.
+func (s *Service) executeQueryPlan(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest, plan *queryPlan) (*backend.QueryDataResponse, error) {
+	resp := backend.NewQueryDataResponse()
+
+	for _, stage := range plan.stages {
+		var order []string
+		byDataSource := make(map[string][]*simplejson.Json)
+
+		for _, n := range stage {
+			if refID, failed := n.failedDependency(resp.Responses); failed {
+				resp.Responses[n.refID] = backend.DataResponse{
+					Error: fmt.Errorf("query %s depends on query %s which failed", n.refID, refID),
+				}
+				continue
+			}
+
+			q, err := n.interpolate(resp.Responses)
+			if err != nil {
+				resp.Responses[n.refID] = backend.DataResponse{Error: err}
+				continue
+			}
+
+			if _, exists := byDataSource[n.dsUID]; !exists {
+				order = append(order, n.dsUID)
+			}
+			byDataSource[n.dsUID] = append(byDataSource[n.dsUID], q)
+		}
+
+		for _, dsUID := range order {
+			subResp, err := s.queryData(ctx, user, skipCache, reqDTO.CloneWithQueries(byDataSource[dsUID]), false)
+			if err != nil {
+				return nil, err
+			}
+
+			for refID, queryResponse := range subResp.Responses {
+				resp.Responses[refID] = queryResponse
+			}
+		}
+	}
+
+	return resp, nil
+}
+
 // handleExpressions handles POST /api/ds/query when there is an expression.
 func (s *Service) handleExpressions(ctx context.Context, user *user.SignedInUser, parsedReq *parsedRequest) (*backend.QueryDataResponse, error) {
 	exprReq := expr.Request{
@@ -200,7 +297,174 @@ func (s *Service) handleQueryData(ctx context.Context, user *user.SignedInUser,
 
 	ctx = httpclient.WithContextualMiddleware(ctx, middlewares...)
 
-	return s.pluginClient.QueryData(ctx, req)
+	return s.queryDataWithCache(ctx, user, ds, req, parsedReq.skipCache)
+}
+
+// queryDataWithCache serves the request from the query cache if the data source opted in to caching.
+// When skipCache is set the cached result is ignored, but the cache is refreshed with the new one.
+func (s *Service) queryDataWithCache(ctx context.Context, user *user.SignedInUser, ds *datasources.DataSource, req *backend.QueryDataRequest, skipCache bool) (*backend.QueryDataResponse, error) {
+	ttl, ok := s.queryCache.ttl(ds)
+	if !ok {
+		return s.queryPlugin(ctx, user, ds, req)
+	}
+
+	key, err := s.queryCache.key(user, ds, req)
+	if err != nil {
+		s.log.Warn("Failed to compute query cache key, skipping the cache", "datasource", ds.Uid, "error", err)
+		return s.queryPlugin(ctx, user, ds, req)
+	}
+
+	if skipCache {
+		recordCacheStatus(ctx, ds.Type, QueryCacheBypass)
+	} else if resp := s.queryCache.get(ctx, key); resp != nil {
+		recordCacheStatus(ctx, ds.Type, QueryCacheHit)
+		return resp, nil
+	} else {
+		recordCacheStatus(ctx, ds.Type, QueryCacheMiss)
+	}
+
+	resp, err := s.queryPlugin(ctx, user, ds, req)
+	if err != nil {
+		return nil, err
+	}
+	s.queryCache.set(ctx, key, resp, ttl)
+	return resp, nil
+}
+
+// queryPlugin sends the request to the data source plugin. Identical requests for the same
+// data source and organization that are in flight at the same time are sent only once.
+func (s *Service) queryPlugin(ctx context.Context, user *user.SignedInUser, ds *datasources.DataSource, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	if s.cfg == nil || !s.cfg.Query.CoalesceRequests {
+		return s.queryPluginWithLimit(ctx, ds, req)
+	}
+
+	key, err := requestKey(user, ds, req, s.isUserSpecific(ds), false)
+	if err != nil {
+		s.log.Warn("Failed to compute query key, skipping request coalescing", "datasource", ds.Uid, "error", err)
+		return s.queryPluginWithLimit(ctx, ds, req)
+	}
+
+	return s.coalescer.do(ctx, key, ds.Type, func(ctx context.Context) (*backend.QueryDataResponse, error) {
+		return s.queryPluginWithLimit(ctx, ds, req)
+	})
+}
+
+// queryPluginWithLimit sends the request to the data source plugin once the concurrency limits allow it.
+func (s *Service) queryPluginWithLimit(ctx context.Context, ds *datasources.DataSource, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	ctx, release, err := s.limiter.Acquire(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := s.pluginClient.QueryData(ctx, req)
+	if !queryFailed(ctx, resp, err) {
+		s.setSecretRotationCredentialInUse(ctx, ds, datasources.SecretRotationCredentialPending)
+		return resp, err
+	}
+	if !credentialFailure(resp, err) {
+		return resp, err
+	}
+	return s.retryWithCurrentSecrets(ctx, ds, req, resp, err)
+}
+
+// retryWithCurrentSecrets retries a failed request with the current secrets of a data source in the grace period
+// of a secret rotation. The fallback only applies to this request: the secrets of the data source are not changed.
+func (s *Service) retryWithCurrentSecrets(ctx context.Context, ds *datasources.DataSource, req *backend.QueryDataRequest, resp *backend.QueryDataResponse, err error) (*backend.QueryDataResponse, error) {
+	inGracePeriod, graceErr := s.dataSourceService.InSecretRotationGracePeriod(ctx, ds)
+	if graceErr != nil {
+		s.log.Warn("Failed to get the secret rotation of the data source", "datasource", ds.Uid, "error", graceErr)
+		return resp, err
+	}
+	if !inGracePeriod {
+		return resp, err
+	}
+
+	instanceSettings, settingsErr := adapters.ModelToInstanceSettings(ds, s.decryptSecureJsonDataFn(datasources.WithCurrentSecrets(ctx)))
+	if settingsErr != nil {
+		return resp, err
+	}
+	instanceSettings.ID = datasources.CurrentSecretsID(ds.Id)
+	retryReq := *req
+	retryReq.PluginContext.DataSourceInstanceSettings = instanceSettings
+
+	retryResp, retryErr := s.pluginClient.QueryData(ctx, &retryReq)
+	if queryFailed(ctx, retryResp, retryErr) {
+		return resp, err
+	}
+	s.log.Debug("Query succeeded with the current secrets of the data source in secret rotation", "datasource", ds.Uid)
+	s.setSecretRotationCredentialInUse(ctx, ds, datasources.SecretRotationCredentialCurrent)
+	return retryResp, retryErr
+}
+
+// setSecretRotationCredentialInUse records the secrets of a successful request, for data sources in secret rotation.
+func (s *Service) setSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) {
+	if err := s.dataSourceService.SetSecretRotationCredentialInUse(ctx, ds, credential); err != nil {
+		s.log.Warn("Failed to record the secrets in use by the data source in secret rotation", "datasource", ds.Uid, "error", err)
+	}
+}
+
+// queryFailed returns true if the plugin failed the request, or every query of the request.
+func queryFailed(ctx context.Context, resp *backend.QueryDataResponse, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	if resp == nil || len(resp.Responses) == 0 {
+		return false
+	}
+	for _, r := range resp.Responses {
+		if r.Error == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// credentialFailureMessages are parts of the error messages of data sources which rejected the credentials of the
+// request, or could not be reached with them. Errors of the plugins only have a message, their type is lost.
+var credentialFailureMessages = []string{
+	"authentication", "unauthorized", "unauthorised", "not authorized", "forbidden", "access denied",
+	"permission denied", "login failed", "password", "credential", "api key", "access token", "401", "403",
+	"x509", "tls:", "certificate", "connection refused", "connection reset", "no such host", "i/o timeout",
+}
+
+// credentialFailure returns true if the failure of a request may be caused by its credentials, so that sending it
+// again with other credentials can succeed. Other failures, like syntax errors in the queries, are not retried.
+func credentialFailure(resp *backend.QueryDataResponse, err error) bool {
+	if err != nil {
+		return isCredentialError(err)
+	}
+	for _, r := range resp.Responses {
+		if isCredentialError(r.Error) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCredentialError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range credentialFailureMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUserSpecific returns true if the identity of the user is forwarded to the data source,
+// so that its results can differ between users.
+func (s *Service) isUserSpecific(ds *datasources.DataSource) bool {
+	if s.cfg != nil && s.cfg.SendUserHeader {
+		return true
+	}
+	return len(ds.AllowedCookies()) > 0 || s.oAuthTokenService.IsOAuthPassThruEnabled(ds)
 }
 
 type parsedQuery struct {
@@ -212,6 +476,7 @@ type parsedRequest struct {
 	hasExpression bool
 	parsedQueries []parsedQuery
 	httpRequest   *http.Request
+	skipCache     bool
 }
 
 func (s *Service) parseMetricRequest(ctx context.Context, user *user.SignedInUser, skipCache bool, reqDTO dtos.MetricRequest) (*parsedRequest, error) {
@@ -223,6 +488,7 @@ func (s *Service) parseMetricRequest(ctx context.Context, user *user.SignedInUse
 	req := &parsedRequest{
 		hasExpression: false,
 		parsedQueries: []parsedQuery{},
+		skipCache:     skipCache,
 	}
 
 	// Parse the queries
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
@@ -14,18 +16,22 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/plugins"
 	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
 	dsSvc "github.com/grafana/grafana/pkg/services/datasources/service"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/query"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	secretskvs "github.com/grafana/grafana/pkg/services/secrets/kvstore"
 	secretsmng "github.com/grafana/grafana/pkg/services/secrets/manager"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
 )
 
 func TestQueryDataMultipleSources(t *testing.T) {
@@ -114,6 +120,73 @@ func TestQueryDataMultipleSources(t *testing.T) {
 	})
 }
 
+func TestQueryDataMultipleSourcesWithReferences(t *testing.T) {
+	newQuery := func(refID, dsUID, expr string) *simplejson.Json {
+		return simplejson.NewFromAny(map[string]interface{}{
+			"refId":      refID,
+			"datasource": map[string]interface{}{"uid": dsUID},
+			"expr":       expr,
+		})
+	}
+
+	setupWithReferences := func(t *testing.T, failing string) (*referencePluginClient, *query.Service) {
+		pc := &referencePluginClient{failing: failing}
+		dc := &fakeDataSourceCache{ds: &datasources.DataSource{Uid: "ds1", Type: "postgres"}}
+		return pc, query.ProvideService(nil, dc, nil, &fakePluginRequestValidator{}, &fakeDatasources.FakeDataSourceService{}, pc, &fakeOAuthTokenService{}, nil, nil, nil)
+	}
+
+	t.Run("queries use the results of the queries they reference", func(t *testing.T) {
+		pc, svc := setupWithReferences(t, "")
+		reqDTO := dtos.MetricRequest{Queries: []*simplejson.Json{
+			newQuery("B", "ds2", `up{host=~"${__ref.A.host:regex}"}`),
+			newQuery("A", "ds1", "SELECT host FROM hosts"),
+		}}
+
+		resp, err := svc.QueryDataMultipleSources(context.Background(), nil, true, reqDTO, true)
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 2)
+		require.Equal(t, []string{"SELECT host FROM hosts", `up{host=~"(a|b)"}`}, pc.queries)
+	})
+
+	t.Run("QueryData executes the queries with references in dependency order", func(t *testing.T) {
+		pc, svc := setupWithReferences(t, "")
+		reqDTO := dtos.MetricRequest{Queries: []*simplejson.Json{
+			newQuery("B", "ds2", `up{host=~"${__ref.A.host:regex}"}`),
+			newQuery("A", "ds1", "SELECT host FROM hosts"),
+		}}
+
+		resp, err := svc.QueryData(context.Background(), nil, true, reqDTO, true)
+		require.NoError(t, err)
+		require.Len(t, resp.Responses, 2)
+		require.Equal(t, []string{"SELECT host FROM hosts", `up{host=~"(a|b)"}`}, pc.queries)
+	})
+
+	t.Run("queries are not executed when a query they reference failed", func(t *testing.T) {
+		pc, svc := setupWithReferences(t, "A")
+		reqDTO := dtos.MetricRequest{Queries: []*simplejson.Json{
+			newQuery("A", "ds1", "SELECT host FROM hosts"),
+			newQuery("B", "ds2", `up{host=~"${__ref.A.host:regex}"}`),
+		}}
+
+		resp, err := svc.QueryDataMultipleSources(context.Background(), nil, true, reqDTO, true)
+		require.NoError(t, err)
+		require.Error(t, resp.Responses["B"].Error)
+		require.Equal(t, []string{"SELECT host FROM hosts"}, pc.queries)
+	})
+
+	t.Run("references cannot be combined with expressions", func(t *testing.T) {
+		_, svc := setupWithReferences(t, "")
+		reqDTO := dtos.MetricRequest{Queries: []*simplejson.Json{
+			newQuery("A", "ds1", "SELECT host FROM hosts"),
+			newQuery("B", "ds2", `up{host=~"${__ref.A.host:regex}"}`),
+			newQuery("C", expr.DatasourceUID, "$B"),
+		}}
+
+		_, err := svc.QueryDataMultipleSources(context.Background(), nil, true, reqDTO, true)
+		require.ErrorIs(t, err, query.ErrQueryReferenceWithExpression)
+	})
+}
+
 func TestQueryData(t *testing.T) {
 	t.Run("it auth custom headers to the request", func(t *testing.T) {
 		token := &oauth2.Token{
@@ -174,6 +247,168 @@ func TestQueryData(t *testing.T) {
 	})
 }
 
+func TestQueryDataCache(t *testing.T) {
+	request := func(expr string) dtos.MetricRequest {
+		q := simplejson.NewFromAny(map[string]interface{}{
+			"datasource": map[string]interface{}{"uid": "ds1"},
+			"refId":      "A",
+			"expr":       expr,
+			"requestId":  util.GenerateShortUID(),
+		})
+		return dtos.MetricRequest{From: "1640995200000", To: "1641081600000", Queries: []*simplejson.Json{q}}
+	}
+
+	doQuery := func(t *testing.T, tc *testContext, skipCache bool, reqDTO dtos.MetricRequest) string {
+		ctx, status := query.WithCacheStatus(context.Background())
+		_, err := tc.queryService.QueryData(ctx, nil, skipCache, reqDTO, false)
+		require.NoError(t, err)
+		return status.Get()
+	}
+
+	t.Run("identical queries are served from the cache", func(t *testing.T) {
+		tc := setupWithCache(t, true)
+		require.Equal(t, query.QueryCacheMiss, doQuery(t, tc, false, request("up")))
+		require.Equal(t, query.QueryCacheHit, doQuery(t, tc, false, request("up")))
+		require.Equal(t, 1, tc.pluginContext.calls)
+	})
+
+	t.Run("different queries are not served from the cache", func(t *testing.T) {
+		tc := setupWithCache(t, true)
+		require.Equal(t, query.QueryCacheMiss, doQuery(t, tc, false, request("up")))
+		require.Equal(t, query.QueryCacheMiss, doQuery(t, tc, false, request("down")))
+		require.Equal(t, 2, tc.pluginContext.calls)
+	})
+
+	t.Run("skipping the cache refreshes the cached result", func(t *testing.T) {
+		tc := setupWithCache(t, true)
+		require.Equal(t, query.QueryCacheBypass, doQuery(t, tc, true, request("up")))
+		require.Equal(t, query.QueryCacheHit, doQuery(t, tc, false, request("up")))
+		require.Equal(t, 1, tc.pluginContext.calls)
+	})
+
+	t.Run("users with different access do not share the cached result", func(t *testing.T) {
+		tc := setupWithCache(t, true)
+		queryAs := func(u *user.SignedInUser) string {
+			ctx, status := query.WithCacheStatus(context.Background())
+			_, err := tc.queryService.QueryData(ctx, u, false, request("up"), false)
+			require.NoError(t, err)
+			return status.Get()
+		}
+		viewer := &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleViewer}
+		otherViewer := &user.SignedInUser{UserID: 2, OrgID: 1, OrgRole: org.RoleViewer}
+		editor := &user.SignedInUser{UserID: 3, OrgID: 1, OrgRole: org.RoleEditor}
+		restricted := &user.SignedInUser{UserID: 4, OrgID: 1, OrgRole: org.RoleViewer, Permissions: map[int64]map[string][]string{
+			1: {"datasources:query": {"datasources:uid:other"}},
+		}}
+
+		require.Equal(t, query.QueryCacheMiss, queryAs(viewer))
+		require.Equal(t, query.QueryCacheHit, queryAs(otherViewer))
+		require.Equal(t, query.QueryCacheMiss, queryAs(editor))
+		require.Equal(t, query.QueryCacheMiss, queryAs(restricted))
+		require.Equal(t, 3, tc.pluginContext.calls)
+	})
+
+	t.Run("data sources that did not opt in are not cached", func(t *testing.T) {
+		tc := setupWithCache(t, false)
+		require.Equal(t, "", doQuery(t, tc, false, request("up")))
+		require.Equal(t, "", doQuery(t, tc, false, request("up")))
+		require.Equal(t, 2, tc.pluginContext.calls)
+	})
+}
+
+func TestQueryDataSecretRotation(t *testing.T) {
+	setupRotation := func(t *testing.T, validPassword string) (*rotatingDataSourceService, *passwordCheckingPluginClient, *query.Service) {
+		dsService := &rotatingDataSourceService{}
+		pc := &passwordCheckingPluginClient{validPassword: validPassword}
+		dc := &fakeDataSourceCache{ds: &datasources.DataSource{Id: 1, Uid: "ds1", Type: "postgres", Updated: time.Unix(100, 0)}}
+		return dsService, pc, query.ProvideService(nil, dc, nil, &fakePluginRequestValidator{}, dsService, pc, &fakeOAuthTokenService{}, nil, nil, nil)
+	}
+
+	t.Run("falls back to the current secrets when the pending ones fail", func(t *testing.T) {
+		dsService, pc, svc := setupRotation(t, "old")
+
+		resp, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.NoError(t, resp.Responses["A"].Error)
+		require.Equal(t, 2, pc.calls)
+
+		// the next query tries the pending secrets first again
+		_, err = svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, 4, pc.calls)
+		require.Empty(t, dsService.updates)
+	})
+
+	t.Run("uses another plugin instance for the current secrets", func(t *testing.T) {
+		_, pc, svc := setupRotation(t, "old")
+
+		_, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, datasources.CurrentSecretsID(1)}, pc.instanceIDs)
+		// the update time is kept, so that neither instance is replaced by the other one
+		for _, updated := range pc.instanceUpdated {
+			require.Equal(t, time.Unix(100, 0), updated)
+		}
+	})
+
+	t.Run("returns the error of the pending secrets when the current ones fail too", func(t *testing.T) {
+		dsService, pc, svc := setupRotation(t, "none")
+
+		resp, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Error(t, resp.Responses["A"].Error)
+		require.Equal(t, 2, pc.calls)
+		require.Empty(t, dsService.updates)
+	})
+
+	t.Run("does not retry successful queries", func(t *testing.T) {
+		_, pc, svc := setupRotation(t, "new")
+
+		_, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, 1, pc.calls)
+	})
+
+	t.Run("does not retry queries which failed for another reason than their credentials", func(t *testing.T) {
+		_, pc, svc := setupRotation(t, "old")
+		pc.queryError = errors.New(`syntax error at or near "selec"`)
+
+		resp, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Error(t, resp.Responses["A"].Error)
+		require.Equal(t, 1, pc.calls)
+	})
+
+	t.Run("records the secrets of the successful requests", func(t *testing.T) {
+		dsService, pc, svc := setupRotation(t, "old")
+
+		_, err := svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, []datasources.SecretRotationCredential{datasources.SecretRotationCredentialCurrent}, dsService.inUse)
+
+		pc.validPassword = "new"
+		_, err = svc.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, []datasources.SecretRotationCredential{
+			datasources.SecretRotationCredentialCurrent, datasources.SecretRotationCredentialPending,
+		}, dsService.inUse)
+	})
+}
+
+func setupWithCache(t *testing.T, dsCachingEnabled bool) *testContext {
+	tc := setup(t)
+	cfg := setting.NewCfg()
+	cfg.QueryCaching = setting.QueryCachingSettings{Enabled: true, TTL: time.Minute, MaxTTL: time.Hour}
+	tc.dataSourceCache.ds = &datasources.DataSource{
+		Uid:      "ds1",
+		Type:     "prometheus",
+		JsonData: simplejson.NewFromAny(map[string]interface{}{"queryCachingEnabled": dsCachingEnabled}),
+	}
+	ds := dsSvc.ProvideService(nil, secretsmng.SetupTestService(t, fakes.NewFakeSecretsStore()), tc.secretStore, nil, featuremgmt.WithFeatures(), acmock.New(), acmock.NewMockedPermissionsService())
+	tc.queryService = query.ProvideService(cfg, tc.dataSourceCache, nil, tc.pluginRequestValidator, ds, tc.pluginContext, tc.oauthTokenService, remotecache.NewFakeStore(t), nil, nil)
+	return tc
+}
+
 func setup(t *testing.T) *testContext {
 	pc := &fakePluginClient{}
 	dc := &fakeDataSourceCache{ds: &datasources.DataSource{}}
@@ -197,7 +432,7 @@ func setup(t *testing.T) *testContext {
 		dataSourceCache:        dc,
 		oauthTokenService:      tc,
 		pluginRequestValidator: rv,
-		queryService:           query.ProvideService(nil, dc, exprService, rv, ds, pc, tc),
+		queryService:           query.ProvideService(nil, dc, exprService, rv, ds, pc, tc, nil, nil, nil),
 	}
 }
 
@@ -256,11 +491,13 @@ func (c *fakeDataSourceCache) GetDatasourceByUID(ctx context.Context, datasource
 type fakePluginClient struct {
 	plugins.Client
 
-	req *backend.QueryDataRequest
+	req   *backend.QueryDataRequest
+	calls int
 }
 
 func (c *fakePluginClient) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	c.req = req
+	c.calls++
 
 	// If an expression query ends up getting directly queried, we want it to return an error in our test.
 	if req.PluginContext.PluginID == "__expr__" {
@@ -269,3 +506,87 @@ func (c *fakePluginClient) QueryData(ctx context.Context, req *backend.QueryData
 
 	return &backend.QueryDataResponse{Responses: make(backend.Responses)}, nil
 }
+
+// referencePluginClient records the queries it receives, and returns hosts for each query.
+type referencePluginClient struct {
+	plugins.Client
+
+	failing string
+	queries []string
+}
+
+func (c *referencePluginClient) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	resp := backend.NewQueryDataResponse()
+	for _, q := range req.Queries {
+		model, err := simplejson.NewJson(q.JSON)
+		if err != nil {
+			return nil, err
+		}
+		c.queries = append(c.queries, model.Get("expr").MustString())
+
+		if q.RefID == c.failing {
+			resp.Responses[q.RefID] = backend.DataResponse{Error: errors.New("query failed")}
+			continue
+		}
+		resp.Responses[q.RefID] = backend.DataResponse{Frames: data.Frames{
+			data.NewFrame("", data.NewField("host", nil, []string{"a", "b"})),
+		}}
+	}
+	return resp, nil
+}
+
+// rotatingDataSourceService is a data source in the grace period of a secret rotation from password "old" to "new".
+type rotatingDataSourceService struct {
+	fakeDatasources.FakeDataSourceService
+
+	updates int
+	inUse   []datasources.SecretRotationCredential
+}
+
+func (s *rotatingDataSourceService) DecryptedValues(ctx context.Context, ds *datasources.DataSource) (map[string]string, error) {
+	if datasources.UsesCurrentSecrets(ctx) {
+		return map[string]string{"password": "old"}, nil
+	}
+	return map[string]string{"password": "new"}, nil
+}
+
+func (s *rotatingDataSourceService) InSecretRotationGracePeriod(ctx context.Context, ds *datasources.DataSource) (bool, error) {
+	return true, nil
+}
+
+func (s *rotatingDataSourceService) SetSecretRotationCredentialInUse(ctx context.Context, ds *datasources.DataSource, credential datasources.SecretRotationCredential) error {
+	s.inUse = append(s.inUse, credential)
+	return nil
+}
+
+func (s *rotatingDataSourceService) UpdateDataSource(ctx context.Context, cmd *datasources.UpdateDataSourceCommand) error {
+	s.updates++
+	return nil
+}
+
+type passwordCheckingPluginClient struct {
+	plugins.Client
+
+	validPassword   string
+	queryError      error
+	calls           int
+	instanceIDs     []int64
+	instanceUpdated []time.Time
+}
+
+func (c *passwordCheckingPluginClient) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	c.calls++
+	c.instanceIDs = append(c.instanceIDs, req.PluginContext.DataSourceInstanceSettings.ID)
+	c.instanceUpdated = append(c.instanceUpdated, req.PluginContext.DataSourceInstanceSettings.Updated)
+	resp := backend.NewQueryDataResponse()
+	if c.queryError != nil {
+		resp.Responses["A"] = backend.DataResponse{Error: c.queryError}
+		return resp, nil
+	}
+	if req.PluginContext.DataSourceInstanceSettings.DecryptedSecureJSONData["password"] != c.validPassword {
+		resp.Responses["A"] = backend.DataResponse{Error: errors.New("password authentication failed")}
+		return resp, nil
+	}
+	resp.Responses["A"] = backend.DataResponse{}
+	return resp, nil
+}
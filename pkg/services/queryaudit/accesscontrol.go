@@ -0,0 +1,29 @@
+package queryaudit
+
+import (
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	// ActionRead allows searching the audited queries of the data sources of the current organization.
+	ActionRead = "queryaudit:read"
+)
+
+func registerRoles(service accesscontrol.Service) error {
+	reader := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        "fixed:queryaudit:reader",
+			DisplayName: "Query audit reader",
+			Description: "Search the audited queries of the data sources.",
+			Group:       "Query audit",
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: ActionRead,
+				},
+			},
+		},
+		Grants: []string{accesscontrol.RoleGrafanaAdmin},
+	}
+
+	return service.DeclareFixedRoles(reader)
+}
@@ -0,0 +1,100 @@
+package queryaudit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+func (s *Service) registerAPIEndpoints(routeRegister routing.RouteRegister) {
+	authorize := ac.Middleware(s.accessControl)
+	routeRegister.Get("/api/admin/query-audit", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.searchHandler))
+}
+
+// swagger:route GET /admin/query-audit admin searchQueryAudit
+//
+// Search the audited queries of the data sources of an organization, the most recent first. The organization
+// defaults to the current organization of the user, only server admins can search another organization. Only
+// available when the queries are audited to the database.
+//
+// You need to have a permission with action `queryaudit:read`.
+//
+// Responses:
+// 200: searchQueryAuditResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) searchHandler(c *models.ReqContext) response.Response {
+	query := SearchQuery{
+		OrgID:         c.QueryInt64("orgId"),
+		DataSourceUID: c.Query("datasourceUid"),
+		UserID:        c.QueryInt64("userId"),
+		Limit:         c.QueryInt("limit"),
+	}
+	if query.OrgID <= 0 {
+		query.OrgID = c.SignedInUser.OrgID
+	}
+	if query.OrgID != c.SignedInUser.OrgID && !c.SignedInUser.IsGrafanaAdmin {
+		return response.Error(http.StatusForbidden, "Only server admins can search the queries of another organization", nil)
+	}
+	if from := c.QueryInt64("from"); from > 0 {
+		query.From = time.UnixMilli(from)
+	}
+	if to := c.QueryInt64("to"); to > 0 {
+		query.To = time.UnixMilli(to)
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+
+	events, err := s.store.search(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to search query audit events", err)
+	}
+	return response.JSON(http.StatusOK, events)
+}
+
+// swagger:parameters searchQueryAudit
+type SearchQueryAuditParams struct {
+	// Organization of the data sources, defaults to the current organization.
+	// in:query
+	// required:false
+	OrgID int64 `json:"orgId"`
+	// in:query
+	// required:false
+	DatasourceUID string `json:"datasourceUid"`
+	// in:query
+	// required:false
+	UserID int64 `json:"userId"`
+	// Epoch timestamp in milliseconds.
+	// in:query
+	// required:false
+	From int64 `json:"from"`
+	// Epoch timestamp in milliseconds.
+	// in:query
+	// required:false
+	To int64 `json:"to"`
+	// in:query
+	// required:false
+	// default:100
+	Limit int `json:"limit"`
+}
+
+// swagger:response searchQueryAuditResponse
+type SearchQueryAuditResponse struct {
+	// in: body
+	Body []Event `json:"body"`
+}
@@ -0,0 +1,135 @@
+package queryaudit
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+// queryAuditRecord is a row of the query_audit table.
+type queryAuditRecord struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	Created        int64  `xorm:"'created'"`
+	OrgID          int64  `xorm:"org_id"`
+	UserID         int64  `xorm:"user_id"`
+	UserLogin      string `xorm:"user_login"`
+	Source         string `xorm:"source"`
+	DataSourceUID  string `xorm:"datasource_uid"`
+	DataSourceType string `xorm:"datasource_type"`
+	DashboardUID   string `xorm:"dashboard_uid"`
+	PanelID        int64  `xorm:"panel_id"`
+	DurationMs     int64  `xorm:"duration_ms"`
+	Status         string `xorm:"status"`
+	StatusCode     int    `xorm:"status_code"`
+	Error          string `xorm:"error"`
+	Query          string `xorm:"query"`
+}
+
+func (r queryAuditRecord) TableName() string {
+	return "query_audit"
+}
+
+func (r queryAuditRecord) event() Event {
+	return Event{
+		Timestamp:      time.UnixMilli(r.Created),
+		OrgID:          r.OrgID,
+		UserID:         r.UserID,
+		UserLogin:      r.UserLogin,
+		Source:         Source(r.Source),
+		DataSourceUID:  r.DataSourceUID,
+		DataSourceType: r.DataSourceType,
+		DashboardUID:   r.DashboardUID,
+		PanelID:        r.PanelID,
+		DurationMs:     r.DurationMs,
+		Status:         Status(r.Status),
+		StatusCode:     r.StatusCode,
+		Error:          r.Error,
+		Query:          r.Query,
+	}
+}
+
+// SearchQuery filters the events stored in the database.
+type SearchQuery struct {
+	OrgID         int64
+	DataSourceUID string
+	UserID        int64
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+// databaseSink stores the events in the query_audit table, for the configured retention.
+type databaseSink struct {
+	sqlStore db.DB
+}
+
+func (s *databaseSink) Write(ctx context.Context, e Event) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(&queryAuditRecord{
+			Created:        e.Timestamp.UnixMilli(),
+			OrgID:          e.OrgID,
+			UserID:         e.UserID,
+			UserLogin:      e.UserLogin,
+			Source:         string(e.Source),
+			DataSourceUID:  e.DataSourceUID,
+			DataSourceType: e.DataSourceType,
+			DashboardUID:   e.DashboardUID,
+			PanelID:        e.PanelID,
+			DurationMs:     e.DurationMs,
+			Status:         string(e.Status),
+			StatusCode:     e.StatusCode,
+			Error:          e.Error,
+			Query:          e.Query,
+		})
+		return err
+	})
+}
+
+func (s *databaseSink) Close() error {
+	return nil
+}
+
+func (s *databaseSink) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		res, err := sess.Exec("DELETE FROM query_audit WHERE created < ?", before.UnixMilli())
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+// search returns the events matching the query, the most recent first.
+func (s *databaseSink) search(ctx context.Context, query SearchQuery) ([]Event, error) {
+	var records []queryAuditRecord
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		q := sess.Where("org_id = ?", query.OrgID)
+		if query.DataSourceUID != "" {
+			q = q.And("datasource_uid = ?", query.DataSourceUID)
+		}
+		if query.UserID != 0 {
+			q = q.And("user_id = ?", query.UserID)
+		}
+		if !query.From.IsZero() {
+			q = q.And("created >= ?", query.From.UnixMilli())
+		}
+		if !query.To.IsZero() {
+			q = q.And("created <= ?", query.To.UnixMilli())
+		}
+		return q.Desc("created", "id").Limit(query.Limit).Find(&records)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(records))
+	for _, r := range records {
+		events = append(events, r.event())
+	}
+	return events, nil
+}
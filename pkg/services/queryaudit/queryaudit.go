@@ -0,0 +1,243 @@
+package queryaudit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// retentionInterval is the interval between two deletions of the expired events.
+const retentionInterval = time.Hour
+
+var (
+	eventsDroppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "query_audit_events_dropped_total",
+		Help:      "Number of query audit events dropped because the buffer of events was full",
+	})
+	writeFailuresCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Name:      "query_audit_write_failures_total",
+		Help:      "Number of query audit events which could not be written to a sink",
+	}, []string{"sink"})
+)
+
+// Source is how a data source was queried.
+type Source string
+
+const (
+	// SourceQuery is a query of the query API, sent to the data source plugin.
+	SourceQuery Source = "query"
+	// SourceProxy is a call of the data source proxy.
+	SourceProxy Source = "proxy"
+)
+
+// Status is the outcome of a query.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusError   Status = "error"
+)
+
+// Event is the record of a query of a data source by a user.
+type Event struct {
+	Timestamp      time.Time `json:"timestamp"`
+	OrgID          int64     `json:"orgId"`
+	UserID         int64     `json:"userId"`
+	UserLogin      string    `json:"userLogin"`
+	Source         Source    `json:"source"`
+	DataSourceUID  string    `json:"datasourceUid"`
+	DataSourceType string    `json:"datasourceType"`
+	DashboardUID   string    `json:"dashboardUid,omitempty"`
+	PanelID        int64     `json:"panelId,omitempty"`
+	DurationMs     int64     `json:"durationMs"`
+	Status         Status    `json:"status"`
+	// StatusCode is the HTTP status code of data source proxy calls.
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Query is the text of the query, redacted according to the configuration.
+	Query string `json:"query,omitempty"`
+}
+
+// Sink is a destination of the query audit events.
+type Sink interface {
+	// Write records an event.
+	Write(ctx context.Context, e Event) error
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+// expiringSink is a sink which keeps the events for the configured retention.
+type expiringSink interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Service records the queries of data sources, and writes them asynchronously to the configured sinks.
+type Service struct {
+	cfg      setting.QueryAuditSettings
+	redactor *redactor
+	sinks    map[string]Sink
+	store    *databaseSink
+	events   chan Event
+	log      log.Logger
+
+	accessControl accesscontrol.AccessControl
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore db.DB, routeRegister routing.RouteRegister, accessControl accesscontrol.AccessControl,
+	accessControlService accesscontrol.Service) (*Service, error) {
+	s := &Service{
+		cfg:    cfg.QueryAudit,
+		sinks:  map[string]Sink{},
+		events: make(chan Event, cfg.QueryAudit.BufferSize),
+		log:    log.New("query_audit"),
+
+		accessControl: accessControl,
+	}
+	if !s.cfg.Enabled {
+		return s, nil
+	}
+
+	var err error
+	if s.redactor, err = newRedactor(s.cfg); err != nil {
+		return nil, err
+	}
+
+	for _, name := range s.cfg.Sinks {
+		var sink Sink
+		switch name {
+		case "database":
+			s.store = &databaseSink{sqlStore: sqlStore}
+			sink = s.store
+		case "file":
+			path := s.cfg.FilePath
+			if path == "" {
+				path = filepath.Join(cfg.LogsPath, "query_audit.log")
+			}
+			if sink, err = newFileSink(path); err != nil {
+				return nil, err
+			}
+		case "syslog":
+			if sink, err = newSyslogSink(s.cfg.SyslogNetwork, s.cfg.SyslogAddress, s.cfg.SyslogFacility, s.cfg.SyslogTag); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown query audit sink %q", name)
+		}
+		s.sinks[name] = sink
+	}
+
+	if s.store != nil {
+		if err := registerRoles(accessControlService); err != nil {
+			return nil, err
+		}
+		s.registerAPIEndpoints(routeRegister)
+	}
+	return s, nil
+}
+
+// IsDisabled returns true if the queries are not audited.
+func (s *Service) IsDisabled() bool {
+	return s == nil || !s.cfg.Enabled
+}
+
+// Run writes the recorded events to the sinks, and deletes the expired events, until the context is canceled.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-s.events:
+			s.write(ctx, e)
+		case <-ticker.C:
+			s.deleteExpired(ctx)
+		case <-ctx.Done():
+			s.flush()
+			return ctx.Err()
+		}
+	}
+}
+
+// Record queues an event to be written to the sinks. The event is dropped if too many events are waiting.
+func (s *Service) Record(e Event) {
+	if s.IsDisabled() {
+		return
+	}
+
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	e.Query = s.redactor.redact(e.Query)
+
+	select {
+	case s.events <- e:
+	default:
+		eventsDroppedCounter.Inc()
+	}
+}
+
+func (s *Service) write(ctx context.Context, e Event) {
+	for name, sink := range s.sinks {
+		if err := sink.Write(ctx, e); err != nil {
+			writeFailuresCounter.WithLabelValues(name).Inc()
+			s.log.Warn("Failed to write query audit event", "sink", name, "error", err)
+		}
+	}
+}
+
+// flush writes the queued events and closes the sinks, when the server shuts down.
+func (s *Service) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for len(s.events) > 0 {
+		s.write(ctx, <-s.events)
+	}
+
+	for name, sink := range s.sinks {
+		if err := sink.Close(); err != nil {
+			s.log.Warn("Failed to close query audit sink", "sink", name, "error", err)
+		}
+	}
+}
+
+func (s *Service) deleteExpired(ctx context.Context) {
+	if s.cfg.Retention <= 0 {
+		return
+	}
+	for name, sink := range s.sinks {
+		es, ok := sink.(expiringSink)
+		if !ok {
+			continue
+		}
+		deleted, err := es.DeleteExpired(ctx, time.Now().Add(-s.cfg.Retention))
+		if err != nil {
+			s.log.Warn("Failed to delete expired query audit events", "sink", name, "error", err)
+			continue
+		}
+		s.log.Debug("Deleted expired query audit events", "sink", name, "count", deleted)
+	}
+}
+
+// DashboardContext returns the UID of the dashboard and the ID of the panel which sent a query, if any.
+func DashboardContext(req *http.Request) (string, int64) {
+	if req == nil {
+		return "", 0
+	}
+	panelID, _ := strconv.ParseInt(req.Header.Get("X-Panel-Id"), 10, 64)
+	return req.Header.Get("X-Dashboard-Uid"), panelID
+}
@@ -0,0 +1,197 @@
+package queryaudit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestRedactor(t *testing.T) {
+	cfg := setting.QueryAuditSettings{
+		QueryText:      "redacted",
+		RedactPatterns: []string{`'(?:[^'\\]|\\.)*'`, `(?i)(?:password|token)"?\s*[=:]\s*"?[^\s&,;"]+`},
+	}
+
+	t.Run("String literals and credentials are redacted", func(t *testing.T) {
+		r, err := newRedactor(cfg)
+		require.NoError(t, err)
+
+		require.Equal(t, "SELECT * FROM users WHERE name = [REDACTED]", r.redact(`SELECT * FROM users WHERE name = 'it\'s me'`))
+		require.Equal(t, "GET api/v1/query?query=up&[REDACTED]", r.redact("GET api/v1/query?query=up&token=abc"))
+		require.Equal(t, `{"[REDACTED]"}`, r.redact(`{"password": "secret"}`))
+	})
+
+	t.Run("Queries are recorded in full or not at all", func(t *testing.T) {
+		cfg := cfg
+		cfg.QueryText = "full"
+		r, err := newRedactor(cfg)
+		require.NoError(t, err)
+		require.Equal(t, "SELECT 'a'", r.redact("SELECT 'a'"))
+
+		cfg.QueryText = "none"
+		r, err = newRedactor(cfg)
+		require.NoError(t, err)
+		require.Empty(t, r.redact("SELECT 'a'"))
+	})
+
+	t.Run("Long queries are truncated", func(t *testing.T) {
+		cfg := cfg
+		cfg.MaxQueryLength = 8
+		r, err := newRedactor(cfg)
+		require.NoError(t, err)
+		require.Equal(t, "SELECT 1...", r.redact("SELECT 1 + 1"))
+		require.Equal(t, "héhéh...", r.redact("héhéhé"))
+	})
+
+	t.Run("Invalid patterns are rejected", func(t *testing.T) {
+		cfg := cfg
+		cfg.RedactPatterns = []string{"("}
+		_, err := newRedactor(cfg)
+		require.Error(t, err)
+	})
+}
+
+func TestService(t *testing.T) {
+	newService := func(t *testing.T, bufferSize int) *Service {
+		t.Helper()
+		cfg := setting.NewCfg()
+		cfg.QueryAudit = setting.QueryAuditSettings{
+			Enabled:    true,
+			Sinks:      []string{"file"},
+			QueryText:  "full",
+			FilePath:   filepath.Join(t.TempDir(), "audit", "query_audit.log"),
+			BufferSize: bufferSize,
+		}
+		s, err := ProvideService(cfg, nil, nil, nil, nil)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("Events are written to the file when the service stops", func(t *testing.T) {
+		s := newService(t, 10)
+		s.Record(Event{OrgID: 1, UserLogin: "admin", Source: SourceQuery, DataSourceUID: "ds", Status: StatusSuccess, Query: "up"})
+		s.Record(Event{OrgID: 1, UserLogin: "admin", Source: SourceProxy, DataSourceUID: "ds", Status: StatusError, StatusCode: 502})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, s.Run(ctx), context.Canceled)
+
+		f, err := os.Open(s.cfg.FilePath)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = f.Close() })
+
+		var events []Event
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e Event
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+			events = append(events, e)
+		}
+		require.Len(t, events, 2)
+		require.Equal(t, "up", events[0].Query)
+		require.False(t, events[0].Timestamp.IsZero())
+		require.Equal(t, 502, events[1].StatusCode)
+	})
+
+	t.Run("Events are dropped when the buffer is full", func(t *testing.T) {
+		s := newService(t, 1)
+		s.Record(Event{Query: "a"})
+		s.Record(Event{Query: "b"})
+		require.Len(t, s.events, 1)
+	})
+
+	t.Run("Nothing is recorded when the service is disabled", func(t *testing.T) {
+		s, err := ProvideService(setting.NewCfg(), nil, nil, nil, nil)
+		require.NoError(t, err)
+		s.Record(Event{Query: "a"})
+		require.Empty(t, s.events)
+
+		var nilService *Service
+		require.True(t, nilService.IsDisabled())
+		nilService.Record(Event{Query: "a"})
+	})
+
+	t.Run("Unknown sinks are rejected", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.QueryAudit = setting.QueryAuditSettings{Enabled: true, Sinks: []string{"kafka"}}
+		_, err := ProvideService(cfg, nil, nil, nil, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestIntegrationDatabaseSink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sink := &databaseSink{sqlStore: sqlstore.InitTestDB(t)}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	events := []Event{
+		{Timestamp: now.Add(-48 * time.Hour), OrgID: 1, UserID: 1, DataSourceUID: "prometheus", Status: StatusSuccess},
+		{Timestamp: now.Add(-time.Hour), OrgID: 1, UserID: 2, DataSourceUID: "loki", Status: StatusSuccess, Query: `{job="grafana"}`},
+		{Timestamp: now, OrgID: 1, UserID: 1, DataSourceUID: "prometheus", DashboardUID: "dash", PanelID: 2, Status: StatusError, Error: "timeout"},
+		{Timestamp: now, OrgID: 2, UserID: 1, DataSourceUID: "prometheus", Status: StatusSuccess},
+	}
+	for _, e := range events {
+		require.NoError(t, sink.Write(ctx, e))
+	}
+
+	res, err := sink.search(ctx, SearchQuery{OrgID: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	require.Equal(t, events[2].Timestamp.UnixMilli(), res[0].Timestamp.UnixMilli())
+	require.Equal(t, "timeout", res[0].Error)
+	require.Equal(t, int64(2), res[0].PanelID)
+
+	res, err = sink.search(ctx, SearchQuery{OrgID: 1, DataSourceUID: "prometheus", From: now.Add(-24 * time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	res, err = sink.search(ctx, SearchQuery{OrgID: 1, UserID: 2, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, `{job="grafana"}`, res[0].Query)
+
+	search := func(url string) []Event {
+		t.Helper()
+		s := &Service{store: sink}
+		c := &models.ReqContext{
+			Context:      &web.Context{Req: httptest.NewRequest(http.MethodGet, url, nil)},
+			SignedInUser: &user.SignedInUser{OrgID: 1, IsGrafanaAdmin: true},
+		}
+		resp := s.searchHandler(c)
+		require.Equal(t, http.StatusOK, resp.Status())
+		var res []Event
+		require.NoError(t, json.Unmarshal(resp.Body(), &res))
+		return res
+	}
+	require.Len(t, search("/api/admin/query-audit"), 3)
+	res = search("/api/admin/query-audit?orgId=2")
+	require.Len(t, res, 1)
+	require.Equal(t, int64(2), res[0].OrgID)
+
+	c := &models.ReqContext{
+		Context:      &web.Context{Req: httptest.NewRequest(http.MethodGet, "/api/admin/query-audit?orgId=2", nil)},
+		SignedInUser: &user.SignedInUser{OrgID: 1},
+	}
+	require.Equal(t, http.StatusForbidden, (&Service{store: sink}).searchHandler(c).Status())
+
+	deleted, err := sink.DeleteExpired(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,50 @@
+package queryaudit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const redacted = "[REDACTED]"
+
+// redactor removes sensitive values from the text of queries before it is recorded.
+type redactor struct {
+	mode      string
+	patterns  []*regexp.Regexp
+	maxLength int
+}
+
+func newRedactor(cfg setting.QueryAuditSettings) (*redactor, error) {
+	r := &redactor{mode: cfg.QueryText, maxLength: cfg.MaxQueryLength}
+	if r.mode != "redacted" {
+		return r, nil
+	}
+
+	for _, p := range cfg.RedactPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query audit redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func (r *redactor) redact(query string) string {
+	switch r.mode {
+	case "none":
+		return ""
+	case "redacted":
+		for _, re := range r.patterns {
+			query = re.ReplaceAllString(query, redacted)
+		}
+	}
+
+	if r.maxLength > 0 && len(query) > r.maxLength {
+		query = strings.ToValidUTF8(query[:r.maxLength], "") + "..."
+	}
+	return query
+}
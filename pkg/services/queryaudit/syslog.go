@@ -0,0 +1,100 @@
+package queryaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// severityInfo is the syslog severity of the query audit events.
+const severityInfo = 6
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSink sends the events as RFC 5424 syslog messages, with the event encoded as JSON in the message.
+// The connection is opened on the first event, and opened again after a failed write.
+type syslogSink struct {
+	network  string
+	address  string
+	priority int
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogSink(network, address, facility, tag string) (*syslogSink, error) {
+	f, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown query audit syslog facility %q", facility)
+	}
+	if address == "" {
+		network, address = "unixgram", "/dev/log"
+	}
+	if network == "" {
+		network = "udp"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{
+		network:  network,
+		address:  address,
+		priority: f*8 + severityInfo,
+		tag:      tag,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *syslogSink) Write(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", s.priority, e.Timestamp.UTC().Format(time.RFC3339Nano),
+		s.hostname, s.tag, os.Getpid(), body)
+	// stream connections need a delimiter between messages
+	if s.network == "tcp" || s.network == "tcp4" || s.network == "tcp6" || s.network == "unix" {
+		msg += "\n"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		var d net.Dialer
+		if s.conn, err = d.DialContext(ctx, s.network, s.address); err != nil {
+			s.conn = nil
+			return err
+		}
+	}
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
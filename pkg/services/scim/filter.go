@@ -0,0 +1,416 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Expression is a parsed SCIM filter, see section 3.4.2.2 of RFC 7644. Filters are evaluated on the JSON
+// representation of resources, attribute names and string values are compared case-insensitively.
+type Expression interface {
+	Matches(resource map[string]interface{}) bool
+}
+
+type logicalExpression struct {
+	and         bool
+	left, right Expression
+}
+
+func (e *logicalExpression) Matches(resource map[string]interface{}) bool {
+	if e.and {
+		return e.left.Matches(resource) && e.right.Matches(resource)
+	}
+	return e.left.Matches(resource) || e.right.Matches(resource)
+}
+
+type notExpression struct {
+	expr Expression
+}
+
+func (e *notExpression) Matches(resource map[string]interface{}) bool {
+	return !e.expr.Matches(resource)
+}
+
+type attributeExpression struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (e *attributeExpression) Matches(resource map[string]interface{}) bool {
+	values := attributeValues(resource, e.path)
+	switch e.op {
+	case "pr":
+		for _, v := range values {
+			if isPresent(v) {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return !matchesAny(values, "eq", e.value)
+	default:
+		return matchesAny(values, e.op, e.value)
+	}
+}
+
+// valuePathExpression matches the resources with a value of a multi-valued attribute that matches the filter, for
+// example emails[type eq "work"].
+type valuePathExpression struct {
+	attribute string
+	filter    Expression
+}
+
+func (e *valuePathExpression) Matches(resource map[string]interface{}) bool {
+	for _, v := range attributeValues(resource, []string{e.attribute}) {
+		if m, ok := v.(map[string]interface{}); ok && e.filter.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+// ParseFilter parses a SCIM filter.
+func ParseFilter(filter string) (Expression, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, invalidFilter("unexpected %q", p.peek())
+	}
+	return expr, nil
+}
+
+func invalidFilter(format string, args ...interface{}) error {
+	return NewError(http.StatusBadRequest, ErrorTypeInvalidFilter, "invalid filter: "+fmt.Sprintf(format, args...))
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) expect(token string) error {
+	if t := p.next(); t != token {
+		return invalidFilter("expected %q instead of %q", token, t)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (Expression, error) {
+	if !strings.EqualFold(p.peek(), "not") {
+		return p.parsePrimary()
+	}
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &notExpression{expr: expr}, nil
+}
+
+func (p *filterParser) parsePrimary() (Expression, error) {
+	if p.peek() == "(" {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	name := p.next()
+	if name == "" || isDelimiter(name) || strings.HasPrefix(name, `"`) {
+		return nil, invalidFilter("expected an attribute instead of %q", name)
+	}
+	path := parseAttributePath(name)
+
+	if p.peek() == "[" {
+		p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valuePathExpression{attribute: path[0], filter: filter}, nil
+	}
+
+	op := strings.ToLower(p.next())
+	if !operators[op] {
+		return nil, invalidFilter("unknown operator %q", op)
+	}
+	if op == "pr" {
+		return &attributeExpression{path: path, op: op}, nil
+	}
+
+	value, err := parseValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return &attributeExpression{path: path, op: op, value: value}, nil
+}
+
+// parseAttributePath returns the attribute and the sub-attribute of a path, without the schema URN prefix.
+func parseAttributePath(attribute string) []string {
+	if strings.HasPrefix(strings.ToLower(attribute), "urn:") {
+		if i := strings.LastIndex(attribute, ":"); i >= 0 {
+			attribute = attribute[i+1:]
+		}
+	}
+	return strings.SplitN(attribute, ".", 2)
+}
+
+func parseValue(token string) (interface{}, error) {
+	if token == "" || isDelimiter(token) {
+		return nil, invalidFilter("expected a value instead of %q", token)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(token), &value); err != nil {
+		return nil, invalidFilter("invalid value %s", token)
+	}
+	return value, nil
+}
+
+func isDelimiter(token string) bool {
+	return token == "(" || token == ")" || token == "[" || token == "]"
+}
+
+func tokenize(filter string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(filter) && filter[end] != '"'; end++ {
+				if filter[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(filter) {
+				return nil, invalidFilter("unterminated string")
+			}
+			tokens = append(tokens, filter[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for ; end < len(filter) && !strings.ContainsRune(" \t\n\r()[]\"", rune(filter[end])); end++ {
+			}
+			tokens = append(tokens, filter[i:end])
+			i = end
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, invalidFilter("empty filter")
+	}
+	return tokens, nil
+}
+
+// attribute returns the value of an attribute of a resource, attribute names are case-insensitive.
+func attribute(resource map[string]interface{}, name string) (string, interface{}, bool) {
+	if v, ok := resource[name]; ok {
+		return name, v, true
+	}
+	for key, v := range resource {
+		if strings.EqualFold(key, name) {
+			return key, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// attributeValues returns the values of an attribute path, the values of multi-valued attributes are flattened.
+func attributeValues(resource map[string]interface{}, path []string) []interface{} {
+	_, v, ok := attribute(resource, path[0])
+	if !ok {
+		return nil
+	}
+	values, isList := v.([]interface{})
+	if !isList {
+		values = []interface{}{v}
+	}
+	if len(path) == 1 {
+		return values
+	}
+
+	var result []interface{}
+	for _, value := range values {
+		if m, ok := value.(map[string]interface{}); ok {
+			result = append(result, attributeValues(m, path[1:])...)
+		}
+	}
+	return result
+}
+
+func isPresent(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case []interface{}:
+		return len(value) > 0
+	case map[string]interface{}:
+		return len(value) > 0
+	}
+	return true
+}
+
+func matchesAny(values []interface{}, op string, expected interface{}) bool {
+	if expected == nil {
+		// eq null matches attributes without value
+		for _, v := range values {
+			if isPresent(v) {
+				return false
+			}
+		}
+		return op == "eq"
+	}
+	for _, v := range values {
+		if compare(v, op, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(actual interface{}, op string, expected interface{}) bool {
+	switch e := expected.(type) {
+	case bool:
+		a, ok := actual.(bool)
+		return ok && op == "eq" && a == e
+	case float64:
+		switch a := actual.(type) {
+		case float64:
+			cmp := 0
+			if a < e {
+				cmp = -1
+			} else if a > e {
+				cmp = 1
+			}
+			return compareOrder(cmp, op)
+		case string:
+			// identifiers are strings, but identity providers may filter them with numbers
+			return compareStrings(a, strconv.FormatFloat(e, 'f', -1, 64), op)
+		}
+	case string:
+		switch a := actual.(type) {
+		case string:
+			return compareStrings(a, e, op)
+		case float64:
+			return compareStrings(strconv.FormatFloat(a, 'f', -1, 64), e, op)
+		case bool:
+			return op == "eq" && strings.EqualFold(strconv.FormatBool(a), e)
+		}
+	}
+	return false
+}
+
+func compareStrings(actual string, expected string, op string) bool {
+	actual, expected = strings.ToLower(actual), strings.ToLower(expected)
+	switch op {
+	case "co":
+		return strings.Contains(actual, expected)
+	case "sw":
+		return strings.HasPrefix(actual, expected)
+	case "ew":
+		return strings.HasSuffix(actual, expected)
+	}
+	return compareOrder(strings.Compare(actual, expected), op)
+}
+
+// compareOrder returns whether the result of a comparison, which is negative, 0 or positive, satisfies an operator.
+func compareOrder(cmp int, op string) bool {
+	switch op {
+	case "eq":
+		return cmp == 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	resource := map[string]interface{}{
+		"userName":    "Alice",
+		"displayName": "Alice Smith",
+		"active":      true,
+		"externalId":  "00u1",
+		"emails": []interface{}{
+			map[string]interface{}{"value": "alice@example.com", "type": "work", "primary": true},
+			map[string]interface{}{"value": "alice@home.example", "type": "home"},
+		},
+		"meta": map[string]interface{}{"resourceType": "User"},
+	}
+
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{filter: `userName eq "alice"`, matches: true},
+		{filter: `UserName Eq "ALICE"`, matches: true},
+		{filter: `userName eq "bob"`, matches: false},
+		{filter: `userName ne "bob"`, matches: true},
+		{filter: `displayName co "smith"`, matches: true},
+		{filter: `displayName sw "alice"`, matches: true},
+		{filter: `displayName ew "alice"`, matches: false},
+		{filter: `userName gt "aardvark"`, matches: true},
+		{filter: `userName lt "aardvark"`, matches: false},
+		{filter: `active eq true`, matches: true},
+		{filter: `active eq false`, matches: false},
+		{filter: `externalId pr`, matches: true},
+		{filter: `nickName pr`, matches: false},
+		{filter: `emails.value eq "alice@home.example"`, matches: true},
+		{filter: `emails[type eq "work" and value co "example.com"]`, matches: true},
+		{filter: `emails[type eq "other"]`, matches: false},
+		{filter: `meta.resourceType eq "User"`, matches: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, matches: true},
+		{filter: `userName eq "bob" or displayName sw "alice"`, matches: true},
+		{filter: `userName eq "alice" and active eq false`, matches: false},
+		{filter: `not (userName eq "bob")`, matches: true},
+		{filter: `(userName eq "bob" or userName eq "alice") and active eq true`, matches: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			expr, err := ParseFilter(tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.matches, expr.Matches(resource))
+		})
+	}
+}
+
+func TestParseFilterNumericIdentifiers(t *testing.T) {
+	expr, err := ParseFilter(`value eq 12`)
+	require.NoError(t, err)
+	require.True(t, expr.Matches(map[string]interface{}{"value": "12"}))
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	filters := []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName foo "alice"`,
+		`userName eq "alice`,
+		`userName eq alice`,
+		`(userName eq "alice"`,
+		`emails[type eq "work"`,
+		`userName eq "alice" bob`,
+	}
+
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			_, err := ParseFilter(filter)
+			require.Error(t, err)
+			scimErr, ok := asError(err)
+			require.True(t, ok)
+			require.Equal(t, ErrorTypeInvalidFilter, scimErr.ScimType)
+			require.Equal(t, "400", scimErr.Status)
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+// teamMemberPermission is the permission of the members of provisioned teams, team admins are managed in Grafana.
+const teamMemberPermission = "Member"
+
+func groupKey(id int64) string {
+	return fmt.Sprintf("groups/%d", id)
+}
+
+func (s *Service) listGroups(c *models.ReqContext) response.Response {
+	q, err := parseListQuery(c)
+	if err != nil {
+		return s.handleError(c, "Failed to list groups", err)
+	}
+
+	query := &models.SearchTeamsQuery{OrgId: c.OrgID, SignedInUser: c.SignedInUser}
+	if err := s.teamService.SearchTeams(c.Req.Context(), query); err != nil {
+		return s.handleError(c, "Failed to list groups", err)
+	}
+	externalIDs, err := s.getExternalIDs(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return s.handleError(c, "Failed to list groups", err)
+	}
+
+	withMembers := !excludesMembers(c)
+	resources := make([]interface{}, 0, len(query.Result.Teams))
+	for _, team := range query.Result.Teams {
+		group, err := s.toGroup(c, team, externalIDs[groupKey(team.Id)], withMembers)
+		if err != nil {
+			return s.handleError(c, "Failed to list groups", err)
+		}
+		resources = append(resources, group)
+	}
+
+	result, err := q.list(resources)
+	if err != nil {
+		return s.handleError(c, "Failed to list groups", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) getGroup(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrGroupNotFound)
+	}
+
+	result, err := s.getGroupResource(c, id, !excludesMembers(c))
+	if err != nil {
+		return s.handleError(c, "Failed to get group", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) createGroup(c *models.ReqContext) response.Response {
+	g := Group{}
+	if err := bind(c, &g); err != nil {
+		return s.handleError(c, "Failed to create group", err)
+	}
+	if err := validateGroup(&g); err != nil {
+		return s.handleError(c, "Failed to create group", err)
+	}
+	memberIDs, err := s.getMemberIDs(c, &g)
+	if err != nil {
+		return s.handleError(c, "Failed to create group", err)
+	}
+
+	team, err := s.teamService.CreateTeam(g.DisplayName, "", c.OrgID)
+	if err != nil {
+		if errors.Is(err, models.ErrTeamNameTaken) {
+			return errorResponse(ErrGroupExists)
+		}
+		return s.handleError(c, "Failed to create group", err)
+	}
+
+	if err := s.syncMembers(c, team.Id, memberIDs); err != nil {
+		return s.handleError(c, "Failed to update the members of the group", err)
+	}
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, groupKey(team.Id), g.ExternalID); err != nil {
+		return s.handleError(c, "Failed to set the external ID of the group", err)
+	}
+
+	result, err := s.getGroupResource(c, team.Id, true)
+	if err != nil {
+		return s.handleError(c, "Failed to get group", err)
+	}
+	return jsonResponse(http.StatusCreated, result)
+}
+
+func (s *Service) replaceGroup(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrGroupNotFound)
+	}
+
+	g := Group{}
+	if err := bind(c, &g); err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+
+	result, err := s.updateGroup(c, id, &g)
+	if err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) patchGroup(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrGroupNotFound)
+	}
+
+	patch := PatchRequest{}
+	if err := bind(c, &patch); err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+
+	current, err := s.getGroupResource(c, id, true)
+	if err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+	m, err := toMap(current)
+	if err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+	if err := ApplyPatch(m, patch.Operations); err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+	g := Group{}
+	if err := fromMap(m, &g); err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+
+	result, err := s.updateGroup(c, id, &g)
+	if err != nil {
+		return s.handleError(c, "Failed to update group", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) deleteGroup(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrGroupNotFound)
+	}
+
+	if _, err := s.getTeam(c, id); err != nil {
+		return s.handleError(c, "Failed to delete group", err)
+	}
+	if err := s.teamService.DeleteTeam(c.Req.Context(), &models.DeleteTeamCommand{OrgId: c.OrgID, Id: id}); err != nil {
+		if errors.Is(err, models.ErrTeamNotFound) {
+			return errorResponse(ErrGroupNotFound)
+		}
+		return s.handleError(c, "Failed to delete group", err)
+	}
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, groupKey(id), ""); err != nil {
+		return s.handleError(c, "Failed to delete the external ID of the group", err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+// updateGroup replaces the name and the members of a team, the email of the team is kept.
+func (s *Service) updateGroup(c *models.ReqContext, id int64, g *Group) (*Group, error) {
+	if err := validateGroup(g); err != nil {
+		return nil, err
+	}
+
+	team, err := s.getTeam(c, id)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs, err := s.getMemberIDs(c, g)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.DisplayName != team.Name {
+		if err := s.teamService.UpdateTeam(c.Req.Context(), &models.UpdateTeamCommand{
+			Id:    id,
+			Name:  g.DisplayName,
+			Email: team.Email,
+			OrgId: c.OrgID,
+		}); err != nil {
+			if errors.Is(err, models.ErrTeamNameTaken) {
+				return nil, ErrGroupExists
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.syncMembers(c, id, memberIDs); err != nil {
+		return nil, err
+	}
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, groupKey(id), g.ExternalID); err != nil {
+		return nil, err
+	}
+	return s.getGroupResource(c, id, true)
+}
+
+// syncMembers adds the missing members to a team and removes the members which aren't in the group. Existing members
+// keep their permission.
+func (s *Service) syncMembers(c *models.ReqContext, teamID int64, memberIDs map[int64]bool) error {
+	members, err := s.getTeamMembers(c, teamID)
+	if err != nil {
+		return err
+	}
+
+	teamIDString := strconv.FormatInt(teamID, 10)
+	existing := make(map[int64]bool, len(members))
+	for _, member := range members {
+		existing[member.UserId] = true
+		if memberIDs[member.UserId] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(c.Req.Context(), c.OrgID, accesscontrol.User{ID: member.UserId}, teamIDString, ""); err != nil {
+			return err
+		}
+	}
+
+	for userID := range memberIDs {
+		if existing[userID] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(c.Req.Context(), c.OrgID, accesscontrol.User{ID: userID}, teamIDString, teamMemberPermission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getMemberIDs returns the IDs of the members of a group, which must be users of the organization.
+func (s *Service) getMemberIDs(c *models.ReqContext, g *Group) (map[int64]bool, error) {
+	memberIDs := make(map[int64]bool, len(g.Members))
+	for _, member := range g.Members {
+		invalidMember := NewError(http.StatusBadRequest, ErrorTypeInvalidValue, fmt.Sprintf("member %q isn't a user of the organization", member.Value))
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return nil, invalidMember
+		}
+		if _, err := s.getOrgUser(c, id); err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return nil, invalidMember
+			}
+			return nil, err
+		}
+		memberIDs[id] = true
+	}
+	return memberIDs, nil
+}
+
+func (s *Service) getTeam(c *models.ReqContext, id int64) (*models.TeamDTO, error) {
+	query := &models.GetTeamByIdQuery{OrgId: c.OrgID, Id: id, SignedInUser: c.SignedInUser}
+	if err := s.teamService.GetTeamById(c.Req.Context(), query); err != nil {
+		if errors.Is(err, models.ErrTeamNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return query.Result, nil
+}
+
+func (s *Service) getTeamMembers(c *models.ReqContext, teamID int64) ([]*models.TeamMemberDTO, error) {
+	query := &models.GetTeamMembersQuery{OrgId: c.OrgID, TeamId: teamID, SignedInUser: c.SignedInUser}
+	if err := s.teamService.GetTeamMembers(c.Req.Context(), query); err != nil {
+		return nil, err
+	}
+	return query.Result, nil
+}
+
+func (s *Service) getGroupResource(c *models.ReqContext, id int64, withMembers bool) (*Group, error) {
+	team, err := s.getTeam(c, id)
+	if err != nil {
+		return nil, err
+	}
+	externalID, err := s.getExternalID(c.Req.Context(), c.OrgID, groupKey(id))
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(c, team, externalID, withMembers)
+}
+
+func (s *Service) toGroup(c *models.ReqContext, team *models.TeamDTO, externalID string, withMembers bool) (*Group, error) {
+	id := strconv.FormatInt(team.Id, 10)
+	g := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		ExternalID:  externalID,
+		DisplayName: team.Name,
+		Members:     []MultiValuedAttribute{},
+		Meta: &Meta{
+			ResourceType: ResourceTypeGroup,
+			Location:     s.location("Groups", id),
+		},
+	}
+	if !withMembers {
+		return g, nil
+	}
+
+	members, err := s.getTeamMembers(c, team.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		memberID := strconv.FormatInt(member.UserId, 10)
+		g.Members = append(g.Members, MultiValuedAttribute{
+			Value:   memberID,
+			Display: member.Login,
+			Ref:     s.location("Users", memberID),
+		})
+	}
+	return g, nil
+}
+
+func validateGroup(g *Group) error {
+	if strings.TrimSpace(g.DisplayName) == "" {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "displayName is required")
+	}
+	return nil
+}
+
+// excludesMembers returns whether the request excludes the members of groups, identity providers exclude them to
+// list large groups faster.
+func excludesMembers(c *models.ReqContext) bool {
+	for _, attribute := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Schemas of the SCIM 2.0 resources and messages, see RFC 7643 and RFC 7644.
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// Error types of SCIM errors, see section 3.12 of RFC 7644.
+const (
+	ErrorTypeInvalidFilter = "invalidFilter"
+	ErrorTypeInvalidPath   = "invalidPath"
+	ErrorTypeInvalidValue  = "invalidValue"
+	ErrorTypeInvalidSyntax = "invalidSyntax"
+	ErrorTypeNoTarget      = "noTarget"
+	ErrorTypeUniqueness    = "uniqueness"
+	ErrorTypeMutability    = "mutability"
+)
+
+var (
+	ErrUserNotFound  = NewError(http.StatusNotFound, "", "user not found")
+	ErrGroupNotFound = NewError(http.StatusNotFound, "", "group not found")
+	ErrUserExists    = NewError(http.StatusConflict, ErrorTypeUniqueness, "a user with the same userName or email already exists")
+	ErrGroupExists   = NewError(http.StatusConflict, ErrorTypeUniqueness, "a group with the same displayName already exists")
+	ErrAdminUser     = NewError(http.StatusForbidden, "", "Grafana server admins can't be provisioned")
+	ErrGlobalUser    = NewError(http.StatusForbidden, "", "the user belongs to other organizations, changing it requires the global users:write or users:disable permission")
+)
+
+// Error is a SCIM error, which is returned with the SCIM error schema.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewError(status int, scimType string, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// StatusCode returns the HTTP status of the error.
+func (e *Error) StatusCode() int {
+	status, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+func asError(err error) (*Error, bool) {
+	var scimErr *Error
+	ok := errors.As(err, &scimErr)
+	return scimErr, ok
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+type MultiValuedAttribute struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is a SCIM user, which is a Grafana user of the organization of the service account.
+type User struct {
+	Schemas     []string               `json:"schemas"`
+	ID          string                 `json:"id,omitempty"`
+	ExternalID  string                 `json:"externalId,omitempty"`
+	UserName    string                 `json:"userName"`
+	Name        *Name                  `json:"name,omitempty"`
+	DisplayName string                 `json:"displayName,omitempty"`
+	Emails      []MultiValuedAttribute `json:"emails,omitempty"`
+	// Active is nil when it isn't set in a request, users are active by default
+	Active *bool `json:"active,omitempty"`
+	Meta   *Meta `json:"meta,omitempty"`
+}
+
+// Group is a SCIM group, which is a Grafana team.
+type Group struct {
+	Schemas     []string               `json:"schemas"`
+	ID          string                 `json:"id,omitempty"`
+	ExternalID  string                 `json:"externalId,omitempty"`
+	DisplayName string                 `json:"displayName"`
+	Members     []MultiValuedAttribute `json:"members"`
+	Meta        *Meta                  `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	// Op is add, remove or replace, identity providers send them with any case
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
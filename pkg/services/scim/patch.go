@@ -0,0 +1,233 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ApplyPatch applies the operations of a PATCH request to the JSON representation of a resource, see section 3.5.2
+// of RFC 7644.
+func ApplyPatch(resource map[string]interface{}, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "remove" && op != "replace" {
+			return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, fmt.Sprintf("unknown operation %q", operation.Op))
+		}
+
+		if operation.Path != "" {
+			if err := applyOperation(resource, op, operation.Path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// without path, the value contains the attributes to add or replace
+		if op == "remove" {
+			return NewError(http.StatusBadRequest, ErrorTypeNoTarget, "remove operations require a path")
+		}
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "operations without path require an object value")
+		}
+		for path, value := range values {
+			if err := applyOperation(resource, op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type patchPath struct {
+	attribute    string
+	filter       Expression
+	subAttribute string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	start := strings.Index(path, "[")
+	if start < 0 {
+		attributePath := parseAttributePath(path)
+		p := &patchPath{attribute: attributePath[0]}
+		if len(attributePath) > 1 {
+			p.subAttribute = attributePath[1]
+		}
+		return p, nil
+	}
+
+	end := strings.LastIndex(path, "]")
+	if end < start {
+		return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidPath, fmt.Sprintf("invalid path %q", path))
+	}
+	filter, err := ParseFilter(path[start+1 : end])
+	if err != nil {
+		return nil, err
+	}
+	p := &patchPath{attribute: parseAttributePath(path[:start])[0], filter: filter}
+
+	rest := path[end+1:]
+	if rest != "" {
+		if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+			return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidPath, fmt.Sprintf("invalid path %q", path))
+		}
+		p.subAttribute = rest[1:]
+	}
+	return p, nil
+}
+
+func applyOperation(resource map[string]interface{}, op string, path string, value interface{}) error {
+	p, err := parsePatchPath(path)
+	if err != nil {
+		return err
+	}
+
+	key, existing, found := attribute(resource, p.attribute)
+	if !found {
+		key = p.attribute
+	}
+
+	if p.filter != nil {
+		values, _ := existing.([]interface{})
+		updated, err := applyFilteredOperation(values, op, p, value)
+		if err != nil {
+			return err
+		}
+		resource[key] = updated
+		return nil
+	}
+
+	if p.subAttribute != "" {
+		switch parent := existing.(type) {
+		case map[string]interface{}:
+			setOrRemove(parent, op, p.subAttribute, value)
+		case []interface{}:
+			for _, v := range parent {
+				if m, ok := v.(map[string]interface{}); ok {
+					setOrRemove(m, op, p.subAttribute, value)
+				}
+			}
+		default:
+			if op != "remove" {
+				resource[key] = map[string]interface{}{p.subAttribute: value}
+			}
+		}
+		return nil
+	}
+
+	values, isList := existing.([]interface{})
+	switch op {
+	case "add":
+		if !isList {
+			resource[key] = value
+			return nil
+		}
+		for _, v := range asList(value) {
+			if !containsValue(values, v) {
+				values = append(values, v)
+			}
+		}
+		resource[key] = values
+	case "replace":
+		resource[key] = value
+	case "remove":
+		if !isList || value == nil {
+			delete(resource, key)
+			return nil
+		}
+		// some identity providers remove members with their values instead of a filter
+		remaining := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			if !containsValue(asList(value), v) {
+				remaining = append(remaining, v)
+			}
+		}
+		resource[key] = remaining
+	}
+	return nil
+}
+
+// applyFilteredOperation applies an operation to the values of a multi-valued attribute which match the filter of
+// the path, for example members[value eq "2"] or emails[type eq "work"].value.
+func applyFilteredOperation(values []interface{}, op string, p *patchPath, value interface{}) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(values))
+	matched := false
+	for _, v := range values {
+		m, ok := v.(map[string]interface{})
+		if !ok || !p.filter.Matches(m) {
+			result = append(result, v)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == "remove" && p.subAttribute == "":
+			continue
+		case p.subAttribute != "":
+			setOrRemove(m, op, p.subAttribute, value)
+		default:
+			if replacement, ok := value.(map[string]interface{}); ok {
+				for k, v := range replacement {
+					setOrRemove(m, op, k, v)
+				}
+			}
+		}
+		result = append(result, m)
+	}
+
+	if matched || op == "remove" {
+		return result, nil
+	}
+
+	// when no value matches, the value is added with the attributes of the filter, for example emails[type eq
+	// "work"].value adds a work email
+	if p.subAttribute == "" {
+		return nil, NewError(http.StatusBadRequest, ErrorTypeNoTarget, "no value matches the filter of the path")
+	}
+	v := map[string]interface{}{p.subAttribute: value}
+	if expr, ok := p.filter.(*attributeExpression); ok && expr.op == "eq" && len(expr.path) == 1 {
+		v[expr.path[0]] = expr.value
+	}
+	return append(result, v), nil
+}
+
+func setOrRemove(resource map[string]interface{}, op string, name string, value interface{}) {
+	key, _, found := attribute(resource, name)
+	if !found {
+		key = name
+	}
+	if op == "remove" {
+		delete(resource, key)
+		return
+	}
+	resource[key] = value
+}
+
+func asList(value interface{}) []interface{} {
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	return []interface{}{value}
+}
+
+// containsValue returns whether a multi-valued attribute contains a value, values are compared by their value
+// sub-attribute when they have one.
+func containsValue(values []interface{}, value interface{}) bool {
+	m, isMap := value.(map[string]interface{})
+	for _, v := range values {
+		if isMap {
+			if other, ok := v.(map[string]interface{}); ok {
+				_, a, aFound := attribute(m, "value")
+				_, b, bFound := attribute(other, "value")
+				if aFound && bFound && fmt.Sprint(a) == fmt.Sprint(b) {
+					return true
+				}
+			}
+		}
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testUserResource() map[string]interface{} {
+	return map[string]interface{}{
+		"userName":    "alice",
+		"displayName": "Alice",
+		"active":      true,
+		"emails": []interface{}{
+			map[string]interface{}{"value": "alice@example.com", "type": "work", "primary": true},
+		},
+	}
+}
+
+func testGroupResource() map[string]interface{} {
+	return map[string]interface{}{
+		"displayName": "Developers",
+		"members": []interface{}{
+			map[string]interface{}{"value": "1"},
+			map[string]interface{}{"value": "2"},
+		},
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	t.Run("replace an attribute", func(t *testing.T) {
+		resource := testUserResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "Replace", Path: "active", Value: false}})
+		require.NoError(t, err)
+		require.Equal(t, false, resource["active"])
+	})
+
+	t.Run("replace attributes without path", func(t *testing.T) {
+		resource := testUserResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "replace", Value: map[string]interface{}{
+			"displayName": "Alice Smith",
+			"Active":      false,
+		}}})
+		require.NoError(t, err)
+		require.Equal(t, "Alice Smith", resource["displayName"])
+		require.Equal(t, false, resource["active"])
+	})
+
+	t.Run("replace a value of a multi-valued attribute with a filter", func(t *testing.T) {
+		resource := testUserResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "replace", Path: `emails[type eq "work"].value`, Value: "alice@example.org"}})
+		require.NoError(t, err)
+		emails := resource["emails"].([]interface{})
+		require.Len(t, emails, 1)
+		require.Equal(t, "alice@example.org", emails[0].(map[string]interface{})["value"])
+	})
+
+	t.Run("add a value of a multi-valued attribute with a filter which doesn't match", func(t *testing.T) {
+		resource := testUserResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "add", Path: `emails[type eq "home"].value`, Value: "alice@home.example"}})
+		require.NoError(t, err)
+		emails := resource["emails"].([]interface{})
+		require.Len(t, emails, 2)
+		require.Equal(t, map[string]interface{}{"type": "home", "value": "alice@home.example"}, emails[1])
+	})
+
+	t.Run("replace a sub-attribute", func(t *testing.T) {
+		resource := testUserResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "replace", Path: "name.givenName", Value: "Alice"}})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"givenName": "Alice"}, resource["name"])
+	})
+
+	t.Run("add members without duplicates", func(t *testing.T) {
+		resource := testGroupResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "add", Path: "members", Value: []interface{}{
+			map[string]interface{}{"value": "2"},
+			map[string]interface{}{"value": "3"},
+		}}})
+		require.NoError(t, err)
+		require.Len(t, resource["members"], 3)
+	})
+
+	t.Run("remove a member with a filter", func(t *testing.T) {
+		resource := testGroupResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "remove", Path: `members[value eq "1"]`}})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{map[string]interface{}{"value": "2"}}, resource["members"])
+	})
+
+	t.Run("remove a member with a value", func(t *testing.T) {
+		resource := testGroupResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "remove", Path: "members", Value: []interface{}{
+			map[string]interface{}{"value": "2"},
+		}}})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{map[string]interface{}{"value": "1"}}, resource["members"])
+	})
+
+	t.Run("remove all members", func(t *testing.T) {
+		resource := testGroupResource()
+		err := ApplyPatch(resource, []PatchOperation{{Op: "remove", Path: "members"}})
+		require.NoError(t, err)
+		require.NotContains(t, resource, "members")
+	})
+
+	t.Run("remove without path", func(t *testing.T) {
+		err := ApplyPatch(testGroupResource(), []PatchOperation{{Op: "remove"}})
+		scimErr, ok := asError(err)
+		require.True(t, ok)
+		require.Equal(t, ErrorTypeNoTarget, scimErr.ScimType)
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		err := ApplyPatch(testGroupResource(), []PatchOperation{{Op: "move", Path: "members"}})
+		scimErr, ok := asError(err)
+		require.True(t, ok)
+		require.Equal(t, ErrorTypeInvalidSyntax, scimErr.ScimType)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		err := ApplyPatch(testGroupResource(), []PatchOperation{{Op: "remove", Path: `members[value eq "1"`}})
+		scimErr, ok := asError(err)
+		require.True(t, ok)
+		require.Equal(t, ErrorTypeInvalidPath, scimErr.ScimType)
+	})
+}
@@ -0,0 +1,362 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	contentType = "application/scim+json"
+
+	// kvNamespace stores the external IDs of the users and groups, which identity providers use to link their
+	// resources to the resources of Grafana
+	kvNamespace = "scim"
+
+	defaultCount = 100
+	maxCount     = 1000
+)
+
+// Service is a SCIM 2.0 server, which lets identity providers provision the users and the teams of the organization
+// of a service account. See RFC 7643 and RFC 7644.
+type Service struct {
+	cfg                    *setting.Cfg
+	routeRegister          routing.RouteRegister
+	accessControl          accesscontrol.AccessControl
+	userService            user.Service
+	orgService             org.Service
+	teamService            team.Service
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	authTokenService       models.UserTokenService
+	kvStore                kvstore.KVStore
+	log                    log.Logger
+}
+
+func ProvideService(
+	cfg *setting.Cfg,
+	routeRegister routing.RouteRegister,
+	accessControl accesscontrol.AccessControl,
+	userService user.Service,
+	orgService org.Service,
+	teamService team.Service,
+	teamPermissionsService accesscontrol.TeamPermissionsService,
+	authTokenService models.UserTokenService,
+	kvStore kvstore.KVStore,
+) *Service {
+	s := &Service{
+		cfg:                    cfg,
+		routeRegister:          routeRegister,
+		accessControl:          accessControl,
+		userService:            userService,
+		orgService:             orgService,
+		teamService:            teamService,
+		teamPermissionsService: teamPermissionsService,
+		authTokenService:       authTokenService,
+		kvStore:                kvStore,
+		log:                    log.New("scim"),
+	}
+
+	// Register routes only when SCIM is enabled
+	if cfg.SCIMEnabled {
+		s.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+func (s *Service) registerAPIEndpoints() {
+	auth := accesscontrol.Middleware(s.accessControl)
+	usersRead := auth(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersRead))
+	usersWrite := auth(middleware.ReqOrgAdmin, accesscontrol.EvalAll(
+		accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersAdd),
+		accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersWrite),
+		accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersRemove),
+	))
+	groupsRead := auth(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(accesscontrol.ActionTeamsRead))
+	groupsWrite := auth(middleware.ReqOrgAdmin, accesscontrol.EvalAll(
+		accesscontrol.EvalPermission(accesscontrol.ActionTeamsCreate),
+		accesscontrol.EvalPermission(accesscontrol.ActionTeamsWrite),
+		accesscontrol.EvalPermission(accesscontrol.ActionTeamsDelete),
+		accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsWrite),
+	))
+
+	s.routeRegister.Group("/api/scim/v2", func(scimRoute routing.RouteRegister) {
+		scimRoute.Get("/ServiceProviderConfig", routing.Wrap(s.getServiceProviderConfig))
+		scimRoute.Get("/ResourceTypes", routing.Wrap(s.getResourceTypes))
+
+		scimRoute.Get("/Users", usersRead, routing.Wrap(s.listUsers))
+		scimRoute.Post("/Users", usersWrite, routing.Wrap(s.createUser))
+		scimRoute.Get("/Users/:id", usersRead, routing.Wrap(s.getUser))
+		scimRoute.Put("/Users/:id", usersWrite, routing.Wrap(s.replaceUser))
+		scimRoute.Patch("/Users/:id", usersWrite, routing.Wrap(s.patchUser))
+		scimRoute.Delete("/Users/:id", usersWrite, routing.Wrap(s.deleteUser))
+
+		scimRoute.Get("/Groups", groupsRead, routing.Wrap(s.listGroups))
+		scimRoute.Post("/Groups", groupsWrite, routing.Wrap(s.createGroup))
+		scimRoute.Get("/Groups/:id", groupsRead, routing.Wrap(s.getGroup))
+		scimRoute.Put("/Groups/:id", groupsWrite, routing.Wrap(s.replaceGroup))
+		scimRoute.Patch("/Groups/:id", groupsWrite, routing.Wrap(s.patchGroup))
+		scimRoute.Delete("/Groups/:id", groupsWrite, routing.Wrap(s.deleteGroup))
+	}, middleware.ReqSignedIn, s.reqServiceAccount)
+}
+
+// reqServiceAccount rejects the requests which aren't authenticated with a service account token, identity
+// providers provision the organization of their service account.
+func (s *Service) reqServiceAccount(c *models.ReqContext) {
+	if c.SignedInUser.UserID > 0 {
+		usr, err := s.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: c.SignedInUser.UserID})
+		if err != nil {
+			s.handleError(c, "Failed to get service account", err).WriteTo(c)
+			return
+		}
+		if usr.IsServiceAccount {
+			return
+		}
+	}
+	errorResponse(NewError(http.StatusForbidden, "", "SCIM requests must be authenticated with a service account token")).WriteTo(c)
+}
+
+func (s *Service) getServiceProviderConfig(c *models.ReqContext) response.Response {
+	return jsonResponse(http.StatusOK, map[string]interface{}{
+		"schemas":          []string{SchemaServiceProviderConfig},
+		"documentationUri": "https://grafana.com/docs/grafana/latest/developers/http_api/scim/",
+		"patch":            map[string]bool{"supported": true},
+		"bulk":             map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]interface{}{"supported": true, "maxResults": maxCount},
+		"changePassword":   map[string]bool{"supported": false},
+		"sort":             map[string]bool{"supported": false},
+		"etag":             map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Service account token",
+			"description": "Authentication with the token of a service account of the organization",
+			"primary":     true,
+		}},
+	})
+}
+
+func (s *Service) getResourceTypes(c *models.ReqContext) response.Response {
+	resourceType := func(name string, endpoint string, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":  []string{SchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta":     Meta{ResourceType: "ResourceType", Location: s.location("ResourceTypes", name)},
+		}
+	}
+	resources := []interface{}{
+		resourceType(ResourceTypeUser, "/Users", SchemaUser),
+		resourceType(ResourceTypeGroup, "/Groups", SchemaGroup),
+	}
+	return jsonResponse(http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// location returns the URL of a resource.
+func (s *Service) location(endpoint string, id string) string {
+	return fmt.Sprintf("%sapi/scim/v2/%s/%s", s.cfg.AppURL, endpoint, id)
+}
+
+func (s *Service) getExternalID(ctx context.Context, orgID int64, key string) (string, error) {
+	externalID, _, err := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace).Get(ctx, key)
+	return externalID, err
+}
+
+func (s *Service) setExternalID(ctx context.Context, orgID int64, key string, externalID string) error {
+	kv := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace)
+	if externalID == "" {
+		return kv.Del(ctx, key)
+	}
+	return kv.Set(ctx, key, externalID)
+}
+
+// getExternalIDs returns the external IDs of the users or groups of an organization, by key.
+func (s *Service) getExternalIDs(ctx context.Context, orgID int64) (map[string]string, error) {
+	all, err := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace).GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return all[orgID], nil
+}
+
+// listQuery is the filter and the pagination of a list request.
+type listQuery struct {
+	filter     Expression
+	startIndex int
+	count      int
+}
+
+func parseListQuery(c *models.ReqContext) (*listQuery, error) {
+	q := &listQuery{startIndex: 1, count: defaultCount}
+
+	if filter := c.Query("filter"); filter != "" {
+		expr, err := ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		q.filter = expr
+	}
+
+	if startIndex := c.Query("startIndex"); startIndex != "" {
+		i, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "startIndex must be a number")
+		}
+		// values less than 1 are interpreted as 1
+		if i > 1 {
+			q.startIndex = i
+		}
+	}
+
+	if count := c.Query("count"); count != "" {
+		i, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "count must be a number")
+		}
+		if i < 0 {
+			i = 0
+		}
+		if i > maxCount {
+			i = maxCount
+		}
+		q.count = i
+	}
+	return q, nil
+}
+
+// list returns a page of the resources which match the filter.
+func (q *listQuery) list(resources []interface{}) (*ListResponse, error) {
+	matching := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		if q.filter != nil {
+			m, err := toMap(resource)
+			if err != nil {
+				return nil, err
+			}
+			if !q.filter.Matches(m) {
+				continue
+			}
+		}
+		matching = append(matching, resource)
+	}
+
+	start := q.startIndex - 1
+	if start > len(matching) {
+		start = len(matching)
+	}
+	end := start + q.count
+	if end > len(matching) {
+		end = len(matching)
+	}
+
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(matching),
+		StartIndex:   q.startIndex,
+		ItemsPerPage: end - start,
+		Resources:    matching[start:end],
+	}, nil
+}
+
+// toMap returns the JSON representation of a resource, which filters and PATCH operations are applied to.
+func toMap(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func fromMap(m map[string]interface{}, resource interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return decode(data, resource)
+}
+
+// bind decodes the body of a request. Identity providers send SCIM requests with the application/scim+json content
+// type, which web.Bind doesn't accept.
+func bind(c *models.ReqContext, v interface{}) error {
+	data, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		return err
+	}
+	return decode(data, v)
+}
+
+func decode(data []byte, v interface{}) error {
+	// some identity providers send booleans as strings, for example "active": "False"
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "invalid JSON: "+err.Error())
+	}
+	if key, value, ok := attribute(m, "active"); ok {
+		if s, isString := value.(string); isString {
+			active, err := strconv.ParseBool(strings.ToLower(s))
+			if err != nil {
+				return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "active must be a boolean")
+			}
+			m[key] = active
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "invalid resource: "+err.Error())
+	}
+	return nil
+}
+
+func jsonResponse(status int, body interface{}) response.Response {
+	return response.JSON(status, body).SetHeader("Content-Type", contentType)
+}
+
+func errorResponse(err *Error) *response.NormalResponse {
+	return response.JSON(err.StatusCode(), err).SetHeader("Content-Type", contentType)
+}
+
+// handleError returns the SCIM error of an error, unexpected errors are logged and returned as internal errors.
+func (s *Service) handleError(c *models.ReqContext, message string, err error) response.Response {
+	if scimErr, ok := asError(err); ok {
+		return errorResponse(scimErr)
+	}
+	c.Logger.Error(message, "error", err)
+	return errorResponse(NewError(http.StatusInternalServerError, "", message))
+}
+
+func parseID(c *models.ReqContext) (int64, bool) {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	return id, err == nil
+}
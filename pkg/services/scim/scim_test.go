@@ -0,0 +1,304 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+type fakeUserService struct {
+	*usertest.FakeUserService
+	disabled map[int64]bool
+	updated  []int64
+	deleted  []int64
+	// newLogin makes the lookups by login and email return that the user doesn't exist
+	newLogin    bool
+	createdUser *user.User
+}
+
+func (f *fakeUserService) Disable(ctx context.Context, cmd *user.DisableUserCommand) error {
+	f.disabled[cmd.UserID] = cmd.IsDisabled
+	return nil
+}
+
+func (f *fakeUserService) Update(ctx context.Context, cmd *user.UpdateUserCommand) error {
+	f.updated = append(f.updated, cmd.UserID)
+	return nil
+}
+
+func (f *fakeUserService) Delete(ctx context.Context, cmd *user.DeleteUserCommand) error {
+	f.deleted = append(f.deleted, cmd.UserID)
+	return nil
+}
+
+func (f *fakeUserService) Create(ctx context.Context, cmd *user.CreateUserCommand) (*user.User, error) {
+	return f.createdUser, nil
+}
+
+func (f *fakeUserService) GetByLogin(ctx context.Context, query *user.GetUserByLoginQuery) (*user.User, error) {
+	if f.newLogin {
+		return nil, user.ErrUserNotFound
+	}
+	return f.FakeUserService.GetByLogin(ctx, query)
+}
+
+func (f *fakeUserService) GetByEmail(ctx context.Context, query *user.GetUserByEmailQuery) (*user.User, error) {
+	if f.newLogin {
+		return nil, user.ErrUserNotFound
+	}
+	return f.FakeUserService.GetByEmail(ctx, query)
+}
+
+type scimTestEnv struct {
+	server         *webtest.Server
+	userService    *fakeUserService
+	orgService     *orgtest.FakeOrgService
+	revokedTokens  []int64
+	isGrafanaAdmin bool
+}
+
+func setupSCIMServer(t *testing.T, serviceAccount bool) *scimTestEnv {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.SCIMEnabled = true
+	cfg.AppURL = "http://localhost:3000/"
+
+	env := &scimTestEnv{}
+	env.userService = &fakeUserService{
+		FakeUserService: usertest.NewUserServiceFake(),
+		disabled:        map[int64]bool{},
+	}
+	env.userService.ExpectedUser = &user.User{ID: 1, IsServiceAccount: serviceAccount}
+
+	orgService := orgtest.NewOrgServiceFake()
+	env.orgService = orgService
+	orgService.ExpectedOrgUsers = []*org.OrgUserDTO{
+		{OrgID: 1, UserID: 2, Login: "alice", Email: "alice@example.com", Name: "Alice"},
+		{OrgID: 1, UserID: 3, Login: "bob", Email: "bob@example.com", Name: "Bob"},
+	}
+
+	authTokenService := auth.NewFakeUserAuthTokenService()
+	authTokenService.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+		env.revokedTokens = append(env.revokedTokens, userID)
+		return nil
+	}
+
+	routeRegister := routing.NewRouteRegister()
+	ProvideService(cfg, routeRegister, acmock.New().WithDisabled(), env.userService, orgService, &teamtest.FakeService{},
+		acmock.NewMockedPermissionsService(), authTokenService, kvstore.ProvideService(sqlstore.InitTestDB(t)))
+	env.server = webtest.NewServer(t, routeRegister)
+	return env
+}
+
+func (env *scimTestEnv) send(t *testing.T, method string, target string, body string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+
+	req := env.server.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	webtest.RequestWithSignedInUser(req, &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleAdmin, IsGrafanaAdmin: env.isGrafanaAdmin})
+	resp, err := env.server.Send(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, resp.Body.Close()) })
+
+	result := map[string]interface{}{}
+	if resp.StatusCode != http.StatusNoContent {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	}
+	return resp, result
+}
+
+func TestSCIMAPI(t *testing.T) {
+	t.Run("requests which aren't authenticated with a service account are rejected", func(t *testing.T) {
+		env := setupSCIMServer(t, false)
+		resp, result := env.send(t, http.MethodGet, "/api/scim/v2/Users", "")
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Equal(t, []interface{}{SchemaError}, result["schemas"])
+	})
+
+	t.Run("list users with a filter", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		resp, result := env.send(t, http.MethodGet, `/api/scim/v2/Users?filter=userName+eq+%22Bob%22`, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, contentType, resp.Header.Get("Content-Type"))
+		require.EqualValues(t, 1, result["totalResults"])
+
+		resources := result["Resources"].([]interface{})
+		require.Len(t, resources, 1)
+		bob := resources[0].(map[string]interface{})
+		require.Equal(t, "3", bob["id"])
+		require.Equal(t, "bob", bob["userName"])
+		require.Equal(t, true, bob["active"])
+		require.Equal(t, "http://localhost:3000/api/scim/v2/Users/3", bob["meta"].(map[string]interface{})["location"])
+	})
+
+	t.Run("list users with an invalid filter", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		resp, result := env.send(t, http.MethodGet, `/api/scim/v2/Users?filter=userName+eq`, "")
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, ErrorTypeInvalidFilter, result["scimType"])
+	})
+
+	t.Run("get a user which isn't in the organization", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		resp, _ := env.send(t, http.MethodGet, "/api/scim/v2/Users/4", "")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("deactivating a user disables it and revokes its sessions", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		// the fake returns the same user for the service account and for bob
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", Email: "bob@example.com", Name: "Bob", IsServiceAccount: true}
+		resp, _ := env.send(t, http.MethodPatch, "/api/scim/v2/Users/3", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "Replace", "path": "active", "value": "False"}]
+		}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, map[int64]bool{3: true}, env.userService.disabled)
+		require.Equal(t, []int64{3}, env.revokedTokens)
+	})
+
+	t.Run("users of other organizations can't be changed without the global permission", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", Email: "bob@example.com", Name: "Bob", IsServiceAccount: true}
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1}, {OrgID: 2}}
+
+		resp, _ := env.send(t, http.MethodPatch, "/api/scim/v2/Users/3", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "Replace", "path": "active", "value": false}]
+		}`)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = env.send(t, http.MethodPatch, "/api/scim/v2/Users/3", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "Replace", "path": "displayName", "value": "Robert"}]
+		}`)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Empty(t, env.userService.disabled)
+		require.Empty(t, env.userService.updated)
+		require.Empty(t, env.revokedTokens)
+
+		env.isGrafanaAdmin = true
+		resp, _ = env.send(t, http.MethodPatch, "/api/scim/v2/Users/3", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "Replace", "path": "active", "value": false}]
+		}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, map[int64]bool{3: true}, env.userService.disabled)
+	})
+
+	t.Run("users provisioned by the organization can be changed", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.newLogin = true
+		env.userService.createdUser = &user.User{ID: 3, Login: "bob"}
+		resp, _ := env.send(t, http.MethodPost, "/api/scim/v2/Users", `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "bob"
+		}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		// the user was added to another organization afterwards
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", Email: "bob@example.com", Name: "Bob", IsServiceAccount: true}
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1}, {OrgID: 2}}
+		resp, _ = env.send(t, http.MethodPatch, "/api/scim/v2/Users/3", `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "Replace", "path": "active", "value": false}]
+		}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, map[int64]bool{3: true}, env.userService.disabled)
+	})
+
+	t.Run("deleting a user of other organizations only removes it from the organization", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", IsServiceAccount: true}
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1}, {OrgID: 2}}
+		resp, _ := env.send(t, http.MethodDelete, "/api/scim/v2/Users/3", "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Empty(t, env.userService.disabled)
+		require.Empty(t, env.revokedTokens)
+	})
+
+	t.Run("deleting a user of the organization only disables it", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", IsServiceAccount: true}
+		env.orgService.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1}}
+		resp, _ := env.send(t, http.MethodDelete, "/api/scim/v2/Users/3", "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Equal(t, map[int64]bool{3: true}, env.userService.disabled)
+		require.Equal(t, []int64{3}, env.revokedTokens)
+	})
+
+	t.Run("the created user is deleted when it can't be added to the organization", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.newLogin = true
+		env.userService.createdUser = &user.User{ID: 5, Login: "carol"}
+		env.orgService.ExpectedError = errors.New("failed")
+		resp, _ := env.send(t, http.MethodPost, "/api/scim/v2/Users", `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "carol"
+		}`)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, []int64{5}, env.userService.deleted)
+	})
+
+	t.Run("Grafana server admins can't be modified", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		env.userService.ExpectedUser = &user.User{ID: 3, Login: "bob", IsAdmin: true, IsServiceAccount: true}
+		resp, _ := env.send(t, http.MethodDelete, "/api/scim/v2/Users/3", "")
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Empty(t, env.userService.disabled)
+	})
+
+	t.Run("create a user which already exists", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		resp, result := env.send(t, http.MethodPost, "/api/scim/v2/Users", `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "bob"
+		}`)
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		require.Equal(t, ErrorTypeUniqueness, result["scimType"])
+	})
+
+	t.Run("create a group with a member which isn't in the organization", func(t *testing.T) {
+		env := setupSCIMServer(t, true)
+		resp, result := env.send(t, http.MethodPost, "/api/scim/v2/Groups", `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"displayName": "Developers",
+			"members": [{"value": "4"}]
+		}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, ErrorTypeInvalidValue, result["scimType"])
+	})
+}
+
+func TestSCIMAPIDisabled(t *testing.T) {
+	routeRegister := routing.NewRouteRegister()
+	ProvideService(setting.NewCfg(), routeRegister, acmock.New().WithDisabled(), usertest.NewUserServiceFake(),
+		orgtest.NewOrgServiceFake(), &teamtest.FakeService{}, acmock.NewMockedPermissionsService(),
+		auth.NewFakeUserAuthTokenService(), nil)
+	server := webtest.NewServer(t, routeRegister)
+
+	req := server.NewGetRequest("/api/scim/v2/Users")
+	webtest.RequestWithSignedInUser(req, &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleAdmin})
+	resp, err := server.Send(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
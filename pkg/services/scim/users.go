@@ -0,0 +1,445 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func userKey(id int64) string {
+	return fmt.Sprintf("users/%d", id)
+}
+
+// provisionedKey marks the users which were created by the identity provider of an organization
+func provisionedKey(id int64) string {
+	return fmt.Sprintf("provisioned/users/%d", id)
+}
+
+func (s *Service) listUsers(c *models.ReqContext) response.Response {
+	q, err := parseListQuery(c)
+	if err != nil {
+		return s.handleError(c, "Failed to list users", err)
+	}
+
+	orgUsers, err := s.orgService.GetOrgUsers(c.Req.Context(), &org.GetOrgUsersQuery{OrgID: c.OrgID, User: c.SignedInUser})
+	if err != nil {
+		return s.handleError(c, "Failed to list users", err)
+	}
+	externalIDs, err := s.getExternalIDs(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return s.handleError(c, "Failed to list users", err)
+	}
+
+	resources := make([]interface{}, 0, len(orgUsers))
+	for _, orgUser := range orgUsers {
+		resources = append(resources, s.toUser(orgUser, externalIDs[userKey(orgUser.UserID)]))
+	}
+
+	result, err := q.list(resources)
+	if err != nil {
+		return s.handleError(c, "Failed to list users", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) getUser(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrUserNotFound)
+	}
+
+	result, err := s.getUserResource(c, id)
+	if err != nil {
+		return s.handleError(c, "Failed to get user", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) createUser(c *models.ReqContext) response.Response {
+	u := User{}
+	if err := bind(c, &u); err != nil {
+		return s.handleError(c, "Failed to create user", err)
+	}
+	if err := validateUser(&u); err != nil {
+		return s.handleError(c, "Failed to create user", err)
+	}
+
+	email := primaryEmail(&u)
+	if err := s.checkUniqueUser(c.Req.Context(), u.UserName, email, 0); err != nil {
+		return s.handleError(c, "Failed to create user", err)
+	}
+
+	// users authenticate with the identity provider, they get a random password which they can't know
+	password, err := util.GetRandomString(32)
+	if err != nil {
+		return s.handleError(c, "Failed to create user", err)
+	}
+
+	created, err := s.userService.Create(c.Req.Context(), &user.CreateUserCommand{
+		Login:        u.UserName,
+		Email:        email,
+		Name:         displayName(&u),
+		Password:     password,
+		IsDisabled:   u.Active != nil && !*u.Active,
+		SkipOrgSetup: true,
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrUserAlreadyExists) {
+			return errorResponse(ErrUserExists)
+		}
+		return s.handleError(c, "Failed to create user", err)
+	}
+
+	if err := s.orgService.AddOrgUser(c.Req.Context(), &org.AddOrgUserCommand{
+		OrgID:  c.OrgID,
+		UserID: created.ID,
+		Role:   org.RoleType(s.cfg.AutoAssignOrgRole),
+	}); err != nil {
+		// the user doesn't belong to any organization, the identity provider creates it again when it retries
+		if err := s.userService.Delete(c.Req.Context(), &user.DeleteUserCommand{UserID: created.ID}); err != nil {
+			c.Logger.Error("Failed to delete user after failing to add it to organization", "userId", created.ID, "error", err)
+		}
+		return s.handleError(c, "Failed to add user to organization", err)
+	}
+	if err := kvstore.WithNamespace(s.kvStore, c.OrgID, kvNamespace).Set(c.Req.Context(), provisionedKey(created.ID), "true"); err != nil {
+		return s.handleError(c, "Failed to mark the user as provisioned", err)
+	}
+	if err := s.userService.SetUsingOrg(c.Req.Context(), &user.SetUsingOrgCommand{UserID: created.ID, OrgID: c.OrgID}); err != nil {
+		return s.handleError(c, "Failed to set the organization of the user", err)
+	}
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, userKey(created.ID), u.ExternalID); err != nil {
+		return s.handleError(c, "Failed to set the external ID of the user", err)
+	}
+
+	result, err := s.getUserResource(c, created.ID)
+	if err != nil {
+		return s.handleError(c, "Failed to get user", err)
+	}
+	return jsonResponse(http.StatusCreated, result)
+}
+
+func (s *Service) replaceUser(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrUserNotFound)
+	}
+
+	u := User{}
+	if err := bind(c, &u); err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+
+	result, err := s.updateUser(c, id, &u)
+	if err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Service) patchUser(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrUserNotFound)
+	}
+
+	patch := PatchRequest{}
+	if err := bind(c, &patch); err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+
+	current, err := s.getUserResource(c, id)
+	if err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+	m, err := toMap(current)
+	if err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+	if err := ApplyPatch(m, patch.Operations); err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+	u := User{}
+	if err := fromMap(m, &u); err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+
+	result, err := s.updateUser(c, id, &u)
+	if err != nil {
+		return s.handleError(c, "Failed to update user", err)
+	}
+	return jsonResponse(http.StatusOK, result)
+}
+
+// deleteUser removes the user from the organization. Users who don't belong to another organization are disabled as
+// well, since they could still sign in otherwise, but they aren't deleted so that their resources are kept. Users of
+// other organizations are left unchanged for these organizations.
+func (s *Service) deleteUser(c *models.ReqContext) response.Response {
+	id, ok := parseID(c)
+	if !ok {
+		return errorResponse(ErrUserNotFound)
+	}
+
+	usr, err := s.getProvisionableUser(c, id)
+	if err != nil {
+		return s.handleError(c, "Failed to delete user", err)
+	}
+
+	onlyOrg, err := s.belongsToOrgOnly(c, id)
+	if err != nil {
+		return s.handleError(c, "Failed to delete user", err)
+	}
+	if onlyOrg {
+		if err := s.setUserActive(c.Req.Context(), usr, false); err != nil {
+			return s.handleError(c, "Failed to disable user", err)
+		}
+	}
+	if err := s.orgService.RemoveOrgUser(c.Req.Context(), &org.RemoveOrgUserCommand{UserID: id, OrgID: c.OrgID}); err != nil {
+		return s.handleError(c, "Failed to remove user from organization", err)
+	}
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, userKey(id), ""); err != nil {
+		return s.handleError(c, "Failed to delete the external ID of the user", err)
+	}
+	if err := kvstore.WithNamespace(s.kvStore, c.OrgID, kvNamespace).Del(c.Req.Context(), provisionedKey(id)); err != nil {
+		return s.handleError(c, "Failed to delete user", err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+// updateUser replaces the attributes of a user. When active isn't set, the user keeps its state.
+func (s *Service) updateUser(c *models.ReqContext, id int64, u *User) (*User, error) {
+	if err := validateUser(u); err != nil {
+		return nil, err
+	}
+
+	usr, err := s.getProvisionableUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	email := primaryEmail(u)
+	name := displayName(u)
+	if u.UserName != usr.Login || email != usr.Email || name != usr.Name {
+		if err := s.checkUniqueUser(c.Req.Context(), u.UserName, email, id); err != nil {
+			return nil, err
+		}
+		if err := s.checkGlobalChange(c, id, accesscontrol.ActionUsersWrite); err != nil {
+			return nil, err
+		}
+		if err := s.userService.Update(c.Req.Context(), &user.UpdateUserCommand{
+			UserID: id,
+			Login:  u.UserName,
+			Email:  email,
+			Name:   name,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Active != nil && *u.Active == usr.IsDisabled {
+		if err := s.checkGlobalChange(c, id, accesscontrol.ActionUsersDisable); err != nil {
+			return nil, err
+		}
+		if err := s.setUserActive(c.Req.Context(), usr, *u.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.setExternalID(c.Req.Context(), c.OrgID, userKey(id), u.ExternalID); err != nil {
+		return nil, err
+	}
+	return s.getUserResource(c, id)
+}
+
+// getProvisionableUser returns a user of the organization which can be modified with SCIM. Grafana server admins are
+// excluded, since an identity provider of an organization must not change users who administer the whole server.
+func (s *Service) getProvisionableUser(c *models.ReqContext, id int64) (*user.User, error) {
+	if _, err := s.getOrgUser(c, id); err != nil {
+		return nil, err
+	}
+
+	usr, err := s.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: id})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if usr.IsAdmin {
+		return nil, ErrAdminUser
+	}
+	return usr, nil
+}
+
+// checkGlobalChange returns ErrGlobalUser when the identity provider changes the attributes or the state of a user,
+// which apply to all organizations, without the global permission to do it. The identity provider can change the users
+// it created and the users who belong to its organization only.
+func (s *Service) checkGlobalChange(c *models.ReqContext, id int64, action string) error {
+	provisioned, _, err := kvstore.WithNamespace(s.kvStore, c.OrgID, kvNamespace).Get(c.Req.Context(), provisionedKey(id))
+	if err != nil {
+		return err
+	}
+	if provisioned != "" {
+		return nil
+	}
+
+	onlyOrg, err := s.belongsToOrgOnly(c, id)
+	if err != nil {
+		return err
+	}
+	if onlyOrg {
+		return nil
+	}
+
+	hasAccess := accesscontrol.HasAccess(s.accessControl, c)
+	if hasAccess(accesscontrol.ReqGrafanaAdmin, accesscontrol.EvalPermission(action, accesscontrol.Scope("global.users", "id", strconv.FormatInt(id, 10)))) {
+		return nil
+	}
+	return ErrGlobalUser
+}
+
+// belongsToOrgOnly returns whether the organization of the request is the only organization of the user.
+func (s *Service) belongsToOrgOnly(c *models.ReqContext, id int64) (bool, error) {
+	orgs, err := s.orgService.GetUserOrgList(c.Req.Context(), &org.GetUserOrgListQuery{UserID: id})
+	if err != nil {
+		return false, err
+	}
+	for _, o := range orgs {
+		if o.OrgID != c.OrgID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// setUserActive enables or disables a user, the sessions of disabled users are revoked.
+func (s *Service) setUserActive(ctx context.Context, usr *user.User, active bool) error {
+	if err := s.userService.Disable(ctx, &user.DisableUserCommand{UserID: usr.ID, IsDisabled: !active}); err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+	return s.authTokenService.RevokeAllUserTokens(ctx, usr.ID)
+}
+
+// checkUniqueUser returns ErrUserExists when another user has the login or the email.
+func (s *Service) checkUniqueUser(ctx context.Context, login string, email string, id int64) error {
+	check := func(usr *user.User, err error) error {
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				return nil
+			}
+			return err
+		}
+		if usr.ID != id {
+			return ErrUserExists
+		}
+		return nil
+	}
+
+	if err := check(s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: login})); err != nil {
+		return err
+	}
+	if email == "" {
+		return nil
+	}
+	return check(s.userService.GetByEmail(ctx, &user.GetUserByEmailQuery{Email: email}))
+}
+
+func (s *Service) getOrgUser(c *models.ReqContext, id int64) (*org.OrgUserDTO, error) {
+	orgUsers, err := s.orgService.GetOrgUsers(c.Req.Context(), &org.GetOrgUsersQuery{OrgID: c.OrgID, UserID: id, User: c.SignedInUser})
+	if err != nil {
+		return nil, err
+	}
+	for _, orgUser := range orgUsers {
+		if orgUser.UserID == id {
+			return orgUser, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *Service) getUserResource(c *models.ReqContext, id int64) (*User, error) {
+	orgUser, err := s.getOrgUser(c, id)
+	if err != nil {
+		return nil, err
+	}
+	externalID, err := s.getExternalID(c.Req.Context(), c.OrgID, userKey(id))
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(orgUser, externalID), nil
+}
+
+func (s *Service) toUser(orgUser *org.OrgUserDTO, externalID string) *User {
+	id := strconv.FormatInt(orgUser.UserID, 10)
+	active := !orgUser.IsDisabled
+	created, updated := orgUser.Created, orgUser.Updated
+
+	u := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          id,
+		ExternalID:  externalID,
+		UserName:    orgUser.Login,
+		DisplayName: orgUser.Name,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: ResourceTypeUser,
+			Created:      &created,
+			LastModified: &updated,
+			Location:     s.location("Users", id),
+		},
+	}
+	if orgUser.Name != "" {
+		u.Name = &Name{Formatted: orgUser.Name}
+	}
+	if orgUser.Email != "" {
+		u.Emails = []MultiValuedAttribute{{Value: orgUser.Email, Type: "work", Primary: true}}
+	}
+	return u
+}
+
+func validateUser(u *User) error {
+	if strings.TrimSpace(u.UserName) == "" {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "userName is required")
+	}
+	return nil
+}
+
+// primaryEmail returns the primary email of a user, or its first email.
+func primaryEmail(u *User) string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// displayName returns the name of a user, identity providers set either the display name or the name.
+func displayName(u *User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
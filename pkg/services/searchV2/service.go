@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/registry"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/contexthandler"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -189,6 +190,7 @@ func (s *StandardSearchService) getUser(ctx context.Context, backendUser *backen
 
 	if _, ok := usr.Permissions[orgId]; ok {
 		// permissions as part of the `s.sql.GetSignedInUser` query - return early
+		restrictToToken(ctx, usr, orgId)
 		return usr, nil
 	}
 
@@ -201,9 +203,20 @@ func (s *StandardSearchService) getUser(ctx context.Context, backendUser *backen
 	}
 
 	usr.Permissions[orgId] = accesscontrol.GroupScopesByAction(permissions)
+	restrictToToken(ctx, usr, orgId)
 	return usr, nil
 }
 
+// restrictToToken restricts the permissions of the user to the permissions of the service account token of the
+// request, the user is loaded again from the backend user so the permissions of the request context don't apply.
+func restrictToToken(ctx context.Context, usr *user.SignedInUser, orgId int64) {
+	c := contexthandler.FromContext(ctx)
+	if c == nil || c.TokenPermissions == nil || c.SignedInUser == nil || c.SignedInUser.UserID != usr.UserID {
+		return
+	}
+	usr.Permissions[orgId] = accesscontrol.RestrictPermissions(usr.Permissions[orgId], c.TokenPermissions)
+}
+
 func (s *StandardSearchService) DoDashboardQuery(ctx context.Context, user *backend.User, orgID int64, q DashboardQuery) *backend.DataResponse {
 	start := time.Now()
 
@@ -0,0 +1,45 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/contexthandler/ctxkey"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestRestrictToToken(t *testing.T) {
+	permissions := func() map[int64]map[string][]string {
+		return map[int64]map[string][]string{1: {
+			"dashboards:read":  {"dashboards:*"},
+			"dashboards:write": {"dashboards:*"},
+		}}
+	}
+	reqContext := func(userID int64) *models.ReqContext {
+		return &models.ReqContext{
+			SignedInUser:     &user.SignedInUser{UserID: userID, OrgID: 1},
+			TokenPermissions: map[string][]string{"dashboards:read": {"dashboards:uid:a"}},
+		}
+	}
+
+	t.Run("permissions are restricted to the token of the request", func(t *testing.T) {
+		usr := &user.SignedInUser{UserID: 2, OrgID: 1, Permissions: permissions()}
+		restrictToToken(ctxkey.Set(context.Background(), reqContext(2)), usr, 1)
+		require.Equal(t, map[string][]string{"dashboards:read": {"dashboards:uid:a"}}, usr.Permissions[1])
+	})
+
+	t.Run("permissions of another user are not restricted", func(t *testing.T) {
+		usr := &user.SignedInUser{UserID: 3, OrgID: 1, Permissions: permissions()}
+		restrictToToken(ctxkey.Set(context.Background(), reqContext(2)), usr, 1)
+		require.Equal(t, permissions()[1], usr.Permissions[1])
+	})
+
+	t.Run("permissions are not restricted without a request", func(t *testing.T) {
+		usr := &user.SignedInUser{UserID: 2, OrgID: 1, Permissions: permissions()}
+		restrictToToken(context.Background(), usr, 1)
+		require.Equal(t, permissions()[1], usr.Permissions[1])
+	})
+}
@@ -8,6 +8,8 @@ const (
 	QuitOnPluginStartupFailureKey = "quit_on_secrets_plugin_startup_failure"
 	PluginNamespace               = "secretsmanagerplugin"
 	DataSourceSecretType          = "datasource"
+	DataSourcePendingSecretType   = "datasource-pending"
+	TOTPSecretType                = "totp"
 )
 
 // Item stored in k/v store.
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,9 +11,12 @@ import (
 	"github.com/grafana/grafana/pkg/api/response"
 	apikeygenprefix "github.com/grafana/grafana/pkg/components/apikeygenprefixed"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts/database"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/web"
 )
 
@@ -39,6 +43,10 @@ type TokenDTO struct {
 	HasExpired bool `json:"hasExpired"`
 	// example: false
 	IsRevoked *bool `json:"isRevoked"`
+	// example: 192.168.1.1
+	LastUsedIP *string `json:"lastUsedIp"`
+	// Permissions the token is restricted to, tokens without permissions have all the permissions of the service account
+	Permissions []apikey.Permission `json:"permissions,omitempty"`
 }
 
 func hasExpired(expiration *int64) bool {
@@ -88,6 +96,11 @@ func (api *ServiceAccountsAPI) ListTokens(ctx *models.ReqContext) response.Respo
 			secondsUntilExpiration float64    = 0
 		)
 
+		permissions, err := token.GetPermissions()
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to read the permissions of the token", err)
+		}
+
 		isExpired := hasExpired(t.Expires)
 		if t.Expires != nil {
 			v := time.Unix(*t.Expires, 0)
@@ -106,6 +119,8 @@ func (api *ServiceAccountsAPI) ListTokens(ctx *models.ReqContext) response.Respo
 			HasExpired:             isExpired,
 			LastUsedAt:             token.LastUsedAt,
 			IsRevoked:              token.IsRevoked,
+			LastUsedIP:             token.LastUsedIP,
+			Permissions:            permissions,
 		}
 	}
 
@@ -134,7 +149,8 @@ func (api *ServiceAccountsAPI) CreateToken(c *models.ReqContext) response.Respon
 	}
 
 	// confirm service account exists
-	if _, err := api.store.RetrieveServiceAccount(c.Req.Context(), c.OrgID, saID); err != nil {
+	sa, err := api.store.RetrieveServiceAccount(c.Req.Context(), c.OrgID, saID)
+	if err != nil {
 		switch {
 		case errors.Is(err, serviceaccounts.ErrServiceAccountNotFound):
 			return response.Error(http.StatusNotFound, "Failed to retrieve service account", err)
@@ -151,6 +167,12 @@ func (api *ServiceAccountsAPI) CreateToken(c *models.ReqContext) response.Respon
 	// Force affected service account to be the one referenced in the URL
 	cmd.OrgId = c.OrgID
 
+	if len(cmd.Permissions) > 0 {
+		if resp := api.validateTokenPermissions(c, sa, cmd.Permissions); resp != nil {
+			return resp
+		}
+	}
+
 	if api.cfg.ApiKeyMaxSecondsToLive != -1 {
 		if cmd.SecondsToLive == 0 {
 			return response.Error(http.StatusBadRequest, "Number of seconds before expiration should be set", nil)
@@ -186,6 +208,45 @@ func (api *ServiceAccountsAPI) CreateToken(c *models.ReqContext) response.Respon
 	return response.JSON(http.StatusOK, result)
 }
 
+// validateTokenPermissions returns an error response when the permissions of a new token aren't granted to its service
+// account, a token can only be restricted to a subset of the permissions of its service account.
+func (api *ServiceAccountsAPI) validateTokenPermissions(c *models.ReqContext, sa *serviceaccounts.ServiceAccountProfileDTO, permissions []apikey.Permission) response.Response {
+	if api.accesscontrolService.IsDisabled() {
+		return response.Error(http.StatusBadRequest, "Token permissions require role-based access control", nil)
+	}
+
+	saUser := &user.SignedInUser{
+		UserID:  sa.Id,
+		OrgID:   c.OrgID,
+		OrgRole: org.RoleType(sa.Role),
+		Login:   sa.Login,
+	}
+	saPermissions, err := api.accesscontrolService.GetUserPermissions(c.Req.Context(), saUser, accesscontrol.Options{ReloadCache: true})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the permissions of the service account", err)
+	}
+	granted := accesscontrol.GroupScopesByAction(saPermissions)
+
+	for _, p := range permissions {
+		if p.Action == "" {
+			return response.Error(http.StatusBadRequest, "Token permissions require an action", nil)
+		}
+		if _, ok := granted[p.Action]; !ok {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Token permission action %q is unknown or isn't granted to the service account", p.Action), nil)
+		}
+		if p.Scope == "" {
+			continue
+		}
+		if !accesscontrol.ValidateScope(p.Scope) {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Token permission scope %q is invalid", p.Scope), nil)
+		}
+		if !accesscontrol.EvalPermission(p.Action, p.Scope).Evaluate(granted) {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Token permission %q with scope %q isn't granted to the service account", p.Action, p.Scope), nil)
+		}
+	}
+	return nil
+}
+
 // swagger:route DELETE /serviceaccounts/{serviceAccountId}/tokens/{tokenId} service_accounts deleteToken
 //
 // # DeleteToken deletes service account tokens
@@ -59,11 +59,23 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 	svcmock := tests.ServiceAccountMock{}
 	sa := tests.SetupUserServiceAccount(t, store, tests.TestUser{Login: "sa", IsServiceAccount: true})
 
+	// the signed in user can write service accounts, the service account can read the dashboards of a folder and users
+	saPermissions := func(c context.Context, siu *user.SignedInUser, _ accesscontrol.Options) ([]accesscontrol.Permission, error) {
+		if siu.Login == sa.Login {
+			return []accesscontrol.Permission{
+				{Action: "dashboards:read", Scope: "folders:uid:a"},
+				{Action: "users:read", Scope: "global.users:id:2"},
+			}, nil
+		}
+		return []accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: serviceaccounts.ScopeAll}}, nil
+	}
+
 	type testCreateSAToken struct {
-		desc         string
-		expectedCode int
-		body         map[string]interface{}
-		acmock       *accesscontrolmock.Mock
+		desc                string
+		expectedCode        int
+		body                map[string]interface{}
+		acmock              *accesscontrolmock.Mock
+		expectedPermissions []apikey.Permission
 	}
 
 	testCases := []testCreateSAToken{
@@ -115,6 +127,49 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 			body:         map[string]interface{}{"name": "Test4", "role": "Viewer"},
 			expectedCode: http.StatusForbidden,
 		},
+		{
+			desc:   "should be ok to create serviceaccount token with permissions",
+			acmock: tests.SetupMockAccesscontrol(t, saPermissions, false),
+			body: map[string]interface{}{"name": "Test5", "secondsToLive": 1, "permissions": []map[string]string{
+				{"action": "dashboards:read", "scope": "folders:uid:a"},
+				{"action": "users:read"},
+			}},
+			expectedCode:        http.StatusOK,
+			expectedPermissions: []apikey.Permission{{Action: "dashboards:read", Scope: "folders:uid:a"}, {Action: "users:read"}},
+		},
+		{
+			desc:   "should be bad request to create serviceaccount token with an invalid permission scope",
+			acmock: tests.SetupMockAccesscontrol(t, saPermissions, false),
+			body: map[string]interface{}{"name": "Test6", "secondsToLive": 1, "permissions": []map[string]string{
+				{"action": "dashboards:read", "scope": "folders:*:a"},
+			}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:   "should be bad request to create serviceaccount token with an unknown action",
+			acmock: tests.SetupMockAccesscontrol(t, saPermissions, false),
+			body: map[string]interface{}{"name": "Test7", "secondsToLive": 1, "permissions": []map[string]string{
+				{"action": "dashboards:reed"},
+			}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:   "should be bad request to create serviceaccount token with a permission the service account doesn't have",
+			acmock: tests.SetupMockAccesscontrol(t, saPermissions, false),
+			body: map[string]interface{}{"name": "Test8", "secondsToLive": 1, "permissions": []map[string]string{
+				{"action": "dashboards:write"},
+			}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:   "should be bad request to create serviceaccount token with a scope wider than the service account's",
+			acmock: tests.SetupMockAccesscontrol(t, saPermissions, false),
+			body: map[string]interface{}{"name": "Test9", "secondsToLive": 1, "permissions": []map[string]string{
+				{"action": "users:read", "scope": "global.users:*"},
+				{"action": "dashboards:read", "scope": "dashboards:*"},
+			}},
+			expectedCode: http.StatusBadRequest,
+		},
 	}
 
 	var requestResponse = func(server *web.Mux, httpMethod, requestpath string, requestBody io.Reader) *httptest.ResponseRecorder {
@@ -157,6 +212,10 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 				assert.Equal(t, sa.OrgID, query.Result.OrgId)
 				assert.True(t, strings.HasPrefix(actualBody["key"].(string), "glsa"))
 
+				permissions, err := query.Result.GetPermissions()
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedPermissions, permissions)
+
 				keyInfo, err := apikeygenprefix.Decode(actualBody["key"].(string))
 				assert.NoError(t, err)
 
@@ -283,6 +342,8 @@ func TestServiceAccountsAPI_ListTokens(t *testing.T) {
 	var saId int64 = 1
 	var timeInFuture = time.Now().Add(time.Second * 100).Unix()
 	var timeInPast = time.Now().Add(-time.Second * 100).Unix()
+	var lastUsedIP = "192.168.1.1"
+	var permissions = `[{"action":"dashboards:read"}]`
 
 	testCases := []testCreateSAToken{
 		{
@@ -345,6 +406,27 @@ func TestServiceAccountsAPI_ListTokens(t *testing.T) {
 			expectedResponseBodyField: "secondsUntilExpiration",
 			expectedCode:              http.StatusOK,
 		},
+		{
+			desc: "should be able to list serviceaccount with token permissions and last used IP",
+			tokens: []apikey.APIKey{{
+				Id:               1,
+				OrgId:            1,
+				ServiceAccountId: &saId,
+				Name:             "Test4",
+				LastUsedIP:       &lastUsedIP,
+				Permissions:      &permissions,
+			}},
+			acmock: tests.SetupMockAccesscontrol(
+				t,
+				func(c context.Context, siu *user.SignedInUser, _ accesscontrol.Options) ([]accesscontrol.Permission, error) {
+					return []accesscontrol.Permission{{Action: serviceaccounts.ActionRead, Scope: "serviceaccounts:id:1"}}, nil
+				},
+				false,
+			),
+			expectedHasExpired:        false,
+			expectedResponseBodyField: "permissions",
+			expectedCode:              http.StatusOK,
+		},
 	}
 
 	var requestResponse = func(server *web.Mux, httpMethod, requestpath string, requestBody io.Reader) *httptest.ResponseRecorder {
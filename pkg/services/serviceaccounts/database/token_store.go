@@ -50,6 +50,7 @@ func (s *ServiceAccountsStoreImpl) AddServiceAccountToken(ctx context.Context, s
 			Key:              cmd.Key,
 			SecondsToLive:    cmd.SecondsToLive,
 			ServiceAccountID: &serviceAccountId,
+			Permissions:      cmd.Permissions,
 		}
 
 		if err := s.apiKeyService.AddAPIKey(ctx, addKeyCmd); err != nil {
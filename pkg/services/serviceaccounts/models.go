@@ -70,11 +70,12 @@ type GetSATokensQuery struct {
 }
 
 type AddServiceAccountTokenCommand struct {
-	Name          string         `json:"name" binding:"Required"`
-	OrgId         int64          `json:"-"`
-	Key           string         `json:"-"`
-	SecondsToLive int64          `json:"secondsToLive"`
-	Result        *apikey.APIKey `json:"-"`
+	Name          string              `json:"name" binding:"Required"`
+	OrgId         int64               `json:"-"`
+	Key           string              `json:"-"`
+	SecondsToLive int64               `json:"secondsToLive"`
+	Permissions   []apikey.Permission `json:"permissions"`
+	Result        *apikey.APIKey      `json:"-"`
 }
 
 // swagger: model
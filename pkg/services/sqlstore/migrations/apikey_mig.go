@@ -100,4 +100,13 @@ func addApiKeyMigrations(mg *Migrator) {
 	mg.AddMigration("Add is_revoked column to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
 		Name: "is_revoked", Type: DB_Bool, Nullable: true, Default: "0",
 	}))
+
+	mg.AddMigration("Add last_used_ip column to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "last_used_ip", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
+
+	// permissions restrict a service account token to a subset of the permissions of its service account
+	mg.AddMigration("Add permissions column to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "permissions", Type: DB_Text, Nullable: true,
+	}))
 }
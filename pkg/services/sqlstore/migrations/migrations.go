@@ -98,6 +98,10 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	ualert.UpdateRuleGroupIndexMigration(mg)
 	accesscontrol.AddManagedFolderAlertActionsRepeatMigration(mg)
 	accesscontrol.AddAdminOnlyMigration(mg)
+
+	addQueryAuditMigrations(mg)
+
+	addTOTPMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
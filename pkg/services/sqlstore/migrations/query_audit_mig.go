@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addQueryAuditMigrations(mg *Migrator) {
+	queryAuditV1 := Table{
+		Name: "query_audit",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_login", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "source", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "datasource_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "datasource_type", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "panel_id", Type: DB_BigInt, Nullable: false},
+			{Name: "duration_ms", Type: DB_BigInt, Nullable: false},
+			{Name: "status", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "status_code", Type: DB_Int, Nullable: false},
+			{Name: "error", Type: DB_Text, Nullable: false},
+			{Name: "query", Type: DB_MediumText, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "created"}},
+			{Cols: []string{"org_id", "datasource_uid", "created"}},
+			{Cols: []string{"created"}},
+		},
+	}
+
+	mg.AddMigration("create query_audit table v1", NewAddTableMigration(queryAuditV1))
+
+	mg.AddMigration("add index query_audit.org_id-created", NewAddIndexMigration(queryAuditV1, queryAuditV1.Indices[0]))
+	mg.AddMigration("add index query_audit.org_id-datasource_uid-created", NewAddIndexMigration(queryAuditV1, queryAuditV1.Indices[1]))
+	mg.AddMigration("add index query_audit.created", NewAddIndexMigration(queryAuditV1, queryAuditV1.Indices[2]))
+}
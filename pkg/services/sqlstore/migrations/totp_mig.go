@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addTOTPMigrations(mg *Migrator) {
+	usedCodeV1 := Table{
+		Name: "user_totp_used_code",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "step", Type: DB_BigInt, Nullable: false},
+			{Name: "recovery_code", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id", "step", "recovery_code"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create user_totp_used_code table v1", NewAddTableMigration(usedCodeV1))
+
+	mg.AddMigration("add unique index user_totp_used_code.user_id-step-recovery_code", NewAddIndexMigration(usedCodeV1, usedCodeV1.Indices[0]))
+}
@@ -0,0 +1,65 @@
+package tokenexpiry
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+)
+
+func (s *Service) registerAPIEndpoints() {
+	auth := ac.Middleware(s.accessControl)
+
+	s.routeRegister.Get("/api/auth/keys/report", auth(middleware.ReqOrgAdmin, ac.EvalAny(
+		ac.EvalPermission(ac.ActionAPIKeyRead, ac.ScopeAPIKeysAll),
+		ac.EvalPermission(serviceaccounts.ActionRead, serviceaccounts.ScopeAll),
+	)), routing.Wrap(s.reportHandler))
+}
+
+// swagger:route GET /auth/keys/report api_keys getExpiringAndStaleTokens
+//
+// Get the API keys and service account tokens of the current organization which expire soon or haven't been used for a
+// long time.
+//
+// Responses:
+// 200: getExpiringAndStaleTokensResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *Service) reportHandler(c *models.ReqContext) response.Response {
+	tokens, err := s.Report(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the expiring and stale tokens", err)
+	}
+
+	if s.accessControl.IsDisabled() {
+		return response.JSON(http.StatusOK, tokens)
+	}
+
+	filtered := make([]*Token, 0, len(tokens))
+	for _, token := range tokens {
+		evaluator := ac.EvalPermission(ac.ActionAPIKeyRead, ac.Scope("apikeys", "id", strconv.FormatInt(token.ID, 10)))
+		if token.ServiceAccountID != nil {
+			evaluator = ac.EvalPermission(serviceaccounts.ActionRead, ac.Scope("serviceaccounts", "id", strconv.FormatInt(*token.ServiceAccountID, 10)))
+		}
+		ok, err := s.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate the token permissions", err)
+		}
+		if ok {
+			filtered = append(filtered, token)
+		}
+	}
+	return response.JSON(http.StatusOK, filtered)
+}
+
+// swagger:response getExpiringAndStaleTokensResponse
+type GetExpiringAndStaleTokensResponse struct {
+	// in: body
+	Body []Token `json:"body"`
+}
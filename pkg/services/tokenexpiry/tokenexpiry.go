@@ -0,0 +1,386 @@
+// Package tokenexpiry reports the API keys and service account tokens which expire soon or aren't used anymore, and
+// notifies the org admins about them.
+package tokenexpiry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	lockActionName = "notify expiring and stale tokens"
+	emailTemplate  = "token_expiry"
+	dateFormat     = "2006-01-02"
+
+	// kvNamespace stores the issue each token was last notified for, so the org admins are only notified again when
+	// the issue changes, for example when an expiring token expires
+	kvNamespace = "token_expiry"
+)
+
+var timeNow = time.Now
+
+// Reason is why a token is reported.
+type Reason string
+
+const (
+	// ReasonExpiring is reported for tokens which expire within the notify_before duration, or which expired within
+	// that duration.
+	ReasonExpiring Reason = "expiring"
+	// ReasonStale is reported for tokens which haven't been used for the stale_after duration.
+	ReasonStale Reason = "stale"
+)
+
+// Token is an API key or a service account token which expires soon or isn't used anymore.
+type Token struct {
+	ID    int64  `json:"id"`
+	OrgID int64  `json:"-"`
+	Name  string `json:"name"`
+	// ServiceAccountID is the service account of the token, or null for API keys.
+	ServiceAccountID   *int64     `json:"serviceAccountId"`
+	ServiceAccountName string     `json:"serviceAccountName,omitempty"`
+	Created            time.Time  `json:"created"`
+	Expiration         *time.Time `json:"expiration"`
+	LastUsedAt         *time.Time `json:"lastUsedAt"`
+	LastUsedIP         *string    `json:"lastUsedIp"`
+	Reasons            []Reason   `json:"reasons"`
+	// Revoked is set when the token has been revoked because it was stale.
+	Revoked bool `json:"-"`
+}
+
+// HasReason returns whether the token is reported for the reason.
+func (t *Token) HasReason(reason Reason) bool {
+	for _, r := range t.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+type Service struct {
+	cfg                 setting.TokenExpirySettings
+	appURL              string
+	log                 log.Logger
+	accessControl       accesscontrol.AccessControl
+	apiKeyService       apikey.Service
+	orgService          org.Service
+	userService         user.Service
+	notificationService notifications.Service
+	serverLock          *serverlock.ServerLockService
+	routeRegister       routing.RouteRegister
+	kvStore             kvstore.KVStore
+}
+
+func ProvideService(cfg *setting.Cfg, routeRegister routing.RouteRegister, accessControl accesscontrol.AccessControl,
+	apiKeyService apikey.Service, orgService org.Service, userService user.Service,
+	notificationService notifications.Service, serverLock *serverlock.ServerLockService, kvStore kvstore.KVStore) *Service {
+	s := &Service{
+		cfg:                 cfg.TokenExpiry,
+		appURL:              cfg.AppURL,
+		log:                 log.New("token_expiry"),
+		accessControl:       accessControl,
+		apiKeyService:       apiKeyService,
+		orgService:          orgService,
+		userService:         userService,
+		notificationService: notificationService,
+		serverLock:          serverLock,
+		routeRegister:       routeRegister,
+		kvStore:             kvStore,
+	}
+	s.registerAPIEndpoints()
+	return s
+}
+
+// IsDisabled disables the background notifications, the report API is always available.
+func (s *Service) IsDisabled() bool {
+	return !s.cfg.Enabled
+}
+
+func (s *Service) Run(ctx context.Context) error {
+	s.lockAndNotify(ctx)
+
+	// the server lock runs the notifications once per check interval across all the instances
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.lockAndNotify(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Service) lockAndNotify(ctx context.Context) {
+	err := s.serverLock.LockAndExecute(ctx, lockActionName, s.cfg.CheckInterval, func(ctx context.Context) {
+		if err := s.notify(ctx); err != nil {
+			s.log.Error("Failed to notify expiring and stale tokens", "error", err)
+		}
+	})
+	if err != nil {
+		s.log.Error("Failed to lock and execute the notification of expiring and stale tokens", "error", err)
+	}
+}
+
+// Report returns the tokens of an organization which expire soon or aren't used anymore, -1 returns the tokens of all
+// the organizations.
+func (s *Service) Report(ctx context.Context, orgID int64) ([]*Token, error) {
+	now := timeNow()
+	query := &apikey.GetExpiringOrStaleQuery{
+		OrgID:         orgID,
+		ExpiresBefore: now.Add(s.cfg.NotifyBefore),
+		UnusedSince:   now.Add(-s.cfg.StaleAfter),
+		ExpiredSince:  now.Add(-s.cfg.NotifyBefore),
+	}
+	keys, err := s.apiKeyService.GetExpiringOrStaleAPIKeys(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccountNames := map[int64]string{}
+	tokens := make([]*Token, 0, len(keys))
+	for _, key := range keys {
+		token := &Token{
+			ID:               key.Id,
+			OrgID:            key.OrgId,
+			Name:             key.Name,
+			ServiceAccountID: key.ServiceAccountId,
+			Created:          key.Created,
+			LastUsedAt:       key.LastUsedAt,
+			LastUsedIP:       key.LastUsedIP,
+		}
+		if key.Expires != nil {
+			expiration := time.Unix(*key.Expires, 0)
+			token.Expiration = &expiration
+			if !expiration.After(query.ExpiresBefore) {
+				token.Reasons = append(token.Reasons, ReasonExpiring)
+			}
+		}
+		lastUsed := key.Created
+		if key.LastUsedAt != nil {
+			lastUsed = *key.LastUsedAt
+		}
+		if lastUsed.Before(query.UnusedSince) {
+			token.Reasons = append(token.Reasons, ReasonStale)
+		}
+		if len(token.Reasons) == 0 {
+			continue
+		}
+
+		if key.ServiceAccountId != nil {
+			name, ok := serviceAccountNames[*key.ServiceAccountId]
+			if !ok {
+				name, err = s.getServiceAccountName(ctx, *key.ServiceAccountId)
+				if err != nil {
+					return nil, err
+				}
+				serviceAccountNames[*key.ServiceAccountId] = name
+			}
+			token.ServiceAccountName = name
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (s *Service) getServiceAccountName(ctx context.Context, id int64) (string, error) {
+	u, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: id})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if u.Name != "" {
+		return u.Name, nil
+	}
+	return u.Login, nil
+}
+
+// notify revokes the stale tokens when configured, and emails the tokens of each organization to its admins. Tokens
+// are only emailed again when their issue changes.
+func (s *Service) notify(ctx context.Context) error {
+	tokens, err := s.Report(ctx, -1)
+	if err != nil {
+		return err
+	}
+
+	notified, err := kvstore.WithNamespace(s.kvStore, kvstore.AllOrganizations, kvNamespace).GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	reported := map[int64]map[string]bool{}
+	tokensByOrg := map[int64][]*Token{}
+	for _, token := range tokens {
+		if s.cfg.RevokeStale && token.HasReason(ReasonStale) {
+			if err := s.apiKeyService.RevokeAPIKey(ctx, token.OrgID, token.ID); err != nil {
+				s.log.Error("Failed to revoke stale token", "orgId", token.OrgID, "tokenId", token.ID, "error", err)
+			} else {
+				token.Revoked = true
+				s.log.Info("Revoked stale token", "orgId", token.OrgID, "tokenId", token.ID, "name", token.Name)
+			}
+		}
+
+		if reported[token.OrgID] == nil {
+			reported[token.OrgID] = map[string]bool{}
+		}
+		reported[token.OrgID][tokenKey(token.ID)] = true
+		if notified[token.OrgID][tokenKey(token.ID)] == tokenIssue(token, now) {
+			continue
+		}
+		tokensByOrg[token.OrgID] = append(tokensByOrg[token.OrgID], token)
+	}
+
+	// tokens which aren't reported anymore are notified again if they are reported later
+	for orgID, keys := range notified {
+		for key := range keys {
+			if reported[orgID][key] {
+				continue
+			}
+			if err := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace).Del(ctx, key); err != nil {
+				s.log.Error("Failed to delete the notification of a token", "orgId", orgID, "key", key, "error", err)
+			}
+		}
+	}
+
+	orgIDs := make([]int64, 0, len(tokensByOrg))
+	for orgID := range tokensByOrg {
+		orgIDs = append(orgIDs, orgID)
+	}
+	sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+
+	for _, orgID := range orgIDs {
+		if err := s.notifyOrgAdmins(ctx, orgID, tokensByOrg[orgID]); err != nil {
+			if errors.Is(err, models.ErrSmtpNotEnabled) {
+				s.log.Warn("Cannot notify expiring and stale tokens since SMTP is not enabled")
+				return nil
+			}
+			s.log.Error("Failed to notify the expiring and stale tokens of the organization", "orgId", orgID, "error", err)
+			continue
+		}
+
+		kv := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace)
+		for _, token := range tokensByOrg[orgID] {
+			if err := kv.Set(ctx, tokenKey(token.ID), tokenIssue(token, now)); err != nil {
+				s.log.Error("Failed to store the notification of a token", "orgId", orgID, "tokenId", token.ID, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+func tokenKey(id int64) string {
+	return fmt.Sprintf("tokens/%d", id)
+}
+
+type emailToken struct {
+	Name  string
+	Owner string
+	Issue string
+}
+
+func (s *Service) notifyOrgAdmins(ctx context.Context, orgID int64, tokens []*Token) error {
+	users, err := s.orgService.GetOrgUsers(ctx, &org.GetOrgUsersQuery{
+		OrgID:                    orgID,
+		DontEnforceAccessControl: true,
+		User:                     &user.SignedInUser{OrgID: orgID},
+	})
+	if err != nil {
+		return err
+	}
+	to := make([]string, 0)
+	for _, u := range users {
+		if u.Role == string(org.RoleAdmin) && !u.IsDisabled && u.Email != "" {
+			to = append(to, u.Email)
+		}
+	}
+	if len(to) == 0 {
+		s.log.Debug("No org admin to notify of the expiring and stale tokens", "orgId", orgID)
+		return nil
+	}
+
+	o, err := s.orgService.GetByID(ctx, &org.GetOrgByIdQuery{ID: orgID})
+	if err != nil {
+		return err
+	}
+
+	emailTokens := make([]emailToken, 0, len(tokens))
+	for _, token := range tokens {
+		emailTokens = append(emailTokens, emailToken{
+			Name:  token.Name,
+			Owner: tokenOwner(token),
+			Issue: tokenIssue(token, timeNow()),
+		})
+	}
+
+	return s.notificationService.SendEmailCommandHandler(ctx, &models.SendEmailCommand{
+		To:       to,
+		Template: emailTemplate,
+		Data: map[string]interface{}{
+			"OrgName":            o.Name,
+			"TokenCount":         len(tokens),
+			"Tokens":             emailTokens,
+			"ServiceAccountsUrl": s.appURL + "org/serviceaccounts",
+			"ApiKeysUrl":         s.appURL + "org/apikeys",
+		},
+	})
+}
+
+func tokenOwner(token *Token) string {
+	if token.ServiceAccountID == nil {
+		return "API key"
+	}
+	if token.ServiceAccountName == "" {
+		return "Service account"
+	}
+	return fmt.Sprintf("Service account %s", token.ServiceAccountName)
+}
+
+// tokenIssue describes why a token is reported.
+func tokenIssue(token *Token, now time.Time) string {
+	issue := ""
+	if token.HasReason(ReasonStale) {
+		if token.LastUsedAt != nil {
+			issue = fmt.Sprintf("unused since %s", token.LastUsedAt.Format(dateFormat))
+		} else {
+			issue = fmt.Sprintf("never used since its creation on %s", token.Created.Format(dateFormat))
+		}
+		if token.Revoked {
+			return "Revoked, " + issue
+		}
+	}
+	if token.HasReason(ReasonExpiring) && token.Expiration != nil {
+		expires := fmt.Sprintf("expires on %s", token.Expiration.Format(dateFormat))
+		if !token.Expiration.After(now) {
+			expires = fmt.Sprintf("expired on %s", token.Expiration.Format(dateFormat))
+		}
+		if issue == "" {
+			issue = expires
+		} else {
+			issue = expires + ", " + issue
+		}
+	}
+	if issue == "" {
+		return ""
+	}
+	return strings.ToUpper(issue[:1]) + issue[1:]
+}
@@ -0,0 +1,185 @@
+package tokenexpiry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/apikey/apikeytest"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	days := func(n int) time.Time { return now.AddDate(0, 0, n) }
+	unix := func(t time.Time) *int64 { v := t.Unix(); return &v }
+	ptr := func(t time.Time) *time.Time { return &t }
+	saID := int64(10)
+
+	expiring := &apikey.APIKey{Id: 1, OrgId: 1, Name: "expiring", Created: days(-10), LastUsedAt: ptr(days(-1)), Expires: unix(days(3))}
+	stale := &apikey.APIKey{Id: 2, OrgId: 1, Name: "stale", Created: days(-200), LastUsedAt: ptr(days(-100)), ServiceAccountId: &saID}
+	neverUsed := &apikey.APIKey{Id: 3, OrgId: 2, Name: "never used", Created: days(-100)}
+	recent := &apikey.APIKey{Id: 4, OrgId: 2, Name: "recent", Created: days(-100), LastUsedAt: ptr(days(-1)), Expires: unix(days(30))}
+
+	setup := func(revokeStale bool, keys ...*apikey.APIKey) (*Service, *fakeAPIKeyService, *notifications.NotificationServiceMock) {
+		apiKeys := &fakeAPIKeyService{Service: apikeytest.Service{ExpectedAPIKeys: keys}}
+		orgService := orgtest.NewOrgServiceFake()
+		orgService.ExpectedOrg = &org.Org{Name: "Main Org."}
+		orgService.ExpectedOrgUsers = []*org.OrgUserDTO{
+			{Email: "admin@localhost", Role: string(org.RoleAdmin)},
+			{Email: "disabled@localhost", Role: string(org.RoleAdmin), IsDisabled: true},
+			{Email: "editor@localhost", Role: string(org.RoleEditor)},
+		}
+		userService := usertest.NewUserServiceFake()
+		userService.ExpectedUser = &user.User{Login: "sa-robot", Name: "Robot"}
+		notificationService := notifications.MockNotificationService()
+		return &Service{
+			cfg: setting.TokenExpirySettings{
+				Enabled:      true,
+				NotifyBefore: 7 * 24 * time.Hour,
+				StaleAfter:   90 * 24 * time.Hour,
+				RevokeStale:  revokeStale,
+			},
+			appURL:              "http://localhost:3000/",
+			log:                 log.New("test"),
+			apiKeyService:       apiKeys,
+			orgService:          orgService,
+			userService:         userService,
+			notificationService: notificationService,
+			kvStore:             kvstore.ProvideService(sqlstore.InitTestDB(t)),
+		}, apiKeys, notificationService
+	}
+
+	t.Run("reports the expiring and stale tokens with their reasons", func(t *testing.T) {
+		s, _, _ := setup(false, expiring, stale, neverUsed, recent)
+
+		tokens, err := s.Report(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, tokens, 3)
+		require.Equal(t, []Reason{ReasonExpiring}, tokens[0].Reasons)
+		require.Equal(t, []Reason{ReasonStale}, tokens[1].Reasons)
+		require.Equal(t, "Robot", tokens[1].ServiceAccountName)
+		require.Equal(t, []Reason{ReasonStale}, tokens[2].Reasons)
+	})
+
+	t.Run("notifies the enabled org admins of each organization", func(t *testing.T) {
+		s, apiKeys, notificationService := setup(false, expiring, stale, neverUsed)
+		var emails []*models.SendEmailCommand
+		notificationService.EmailHandler = func(_ context.Context, cmd *models.SendEmailCommand) error {
+			emails = append(emails, cmd)
+			return nil
+		}
+
+		require.NoError(t, s.notify(context.Background()))
+
+		require.Empty(t, apiKeys.revoked)
+		require.Len(t, emails, 2)
+		require.Equal(t, []string{"admin@localhost"}, emails[0].To)
+		require.Equal(t, emailTemplate, emails[0].Template)
+		require.Equal(t, 2, emails[0].Data["TokenCount"])
+		require.Equal(t, []emailToken{
+			{Name: "expiring", Owner: "API key", Issue: "Expires on 2022-10-04"},
+			{Name: "stale", Owner: "Service account Robot", Issue: "Unused since 2022-06-23"},
+		}, emails[0].Data["Tokens"])
+		require.Equal(t, []emailToken{
+			{Name: "never used", Owner: "API key", Issue: "Never used since its creation on 2022-06-23"},
+		}, emails[1].Data["Tokens"])
+	})
+
+	t.Run("revokes the stale tokens when enabled", func(t *testing.T) {
+		s, apiKeys, notificationService := setup(true, expiring, stale)
+
+		require.NoError(t, s.notify(context.Background()))
+
+		require.Equal(t, []int64{2}, apiKeys.revoked)
+		require.Equal(t, []emailToken{
+			{Name: "expiring", Owner: "API key", Issue: "Expires on 2022-10-04"},
+			{Name: "stale", Owner: "Service account Robot", Issue: "Revoked, unused since 2022-06-23"},
+		}, notificationService.Email.Data["Tokens"])
+	})
+
+	t.Run("notifies the tokens again only when their issue changes", func(t *testing.T) {
+		s, apiKeys, notificationService := setup(false, expiring, stale)
+		var emails []*models.SendEmailCommand
+		notificationService.EmailHandler = func(_ context.Context, cmd *models.SendEmailCommand) error {
+			emails = append(emails, cmd)
+			return nil
+		}
+
+		require.NoError(t, s.notify(context.Background()))
+		require.Len(t, emails, 1)
+		require.NoError(t, s.notify(context.Background()))
+		require.Len(t, emails, 1)
+
+		// the expiring token expired
+		now = now.AddDate(0, 0, 4)
+		t.Cleanup(func() { now = now.AddDate(0, 0, -4) })
+		require.NoError(t, s.notify(context.Background()))
+		require.Len(t, emails, 2)
+		require.Equal(t, []emailToken{
+			{Name: "expiring", Owner: "API key", Issue: "Expired on 2022-10-04"},
+		}, emails[1].Data["Tokens"])
+
+		// the stale token was used, it is notified again when it is stale again
+		apiKeys.ExpectedAPIKeys = []*apikey.APIKey{expiring}
+		require.NoError(t, s.notify(context.Background()))
+		require.Len(t, emails, 2)
+		apiKeys.ExpectedAPIKeys = []*apikey.APIKey{expiring, stale}
+		require.NoError(t, s.notify(context.Background()))
+		require.Len(t, emails, 3)
+		require.Equal(t, []emailToken{
+			{Name: "stale", Owner: "Service account Robot", Issue: "Unused since 2022-06-23"},
+		}, emails[2].Data["Tokens"])
+	})
+
+	t.Run("notifies the tokens which can't be revoked", func(t *testing.T) {
+		s, apiKeys, notificationService := setup(true, stale, neverUsed)
+		apiKeys.revokeErr = map[int64]error{2: errors.New("failed")}
+
+		require.NoError(t, s.notify(context.Background()))
+
+		require.Equal(t, []int64{3}, apiKeys.revoked)
+		require.Equal(t, []emailToken{
+			{Name: "never used", Owner: "API key", Issue: "Revoked, never used since its creation on 2022-06-23"},
+		}, notificationService.Email.Data["Tokens"])
+	})
+
+	t.Run("does not fail without SMTP", func(t *testing.T) {
+		s, _, notificationService := setup(false, expiring)
+		notificationService.EmailHandler = func(_ context.Context, cmd *models.SendEmailCommand) error {
+			return models.ErrSmtpNotEnabled
+		}
+
+		require.NoError(t, s.notify(context.Background()))
+	})
+}
+
+type fakeAPIKeyService struct {
+	apikeytest.Service
+	revoked   []int64
+	revokeErr map[int64]error
+}
+
+func (f *fakeAPIKeyService) RevokeAPIKey(ctx context.Context, orgID, tokenID int64) error {
+	if err := f.revokeErr[tokenID]; err != nil {
+		return err
+	}
+	f.revoked = append(f.revoked, tokenID)
+	return nil
+}
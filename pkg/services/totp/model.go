@@ -0,0 +1,37 @@
+package totp
+
+import "errors"
+
+var (
+	ErrInvalidCode         = errors.New("invalid two-factor authentication code")
+	ErrNotEnabled          = errors.New("two-factor authentication is not enabled")
+	ErrAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrNoPendingEnrollment = errors.New("no pending two-factor authentication enrollment")
+	ErrEnforcedByOrg       = errors.New("two-factor authentication is required by an organization of the user")
+	ErrChallengeNotFound   = errors.New("two-factor authentication challenge not found or expired")
+	ErrBasicAuthNotAllowed = errors.New("basic authentication is not allowed for users with two-factor authentication")
+)
+
+// Enrollment is the secret of a new enrollment, which is added to an authenticator app by scanning a QR code of the
+// URL, or by entering the secret.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+type Status struct {
+	Enabled  bool `json:"enabled"`
+	Enforced bool `json:"enforced"`
+}
+
+type VerifyCommand struct {
+	Code string `json:"code" binding:"Required"`
+}
+
+type SetEnforcedCommand struct {
+	Enforced bool `json:"enforced"`
+}
+
+type RecoveryCodesResult struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,30 @@
+package totp
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Service manages the TOTP two-factor authentication of the users of the built-in login.
+type Service interface {
+	// IsEnabled returns whether the user has confirmed the enrollment of two-factor authentication.
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	// Enroll generates a new secret for the user, which has to be confirmed with a code before it's used to log in.
+	Enroll(ctx context.Context, usr *user.User) (*Enrollment, error)
+	// ConfirmEnrollment enables two-factor authentication with a code of the enrolled secret, and returns the
+	// recovery codes of the user.
+	ConfirmEnrollment(ctx context.Context, userID int64, code string) ([]string, error)
+	// Verify checks a code or a recovery code of a user with two-factor authentication. Codes and recovery codes can
+	// only be used once, which is checked in the database.
+	Verify(ctx context.Context, userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of a user with two-factor authentication.
+	RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error)
+	// Disable removes the secret and the recovery codes of the user.
+	Disable(ctx context.Context, userID int64) error
+	// IsEnforced returns whether the users of the organization must use two-factor authentication.
+	IsEnforced(ctx context.Context, orgID int64) (bool, error)
+	// IsEnforcedForUser returns whether one of the organizations of the user enforces two-factor authentication.
+	IsEnforcedForUser(ctx context.Context, userID int64) (bool, error)
+	SetEnforced(ctx context.Context, orgID int64, enforced bool) error
+}
@@ -0,0 +1,131 @@
+package totpimpl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	// TOTP codes are HMAC-SHA1, which is the only algorithm all authenticator apps support.
+	// nolint:gosec
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// period and digits of the codes, which are the defaults of authenticator apps
+	period = 30
+	digits = 6
+	// skew is the number of periods before and after the current period whose codes are accepted, for clocks which
+	// aren't in sync
+	skew = 1
+
+	secretSize         = 20
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// keyURI returns the URI of a secret, which authenticator apps read from a QR code.
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func keyURI(issuer string, account string, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(digits))
+	params.Set("period", fmt.Sprint(period))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+func timeStep(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// generateCode returns the HOTP code of a counter, see RFC 4226. The counter of TOTP codes is a time step.
+func generateCode(key []byte, counter int64) string {
+	mac := hmac.New(sha1.New, key)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(counter))
+	mac.Write(b[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// validateCode returns the time step of a valid code. Codes of time steps up to lastStep were already used, and
+// aren't valid anymore.
+func validateCode(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != digits {
+		return 0, false
+	}
+
+	current := timeStep(now)
+	for step := current - skew; step <= current+skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(generateCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes returns new recovery codes, and their hashes which are stored.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b)[:recoveryCodeLength])
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the hash of a recovery code, ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
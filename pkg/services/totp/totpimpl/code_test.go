@@ -0,0 +1,86 @@
+package totpimpl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the secret of the test vectors of RFC 6238, which have 8 digits, the codes are their last 6 digits
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	for unix, code := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		require.Equal(t, code, generateCode(key, timeStep(time.Unix(unix, 0))))
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := timeStep(now)
+
+	t.Run("accepts codes of the current and adjacent time steps", func(t *testing.T) {
+		for _, at := range []time.Time{now, now.Add(-period * time.Second), now.Add(period * time.Second)} {
+			got, ok := validateCode(rfcSecret, "005924", at, 0)
+			require.True(t, ok)
+			require.Equal(t, step, got)
+		}
+
+		_, ok := validateCode(rfcSecret, "005 924", now, 0)
+		require.True(t, ok)
+	})
+
+	t.Run("rejects codes of other time steps", func(t *testing.T) {
+		_, ok := validateCode(rfcSecret, "005924", now.Add(2*period*time.Second), 0)
+		require.False(t, ok)
+	})
+
+	t.Run("rejects used codes", func(t *testing.T) {
+		_, ok := validateCode(rfcSecret, "005924", now, step)
+		require.False(t, ok)
+	})
+
+	t.Run("rejects invalid codes", func(t *testing.T) {
+		for _, code := range []string{"", "005925", "0059240", "abcdef"} {
+			_, ok := validateCode(rfcSecret, code, now, 0)
+			require.False(t, ok, code)
+		}
+	})
+}
+
+func TestKeyURI(t *testing.T) {
+	u, err := url.Parse(keyURI("Grafana", "admin@example.com", "SECRET"))
+	require.NoError(t, err)
+
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Grafana:admin@example.com", u.Path)
+	require.Equal(t, url.Values{
+		"secret":    {"SECRET"},
+		"issuer":    {"Grafana"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}, u.Query())
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Len(t, hashes, recoveryCodeCount)
+
+	require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+	require.Equal(t, hashes[0], hashRecoveryCode(codes[0]))
+	require.Equal(t, hashes[0], hashRecoveryCode(" "+strings.ToUpper(codes[0][:5]+codes[0][6:])))
+}
@@ -0,0 +1,69 @@
+package totpimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+// usedCode is a row of the user_totp_used_code table, a code or a recovery code which was used by a user. The unique
+// index of the table makes sure that a code is only used once, also by concurrent logins on several instances.
+type usedCode struct {
+	ID     int64 `xorm:"pk autoincr 'id'"`
+	UserID int64 `xorm:"user_id"`
+	// Step is the time step of a code, it's 0 for recovery codes
+	Step int64 `xorm:"step"`
+	// RecoveryCode is the hash of a recovery code, it's empty for codes
+	RecoveryCode string `xorm:"recovery_code"`
+	Created      int64  `xorm:"created"`
+}
+
+func (c usedCode) TableName() string {
+	return "user_totp_used_code"
+}
+
+type store struct {
+	sqlStore db.DB
+}
+
+// markUsed records that a code, or a recovery code, is used. It returns false when it was already used.
+func (s *store) markUsed(ctx context.Context, code usedCode) (bool, error) {
+	used := false
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		code.Created = time.Now().Unix()
+		if _, err := sess.Insert(&code); err != nil {
+			if s.sqlStore.GetDialect().IsUniqueConstraintViolation(err) {
+				used = true
+				return nil
+			}
+			return err
+		}
+
+		if code.Step == 0 {
+			return nil
+		}
+		// the codes of older time steps aren't valid anymore
+		_, err := sess.Exec("DELETE FROM user_totp_used_code WHERE user_id = ? AND recovery_code = ? AND step < ?",
+			code.UserID, "", code.Step-2*skew)
+		return err
+	})
+	return !used, err
+}
+
+// deleteUsedRecoveryCodes deletes the recovery codes used by a user, when new recovery codes are generated.
+func (s *store) deleteUsedRecoveryCodes(ctx context.Context, userID int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Exec("DELETE FROM user_totp_used_code WHERE user_id = ? AND recovery_code <> ?", userID, "")
+		return err
+	})
+}
+
+// deleteUsedCodes deletes the codes used by a user, when two-factor authentication is disabled.
+func (s *store) deleteUsedCodes(ctx context.Context, userID int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Exec("DELETE FROM user_totp_used_code WHERE user_id = ?", userID)
+		return err
+	})
+}
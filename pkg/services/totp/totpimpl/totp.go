@@ -0,0 +1,251 @@
+package totpimpl
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/org"
+	secretsKV "github.com/grafana/grafana/pkg/services/secrets/kvstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// users aren't in an organization, their secrets are stored without organization
+	secretOrgID = 0
+
+	kvNamespace = "totp"
+	enforcedKey = "enforced"
+)
+
+// state is the two-factor authentication of a user, which is stored in the secrets store.
+type state struct {
+	Secret  string `json:"secret"`
+	Enabled bool   `json:"enabled"`
+	// RecoveryCodes are the hashes of the unused recovery codes
+	RecoveryCodes []string `json:"recoveryCodes"`
+	// LastStep is the time step of the last used code, the codes of older time steps aren't valid anymore
+	LastStep int64 `json:"lastStep"`
+}
+
+type Service struct {
+	cfg          *setting.Cfg
+	secretsStore secretsKV.SecretsKVStore
+	kvStore      kvstore.KVStore
+	orgService   org.Service
+	// store records the used codes, so that a code can't be used twice
+	store *store
+	log   log.Logger
+	now   func() time.Time
+}
+
+func ProvideService(cfg *setting.Cfg, secretsStore secretsKV.SecretsKVStore, kvStore kvstore.KVStore, sqlStore db.DB, orgService org.Service) totp.Service {
+	return &Service{
+		cfg:          cfg,
+		secretsStore: secretsStore,
+		kvStore:      kvStore,
+		orgService:   orgService,
+		store:        &store{sqlStore: sqlStore},
+		log:          log.New("totp"),
+		now:          time.Now,
+	}
+}
+
+func (s *Service) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	st, err := s.getState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return st != nil && st.Enabled, nil
+}
+
+func (s *Service) Enroll(ctx context.Context, usr *user.User) (*totp.Enrollment, error) {
+	st, err := s.getState(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if st != nil && st.Enabled {
+		return nil, totp.ErrAlreadyEnabled
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setState(ctx, usr.ID, &state{Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	account := usr.Login
+	if account == "" {
+		account = usr.Email
+	}
+	return &totp.Enrollment{
+		Secret: secret,
+		URL:    keyURI(s.cfg.TOTPIssuer, account, secret),
+	}, nil
+}
+
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID int64, code string) ([]string, error) {
+	st, err := s.getState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Enabled {
+		return nil, totp.ErrNoPendingEnrollment
+	}
+
+	step, ok := validateCode(st.Secret, code, s.now(), st.LastStep)
+	if !ok {
+		return nil, totp.ErrInvalidCode
+	}
+	if ok, err := s.store.markUsed(ctx, usedCode{UserID: userID, Step: step}); err != nil || !ok {
+		return nil, usedCodeError(err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	st.Enabled = true
+	st.LastStep = step
+	st.RecoveryCodes = hashes
+	if err := s.setState(ctx, userID, st); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) Verify(ctx context.Context, userID int64, code string) error {
+	st, err := s.getState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if st == nil || !st.Enabled {
+		return totp.ErrNotEnabled
+	}
+
+	if step, ok := validateCode(st.Secret, code, s.now(), st.LastStep); ok {
+		if ok, err := s.store.markUsed(ctx, usedCode{UserID: userID, Step: step}); err != nil || !ok {
+			return usedCodeError(err)
+		}
+		st.LastStep = step
+		return s.setState(ctx, userID, st)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range st.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			if ok, err := s.store.markUsed(ctx, usedCode{UserID: userID, RecoveryCode: hash}); err != nil || !ok {
+				return usedCodeError(err)
+			}
+			st.RecoveryCodes = append(st.RecoveryCodes[:i], st.RecoveryCodes[i+1:]...)
+			s.log.Info("Recovery code used", "userId", userID, "remaining", len(st.RecoveryCodes))
+			return s.setState(ctx, userID, st)
+		}
+	}
+	return totp.ErrInvalidCode
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	st, err := s.getState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || !st.Enabled {
+		return nil, totp.ErrNotEnabled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	st.RecoveryCodes = hashes
+	if err := s.setState(ctx, userID, st); err != nil {
+		return nil, err
+	}
+	if err := s.store.deleteUsedRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) Disable(ctx context.Context, userID int64) error {
+	if err := s.secretsStore.Del(ctx, secretOrgID, strconv.FormatInt(userID, 10), secretsKV.TOTPSecretType); err != nil {
+		return err
+	}
+	return s.store.deleteUsedCodes(ctx, userID)
+}
+
+func (s *Service) IsEnforced(ctx context.Context, orgID int64) (bool, error) {
+	value, exists, err := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace).Get(ctx, enforcedKey)
+	if err != nil || !exists {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+func (s *Service) IsEnforcedForUser(ctx context.Context, userID int64) (bool, error) {
+	enforced, err := kvstore.WithNamespace(s.kvStore, kvstore.AllOrganizations, kvNamespace).GetAll(ctx)
+	if err != nil || len(enforced) == 0 {
+		return false, err
+	}
+
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: userID})
+	if err != nil {
+		return false, err
+	}
+	for _, o := range orgs {
+		if value, ok := enforced[o.OrgID][enforcedKey]; ok {
+			if isEnforced, err := strconv.ParseBool(value); err == nil && isEnforced {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) SetEnforced(ctx context.Context, orgID int64, enforced bool) error {
+	kv := kvstore.WithNamespace(s.kvStore, orgID, kvNamespace)
+	if !enforced {
+		return kv.Del(ctx, enforcedKey)
+	}
+	return kv.Set(ctx, enforcedKey, strconv.FormatBool(enforced))
+}
+
+// usedCodeError returns the error of a code which can't be marked as used, because it was already used by a concurrent
+// request, or because of an error of the database.
+func usedCodeError(err error) error {
+	if err != nil {
+		return err
+	}
+	return totp.ErrInvalidCode
+}
+
+func (s *Service) getState(ctx context.Context, userID int64) (*state, error) {
+	value, exists, err := s.secretsStore.Get(ctx, secretOrgID, strconv.FormatInt(userID, 10), secretsKV.TOTPSecretType)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	st := &state{}
+	if err := json.Unmarshal([]byte(value), st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *Service) setState(ctx context.Context, userID int64, st *state) error {
+	value, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.secretsStore.Set(ctx, secretOrgID, strconv.FormatInt(userID, 10), secretsKV.TOTPSecretType, string(value))
+}
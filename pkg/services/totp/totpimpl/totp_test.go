@@ -0,0 +1,174 @@
+package totpimpl
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgimpl"
+	secretsKV "github.com/grafana/grafana/pkg/services/secrets/kvstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestIntegrationTOTPService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	cfg := setting.NewCfg()
+	cfg.TOTPIssuer = "Grafana"
+	now := time.Unix(1660000000, 0)
+	sqlStore := sqlstore.InitTestDB(t)
+	orgService := orgimpl.ProvideService(sqlStore, cfg)
+	secretsStore := secretsKV.NewFakeSecretsKVStore()
+	s := ProvideService(cfg, secretsStore, kvstore.ProvideService(sqlStore), sqlStore, orgService).(*Service)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	usr, err := sqlStore.CreateUser(ctx, user.CreateUserCommand{Login: "admin", SkipOrgSetup: true})
+	require.NoError(t, err)
+
+	code := func(t *testing.T, secret string, at time.Time) string {
+		t.Helper()
+		key, err := encoding.DecodeString(secret)
+		require.NoError(t, err)
+		return generateCode(key, timeStep(at))
+	}
+
+	enroll := func(t *testing.T) (string, []string) {
+		t.Helper()
+		enrollment, err := s.Enroll(ctx, usr)
+		require.NoError(t, err)
+		recoveryCodes, err := s.ConfirmEnrollment(ctx, usr.ID, code(t, enrollment.Secret, now))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, s.Disable(ctx, usr.ID)) })
+		return enrollment.Secret, recoveryCodes
+	}
+
+	t.Run("enrollment is enabled once confirmed with a valid code", func(t *testing.T) {
+		enrollment, err := s.Enroll(ctx, usr)
+		require.NoError(t, err)
+		u, err := url.Parse(enrollment.URL)
+		require.NoError(t, err)
+		require.Equal(t, enrollment.Secret, u.Query().Get("secret"))
+		require.Equal(t, "/Grafana:admin", u.Path)
+
+		enabled, err := s.IsEnabled(ctx, usr.ID)
+		require.NoError(t, err)
+		require.False(t, enabled)
+
+		_, err = s.ConfirmEnrollment(ctx, usr.ID, "000000")
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		recoveryCodes, err := s.ConfirmEnrollment(ctx, usr.ID, code(t, enrollment.Secret, now))
+		require.NoError(t, err)
+		require.Len(t, recoveryCodes, recoveryCodeCount)
+
+		enabled, err = s.IsEnabled(ctx, usr.ID)
+		require.NoError(t, err)
+		require.True(t, enabled)
+
+		_, err = s.Enroll(ctx, usr)
+		require.ErrorIs(t, err, totp.ErrAlreadyEnabled)
+		_, err = s.ConfirmEnrollment(ctx, usr.ID, code(t, enrollment.Secret, now))
+		require.ErrorIs(t, err, totp.ErrNoPendingEnrollment)
+
+		require.NoError(t, s.Disable(ctx, usr.ID))
+		enabled, err = s.IsEnabled(ctx, usr.ID)
+		require.NoError(t, err)
+		require.False(t, enabled)
+	})
+
+	t.Run("codes can only be used once", func(t *testing.T) {
+		secret, _ := enroll(t)
+
+		// the code of the enrollment was used to confirm it
+		require.ErrorIs(t, s.Verify(ctx, usr.ID, code(t, secret, now)), totp.ErrInvalidCode)
+
+		now = now.Add(period * time.Second)
+		require.NoError(t, s.Verify(ctx, usr.ID, code(t, secret, now)))
+		require.ErrorIs(t, s.Verify(ctx, usr.ID, code(t, secret, now)), totp.ErrInvalidCode)
+	})
+
+	t.Run("recovery codes can only be used once", func(t *testing.T) {
+		_, recoveryCodes := enroll(t)
+
+		require.NoError(t, s.Verify(ctx, usr.ID, recoveryCodes[0]))
+		require.ErrorIs(t, s.Verify(ctx, usr.ID, recoveryCodes[0]), totp.ErrInvalidCode)
+		require.NoError(t, s.Verify(ctx, usr.ID, recoveryCodes[1]))
+
+		regenerated, err := s.RegenerateRecoveryCodes(ctx, usr.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, s.Verify(ctx, usr.ID, recoveryCodes[2]), totp.ErrInvalidCode)
+		require.NoError(t, s.Verify(ctx, usr.ID, regenerated[2]))
+	})
+
+	t.Run("codes are used once by the instances sharing the database", func(t *testing.T) {
+		secret, recoveryCodes := enroll(t)
+		// another instance, whose state of the user isn't updated yet
+		other := ProvideService(cfg, secretsStore, kvstore.ProvideService(sqlStore), sqlStore, orgService).(*Service)
+		other.now = s.now
+		st, err := other.getState(ctx, usr.ID)
+		require.NoError(t, err)
+
+		now = now.Add(period * time.Second)
+		require.NoError(t, s.Verify(ctx, usr.ID, code(t, secret, now)))
+		require.NoError(t, s.Verify(ctx, usr.ID, recoveryCodes[0]))
+
+		require.NoError(t, other.setState(ctx, usr.ID, st))
+		require.ErrorIs(t, other.Verify(ctx, usr.ID, code(t, secret, now)), totp.ErrInvalidCode)
+		require.ErrorIs(t, other.Verify(ctx, usr.ID, recoveryCodes[0]), totp.ErrInvalidCode)
+	})
+
+	t.Run("users without two-factor authentication can't be verified", func(t *testing.T) {
+		require.ErrorIs(t, s.Verify(ctx, 2, "000000"), totp.ErrNotEnabled)
+		_, err := s.RegenerateRecoveryCodes(ctx, 2)
+		require.ErrorIs(t, err, totp.ErrNotEnabled)
+	})
+
+	t.Run("enforcement is set per organization", func(t *testing.T) {
+		require.NoError(t, s.SetEnforced(ctx, 1, true))
+
+		enforced, err := s.IsEnforced(ctx, 1)
+		require.NoError(t, err)
+		require.True(t, enforced)
+		enforced, err = s.IsEnforced(ctx, 2)
+		require.NoError(t, err)
+		require.False(t, enforced)
+
+		require.NoError(t, s.SetEnforced(ctx, 1, false))
+		enforced, err = s.IsEnforced(ctx, 1)
+		require.NoError(t, err)
+		require.False(t, enforced)
+	})
+
+	t.Run("enforcement applies to the users of any organization which enforces it", func(t *testing.T) {
+		first, err := orgService.CreateWithMember(ctx, &org.CreateOrgCommand{Name: "first", UserID: usr.ID})
+		require.NoError(t, err)
+		second, err := orgService.CreateWithMember(ctx, &org.CreateOrgCommand{Name: "second", UserID: usr.ID})
+		require.NoError(t, err)
+		other, err := orgService.CreateWithMember(ctx, &org.CreateOrgCommand{Name: "other"})
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetEnforced(ctx, other.ID, true))
+		enforced, err := s.IsEnforcedForUser(ctx, usr.ID)
+		require.NoError(t, err)
+		require.False(t, enforced)
+
+		require.NoError(t, s.SetEnforced(ctx, second.ID, true))
+		enforced, err = s.IsEnforcedForUser(ctx, usr.ID)
+		require.NoError(t, err)
+		require.True(t, enforced)
+
+		enforced, err = s.IsEnforced(ctx, first.ID)
+		require.NoError(t, err)
+		require.False(t, enforced)
+	})
+}
@@ -0,0 +1,72 @@
+package totptest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+type FakeService struct {
+	ExpectedEnabled       bool
+	ExpectedEnforced      bool
+	ExpectedEnrollment    *totp.Enrollment
+	ExpectedRecoveryCodes []string
+	ExpectedError         error
+	// ValidCode is the only code which is accepted when set
+	ValidCode string
+
+	DisabledUserIDs []int64
+}
+
+func (f *FakeService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	return f.ExpectedEnabled, f.ExpectedError
+}
+
+func (f *FakeService) Enroll(ctx context.Context, usr *user.User) (*totp.Enrollment, error) {
+	return f.ExpectedEnrollment, f.ExpectedError
+}
+
+func (f *FakeService) ConfirmEnrollment(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := f.verify(code); err != nil {
+		return nil, err
+	}
+	f.ExpectedEnabled = true
+	return f.ExpectedRecoveryCodes, nil
+}
+
+func (f *FakeService) Verify(ctx context.Context, userID int64, code string) error {
+	return f.verify(code)
+}
+
+func (f *FakeService) RegenerateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	return f.ExpectedRecoveryCodes, f.ExpectedError
+}
+
+func (f *FakeService) Disable(ctx context.Context, userID int64) error {
+	f.DisabledUserIDs = append(f.DisabledUserIDs, userID)
+	return f.ExpectedError
+}
+
+func (f *FakeService) IsEnforced(ctx context.Context, orgID int64) (bool, error) {
+	return f.ExpectedEnforced, f.ExpectedError
+}
+
+func (f *FakeService) IsEnforcedForUser(ctx context.Context, userID int64) (bool, error) {
+	return f.ExpectedEnforced, f.ExpectedError
+}
+
+func (f *FakeService) SetEnforced(ctx context.Context, orgID int64, enforced bool) error {
+	f.ExpectedEnforced = enforced
+	return f.ExpectedError
+}
+
+func (f *FakeService) verify(code string) error {
+	if f.ExpectedError != nil {
+		return f.ExpectedError
+	}
+	if f.ValidCode != "" && code != f.ValidCode {
+		return totp.ErrInvalidCode
+	}
+	return nil
+}
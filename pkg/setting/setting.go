@@ -272,6 +272,7 @@ type Cfg struct {
 	PluginSettings                   PluginSettings
 	PluginsAllowUnsigned             []string
 	PluginCatalogURL                 string
+	PluginRepositoryURL              string
 	PluginCatalogHiddenPlugins       []string
 	PluginAdminEnabled               bool
 	PluginAdminExternalManageEnabled bool
@@ -298,6 +299,10 @@ type Cfg struct {
 	SigV4VerboseLogging          bool
 	AzureAuthEnabled             bool
 	BasicAuthEnabled             bool
+	TOTPEnabled                  bool
+	TOTPIssuer                   string
+	TOTPChallengeLifetime        time.Duration
+	SCIMEnabled                  bool
 	AdminUser                    string
 	AdminPassword                string
 	AdminEmail                   string
@@ -354,6 +359,7 @@ type Cfg struct {
 	DataProxyIdleConnTimeout       int
 	ResponseLimit                  int64
 	DataProxyRowLimit              int64
+	DataProxyRowBytesLimit         int64
 
 	// DistributedCache
 	RemoteCacheOptions *RemoteCacheOptions
@@ -392,7 +398,15 @@ type Cfg struct {
 	GrafanaJavascriptAgent GrafanaJavascriptAgent
 
 	// Data sources
-	DataSourceLimit int
+	DataSourceLimit                     int
+	DataSourceSecretRotationGracePeriod time.Duration
+	DataSourceSQLiteAllowedPaths        []string
+
+	// Data source health checks
+	DataSourceHealthCheckInterval    time.Duration
+	DataSourceHealthCheckTimeout     time.Duration
+	DataSourceHealthCheckHistorySize int
+	DataSourceHealthCheckAlerts      bool
 
 	// Snapshots
 	SnapshotPublicMode bool
@@ -464,6 +478,18 @@ type Cfg struct {
 
 	Search SearchSettings
 
+	// Data source queries
+	Query QuerySettings
+
+	// Query caching
+	QueryCaching QueryCachingSettings
+
+	// Query audit
+	QueryAudit QueryAuditSettings
+
+	// Expiring and stale API keys and service account tokens
+	TokenExpiry TokenExpirySettings
+
 	// Access Control
 	RBACEnabled         bool
 	RBACPermissionCache bool
@@ -1062,6 +1088,12 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	cfg.DashboardPreviews = readDashboardPreviewsSettings(iniFile)
 	cfg.Storage = readStorageSettings(iniFile)
 	cfg.Search = readSearchSettings(iniFile)
+	cfg.Query = readQuerySettings(iniFile)
+	cfg.QueryCaching = readQueryCachingSettings(iniFile)
+	cfg.QueryAudit = readQueryAuditSettings(iniFile)
+	if cfg.TokenExpiry, err = readTokenExpirySettings(iniFile); err != nil {
+		return err
+	}
 
 	if VerifyEmailEnabled && !cfg.Smtp.Enabled {
 		cfg.Logger.Warn("require_email_validation is enabled but smtp is disabled")
@@ -1354,6 +1386,15 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	BasicAuthEnabled = authBasic.Key("enabled").MustBool(true)
 	cfg.BasicAuthEnabled = BasicAuthEnabled
 
+	// TOTP two-factor authentication
+	authTOTP := iniFile.Section("auth.totp")
+	cfg.TOTPEnabled = authTOTP.Key("enabled").MustBool(false)
+	cfg.TOTPIssuer = valueAsString(authTOTP, "issuer", "Grafana")
+	cfg.TOTPChallengeLifetime = authTOTP.Key("challenge_lifetime").MustDuration(5 * time.Minute)
+
+	// SCIM provisioning
+	cfg.SCIMEnabled = iniFile.Section("auth.scim").Key("enabled").MustBool(false)
+
 	// JWT auth
 	authJWT := iniFile.Section("auth.jwt")
 	cfg.JWTAuthEnabled = authJWT.Key("enabled").MustBool(false)
@@ -1662,6 +1703,18 @@ func (cfg *Cfg) GetContentDeliveryURL(prefix string) string {
 func (cfg *Cfg) readDataSourcesSettings() {
 	datasources := cfg.Raw.Section("datasources")
 	cfg.DataSourceLimit = datasources.Key("datasource_limit").MustInt(5000)
+	cfg.DataSourceSecretRotationGracePeriod = datasources.Key("secret_rotation_grace_period").MustDuration(24 * time.Hour)
+	cfg.DataSourceSQLiteAllowedPaths = util.SplitString(datasources.Key("sqlite_allowed_paths").String())
+	cfg.DataSourceHealthCheckInterval = datasources.Key("health_check_interval").MustDuration(0)
+	cfg.DataSourceHealthCheckTimeout = datasources.Key("health_check_timeout").MustDuration(30 * time.Second)
+	if cfg.DataSourceHealthCheckTimeout <= 0 {
+		cfg.DataSourceHealthCheckTimeout = 30 * time.Second
+	}
+	cfg.DataSourceHealthCheckHistorySize = datasources.Key("health_check_history_size").MustInt(10)
+	if cfg.DataSourceHealthCheckHistorySize <= 0 {
+		cfg.DataSourceHealthCheckHistorySize = 10
+	}
+	cfg.DataSourceHealthCheckAlerts = datasources.Key("health_check_alerts").MustBool(false)
 }
 
 func GetAllowedOriginGlobs(originPatterns []string) ([]glob.Glob, error) {
@@ -38,6 +38,7 @@ func (cfg *Cfg) readPluginSettings(iniFile *ini.File) error {
 	}
 
 	cfg.PluginCatalogURL = pluginsSection.Key("plugin_catalog_url").MustString("https://grafana.com/grafana/plugins/")
+	cfg.PluginRepositoryURL = pluginsSection.Key("plugin_repository_url").MustString("https://grafana.com/api/plugins")
 	cfg.PluginAdminEnabled = pluginsSection.Key("plugin_admin_enabled").MustBool(true)
 	cfg.PluginAdminExternalManageEnabled = pluginsSection.Key("plugin_admin_external_manage_enabled").MustBool(false)
 	catalogHiddenPlugins := pluginsSection.Key("plugin_catalog_hidden_plugins").MustString("")
@@ -0,0 +1,38 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+type QuerySettings struct {
+	// CoalesceRequests sends identical data source queries that are in flight at the same time to the data source only once.
+	CoalesceRequests bool
+	// ConcurrentQueryLimitPerDataSource is the maximum number of queries sent at the same time to a data source. 0 means unlimited.
+	ConcurrentQueryLimitPerDataSource int
+	// ConcurrentQueryLimitPerOrg is the maximum number of queries sent at the same time to the data sources of an organization. 0 means unlimited.
+	ConcurrentQueryLimitPerOrg int
+	// ConcurrentQueryQueueSize is the maximum number of queries waiting for a data source when a limit is reached.
+	ConcurrentQueryQueueSize int
+	// ConcurrentQueryQueueTimeout is how long a query waits for a data source before it is rejected.
+	ConcurrentQueryQueueTimeout time.Duration
+	// ConcurrentQueryExecutionTimeout is how long a query holding a slot of a limited data source can run before it is canceled and its slot released. 0 means unlimited.
+	ConcurrentQueryExecutionTimeout time.Duration
+}
+
+func readQuerySettings(iniFile *ini.File) QuerySettings {
+	s := QuerySettings{}
+
+	section := iniFile.Section("query")
+	s.CoalesceRequests = section.Key("coalesce_requests").MustBool(false)
+	s.ConcurrentQueryLimitPerDataSource = section.Key("concurrent_query_limit_per_datasource").MustInt(0)
+	s.ConcurrentQueryLimitPerOrg = section.Key("concurrent_query_limit_per_org").MustInt(0)
+	s.ConcurrentQueryQueueSize = section.Key("concurrent_query_queue_size").MustInt(100)
+	s.ConcurrentQueryQueueTimeout = section.Key("concurrent_query_queue_timeout").MustDuration(30 * time.Second)
+	s.ConcurrentQueryExecutionTimeout = section.Key("concurrent_query_execution_timeout").MustDuration(0)
+	if s.ConcurrentQueryExecutionTimeout < 0 {
+		s.ConcurrentQueryExecutionTimeout = 0
+	}
+	return s
+}
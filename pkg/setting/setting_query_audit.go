@@ -0,0 +1,68 @@
+package setting
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type QueryAuditSettings struct {
+	// Enabled records the data source queries and data source proxy calls of users.
+	Enabled bool
+	// Sinks are the destinations of the audit events: database, file and/or syslog.
+	Sinks []string
+	// QueryText is how the text of queries is recorded: full, redacted or none.
+	QueryText string
+	// RedactPatterns are the regular expressions replaced in the text of queries when QueryText is redacted.
+	RedactPatterns []string
+	// MaxQueryLength is the maximum length of the recorded text of a query, longer texts are truncated.
+	MaxQueryLength int
+	// BufferSize is the number of events waiting to be written, further events are dropped.
+	BufferSize int
+
+	// Retention is how long events are kept in the database.
+	Retention time.Duration
+	// FilePath is the file the events are appended to as JSON lines.
+	FilePath string
+	// SyslogNetwork and SyslogAddress are the syslog server the events are sent to, a local syslog socket is used
+	// when they are empty.
+	SyslogNetwork  string
+	SyslogAddress  string
+	SyslogFacility string
+	SyslogTag      string
+}
+
+// defaultRedactPatterns redacts single quoted string literals and credentials passed as key/value pairs.
+var defaultRedactPatterns = []string{
+	`'(?:[^'\\]|\\.)*'`,
+	`(?i)(?:password|passwd|pwd|token|secret|api_?key)"?\s*[=:]\s*"?[^\s&,;"]+`,
+}
+
+func readQueryAuditSettings(iniFile *ini.File) QueryAuditSettings {
+	s := QueryAuditSettings{}
+
+	section := iniFile.Section("query_audit")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.Sinks = util.SplitString(section.Key("sinks").MustString("database"))
+	s.QueryText = strings.ToLower(section.Key("query_text").In("redacted", []string{"full", "redacted", "none"}))
+	s.RedactPatterns = defaultRedactPatterns
+	if patterns := strings.Fields(section.Key("redact_patterns").String()); len(patterns) > 0 {
+		s.RedactPatterns = patterns
+	}
+	s.MaxQueryLength = section.Key("max_query_length").MustInt(10000)
+	s.BufferSize = section.Key("buffer_size").MustInt(10000)
+	if s.BufferSize <= 0 {
+		s.BufferSize = 10000
+	}
+
+	s.Retention = section.Key("retention").MustDuration(30 * 24 * time.Hour)
+	s.FilePath = section.Key("file_path").String()
+	s.SyslogNetwork = section.Key("syslog_network").String()
+	s.SyslogAddress = section.Key("syslog_address").String()
+	s.SyslogFacility = section.Key("syslog_facility").MustString("local0")
+	s.SyslogTag = section.Key("syslog_tag").MustString("grafana-query-audit")
+	return s
+}
@@ -0,0 +1,29 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+type QueryCachingSettings struct {
+	// Enabled allows data sources to opt in to caching of query results.
+	Enabled bool
+	// TTL is the default time to live of a cached query result.
+	TTL time.Duration
+	// MaxTTL is the upper limit of the time to live that can be configured by a data source.
+	MaxTTL time.Duration
+}
+
+func readQueryCachingSettings(iniFile *ini.File) QueryCachingSettings {
+	s := QueryCachingSettings{}
+
+	section := iniFile.Section("query_caching")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.TTL = section.Key("ttl").MustDuration(time.Minute)
+	s.MaxTTL = section.Key("max_ttl").MustDuration(time.Hour)
+	if s.TTL > s.MaxTTL {
+		s.TTL = s.MaxTTL
+	}
+	return s
+}
@@ -744,3 +744,25 @@ func TestAlertingEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestDataSourceHealthCheckSettings(t *testing.T) {
+	for _, tc := range []struct {
+		timeout  string
+		expected time.Duration
+	}{
+		{timeout: "10s", expected: 10 * time.Second},
+		{timeout: "0", expected: 30 * time.Second},
+		{timeout: "-5s", expected: 30 * time.Second},
+	} {
+		f := ini.Empty()
+		sec, err := f.NewSection("datasources")
+		require.NoError(t, err)
+		_, err = sec.NewKey("health_check_timeout", tc.timeout)
+		require.NoError(t, err)
+
+		cfg := NewCfg()
+		cfg.Raw = f
+		cfg.readDataSourcesSettings()
+		require.Equal(t, tc.expected, cfg.DataSourceHealthCheckTimeout, tc.timeout)
+	}
+}
@@ -0,0 +1,44 @@
+package setting
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+)
+
+type TokenExpirySettings struct {
+	// Enabled notifies the org admins of the API keys and service account tokens which expire soon or are unused.
+	Enabled bool
+	// CheckInterval is the interval between two notifications.
+	CheckInterval time.Duration
+	// NotifyBefore is how long before their expiration tokens are reported.
+	NotifyBefore time.Duration
+	// StaleAfter is how long tokens can stay unused before they are reported.
+	StaleAfter time.Duration
+	// RevokeStale revokes the tokens which are unused for longer than StaleAfter.
+	RevokeStale bool
+}
+
+func readTokenExpirySettings(iniFile *ini.File) (TokenExpirySettings, error) {
+	s := TokenExpirySettings{}
+
+	section := iniFile.Section("auth.token_expiry")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.RevokeStale = section.Key("revoke_stale").MustBool(false)
+
+	var err error
+	if s.CheckInterval, err = gtime.ParseDuration(valueAsString(section, "check_interval", "1d")); err != nil {
+		return s, err
+	}
+	if s.NotifyBefore, err = gtime.ParseDuration(valueAsString(section, "notify_before", "7d")); err != nil {
+		return s, err
+	}
+	if s.StaleAfter, err = gtime.ParseDuration(valueAsString(section, "stale_after", "90d")); err != nil {
+		return s, err
+	}
+	if s.CheckInterval < time.Hour {
+		s.CheckInterval = time.Hour
+	}
+	return s, nil
+}
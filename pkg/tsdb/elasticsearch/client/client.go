@@ -32,6 +32,14 @@ type DatasourceInfo struct {
 	MaxConcurrentShardRequests int64
 	IncludeFrozen              bool
 	XPack                      bool
+	ConfiguredFields           ConfiguredFields
+}
+
+// ConfiguredFields are the fields of the documents configured in the data source settings
+type ConfiguredFields struct {
+	TimeField       string
+	LogMessageField string
+	LogLevelField   string
 }
 
 const loggerName = "tsdb.elasticsearch.client"
@@ -43,6 +51,7 @@ var (
 // Client represents a client which can interact with elasticsearch api
 type Client interface {
 	GetTimeField() string
+	GetConfiguredFields() ConfiguredFields
 	GetMinInterval(queryInterval string) (time.Duration, error)
 	ExecuteMultisearch(r *MultiSearchRequest) (*MultiSearchResponse, error)
 	MultiSearch() *MultiSearchRequestBuilder
@@ -85,6 +94,12 @@ func (c *baseClientImpl) GetTimeField() string {
 	return c.timeField
 }
 
+func (c *baseClientImpl) GetConfiguredFields() ConfiguredFields {
+	fields := c.ds.ConfiguredFields
+	fields.TimeField = c.timeField
+	return fields
+}
+
 func (c *baseClientImpl) GetMinInterval(queryInterval string) (time.Duration, error) {
 	timeInterval := c.ds.TimeInterval
 	return intervalv2.GetIntervalFrom(queryInterval, timeInterval, 0, 5*time.Second)
@@ -3,6 +3,7 @@ package es
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -107,6 +108,27 @@ func TestClient_ExecuteMultisearch(t *testing.T) {
 
 		assert.Equal(t, 200, res.Status)
 		require.Len(t, res.Responses, 1)
+		require.NotNil(t, res.Responses[0].Hits.Total)
+		assert.Equal(t, int64(4656), res.Responses[0].Hits.Total.Value)
+		assert.Equal(t, "eq", res.Responses[0].Hits.Total.Relation)
+	})
+}
+
+func TestSearchResponseHitsTotal(t *testing.T) {
+	t.Run("Should decode the total as an object", func(t *testing.T) {
+		var hits SearchResponseHits
+		err := json.Unmarshal([]byte(`{ "hits": [], "total": { "value": 10000, "relation": "gte" } }`), &hits)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10000), hits.Total.Value)
+		assert.Equal(t, "gte", hits.Total.Relation)
+	})
+
+	t.Run("Should decode the total as a number", func(t *testing.T) {
+		var hits SearchResponseHits
+		err := json.Unmarshal([]byte(`{ "hits": [], "total": 42 }`), &hits)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), hits.Total.Value)
+		assert.Equal(t, "eq", hits.Total.Relation)
 	})
 }
 
@@ -35,9 +35,12 @@ type SearchRequest struct {
 	Interval    intervalv2.Interval
 	Size        int
 	Sort        map[string]interface{}
+	SearchAfter []interface{}
 	Query       *Query
 	Aggs        AggArray
 	CustomProps map[string]interface{}
+
+	sortFields []string
 }
 
 // MarshalJSON returns the JSON encoding of the request.
@@ -48,6 +51,18 @@ func (r *SearchRequest) MarshalJSON() ([]byte, error) {
 	if len(r.Sort) > 0 {
 		root["sort"] = r.Sort
 	}
+	// several sorts must be sent as an array to keep their order
+	if len(r.sortFields) > 1 {
+		sorts := make([]map[string]interface{}, 0, len(r.sortFields))
+		for _, field := range r.sortFields {
+			sorts = append(sorts, map[string]interface{}{field: r.Sort[field]})
+		}
+		root["sort"] = sorts
+	}
+
+	if len(r.SearchAfter) > 0 {
+		root["search_after"] = r.SearchAfter
+	}
 
 	for key, value := range r.CustomProps {
 		root[key] = value
@@ -64,7 +79,27 @@ func (r *SearchRequest) MarshalJSON() ([]byte, error) {
 
 // SearchResponseHits represents search response hits
 type SearchResponseHits struct {
-	Hits []map[string]interface{}
+	Hits  []map[string]interface{}
+	Total *SearchResponseHitsTotal `json:"total"`
+}
+
+// SearchResponseHitsTotal represents the total number of hits matching a search request
+type SearchResponseHitsTotal struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
+}
+
+// UnmarshalJSON decodes the total number of hits, which is a number when rest_total_hits_as_int is set.
+func (t *SearchResponseHitsTotal) UnmarshalJSON(b []byte) error {
+	var value int64
+	if err := json.Unmarshal(b, &value); err == nil {
+		t.Value = value
+		t.Relation = "eq"
+		return nil
+	}
+
+	type total SearchResponseHitsTotal
+	return json.Unmarshal(b, (*total)(t))
 }
 
 // SearchResponse represents a search response
@@ -12,6 +12,8 @@ type SearchRequestBuilder struct {
 	index        string
 	size         int
 	sort         map[string]interface{}
+	sortFields   []string
+	searchAfter  []interface{}
 	queryBuilder *QueryBuilder
 	aggBuilders  []AggBuilder
 	customProps  map[string]interface{}
@@ -35,7 +37,9 @@ func (b *SearchRequestBuilder) Build() (*SearchRequest, error) {
 		Interval:    b.interval,
 		Size:        b.size,
 		Sort:        b.sort,
+		SearchAfter: b.searchAfter,
 		CustomProps: b.customProps,
+		sortFields:  b.sortFields,
 	}
 
 	if b.queryBuilder != nil {
@@ -67,21 +71,43 @@ func (b *SearchRequestBuilder) Size(size int) *SearchRequestBuilder {
 	return b
 }
 
-// SortDesc adds a sort to the search request
-func (b *SearchRequestBuilder) SortDesc(field, unmappedType string) *SearchRequestBuilder {
+// SortOrder is the order of a sort of a search request
+type SortOrder string
+
+const (
+	SortOrderAsc  SortOrder = "asc"
+	SortOrderDesc SortOrder = "desc"
+)
+
+// Sort adds a sort to the search request, the sorts are applied in the order they are added
+func (b *SearchRequestBuilder) Sort(order SortOrder, field, unmappedType string) *SearchRequestBuilder {
 	props := map[string]string{
-		"order": "desc",
+		"order": string(order),
 	}
 
 	if unmappedType != "" {
 		props["unmapped_type"] = unmappedType
 	}
 
+	if _, exists := b.sort[field]; !exists {
+		b.sortFields = append(b.sortFields, field)
+	}
 	b.sort[field] = props
 
 	return b
 }
 
+// SortDesc adds a descending sort to the search request
+func (b *SearchRequestBuilder) SortDesc(field, unmappedType string) *SearchRequestBuilder {
+	return b.Sort(SortOrderDesc, field, unmappedType)
+}
+
+// SearchAfter sets the sort values of the last hit of the previous page, to request the next page of hits
+func (b *SearchRequestBuilder) SearchAfter(values []interface{}) *SearchRequestBuilder {
+	b.searchAfter = values
+	return b
+}
+
 // AddDocValueField adds a doc value field to the search request
 func (b *SearchRequestBuilder) AddDocValueField(field string) *SearchRequestBuilder {
 	b.customProps["docvalue_fields"] = []string{field}
@@ -91,6 +117,21 @@ func (b *SearchRequestBuilder) AddDocValueField(field string) *SearchRequestBuil
 	return b
 }
 
+// TimeDocValueFormat is the format of the date doc values requested by AddTimeDocValueField, which keeps the
+// nanoseconds of date_nanos fields
+const TimeDocValueFormat = "strict_date_optional_time_nanos"
+
+// AddTimeDocValueField adds a date doc value field to the search request, in the TimeDocValueFormat format
+func (b *SearchRequestBuilder) AddTimeDocValueField(field string) *SearchRequestBuilder {
+	b.customProps["docvalue_fields"] = []interface{}{
+		map[string]string{"field": field, "format": TimeDocValueFormat},
+	}
+
+	b.customProps["script_fields"] = make(map[string]interface{})
+
+	return b
+}
+
 // Query creates and return a query builder
 func (b *SearchRequestBuilder) Query() *QueryBuilder {
 	if b.queryBuilder == nil {
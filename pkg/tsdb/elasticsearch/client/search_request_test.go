@@ -104,6 +104,31 @@ func TestSearchRequest(t *testing.T) {
 		})
 	})
 
+	t.Run("When adding several sorts and search after", func(t *testing.T) {
+		b := setup()
+		b.Sort(SortOrderAsc, timeField, "boolean")
+		b.Sort(SortOrderAsc, "_doc", "")
+		b.SearchAfter([]interface{}{1000, 42})
+
+		sr, err := b.Build()
+		require.Nil(t, err)
+
+		t.Run("When marshal to JSON should generate sorts in order", func(t *testing.T) {
+			body, err := json.Marshal(sr)
+			require.Nil(t, err)
+			json, err := simplejson.NewJson(body)
+			require.Nil(t, err)
+
+			sorts := json.Get("sort").MustArray()
+			require.Len(t, sorts, 2)
+			require.Equal(t, "asc", json.Get("sort").GetIndex(0).GetPath(timeField, "order").MustString())
+			require.Equal(t, "boolean", json.Get("sort").GetIndex(0).GetPath(timeField, "unmapped_type").MustString())
+			require.Equal(t, "asc", json.Get("sort").GetIndex(1).GetPath("_doc", "order").MustString())
+			require.Equal(t, int64(1000), json.Get("search_after").GetIndex(0).MustInt64())
+			require.Equal(t, int64(42), json.Get("search_after").GetIndex(1).MustInt64())
+		})
+	})
+
 	t.Run("When adding doc value field", func(t *testing.T) {
 		b := setup()
 		b.AddDocValueField(timeField)
@@ -146,6 +171,21 @@ func TestSearchRequest(t *testing.T) {
 		})
 	})
 
+	t.Run("When adding time doc value field", func(t *testing.T) {
+		b := setup()
+		b.AddTimeDocValueField(timeField)
+
+		sr, err := b.Build()
+		require.Nil(t, err)
+		body, err := json.Marshal(sr)
+		require.Nil(t, err)
+		json, err := simplejson.NewJson(body)
+		require.Nil(t, err)
+
+		require.Equal(t, timeField, json.Get("docvalue_fields").GetIndex(0).Get("field").MustString())
+		require.Equal(t, "strict_date_optional_time_nanos", json.Get("docvalue_fields").GetIndex(0).Get("format").MustString())
+	})
+
 	t.Run("and adding multiple top level aggs", func(t *testing.T) {
 		b := setup()
 		aggBuilder := b.Agg()
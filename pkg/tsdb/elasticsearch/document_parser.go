@@ -0,0 +1,205 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	es "github.com/grafana/grafana/pkg/tsdb/elasticsearch/client"
+)
+
+// logLevelFieldName is the name of the field used by the logs visualization to find the level of a log line.
+const logLevelFieldName = "level"
+
+// processDocuments converts the hits of a raw data, raw document or logs query to a data frame.
+func (rp *responseParser) processDocuments(res *es.SearchResponse, target *Query, debugInfo *simplejson.Json) backend.DataResponse {
+	timeField := rp.ConfiguredFields.TimeField
+	if timeField == "" {
+		timeField = target.TimeField
+	}
+
+	var hits []map[string]interface{}
+	custom := map[string]interface{}{}
+	if res.Hits != nil {
+		hits = res.Hits.Hits
+		if res.Hits.Total != nil {
+			custom["total"] = res.Hits.Total.Value
+		}
+	}
+	if searchAfter := searchAfterOf(hits); len(searchAfter) > 0 {
+		custom["searchAfter"] = searchAfter
+	}
+	if debugInfo != nil {
+		for k, v := range debugInfo.MustMap() {
+			custom[k] = v
+		}
+	}
+
+	var frame *data.Frame
+	switch target.Metrics[0].Type {
+	case rawDocumentType:
+		frame = rawDocumentFrame(target.RefID, hits, timeField)
+	case logsType:
+		frame = documentsFrame(target.RefID, hits, timeField, rp.ConfiguredFields.LogMessageField, rp.ConfiguredFields.LogLevelField)
+		frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeLogs}
+	default:
+		frame = documentsFrame(target.RefID, hits, timeField, "", "")
+		frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	}
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.Custom = custom
+
+	return backend.DataResponse{Frames: data.Frames{frame}}
+}
+
+// rawDocumentFrame returns a frame with a single field holding the documents as JSON.
+func rawDocumentFrame(refID string, hits []map[string]interface{}, timeField string) *data.Frame {
+	values := make([]*json.RawMessage, 0, len(hits))
+	for _, hit := range hits {
+		doc := map[string]interface{}{}
+		if source, ok := hit["_source"].(map[string]interface{}); ok {
+			for k, v := range source {
+				doc[k] = v
+			}
+		}
+		for k, v := range documentOf(map[string]interface{}{"_id": hit["_id"], "_type": hit["_type"], "_index": hit["_index"], "fields": hit["fields"]}, timeField) {
+			doc[k] = v
+		}
+		doc["sort"] = hit["sort"]
+
+		b, err := json.Marshal(doc)
+		if err != nil {
+			values = append(values, nil)
+			continue
+		}
+		raw := json.RawMessage(b)
+		values = append(values, &raw)
+	}
+	return data.NewFrame(refID, data.NewField(refID, nil, values))
+}
+
+// documentsFrame returns a frame with a field for each property of the documents, the time field first.
+// The message field is second when it is set, and the level field is copied to a `level` field.
+func documentsFrame(refID string, hits []map[string]interface{}, timeField, messageField, levelField string) *data.Frame {
+	docs := make([]map[string]interface{}, 0, len(hits))
+	names := map[string]bool{}
+	for _, hit := range hits {
+		doc := documentOf(hit, timeField)
+		for name := range doc {
+			names[name] = true
+		}
+		docs = append(docs, doc)
+	}
+
+	var ordered []string
+	for name := range names {
+		if name != timeField && name != messageField {
+			ordered = append(ordered, name)
+		}
+	}
+	sort.Strings(ordered)
+	if messageField != "" && names[messageField] {
+		ordered = append([]string{messageField}, ordered...)
+	}
+
+	fields := []*data.Field{newTimeField(timeField, docs)}
+	for _, name := range ordered {
+		fields = append(fields, newDocumentField(name, docs))
+	}
+	if levelField != "" && levelField != logLevelFieldName && names[levelField] && !names[logLevelFieldName] {
+		level := newDocumentField(levelField, docs)
+		level.Name = logLevelFieldName
+		fields = append(fields, level)
+	}
+
+	return data.NewFrame(refID, fields...)
+}
+
+// newTimeField returns the time field of the documents. The time is requested as a strict_date_optional_time_nanos
+// date string, epoch milliseconds are parsed as well.
+func newTimeField(name string, docs []map[string]interface{}) *data.Field {
+	values := make([]*time.Time, len(docs))
+	for i, doc := range docs {
+		switch v := doc[name].(type) {
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				values[i] = &t
+			}
+		case float64:
+			t := time.UnixMilli(int64(v)).UTC()
+			values[i] = &t
+		}
+	}
+	return data.NewField(name, nil, values)
+}
+
+// newDocumentField returns a field for a property of the documents. The type of the field is the type of the
+// values if all the values have the same type, nested objects and values of different types are kept as JSON.
+func newDocumentField(name string, docs []map[string]interface{}) *data.Field {
+	fieldType := data.FieldTypeUnknown
+	for _, doc := range docs {
+		var t data.FieldType
+		switch doc[name].(type) {
+		case nil:
+			continue
+		case float64:
+			t = data.FieldTypeNullableFloat64
+		case bool:
+			t = data.FieldTypeNullableBool
+		case string:
+			t = data.FieldTypeNullableString
+		default:
+			t = data.FieldTypeNullableJSON
+		}
+		if fieldType == data.FieldTypeUnknown {
+			fieldType = t
+		} else if fieldType != t {
+			fieldType = data.FieldTypeNullableJSON
+			break
+		}
+	}
+
+	switch fieldType {
+	case data.FieldTypeNullableFloat64:
+		values := make([]*float64, len(docs))
+		for i, doc := range docs {
+			if v, ok := doc[name].(float64); ok {
+				values[i] = &v
+			}
+		}
+		return data.NewField(name, nil, values)
+	case data.FieldTypeNullableBool:
+		values := make([]*bool, len(docs))
+		for i, doc := range docs {
+			if v, ok := doc[name].(bool); ok {
+				values[i] = &v
+			}
+		}
+		return data.NewField(name, nil, values)
+	case data.FieldTypeNullableJSON:
+		values := make([]*json.RawMessage, len(docs))
+		for i, doc := range docs {
+			if doc[name] == nil {
+				continue
+			}
+			if b, err := json.Marshal(doc[name]); err == nil {
+				raw := json.RawMessage(b)
+				values[i] = &raw
+			}
+		}
+		return data.NewField(name, nil, values)
+	default:
+		values := make([]*string, len(docs))
+		for i, doc := range docs {
+			if v, ok := doc[name].(string); ok {
+				values[i] = &v
+			}
+		}
+		return data.NewField(name, nil, values)
+	}
+}
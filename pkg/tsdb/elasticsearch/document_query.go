@@ -0,0 +1,191 @@
+package elasticsearch
+
+import (
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	es "github.com/grafana/grafana/pkg/tsdb/elasticsearch/client"
+)
+
+const (
+	// defaultDocumentsSize is the number of documents returned by raw data, raw document and logs queries
+	// which do not set a size.
+	defaultDocumentsSize = 500
+	// maxDocumentsPerRequest is the default maximum number of hits of a search request (index.max_result_window),
+	// more documents are fetched in pages with search_after.
+	maxDocumentsPerRequest = 10000
+)
+
+// isDocumentQuery returns true if the query returns documents instead of aggregations.
+func isDocumentQuery(q *Query) bool {
+	if len(q.Metrics) == 0 {
+		return false
+	}
+	switch q.Metrics[0].Type {
+	case rawDataType, rawDocumentType, logsType:
+		return true
+	default:
+		return false
+	}
+}
+
+// documentsSize returns the number of documents requested by a raw data, raw document or logs query.
+func documentsSize(q *Query) int {
+	metric := q.Metrics[0]
+	key := "size"
+	if metric.Type == logsType {
+		key = "limit"
+	}
+
+	size := defaultDocumentsSize
+	if v, err := metric.Settings.Get(key).Int(); err == nil {
+		size = v
+	} else if v, err := metric.Settings.Get(key).String(); err == nil {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			size = parsed
+		}
+	}
+	if size <= 0 {
+		size = defaultDocumentsSize
+	}
+	return size
+}
+
+func pageSize(size int) int {
+	if size > maxDocumentsPerRequest {
+		return maxDocumentsPerRequest
+	}
+	return size
+}
+
+// processDocumentQuery requests a page of documents sorted by time. The documents are sorted by their position
+// in the shard as well, so that the documents of a page are stable when they have the same time.
+func (e *timeSeriesQuery) processDocumentQuery(q *Query, b *es.SearchRequestBuilder, searchAfter []interface{}, size int) {
+	order := es.SortOrderDesc
+	if q.Metrics[0].Settings.Get("sortDirection").MustString() == "asc" {
+		order = es.SortOrderAsc
+	}
+
+	timeField := e.client.GetTimeField()
+	b.Size(size)
+	b.Sort(order, timeField, "boolean")
+	b.Sort(order, "_doc", "")
+	b.AddTimeDocValueField(timeField)
+	if len(searchAfter) > 0 {
+		b.SearchAfter(searchAfter)
+	}
+}
+
+// fetchDocumentPages fetches the next pages of the document queries which requested more documents than a single
+// search request returns, and appends their hits to the hits of the first page.
+func (e *timeSeriesQuery) fetchDocumentPages(queries []*Query, responses []*es.SearchResponse, from, to int64) error {
+	done := make(map[int]bool)
+
+	for {
+		ms := e.client.MultiSearch()
+		var pending []int
+
+		for i, q := range queries {
+			if done[i] || i >= len(responses) || !isDocumentQuery(q) {
+				continue
+			}
+			res := responses[i]
+			if res.Error != nil || res.Hits == nil || len(res.Hits.Hits) == 0 {
+				continue
+			}
+
+			fetched := len(res.Hits.Hits)
+			size := documentsSize(q)
+			// a page smaller than the maximum is the last page
+			if fetched >= size || fetched%maxDocumentsPerRequest != 0 {
+				continue
+			}
+			searchAfter := searchAfterOf(res.Hits.Hits)
+			if len(searchAfter) == 0 {
+				continue
+			}
+
+			b, err := e.newSearch(q, ms, from, to)
+			if err != nil {
+				return err
+			}
+			e.processDocumentQuery(q, b, searchAfter, pageSize(size-fetched))
+			pending = append(pending, i)
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		req, err := ms.Build()
+		if err != nil {
+			return err
+		}
+		res, err := e.client.ExecuteMultisearch(req)
+		if err != nil {
+			return err
+		}
+
+		for j, i := range pending {
+			if j >= len(res.Responses) || res.Responses[j].Error != nil || res.Responses[j].Hits == nil ||
+				len(res.Responses[j].Hits.Hits) == 0 {
+				done[i] = true
+				continue
+			}
+			responses[i].Hits.Hits = append(responses[i].Hits.Hits, res.Responses[j].Hits.Hits...)
+		}
+	}
+}
+
+// searchAfterOf returns the sort values of the last hit, to request the next page of documents.
+func searchAfterOf(hits []map[string]interface{}) []interface{} {
+	if len(hits) == 0 {
+		return nil
+	}
+	values, _ := hits[len(hits)-1]["sort"].([]interface{})
+	return values
+}
+
+// flatten returns the properties of a document, with the properties of nested objects prefixed by the name of
+// the object, for example `{"a": {"b": 1}}` becomes `{"a.b": 1}`. Objects nested deeper than maxDepth are kept.
+func flatten(target map[string]interface{}, maxDepth int) map[string]interface{} {
+	res := make(map[string]interface{})
+	var step func(obj map[string]interface{}, prefix string, depth int)
+	step = func(obj map[string]interface{}, prefix string, depth int) {
+		for key, value := range obj {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 && depth < maxDepth {
+				step(nested, name, depth+1)
+				continue
+			}
+			res[name] = value
+		}
+	}
+	step(target, "", 1)
+	return res
+}
+
+// documentOf returns the flattened properties of a hit, and the metadata of the hit prefixed by an underscore.
+func documentOf(hit map[string]interface{}, timeField string) map[string]interface{} {
+	doc := map[string]interface{}{
+		"_id":    hit["_id"],
+		"_type":  hit["_type"],
+		"_index": hit["_index"],
+	}
+	if source, ok := hit["_source"].(map[string]interface{}); ok {
+		for k, v := range flatten(source, 10) {
+			doc[k] = v
+		}
+	}
+
+	// the time field is taken from the doc values, which have the same format for all documents
+	if fields, ok := hit["fields"].(map[string]interface{}); ok {
+		if values := simplejson.NewFromAny(fields).Get(timeField).MustArray(); len(values) > 0 {
+			doc[timeField] = values[0]
+		}
+	}
+	return doc
+}
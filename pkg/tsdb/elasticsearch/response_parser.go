@@ -20,6 +20,9 @@ const (
 	percentilesType   = "percentiles"
 	extendedStatsType = "extended_stats"
 	topMetricsType    = "top_metrics"
+	rawDataType       = "raw_data"
+	rawDocumentType   = "raw_document"
+	logsType          = "logs"
 	// Bucket types
 	dateHistType    = "date_histogram"
 	histogramType   = "histogram"
@@ -29,16 +32,19 @@ const (
 )
 
 type responseParser struct {
-	Responses []*es.SearchResponse
-	Targets   []*Query
-	DebugInfo *es.SearchDebugInfo
+	Responses        []*es.SearchResponse
+	Targets          []*Query
+	DebugInfo        *es.SearchDebugInfo
+	ConfiguredFields es.ConfiguredFields
 }
 
-var newResponseParser = func(responses []*es.SearchResponse, targets []*Query, debugInfo *es.SearchDebugInfo) *responseParser {
+var newResponseParser = func(responses []*es.SearchResponse, targets []*Query, debugInfo *es.SearchDebugInfo,
+	configuredFields es.ConfiguredFields) *responseParser {
 	return &responseParser{
-		Responses: responses,
-		Targets:   targets,
-		DebugInfo: debugInfo,
+		Responses:        responses,
+		Targets:          targets,
+		DebugInfo:        debugInfo,
+		ConfiguredFields: configuredFields,
 	}
 }
 
@@ -72,6 +78,11 @@ func (rp *responseParser) getTimeSeries() (*backend.QueryDataResponse, error) {
 			continue
 		}
 
+		if isDocumentQuery(target) {
+			result.Responses[target.RefID] = rp.processDocuments(res, target, debugInfo)
+			continue
+		}
+
 		queryRes := backend.DataResponse{}
 
 		props := make(map[string]string)
@@ -258,21 +269,8 @@ func (rp *responseParser) processMetrics(esAgg *simplejson.Json, target *Query,
 					key := castToFloat(bucket.Get("key"))
 
 					timeVector = append(timeVector, time.Unix(int64(*key)/1000, 0).UTC())
-
-					for _, stat := range stats.MustArray() {
-						stat := stat.(map[string]interface{})
-
-						metrics, hasMetrics := stat["metrics"]
-						if hasMetrics {
-							metrics := metrics.(map[string]interface{})
-							metricValue, hasMetricValue := metrics[metricField.(string)]
-
-							if hasMetricValue && metricValue != nil {
-								v := metricValue.(float64)
-								values = append(values, &v)
-							}
-						}
-					}
+					// the size of top_metrics is 1, a bucket without documents has no top metrics
+					values = append(values, castToFloat(stats.GetIndex(0).GetPath("metrics", metricField.(string))))
 				}
 
 				frames = append(frames, data.NewFrame("",
@@ -337,18 +335,11 @@ func (rp *responseParser) processMetrics(esAgg *simplejson.Json, target *Query,
 			for _, v := range esAggBuckets {
 				bucket := simplejson.NewFromAny(v)
 				key := castToFloat(bucket.Get("key"))
-				valueObj, err := bucket.Get(metric.ID).Map()
-				if err != nil {
+				if _, err := bucket.Get(metric.ID).Map(); err != nil {
 					continue
 				}
-				var value *float64
-				if _, ok := valueObj["normalized_value"]; ok {
-					value = castToFloat(bucket.GetPath(metric.ID, "normalized_value"))
-				} else {
-					value = castToFloat(bucket.GetPath(metric.ID, "value"))
-				}
 				timeVector = append(timeVector, time.Unix(int64(*key)/1000, 0).UTC())
-				values = append(values, value)
+				values = append(values, metricValue(bucket, metric.ID))
 			}
 			frames = append(frames, data.NewFrame("",
 				data.NewField("time", nil, timeVector),
@@ -469,6 +460,17 @@ func (rp *responseParser) processAggregationDocs(esAgg *simplejson.Json, aggDef
 					addMetricValue(values, rp.getMetricName(metric.Type), value)
 					break
 				}
+			case topMetricsType:
+				baseName := rp.getMetricName(metric.Type)
+				metricFields := metric.Settings.Get("metrics").MustStringArray()
+				for _, metricField := range metricFields {
+					// the name of the metric is added when several metrics are selected
+					metricName := baseName
+					if len(metricFields) > 1 {
+						metricName += " " + metricField
+					}
+					addMetricValue(values, metricName, castToFloat(bucket.GetPath(metric.ID, "top").GetIndex(0).GetPath("metrics", metricField)))
+				}
 			default:
 				metricName := rp.getMetricName(metric.Type)
 				otherMetrics := make([]*MetricAgg, 0)
@@ -487,7 +489,7 @@ func (rp *responseParser) processAggregationDocs(esAgg *simplejson.Json, aggDef
 					}
 				}
 
-				addMetricValue(values, metricName, castToFloat(bucket.GetPath(metric.ID, "value")))
+				addMetricValue(values, metricName, metricValue(bucket, metric.ID))
 			}
 		}
 
@@ -503,6 +505,15 @@ func (rp *responseParser) processAggregationDocs(esAgg *simplejson.Json, aggDef
 	return nil
 }
 
+// metricValue returns the value of a metric of a bucket. Derivatives with a unit have their value normalized
+// to the unit in normalized_value.
+func metricValue(bucket *simplejson.Json, metricID string) *float64 {
+	if normalized, ok := bucket.Get(metricID).CheckGet("normalized_value"); ok {
+		return castToFloat(normalized)
+	}
+	return castToFloat(bucket.GetPath(metricID, "value"))
+}
+
 func extractDataField(name string, v interface{}) *data.Field {
 	switch v.(type) {
 	case *string:
@@ -609,7 +620,7 @@ func (rp *responseParser) getFieldName(dataField data.Field, target *Query, metr
 		return frameName
 	}
 	// todo, if field and pipelineAgg
-	if field != "" && isPipelineAgg(metricType) {
+	if (field != "" || isPipelineAggWithMultipleBucketPaths(metricType)) && isPipelineAgg(metricType) {
 		if isPipelineAggWithMultipleBucketPaths(metricType) {
 			metricID := ""
 			if v, ok := dataField.Labels["metricId"]; ok {
@@ -619,9 +630,20 @@ func (rp *responseParser) getFieldName(dataField data.Field, target *Query, metr
 			for _, metric := range target.Metrics {
 				if metric.ID == metricID {
 					metricName = metric.Settings.Get("script").MustString()
-					for name, pipelineAgg := range metric.PipelineVariables {
+					// longer names first, so that params.var10 isn't replaced by the description of params.var1
+					names := make([]string, 0, len(metric.PipelineVariables))
+					for name := range metric.PipelineVariables {
+						names = append(names, name)
+					}
+					sort.Slice(names, func(i, j int) bool {
+						if len(names[i]) != len(names[j]) {
+							return len(names[i]) > len(names[j])
+						}
+						return names[i] < names[j]
+					})
+					for _, name := range names {
 						for _, m := range target.Metrics {
-							if m.ID == pipelineAgg {
+							if m.ID == metric.PipelineVariables[name] {
 								metricName = strings.ReplaceAll(metricName, "params."+name, describeMetric(m.Type, m.Field))
 							}
 						}
@@ -632,7 +654,7 @@ func (rp *responseParser) getFieldName(dataField data.Field, target *Query, metr
 			found := false
 			for _, metric := range target.Metrics {
 				if metric.ID == field {
-					metricName += " " + describeMetric(metric.Type, field)
+					metricName += " " + describeMetric(metric.Type, metric.Field)
 					found = true
 				}
 			}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	es "github.com/grafana/grafana/pkg/tsdb/elasticsearch/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -969,6 +970,173 @@ func TestResponseParser(t *testing.T) {
 		})
 	})
 
+	t.Run("With derivative with unit", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [
+					{ "id": "1", "type": "sum", "field": "@value", "hide": true },
+					{ "id": "3", "type": "derivative", "field": "1", "settings": { "unit": "1s" } }
+				],
+				"bucketAggs": [{ "type": "date_histogram", "field": "@timestamp", "id": "2" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"aggregations": {
+					"2": {
+						"buckets": [
+							{ "1": { "value": 10 }, "doc_count": 10, "key": 1000 },
+							{ "1": { "value": 70 }, "3": { "value": 60, "normalized_value": 6 }, "doc_count": 10, "key": 11000 }
+						]
+					}
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames := result.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Equal(t, 1, frame.Fields[1].Len())
+		assert.Equal(t, "Derivative Sum @value", frame.Fields[1].Config.DisplayNameFromDS)
+		v, _ := frame.FloatAt(1, 0)
+		assert.Equal(t, 6., v)
+	})
+
+	t.Run("With bucket_script with variables sharing a prefix", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [
+					{ "id": "1", "type": "sum", "field": "@value", "hide": true },
+					{ "id": "3", "type": "max", "field": "@value", "hide": true },
+					{
+						"id": "4",
+						"type": "bucket_script",
+						"pipelineVariables": [{ "name": "var1", "pipelineAgg": "1" }, { "name": "var10", "pipelineAgg": "3" }],
+						"settings": { "script": "params.var1 * params.var10" }
+					}
+				],
+				"bucketAggs": [{ "type": "date_histogram", "field": "@timestamp", "id": "2" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"aggregations": {
+					"2": {
+						"buckets": [
+							{ "1": { "value": 2 }, "3": { "value": 3 }, "4": { "value": 6 }, "doc_count": 60, "key": 1000 }
+						]
+					}
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames := result.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		assert.Equal(t, "Sum @value * Max @value", frames[0].Fields[1].Config.DisplayNameFromDS)
+	})
+
+	t.Run("With top_metrics and a bucket without documents", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [
+					{
+						"type": "top_metrics",
+						"settings": { "order": "desc", "orderBy": "@timestamp", "metrics": ["@value"] },
+						"id": "1"
+					}
+				],
+				"bucketAggs": [{ "type": "date_histogram", "field": "@timestamp", "id": "3" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"aggregations": {
+					"3": {
+						"buckets": [
+							{ "key": 1609459200000, "1": { "top": [] } },
+							{ "key": 1609459210000, "1": { "top": [{ "sort": ["2021-01-01T00:00:10.000Z"], "metrics": { "@value": 1 } }] } }
+						]
+					}
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames := result.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		require.Equal(t, 2, frames[0].Fields[0].Len())
+		require.Equal(t, 2, frames[0].Fields[1].Len())
+		assert.Nil(t, frames[0].Fields[1].At(0))
+		v, _ := frames[0].FloatAt(1, 1)
+		assert.Equal(t, 1., v)
+	})
+
+	t.Run("With top_metrics and derivative without date histogram", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [
+					{
+						"type": "top_metrics",
+						"settings": { "order": "desc", "orderBy": "@timestamp", "metrics": ["@value", "@anotherValue"] },
+						"id": "1"
+					},
+					{ "id": "3", "type": "derivative", "field": "_count", "settings": { "unit": "1s" } }
+				],
+				"bucketAggs": [{ "type": "terms", "field": "host", "id": "2" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"aggregations": {
+					"2": {
+						"buckets": [
+							{
+								"key": "server1",
+								"doc_count": 10,
+								"1": { "top": [{ "sort": ["2021-01-01T00:00:10.000Z"], "metrics": { "@value": 1, "@anotherValue": 2 } }] },
+								"3": { "value": 20, "normalized_value": 2 }
+							}
+						]
+					}
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames := result.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Len(t, frame.Fields, 4)
+		require.Equal(t, "host", frame.Fields[0].Name)
+		require.Equal(t, "Top Metrics @value", frame.Fields[1].Name)
+		require.Equal(t, "Top Metrics @anotherValue", frame.Fields[2].Name)
+		require.Equal(t, "Derivative", frame.Fields[3].Name)
+		v, _ := frame.FloatAt(1, 0)
+		assert.Equal(t, 1., v)
+		v, _ = frame.FloatAt(2, 0)
+		assert.Equal(t, 2., v)
+		v, _ = frame.FloatAt(3, 0)
+		assert.Equal(t, 2., v)
+	})
+
 	t.Run("With top_metrics", func(t *testing.T) {
 		targets := map[string]string{
 			"A": `{
@@ -1061,6 +1229,112 @@ func TestResponseParser(t *testing.T) {
 	})
 }
 
+func TestProcessDocuments(t *testing.T) {
+	response := `{
+		"responses": [
+			{
+				"hits": {
+					"total": { "value": 109, "relation": "eq" },
+					"hits": [
+						{
+							"_id": "1",
+							"_type": "_doc",
+							"_index": "logs-2018.05.15",
+							"_source": { "@timestamp": "2018-05-15T17:50:00Z", "message": "hello", "lvl": "info", "bytes": 10, "ok": true, "host": { "name": "a" }, "tags": ["x"] },
+							"fields": { "@timestamp": ["2018-05-15T17:50:00.123456789Z"] },
+							"sort": [1526406600123, 1]
+						},
+						{
+							"_id": "2",
+							"_type": "_doc",
+							"_index": "logs-2018.05.15",
+							"_source": { "@timestamp": "2018-05-15T17:51:00Z", "message": "world", "lvl": "error", "bytes": "many", "host": { "name": "b" } },
+							"fields": { "@timestamp": ["2018-05-15T17:51:00Z"] },
+							"sort": [1526406660000, 2]
+						}
+					]
+				}
+			}
+		]
+	}`
+
+	t.Run("Raw data query returns a table of the flattened documents", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{ "timeField": "@timestamp", "metrics": [{ "type": "raw_data", "id": "1" }] }`,
+		}
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames := result.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Equal(t, 2, frame.Rows())
+		assert.Equal(t, data.VisTypeTable, string(frame.Meta.PreferredVisualization))
+		assert.Equal(t, int64(109), frame.Meta.Custom.(map[string]interface{})["total"])
+		assert.Equal(t, []interface{}{float64(1526406660000), float64(2)}, frame.Meta.Custom.(map[string]interface{})["searchAfter"])
+
+		names := make([]string, len(frame.Fields))
+		for i, f := range frame.Fields {
+			names[i] = f.Name
+		}
+		require.Equal(t, []string{"@timestamp", "_id", "_index", "_type", "bytes", "host.name", "lvl", "message", "ok", "tags"}, names)
+
+		ts := time.Date(2018, 5, 15, 17, 50, 0, 123456789, time.UTC)
+		assert.Equal(t, data.FieldTypeNullableTime, frame.Fields[0].Type())
+		assert.Equal(t, &ts, frame.Fields[0].At(0))
+		// bytes is a number and a string
+		assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[4].Type())
+		assert.Equal(t, data.FieldTypeNullableString, frame.Fields[5].Type())
+		assert.Equal(t, data.FieldTypeNullableBool, frame.Fields[8].Type())
+		assert.Nil(t, frame.Fields[8].At(1))
+		assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[9].Type())
+	})
+
+	t.Run("Raw document query returns the documents as JSON", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{ "timeField": "@timestamp", "metrics": [{ "type": "raw_document", "id": "1" }] }`,
+		}
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frame := result.Responses["A"].Frames[0]
+		require.Len(t, frame.Fields, 1)
+		require.Equal(t, data.FieldTypeNullableJSON, frame.Fields[0].Type())
+
+		var doc map[string]interface{}
+		err = json.Unmarshal(*frame.Fields[0].At(0).(*json.RawMessage), &doc)
+		require.NoError(t, err)
+		assert.Equal(t, "1", doc["_id"])
+		assert.Equal(t, "2018-05-15T17:50:00.123456789Z", doc["@timestamp"])
+		assert.Equal(t, map[string]interface{}{"name": "a"}, doc["host"])
+	})
+
+	t.Run("Logs query returns the time and message first and the level", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{ "timeField": "@timestamp", "metrics": [{ "type": "logs", "id": "1" }] }`,
+		}
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		rp.ConfiguredFields.LogMessageField = "message"
+		rp.ConfiguredFields.LogLevelField = "lvl"
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frame := result.Responses["A"].Frames[0]
+		assert.Equal(t, data.VisTypeLogs, string(frame.Meta.PreferredVisualization))
+		require.Equal(t, "@timestamp", frame.Fields[0].Name)
+		require.Equal(t, "message", frame.Fields[1].Name)
+
+		level := frame.Fields[len(frame.Fields)-1]
+		require.Equal(t, "level", level.Name)
+		assert.Equal(t, "error", *level.At(1).(*string))
+	})
+}
+
 func newResponseParserForTest(tsdbQueries map[string]string, responseBody string) (*responseParser, error) {
 	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
 	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
@@ -1092,5 +1366,5 @@ func newResponseParserForTest(tsdbQueries map[string]string, responseBody string
 		return nil, err
 	}
 
-	return newResponseParser(response.Responses, queries, nil), nil
+	return newResponseParser(response.Responses, queries, nil, es.ConfiguredFields{TimeField: "@timestamp"}), nil
 }
@@ -57,15 +57,19 @@ func (e *timeSeriesQuery) execute() (*backend.QueryDataResponse, error) {
 		return &backend.QueryDataResponse{}, err
 	}
 
-	rp := newResponseParser(res.Responses, queries, res.DebugInfo)
+	if err := e.fetchDocumentPages(queries, res.Responses, from, to); err != nil {
+		return &backend.QueryDataResponse{}, err
+	}
+
+	rp := newResponseParser(res.Responses, queries, res.DebugInfo, e.client.GetConfiguredFields())
 	return rp.getTimeSeries()
 }
 
-func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilder, from, to int64,
-	result backend.QueryDataResponse) error {
+// newSearch adds a search request for the query, filtered by the time range and the query string of the query.
+func (e *timeSeriesQuery) newSearch(q *Query, ms *es.MultiSearchRequestBuilder, from, to int64) (*es.SearchRequestBuilder, error) {
 	minInterval, err := e.client.GetMinInterval(q.Interval)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	interval := e.intervalCalculator.Calculate(e.dataQueries[0].TimeRange, minInterval, q.MaxDataPoints)
 
@@ -77,18 +81,25 @@ func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilde
 	if q.RawQuery != "" {
 		filters.AddQueryStringFilter(q.RawQuery, true)
 	}
+	return b, nil
+}
+
+func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilder, from, to int64,
+	result backend.QueryDataResponse) error {
+	b, err := e.newSearch(q, ms, from, to)
+	if err != nil {
+		return err
+	}
+
+	if isDocumentQuery(q) {
+		e.processDocumentQuery(q, b, q.Metrics[0].Settings.Get("searchAfter").MustArray(), pageSize(documentsSize(q)))
+		return nil
+	}
 
 	if len(q.BucketAggs) == 0 {
-		if len(q.Metrics) == 0 || q.Metrics[0].Type != "raw_document" {
-			result.Responses[q.RefID] = backend.DataResponse{
-				Error: fmt.Errorf("invalid query, missing metrics and aggregations"),
-			}
-			return nil
+		result.Responses[q.RefID] = backend.DataResponse{
+			Error: fmt.Errorf("invalid query, missing metrics and aggregations"),
 		}
-		metric := q.Metrics[0]
-		b.Size(metric.Settings.Get("size").MustInt(500))
-		b.SortDesc("@timestamp", "boolean")
-		b.AddDocValueField("@timestamp")
 		return nil
 	}
 
@@ -197,6 +208,13 @@ func setIntPath(settings *simplejson.Json, path ...string) {
 	}
 }
 
+// deleteEmptyPath removes an optional setting left empty in the query editor, which Elastic rejects
+func deleteEmptyPath(settings *simplejson.Json, path string) {
+	if stringValue, err := settings.Get(path).String(); err == nil && stringValue == "" {
+		settings.Del(path)
+	}
+}
+
 // Casts values to float when required by Elastic's query DSL
 func (metricAggregation MetricAgg) generateSettingsForDSL() map[string]interface{} {
 	switch metricAggregation.Type {
@@ -207,8 +225,15 @@ func (metricAggregation MetricAgg) generateSettingsForDSL() map[string]interface
 		setFloatPath(metricAggregation.Settings, "settings", "beta")
 		setFloatPath(metricAggregation.Settings, "settings", "gamma")
 		setFloatPath(metricAggregation.Settings, "settings", "period")
+	case "moving_fn":
+		setIntPath(metricAggregation.Settings, "window")
+		setIntPath(metricAggregation.Settings, "shift")
 	case "serial_diff":
 		setFloatPath(metricAggregation.Settings, "lag")
+	case "derivative":
+		deleteEmptyPath(metricAggregation.Settings, "unit")
+	case "cumulative_sum":
+		deleteEmptyPath(metricAggregation.Settings, "format")
 	}
 
 	if isMetricAggregationWithInlineScriptSupport(metricAggregation.Type) {
@@ -465,8 +490,20 @@ func (p *timeSeriesQueryParser) parseMetrics(model *simplejson.Json) ([]*MetricA
 			metric.PipelineVariables = map[string]string{}
 			pvArr := metricJSON.Get("pipelineVariables").MustArray()
 			for _, v := range pvArr {
-				kv := v.(map[string]interface{})
-				metric.PipelineVariables[kv["name"].(string)] = kv["pipelineAgg"].(string)
+				kv := simplejson.NewFromAny(v)
+				name, pipelineAgg := kv.Get("name").MustString(), kv.Get("pipelineAgg").MustString()
+				if name != "" && pipelineAgg != "" {
+					metric.PipelineVariables[name] = pipelineAgg
+				}
+			}
+		} else if isPipelineAgg(metric.Type) {
+			// the metric used by a pipeline aggregation is stored in the field of the aggregation by the query
+			// editor, and in pipelineAgg by older versions of the query editor
+			if metric.PipelineAggregate == "" {
+				metric.PipelineAggregate = metric.Field
+			}
+			if metric.Field == "" {
+				metric.Field = metric.PipelineAggregate
 			}
 		}
 
@@ -303,6 +303,55 @@ func TestExecuteTimeSeriesQuery(t *testing.T) {
 			require.Equal(t, sr.Size, 1337)
 		})
 
+		t.Run("With logs metric sorted by time and position", func(t *testing.T) {
+			c := newFakeClient()
+			_, err := executeTsdbQuery(c, `{
+				"timeField": "@timestamp",
+				"bucketAggs": [],
+				"metrics": [{ "id": "1", "type": "logs", "settings": { "limit": "100", "sortDirection": "asc", "searchAfter": [1526406600000, 3] } }]
+			}`, from, to, 15*time.Second)
+			require.NoError(t, err)
+			sr := c.multisearchRequests[0].Requests[0]
+
+			require.Equal(t, 100, sr.Size)
+			require.Equal(t, map[string]string{"order": "asc", "unmapped_type": "boolean"}, sr.Sort["@timestamp"])
+			require.Equal(t, map[string]string{"order": "asc"}, sr.Sort["_doc"])
+			require.Equal(t, []interface{}{json.Number("1526406600000"), json.Number("3")}, sr.SearchAfter)
+			require.Equal(t, []interface{}{map[string]string{"field": "@timestamp", "format": "strict_date_optional_time_nanos"}}, sr.CustomProps["docvalue_fields"])
+		})
+
+		t.Run("With raw data metric larger than a page", func(t *testing.T) {
+			hits := func(n int, first int) []map[string]interface{} {
+				res := make([]map[string]interface{}, n)
+				for i := range res {
+					res[i] = map[string]interface{}{"_id": first + i, "sort": []interface{}{float64(first + i)}}
+				}
+				return res
+			}
+			c := newFakeClient()
+			c.multiSearchResponses = []*es.MultiSearchResponse{
+				{Responses: []*es.SearchResponse{{Hits: &es.SearchResponseHits{Hits: hits(10000, 0)}}}},
+				{Responses: []*es.SearchResponse{{Hits: &es.SearchResponseHits{Hits: hits(2000, 10000)}}}},
+			}
+			res, err := executeTsdbQuery(c, `{
+				"timeField": "@timestamp",
+				"bucketAggs": [],
+				"metrics": [{ "id": "1", "type": "raw_data", "settings": { "size": 12000 } }]
+			}`, from, to, 15*time.Second)
+			require.NoError(t, err)
+
+			require.Len(t, c.multisearchRequests, 2)
+			require.Equal(t, 10000, c.multisearchRequests[0].Requests[0].Size)
+			next := c.multisearchRequests[1].Requests[0]
+			require.Equal(t, 2000, next.Size)
+			require.Equal(t, []interface{}{float64(9999)}, next.SearchAfter)
+
+			// the query of executeTsdbQuery has no reference ID
+			frames := res.Responses[""].Frames
+			require.Len(t, frames, 1)
+			require.Equal(t, 12000, frames[0].Rows())
+		})
+
 		t.Run("With date histogram agg", func(t *testing.T) {
 			c := newFakeClient()
 			_, err := executeTsdbQuery(c, `{
@@ -668,6 +717,35 @@ func TestExecuteTimeSeriesQuery(t *testing.T) {
 			require.Equal(t, plAgg.BucketPath, "3")
 		})
 
+		t.Run("With derivative referencing a metric in its field and a unit", func(t *testing.T) {
+			c := newFakeClient()
+			_, err := executeTsdbQuery(c, `{
+				"timeField": "@timestamp",
+				"bucketAggs": [
+					{ "type": "date_histogram", "field": "@timestamp", "id": "4" }
+				],
+				"metrics": [
+					{ "id": "3", "type": "sum", "field": "@value" },
+					{ "id": "2", "type": "derivative", "field": "3", "settings": { "unit": "1s" } },
+					{ "id": "5", "type": "cumulative_sum", "field": "3", "settings": { "format": "" } }
+				]
+			}`, from, to, 15*time.Second)
+			require.NoError(t, err)
+			sr := c.multisearchRequests[0].Requests[0]
+
+			derivativeAgg := sr.Aggs[0].Aggregation.Aggs[1]
+			require.Equal(t, "2", derivativeAgg.Key)
+			plAgg := derivativeAgg.Aggregation.Aggregation.(*es.PipelineAggregation)
+			require.Equal(t, "3", plAgg.BucketPath)
+			require.Equal(t, "1s", plAgg.Settings["unit"])
+
+			cumulativeSumAgg := sr.Aggs[0].Aggregation.Aggs[2]
+			require.Equal(t, "5", cumulativeSumAgg.Key)
+			plAgg = cumulativeSumAgg.Aggregation.Aggregation.(*es.PipelineAggregation)
+			require.Equal(t, "3", plAgg.BucketPath)
+			require.NotContains(t, plAgg.Settings, "format")
+		})
+
 		t.Run("With derivative doc count", func(t *testing.T) {
 			c := newFakeClient()
 			_, err := executeTsdbQuery(c, `{
@@ -792,6 +870,37 @@ func TestExecuteTimeSeriesQuery(t *testing.T) {
 			})
 		})
 
+		t.Run("With bucket_script and incomplete pipeline variables", func(t *testing.T) {
+			c := newFakeClient()
+			_, err := executeTsdbQuery(c, `{
+				"timeField": "@timestamp",
+				"bucketAggs": [
+					{ "type": "date_histogram", "field": "@timestamp", "id": "4" }
+				],
+				"metrics": [
+					{ "id": "1", "type": "sum", "field": "@value" },
+					{ "id": "3", "type": "count" },
+					{
+						"id": "2",
+						"type": "bucket_script",
+						"pipelineVariables": [
+							{ "name": "var1", "pipelineAgg": "1" },
+							{ "name": "var2", "pipelineAgg": "3" },
+							{ "name": "var3" }
+						],
+						"settings": { "script": "params.var1 / params.var2" }
+					}
+				]
+			}`, from, to, 15*time.Second)
+			require.NoError(t, err)
+			sr := c.multisearchRequests[0].Requests[0]
+
+			bucketScriptAgg := sr.Aggs[0].Aggregation.Aggs[1]
+			require.Equal(t, "2", bucketScriptAgg.Key)
+			plAgg := bucketScriptAgg.Aggregation.Aggregation.(*es.PipelineAggregation)
+			require.Equal(t, map[string]interface{}{"var1": "1", "var2": "_count"}, plAgg.BucketPath)
+		})
+
 		t.Run("With bucket_script doc count", func(t *testing.T) {
 			c := newFakeClient()
 			_, err := executeTsdbQuery(c, `{
@@ -904,6 +1013,37 @@ func TestSettingsCasting(t *testing.T) {
 		assert.Equal(t, 1., serialDiffSettings["lag"])
 	})
 
+	t.Run("Correctly transforms moving_fn settings", func(t *testing.T) {
+		c := newFakeClient()
+		_, err := executeTsdbQuery(c, `{
+			"timeField": "@timestamp",
+			"bucketAggs": [
+				{ "type": "date_histogram", "field": "@timestamp", "id": "2" }
+			],
+			"metrics": [
+				{ "id": "1", "type": "avg", "field": "@value" },
+				{
+					"id": "3",
+					"type": "moving_fn",
+					"field": "1",
+					"settings": {
+						"window": "5",
+						"shift": "1",
+						"script": "MovingFunctions.unweightedAvg(values)"
+					}
+				}
+			]
+		}`, from, to, 15*time.Second)
+		assert.Nil(t, err)
+		sr := c.multisearchRequests[0].Requests[0]
+
+		movingFnSettings := sr.Aggs[0].Aggregation.Aggs[1].Aggregation.Aggregation.(*es.PipelineAggregation).Settings
+
+		assert.Equal(t, int64(5), movingFnSettings["window"])
+		assert.Equal(t, int64(1), movingFnSettings["shift"])
+		assert.Equal(t, "MovingFunctions.unweightedAvg(values)", movingFnSettings["script"])
+	})
+
 	t.Run("Date Histogram Settings", func(t *testing.T) {
 		t.Run("Correctly transforms date_histogram settings", func(t *testing.T) {
 			c := newFakeClient()
@@ -1047,9 +1187,11 @@ func TestSettingsCasting(t *testing.T) {
 type fakeClient struct {
 	timeField           string
 	multiSearchResponse *es.MultiSearchResponse
-	multiSearchError    error
-	builder             *es.MultiSearchRequestBuilder
-	multisearchRequests []*es.MultiSearchRequest
+	// multiSearchResponses are returned in order before multiSearchResponse, if any
+	multiSearchResponses []*es.MultiSearchResponse
+	multiSearchError     error
+	builder              *es.MultiSearchRequestBuilder
+	multisearchRequests  []*es.MultiSearchRequest
 }
 
 func newFakeClient() *fakeClient {
@@ -1066,12 +1208,21 @@ func (c *fakeClient) GetTimeField() string {
 	return c.timeField
 }
 
+func (c *fakeClient) GetConfiguredFields() es.ConfiguredFields {
+	return es.ConfiguredFields{TimeField: c.timeField}
+}
+
 func (c *fakeClient) GetMinInterval(queryInterval string) (time.Duration, error) {
 	return 15 * time.Second, nil
 }
 
 func (c *fakeClient) ExecuteMultisearch(r *es.MultiSearchRequest) (*es.MultiSearchResponse, error) {
 	c.multisearchRequests = append(c.multisearchRequests, r)
+	if len(c.multiSearchResponses) > 0 {
+		res := c.multiSearchResponses[0]
+		c.multiSearchResponses = c.multiSearchResponses[1:]
+		return res, c.multiSearchError
+	}
 	return c.multiSearchResponse, c.multiSearchError
 }
 
@@ -19,6 +19,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
@@ -30,9 +31,10 @@ import (
 )
 
 type Service struct {
-	logger log.Logger
-	im     instancemgmt.InstanceManager
-	tracer tracing.Tracer
+	logger          log.Logger
+	im              instancemgmt.InstanceManager
+	tracer          tracing.Tracer
+	resourceHandler backend.CallResourceHandler
 }
 
 const (
@@ -41,11 +43,13 @@ const (
 )
 
 func ProvideService(httpClientProvider httpclient.Provider, tracer tracing.Tracer) *Service {
-	return &Service{
+	s := &Service{
 		logger: log.New("tsdb.graphite"),
 		im:     datasource.NewInstanceManager(newInstanceSettings(httpClientProvider)),
 		tracer: tracer,
 	}
+	s.resourceHandler = httpadapter.New(s.registerRoutes())
+	return s
 }
 
 type datasourceInfo struct {
@@ -85,6 +89,10 @@ func (s *Service) getDSInfo(pluginCtx backend.PluginContext) (*datasourceInfo, e
 	return &instance, nil
 }
 
+func (s *Service) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return s.resourceHandler.CallResource(ctx, req, sender)
+}
+
 func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	if len(req.Queries) == 0 {
 		return nil, fmt.Errorf("query contains no queries")
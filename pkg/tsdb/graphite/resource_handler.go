@@ -0,0 +1,200 @@
+package graphite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+// resourceTimeParams are the parameters limiting the time range of the find and autocomplete endpoints.
+var resourceTimeParams = []string{"from", "until"}
+
+func (s *Service) registerRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics/find", s.handleResourceReq("metrics/find", http.MethodPost, "query"))
+	mux.HandleFunc("/metrics/expand", s.handleResourceReq("metrics/expand", http.MethodGet, "query"))
+	mux.HandleFunc("/tags/autoComplete/tags", s.handleResourceReq("tags/autoComplete/tags", http.MethodGet,
+		"expr", "tagPrefix", "limit"))
+	mux.HandleFunc("/tags/autoComplete/values", s.handleResourceReq("tags/autoComplete/values", http.MethodGet,
+		"expr", "tag", "valuePrefix", "limit"))
+	mux.HandleFunc("/functions", s.handleFunctions)
+	return mux
+}
+
+// handleResourceReq returns a handler calling a Graphite endpoint with the given parameters of the resource request,
+// parameters can be in the query string, in the form or in the JSON body of the request.
+func (s *Service) handleResourceReq(endpoint string, method string, params ...string) http.HandlerFunc {
+	params = append(params, resourceTimeParams...)
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		s.logger.Debug("Received resource call", "url", req.URL.String(), "method", req.Method)
+
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			writeErrorResponse(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", req.Method))
+			return
+		}
+		form, err := parseResourceForm(req)
+		if err != nil {
+			writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
+			return
+		}
+
+		values := url.Values{}
+		for _, param := range params {
+			if v, ok := form[param]; ok {
+				values[param] = v
+			}
+		}
+
+		s.forwardResourceReq(rw, req, endpoint, method, values, nil)
+	}
+}
+
+// parseResourceForm returns the parameters of the query string and of the body of the request. A JSON body is an
+// object whose values are strings, numbers, or arrays of them.
+func parseResourceForm(req *http.Request) (url.Values, error) {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		if err := req.ParseForm(); err != nil {
+			return nil, err
+		}
+		return req.Form, nil
+	}
+
+	form := req.URL.Query()
+	body := map[string]interface{}{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for key, value := range body {
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			switch v := v.(type) {
+			case string:
+				form.Add(key, v)
+			case float64:
+				form.Add(key, strconv.FormatFloat(v, 'f', -1, 64))
+			case bool:
+				form.Add(key, strconv.FormatBool(v))
+			case nil:
+			default:
+				return nil, fmt.Errorf("unsupported value of parameter %q", key)
+			}
+		}
+	}
+	return form, nil
+}
+
+// infinityDefaultRegexp matches the Infinity default values of the parameters in the response of /functions, which
+// Graphite 1.1.7 returns although it isn't valid JSON, see https://github.com/graphite-project/graphite-web/issues/2609
+var infinityDefaultRegexp = regexp.MustCompile(`"default": ?Infinity`)
+
+func (s *Service) handleFunctions(rw http.ResponseWriter, req *http.Request) {
+	s.logger.Debug("Received resource call", "url", req.URL.String(), "method", req.Method)
+
+	if req.Method != http.MethodGet {
+		writeErrorResponse(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", req.Method))
+		return
+	}
+
+	s.forwardResourceReq(rw, req, "functions", http.MethodGet, url.Values{}, func(body []byte) []byte {
+		return infinityDefaultRegexp.ReplaceAll(body, []byte(`"default": 1e9999`))
+	})
+}
+
+// forwardResourceReq calls a Graphite endpoint, and writes its response. The body of a successful response is
+// transformed by fixBody when set.
+func (s *Service) forwardResourceReq(rw http.ResponseWriter, req *http.Request, endpoint string, method string,
+	values url.Values, fixBody func([]byte) []byte) {
+	dsInfo, err := s.getDSInfo(httpadapter.PluginConfigFromContext(req.Context()))
+	if err != nil {
+		writeErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("unexpected error %v", err))
+		return
+	}
+
+	graphiteReq, err := s.createResourceRequest(req.Context(), dsInfo, endpoint, method, values)
+	if err != nil {
+		writeErrorResponse(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := dsInfo.HTTPClient.Do(graphiteReq)
+	if err != nil {
+		s.logger.Warn("Graphite resource request failed", "endpoint", endpoint, "error", err)
+		writeErrorResponse(rw, http.StatusBadGateway, fmt.Sprintf("request to Graphite failed: %v", err))
+		return
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			s.logger.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		writeErrorResponse(rw, http.StatusBadGateway, fmt.Sprintf("failed to read Graphite response: %v", err))
+		return
+	}
+
+	if res.StatusCode/100 != 2 {
+		s.logger.Info("Resource request failed", "endpoint", endpoint, "status", res.Status, "body", string(body))
+		writeErrorResponse(rw, res.StatusCode, fmt.Sprintf("request failed, status: %s", res.Status))
+		return
+	}
+
+	if fixBody != nil {
+		body = fixBody(body)
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(body); err != nil {
+		s.logger.Error("Failed to write response", "error", err)
+	}
+}
+
+func (s *Service) createResourceRequest(ctx context.Context, dsInfo *datasourceInfo, endpoint string, method string,
+	values url.Values) (*http.Request, error) {
+	u, err := url.Parse(dsInfo.URL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, endpoint)
+
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(values.Encode())
+	} else {
+		u.RawQuery = values.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		s.logger.Info("Failed to create request", "error", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req, nil
+}
+
+func writeErrorResponse(rw http.ResponseWriter, statusCode int, msg string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+	b, _ := json.Marshal(map[string]string{"message": msg})
+	_, _ = rw.Write(b)
+}
@@ -0,0 +1,138 @@
+package graphite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type fakeSender struct {
+	resp *backend.CallResourceResponse
+}
+
+func (sender *fakeSender) Send(resp *backend.CallResourceResponse) error {
+	sender.resp = resp
+	return nil
+}
+
+func TestCallResource(t *testing.T) {
+	var graphiteReq *http.Request
+	var graphiteForm url.Values
+	graphiteStatus := http.StatusOK
+	graphiteBody := `[{"text": "cpu", "expandable": 1}]`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.NoError(t, req.ParseForm())
+		graphiteReq = req
+		graphiteForm = req.Form
+		rw.WriteHeader(graphiteStatus)
+		_, err := io.WriteString(rw, graphiteBody)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &Service{
+		logger: log.New("tsdb.graphite"),
+		im: datasource.NewInstanceManager(func(settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+			return datasourceInfo{HTTPClient: srv.Client(), URL: srv.URL + "/graphite", Id: settings.ID}, nil
+		}),
+	}
+	s.resourceHandler = httpadapter.New(s.registerRoutes())
+
+	callResource := func(t *testing.T, method string, resourceURL string, body string) *backend.CallResourceResponse {
+		t.Helper()
+		graphiteReq = nil
+
+		req := &backend.CallResourceRequest{
+			PluginContext: backend.PluginContext{
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 1},
+			},
+			Method: method,
+			Path:   strings.SplitN(resourceURL, "?", 2)[0],
+			URL:    resourceURL,
+			Body:   []byte(body),
+		}
+		if strings.HasPrefix(body, "{") {
+			req.Headers = map[string][]string{"Content-Type": {"application/json"}}
+		} else if body != "" {
+			req.Headers = map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}}
+		}
+
+		sender := &fakeSender{}
+		require.NoError(t, s.CallResource(context.Background(), req, sender))
+		return sender.resp
+	}
+
+	t.Run("finds metrics", func(t *testing.T) {
+		resp := callResource(t, http.MethodPost, "metrics/find", "query=servers.*&from=-1h&until=now&target=ignored")
+
+		require.Equal(t, http.StatusOK, resp.Status)
+		require.JSONEq(t, graphiteBody, string(resp.Body))
+		require.Equal(t, http.MethodPost, graphiteReq.Method)
+		require.Equal(t, "/graphite/metrics/find", graphiteReq.URL.Path)
+		require.Equal(t, url.Values{"query": {"servers.*"}, "from": {"-1h"}, "until": {"now"}}, graphiteForm)
+	})
+
+	t.Run("finds metrics with a JSON body", func(t *testing.T) {
+		resp := callResource(t, http.MethodPost, "metrics/find", `{"query": "servers.*", "from": "-1h", "until": 1664000000, "target": "ignored"}`)
+
+		require.Equal(t, http.StatusOK, resp.Status)
+		require.Equal(t, "/graphite/metrics/find", graphiteReq.URL.Path)
+		require.Equal(t, url.Values{"query": {"servers.*"}, "from": {"-1h"}, "until": {"1664000000"}}, graphiteForm)
+	})
+
+	t.Run("autocompletes tags and tag values", func(t *testing.T) {
+		resp := callResource(t, http.MethodGet, "tags/autoComplete/tags?expr=name%3Dcpu&expr=host%3Da&tagPrefix=ho&limit=10", "")
+
+		require.Equal(t, http.StatusOK, resp.Status)
+		require.Equal(t, http.MethodGet, graphiteReq.Method)
+		require.Equal(t, "/graphite/tags/autoComplete/tags", graphiteReq.URL.Path)
+		require.Equal(t, url.Values{"expr": {"name=cpu", "host=a"}, "tagPrefix": {"ho"}, "limit": {"10"}}, graphiteForm)
+
+		resp = callResource(t, http.MethodGet, "tags/autoComplete/values?tag=host&valuePrefix=a", "")
+
+		require.Equal(t, http.StatusOK, resp.Status)
+		require.Equal(t, "/graphite/tags/autoComplete/values", graphiteReq.URL.Path)
+		require.Equal(t, url.Values{"tag": {"host"}, "valuePrefix": {"a"}}, graphiteForm)
+	})
+
+	t.Run("lists functions with valid JSON", func(t *testing.T) {
+		graphiteBody = `{"sum": {"params": [{"name": "limit", "default": Infinity}]}}`
+		t.Cleanup(func() { graphiteBody = `[]` })
+
+		resp := callResource(t, http.MethodGet, "functions", "")
+
+		require.Equal(t, http.StatusOK, resp.Status)
+		require.Equal(t, "/graphite/functions", graphiteReq.URL.Path)
+		require.Equal(t, `{"sum": {"params": [{"name": "limit", "default": 1e9999}]}}`, string(resp.Body))
+	})
+
+	t.Run("returns the status of failed Graphite requests", func(t *testing.T) {
+		graphiteStatus = http.StatusInternalServerError
+		t.Cleanup(func() { graphiteStatus = http.StatusOK })
+
+		resp := callResource(t, http.MethodGet, "metrics/expand?query=servers.*", "")
+
+		require.Equal(t, http.StatusInternalServerError, resp.Status)
+		require.JSONEq(t, `{"message": "request failed, status: 500 Internal Server Error"}`, string(resp.Body))
+	})
+
+	t.Run("rejects unknown resources", func(t *testing.T) {
+		resp := callResource(t, http.MethodGet, "render?target=servers.*", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Status)
+		require.Nil(t, graphiteReq)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -96,6 +97,8 @@ func makeDataRequest(ctx context.Context, lokiDsUrl string, query lokiQuery, hea
 	return req, nil
 }
 
+var errResponseTooLarge = errors.New("the response of Loki is too large")
+
 type lokiError struct {
 	Message string
 }
@@ -205,6 +208,11 @@ func makeRawRequest(ctx context.Context, lokiDsUrl string, resourcePath string,
 }
 
 func (api *LokiAPI) RawQuery(ctx context.Context, resourcePath string) ([]byte, error) {
+	return api.rawQuery(ctx, resourcePath, 0)
+}
+
+// rawQuery returns the response of a resource request, failing when it is larger than maxSize bytes, if maxSize is set.
+func (api *LokiAPI) rawQuery(ctx context.Context, resourcePath string, maxSize int64) ([]byte, error) {
 	req, err := makeRawRequest(ctx, api.url, resourcePath, api.headers)
 	if err != nil {
 		return nil, err
@@ -225,5 +233,16 @@ func (api *LokiAPI) RawQuery(ctx context.Context, resourcePath string) ([]byte,
 		return nil, makeLokiError(resp.Body)
 	}
 
-	return io.ReadAll(resp.Body)
+	if maxSize <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, errResponseTooLarge
+	}
+	return body, nil
 }
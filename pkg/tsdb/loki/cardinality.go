@@ -0,0 +1,193 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cardinalityResource      = "cardinality"
+	labelCardinalityResource = "cardinality/label/"
+
+	// the series of the selector are requested over the last hour when the request has no time range,
+	// like Loki does, and over at most a day
+	defaultCardinalityRange = time.Hour
+	maxCardinalityRange     = 24 * time.Hour
+
+	// the cardinality is computed from at most this number of series, in a response of at most this size
+	maxCardinalitySeries       = 10000
+	maxCardinalityResponseSize = 16 * 1024 * 1024
+)
+
+// labelCardinality is the number of values of a label in the series of a selector.
+type labelCardinality struct {
+	Label       string `json:"label"`
+	ValuesCount int    `json:"valuesCount"`
+	SeriesCount int    `json:"seriesCount"`
+}
+
+// cardinalityResponse is the response of the cardinality resource.
+type cardinalityResponse struct {
+	SeriesCount int                `json:"seriesCount"`
+	Labels      []labelCardinality `json:"labels"`
+}
+
+// labelValueCardinality is the number of series with a value of a label.
+type labelValueCardinality struct {
+	Value       string `json:"value"`
+	SeriesCount int    `json:"seriesCount"`
+}
+
+// labelValuesCardinalityResponse is the response of the label cardinality resource.
+type labelValuesCardinalityResponse struct {
+	Label       string                  `json:"label"`
+	SeriesCount int                     `json:"seriesCount"`
+	Values      []labelValueCardinality `json:"values"`
+}
+
+type seriesResponse struct {
+	Data []map[string]string `json:"data"`
+}
+
+func isCardinalityResource(resourceURL string) bool {
+	return strings.HasPrefix(resourceURL, cardinalityResource+"?") || strings.HasPrefix(resourceURL, labelCardinalityResource)
+}
+
+// cardinality computes the cardinality of the labels of the series matching a selector, from the series API of Loki.
+// `cardinality?query=...&start=...&end=...` returns the number of series and the number of values of each label,
+// `cardinality/label/<name>?query=...` returns the number of series with each value of the label.
+func cardinality(ctx context.Context, api *LokiAPI, resourceURL string) ([]byte, error) {
+	u, err := url.Parse(resourceURL)
+	if err != nil {
+		return nil, err
+	}
+	params := u.Query()
+
+	selector := params.Get("query")
+	if selector == "" {
+		return nil, fmt.Errorf("missing query in cardinality request")
+	}
+	start, end, err := cardinalityTimeRange(params.Get("start"), params.Get("end"))
+	if err != nil {
+		return nil, err
+	}
+
+	seriesParams := url.Values{}
+	seriesParams.Set("match[]", selector)
+	seriesParams.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	seriesParams.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+
+	body, err := api.rawQuery(ctx, "/loki/api/v1/series?"+seriesParams.Encode(), maxCardinalityResponseSize)
+	if errors.Is(err, errResponseTooLarge) {
+		return nil, fmt.Errorf("too many series match the selector, use a more specific selector or a shorter time range")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var series seriesResponse
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, err
+	}
+	if len(series.Data) > maxCardinalitySeries {
+		return nil, fmt.Errorf("more than %d series match the selector, use a more specific selector or a shorter time range", maxCardinalitySeries)
+	}
+
+	if label := strings.TrimPrefix(u.Path, labelCardinalityResource); label != u.Path {
+		return json.Marshal(labelValuesCardinality(series.Data, label))
+	}
+	return json.Marshal(labelsCardinality(series.Data))
+}
+
+// cardinalityTimeRange returns the time range of a cardinality request. The start and end are unix timestamps in
+// nanoseconds, or RFC3339 dates, like in the Loki API.
+func cardinalityTimeRange(startParam string, endParam string) (time.Time, time.Time, error) {
+	end := time.Now()
+	if endParam != "" {
+		t, err := parseLokiTime(endParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end in cardinality request: %w", err)
+		}
+		end = t
+	}
+
+	start := end.Add(-defaultCardinalityRange)
+	if startParam != "" {
+		t, err := parseLokiTime(startParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start in cardinality request: %w", err)
+		}
+		start = t
+	}
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the start of a cardinality request must be before its end")
+	}
+	if end.Sub(start) > maxCardinalityRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("the time range of a cardinality request must be at most %s", maxCardinalityRange)
+	}
+	return start, end, nil
+}
+
+func parseLokiTime(value string) (time.Time, error) {
+	if ns, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, ns), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// labelsCardinality returns the number of values of each label, the labels with the most values first.
+func labelsCardinality(series []map[string]string) cardinalityResponse {
+	values := make(map[string]map[string]bool)
+	seriesCount := make(map[string]int)
+	for _, labels := range series {
+		for name, value := range labels {
+			if values[name] == nil {
+				values[name] = make(map[string]bool)
+			}
+			values[name][value] = true
+			seriesCount[name]++
+		}
+	}
+
+	res := cardinalityResponse{SeriesCount: len(series), Labels: make([]labelCardinality, 0, len(values))}
+	for name, v := range values {
+		res.Labels = append(res.Labels, labelCardinality{Label: name, ValuesCount: len(v), SeriesCount: seriesCount[name]})
+	}
+	sort.Slice(res.Labels, func(i, j int) bool {
+		if res.Labels[i].ValuesCount != res.Labels[j].ValuesCount {
+			return res.Labels[i].ValuesCount > res.Labels[j].ValuesCount
+		}
+		return res.Labels[i].Label < res.Labels[j].Label
+	})
+	return res
+}
+
+// labelValuesCardinality returns the number of series with each value of a label, the most frequent values first.
+func labelValuesCardinality(series []map[string]string, label string) labelValuesCardinalityResponse {
+	counts := make(map[string]int)
+	for _, labels := range series {
+		if value, ok := labels[label]; ok {
+			counts[value]++
+		}
+	}
+
+	res := labelValuesCardinalityResponse{Label: label, Values: make([]labelValueCardinality, 0, len(counts))}
+	for value, count := range counts {
+		res.SeriesCount += count
+		res.Values = append(res.Values, labelValueCardinality{Value: value, SeriesCount: count})
+	}
+	sort.Slice(res.Values, func(i, j int) bool {
+		if res.Values[i].SeriesCount != res.Values[j].SeriesCount {
+			return res.Values[i].SeriesCount > res.Values[j].SeriesCount
+		}
+		return res.Values[i].Value < res.Values[j].Value
+	})
+	return res
+}
@@ -0,0 +1,105 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinality(t *testing.T) {
+	response := []byte(`
+	{
+		"status": "success",
+		"data": [
+			{ "job": "grafana", "level": "info", "instance": "a" },
+			{ "job": "grafana", "level": "error", "instance": "a" },
+			{ "job": "grafana", "level": "info", "instance": "b" },
+			{ "job": "loki", "instance": "c" }
+		]
+	}
+	`)
+
+	t.Run("returns the number of values of each label", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, func(req *http.Request) {
+			require.Equal(t, "/loki/api/v1/series", req.URL.Path)
+			require.Equal(t, `{job=~".+"}`, req.URL.Query().Get("match[]"))
+			require.Equal(t, "1", req.URL.Query().Get("start"))
+			require.Equal(t, "2", req.URL.Query().Get("end"))
+		})
+
+		body, err := cardinality(context.Background(), api, `cardinality?query=%7Bjob%3D~%22.%2B%22%7D&start=1&end=2`)
+		require.NoError(t, err)
+
+		var res cardinalityResponse
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, 4, res.SeriesCount)
+		require.Equal(t, []labelCardinality{
+			{Label: "instance", ValuesCount: 3, SeriesCount: 4},
+			{Label: "job", ValuesCount: 2, SeriesCount: 4},
+			{Label: "level", ValuesCount: 2, SeriesCount: 3},
+		}, res.Labels)
+	})
+
+	t.Run("returns the number of series of each value of a label", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, nil)
+
+		body, err := cardinality(context.Background(), api, `cardinality/label/level?query=%7Bjob%3D%22grafana%22%7D`)
+		require.NoError(t, err)
+
+		var res labelValuesCardinalityResponse
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, "level", res.Label)
+		require.Equal(t, 3, res.SeriesCount)
+		require.Equal(t, []labelValueCardinality{
+			{Value: "info", SeriesCount: 2},
+			{Value: "error", SeriesCount: 1},
+		}, res.Values)
+	})
+
+	t.Run("requests the series of the last hour by default", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, func(req *http.Request) {
+			start, err := strconv.ParseInt(req.URL.Query().Get("start"), 10, 64)
+			require.NoError(t, err)
+			end, err := strconv.ParseInt(req.URL.Query().Get("end"), 10, 64)
+			require.NoError(t, err)
+			require.Equal(t, time.Hour, time.Duration(end-start))
+		})
+
+		_, err := cardinality(context.Background(), api, `cardinality?query=%7Bjob%3D%22grafana%22%7D`)
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects time ranges longer than a day", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, func(req *http.Request) {
+			t.Fatal("the series must not be requested")
+		})
+
+		_, err := cardinality(context.Background(), api, `cardinality?query=%7Bjob%3D%22grafana%22%7D&start=2022-01-01T00:00:00Z&end=2022-01-03T00:00:00Z`)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects selectors matching too many series", func(t *testing.T) {
+		series := make([]map[string]string, maxCardinalitySeries+1)
+		for i := range series {
+			series[i] = map[string]string{"job": "grafana", "instance": strconv.Itoa(i)}
+		}
+		body, err := json.Marshal(map[string]interface{}{"status": "success", "data": series})
+		require.NoError(t, err)
+		api := makeMockedAPI(200, "application/json", body, nil)
+
+		_, err = cardinality(context.Background(), api, `cardinality?query=%7Bjob%3D%22grafana%22%7D&start=1&end=2`)
+		require.Error(t, err)
+	})
+
+	t.Run("requires a selector", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, nil)
+
+		_, err := cardinality(context.Background(), api, `cardinality?start=1&end=2`)
+		require.Error(t, err)
+	})
+}
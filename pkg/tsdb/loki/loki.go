@@ -65,6 +65,8 @@ type QueryJSONModel struct {
 	Resolution   int64  `json:"resolution"`
 	MaxLines     int    `json:"maxLines"`
 	VolumeQuery  bool   `json:"volumeQuery"`
+
+	SupplementaryQueryType string `json:"supplementaryQueryType"`
 }
 
 func parseQueryModel(raw json.RawMessage) (*QueryJSONModel, error) {
@@ -142,13 +144,21 @@ func callResource(ctx context.Context, req *backend.CallResourceRequest, sender
 	}
 	if (!strings.HasPrefix(url, "labels?")) &&
 		(!strings.HasPrefix(url, "label/")) && // the `/label/$label_name/values` form
-		(!strings.HasPrefix(url, "series?")) {
+		(!strings.HasPrefix(url, "series?")) &&
+		!isCardinalityResource(url) {
 		return fmt.Errorf("invalid resource URL: %s", url)
 	}
-	lokiURL := fmt.Sprintf("/loki/api/v1/%s", url)
 
 	api := newLokiAPI(dsInfo.HTTPClient, dsInfo.URL, plog, getAuthHeadersForCallResource(req.Headers))
-	bytes, err := api.RawQuery(ctx, lokiURL)
+
+	var bytes []byte
+	var err error
+	if isCardinalityResource(url) {
+		// the cardinality is not an API of Loki, it is computed from the series
+		bytes, err = cardinality(ctx, api, url)
+	} else {
+		bytes, err = api.RawQuery(ctx, fmt.Sprintf("/loki/api/v1/%s", url))
+	}
 
 	if err != nil {
 		return err
@@ -236,6 +246,10 @@ func runQuery(ctx context.Context, api *LokiAPI, query *lokiQuery) (data.Frames,
 		}
 	}
 
+	if query.SupplementaryQueryType == SupplementaryQueryTypeLogsVolume {
+		return mergeLogsVolumeFrames(frames, query), nil
+	}
+
 	return frames, nil
 }
 
@@ -82,6 +82,17 @@ func parseDirection(jsonValue string) (Direction, error) {
 	}
 }
 
+func parseSupplementaryQueryType(jsonValue string) (SupplementaryQueryType, error) {
+	switch jsonValue {
+	case "logsVolume":
+		return SupplementaryQueryTypeLogsVolume, nil
+	case "":
+		return SupplementaryQueryTypeNone, nil
+	default:
+		return SupplementaryQueryTypeNone, fmt.Errorf("invalid supplementaryQueryType: %s", jsonValue)
+	}
+}
+
 func parseQuery(queryContext *backend.QueryDataRequest) ([]*lokiQuery, error) {
 	qs := []*lokiQuery{}
 	for _, query := range queryContext.Queries {
@@ -115,6 +126,26 @@ func parseQuery(queryContext *backend.QueryDataRequest) ([]*lokiQuery, error) {
 			return nil, err
 		}
 
+		supplementaryQueryType, err := parseSupplementaryQueryType(model.SupplementaryQueryType)
+		if err != nil {
+			return nil, err
+		}
+
+		volumeQuery := model.VolumeQuery
+		if supplementaryQueryType == SupplementaryQueryTypeLogsVolume {
+			// the log volume of a logs query is the number of lines of each level in each step,
+			// or in the whole time range for instant queries, which can be used in alert rules
+			volumeRange := step
+			if queryType == QueryTypeInstant {
+				volumeRange = timeRange
+			}
+			expr, err = logsVolumeExpr(expr, volumeRange)
+			if err != nil {
+				return nil, err
+			}
+			volumeQuery = true
+		}
+
 		qs = append(qs, &lokiQuery{
 			Expr:         expr,
 			QueryType:    queryType,
@@ -125,7 +156,9 @@ func parseQuery(queryContext *backend.QueryDataRequest) ([]*lokiQuery, error) {
 			Start:        start,
 			End:          end,
 			RefID:        query.RefID,
-			VolumeQuery:  model.VolumeQuery,
+			VolumeQuery:  volumeQuery,
+
+			SupplementaryQueryType: supplementaryQueryType,
 		})
 	}
 
@@ -34,6 +34,78 @@ func TestParseQuery(t *testing.T) {
 		require.Equal(t, time.Second*15, models[0].Step)
 		require.Equal(t, "go_goroutines 15s 15000 3000s 3000 3000000", models[0].Expr)
 	})
+	t.Run("parsing log volume query model", func(t *testing.T) {
+		queryContext := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					JSON: []byte(`
+					{
+						"expr": "{job=\"grafana\"} |= \"error\"",
+						"queryType": "instant",
+						"supplementaryQueryType": "logsVolume",
+						"refId": "log-volume-A"
+					}`,
+					),
+					TimeRange: backend.TimeRange{
+						From: time.Now().Add(-3000 * time.Second),
+						To:   time.Now(),
+					},
+					Interval:      time.Second * 15,
+					MaxDataPoints: 200,
+				},
+			},
+		}
+		models, err := parseQuery(queryContext)
+		require.NoError(t, err)
+		require.Equal(t, QueryTypeInstant, models[0].QueryType)
+		require.True(t, models[0].VolumeQuery)
+		require.Equal(t, SupplementaryQueryTypeLogsVolume, models[0].SupplementaryQueryType)
+		require.Equal(t, `sum by (level) (count_over_time({job="grafana"} |= "error"[3000000ms]))`, models[0].Expr)
+	})
+	t.Run("parsing log volume query model of a range query", func(t *testing.T) {
+		queryContext := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					JSON: []byte(`
+					{
+						"expr": "{job=\"grafana\"} |= \"error\"",
+						"queryType": "range",
+						"supplementaryQueryType": "logsVolume",
+						"refId": "log-volume-A"
+					}`,
+					),
+					TimeRange: backend.TimeRange{
+						From: time.Now().Add(-3000 * time.Second),
+						To:   time.Now(),
+					},
+					Interval:      time.Second * 15,
+					MaxDataPoints: 200,
+				},
+			},
+		}
+		models, err := parseQuery(queryContext)
+		require.NoError(t, err)
+		require.Equal(t, QueryTypeRange, models[0].QueryType)
+		require.Equal(t, `sum by (level) (count_over_time({job="grafana"} |= "error"[15000ms]))`, models[0].Expr)
+	})
+	t.Run("parsing log volume query model of a metric query", func(t *testing.T) {
+		queryContext := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{JSON: []byte(`{ "expr": "rate({job=\"grafana\"}[1m])", "supplementaryQueryType": "logsVolume" }`)},
+			},
+		}
+		_, err := parseQuery(queryContext)
+		require.Error(t, err)
+	})
+	t.Run("parsing query model with invalid supplementary query type", func(t *testing.T) {
+		queryContext := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{JSON: []byte(`{ "expr": "{job=\"grafana\"}", "supplementaryQueryType": "logsSample" }`)},
+			},
+		}
+		_, err := parseQuery(queryContext)
+		require.Error(t, err)
+	})
 	t.Run("interpolate variables, range between 1s and 0.5s", func(t *testing.T) {
 		expr := "go_goroutines $__interval $__interval_ms $__range $__range_s $__range_ms"
 
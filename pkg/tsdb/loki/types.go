@@ -16,6 +16,13 @@ const (
 	DirectionForward  Direction = "forward"
 )
 
+type SupplementaryQueryType string
+
+const (
+	SupplementaryQueryTypeNone       SupplementaryQueryType = ""
+	SupplementaryQueryTypeLogsVolume SupplementaryQueryType = "logsVolume"
+)
+
 type lokiQuery struct {
 	Expr         string
 	QueryType    QueryType
@@ -27,4 +34,6 @@ type lokiQuery struct {
 	End          time.Time
 	RefID        string
 	VolumeQuery  bool
+
+	SupplementaryQueryType SupplementaryQueryType
 }
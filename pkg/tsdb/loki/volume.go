@@ -0,0 +1,121 @@
+package loki
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	logLevelLabel   = "level"
+	logLevelUnknown = "unknown"
+)
+
+// logLevels maps the values of the level label to the log levels of the logs visualization,
+// the same way as the frontend does.
+var logLevels = map[string]string{
+	"emerg":         "critical",
+	"fatal":         "critical",
+	"alert":         "critical",
+	"crit":          "critical",
+	"critical":      "critical",
+	"warn":          "warning",
+	"warning":       "warning",
+	"err":           "error",
+	"eror":          "error",
+	"error":         "error",
+	"info":          "info",
+	"information":   "info",
+	"informational": "info",
+	"notice":        "info",
+	"dbug":          "debug",
+	"debug":         "debug",
+	"trace":         "trace",
+}
+
+func normalizeLogLevel(level string) string {
+	if normalized, ok := logLevels[strings.ToLower(level)]; ok {
+		return normalized
+	}
+	return logLevelUnknown
+}
+
+// isLogsQuery returns whether a query returns log lines, log queries start with a stream selector.
+func isLogsQuery(expr string) bool {
+	return strings.HasPrefix(strings.TrimSpace(expr), "{")
+}
+
+// logsVolumeExpr returns the metric query counting the lines of each level of a logs query in each step.
+// The volume of a metric query cannot be computed, so an error is returned for them.
+func logsVolumeExpr(expr string, step time.Duration) (string, error) {
+	if !isLogsQuery(expr) {
+		return "", fmt.Errorf("the log volume is only available for log queries, not for metric queries")
+	}
+	return fmt.Sprintf("sum by (%s) (count_over_time(%s[%dms]))", logLevelLabel, expr, step.Milliseconds()), nil
+}
+
+// mergeLogsVolumeFrames returns a frame for each log level of the series of a log volume query. The series of
+// levels which have the same log level, like `warn` and `warning`, are added up. For instant queries, the frames have
+// a single point with the number of lines of each level in the time range.
+func mergeLogsVolumeFrames(frames data.Frames, query *lokiQuery) data.Frames {
+	valuesByLevel := make(map[string]map[time.Time]float64)
+	for _, frame := range frames {
+		if len(frame.Fields) != 2 || frame.Fields[0].Type() != data.FieldTypeTime || frame.Fields[1].Type() != data.FieldTypeFloat64 {
+			continue
+		}
+
+		level := normalizeLogLevel(frame.Fields[1].Labels[logLevelLabel])
+		values, ok := valuesByLevel[level]
+		if !ok {
+			values = make(map[time.Time]float64)
+			valuesByLevel[level] = values
+		}
+
+		for i := 0; i < frame.Fields[0].Len(); i++ {
+			t := frame.Fields[0].At(i).(time.Time)
+			values[t] += frame.Fields[1].At(i).(float64)
+		}
+	}
+
+	levels := make([]string, 0, len(valuesByLevel))
+	for level := range valuesByLevel {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	merged := make(data.Frames, 0, len(levels))
+	for _, level := range levels {
+		values := valuesByLevel[level]
+		times := make([]time.Time, 0, len(values))
+		for t := range values {
+			times = append(times, t)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+		counts := make([]float64, len(times))
+		for i, t := range times {
+			counts[i] = values[t]
+		}
+
+		timeField := data.NewField(data.TimeSeriesTimeFieldName, nil, times)
+		executedQueryString := "Expr: " + query.Expr
+		if query.QueryType == QueryTypeRange {
+			timeField.Config = &data.FieldConfig{Interval: float64(query.Step.Milliseconds())}
+			executedQueryString += "\n" + "Step: " + query.Step.String()
+		}
+		valueField := data.NewField(data.TimeSeriesValueFieldName, data.Labels{logLevelLabel: level}, counts)
+		valueField.Config = &data.FieldConfig{DisplayNameFromDS: level}
+
+		frame := data.NewFrame(level, timeField, valueField)
+		frame.RefID = query.RefID
+		frame.Meta = &data.FrameMeta{
+			Type:                data.FrameTypeTimeSeriesMany,
+			ExecutedQueryString: executedQueryString,
+		}
+		merged = append(merged, frame)
+	}
+	return merged
+}
@@ -0,0 +1,107 @@
+package loki
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsVolume(t *testing.T) {
+	response := []byte(`
+	{
+		"status": "success",
+		"data": {
+			"resultType" : "matrix",
+			"result": [
+				{ "metric": { "level": "warn" }, "values": [[1, "1"], [2, "2"]] },
+				{ "metric": { "level": "WARNING" }, "values": [[2, "3"], [3, "4"]] },
+				{ "metric": { "level": "info" }, "values": [[1, "5"]] },
+				{ "metric": {}, "values": [[1, "6"]] }
+			]
+		}
+	}
+	`)
+
+	t.Run("log volume series are merged by log level", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", response, func(req *http.Request) {
+			require.Equal(t, "Source=logvolhist", req.Header.Get("X-Query-Tags"))
+		})
+
+		query := &lokiQuery{
+			Expr:                   `sum by (level) (count_over_time({job="grafana"}[1000ms]))`,
+			QueryType:              QueryTypeRange,
+			Direction:              DirectionBackward,
+			Step:                   time.Second,
+			RefID:                  "log-volume-A",
+			VolumeQuery:            true,
+			SupplementaryQueryType: SupplementaryQueryTypeLogsVolume,
+		}
+		frames, err := runQuery(context.Background(), api, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 3)
+
+		names := []string{frames[0].Name, frames[1].Name, frames[2].Name}
+		require.Equal(t, []string{"info", "unknown", "warning"}, names)
+
+		warning := frames[2]
+		require.Equal(t, "log-volume-A", warning.RefID)
+		require.Equal(t, data.FrameType(data.FrameTypeTimeSeriesMany), warning.Meta.Type)
+		require.Equal(t, data.Labels{"level": "warning"}, warning.Fields[1].Labels)
+		require.Equal(t, "warning", warning.Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, 3, warning.Rows())
+		require.Equal(t, time.Unix(1, 0).UTC(), warning.Fields[0].At(0).(time.Time).UTC())
+		require.Equal(t, []float64{1, 5, 4}, []float64{warning.Fields[1].At(0).(float64), warning.Fields[1].At(1).(float64), warning.Fields[1].At(2).(float64)})
+	})
+
+	t.Run("instant log volume returns the number of lines of each log level", func(t *testing.T) {
+		api := makeMockedAPI(200, "application/json", []byte(`
+		{
+			"status": "success",
+			"data": {
+				"resultType" : "vector",
+				"result": [
+					{ "metric": { "level": "err" }, "value": [10, "2"] },
+					{ "metric": { "level": "error" }, "value": [10, "3"] }
+				]
+			}
+		}
+		`), nil)
+
+		query := &lokiQuery{
+			Expr:                   `sum by (level) (count_over_time({job="grafana"}[300000ms]))`,
+			QueryType:              QueryTypeInstant,
+			Direction:              DirectionBackward,
+			RefID:                  "A",
+			VolumeQuery:            true,
+			SupplementaryQueryType: SupplementaryQueryTypeLogsVolume,
+		}
+		frames, err := runQuery(context.Background(), api, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, "error", frames[0].Name)
+		require.Equal(t, 1, frames[0].Rows())
+		require.Equal(t, 5.0, frames[0].Fields[1].At(0))
+		require.Nil(t, frames[0].Fields[0].Config)
+	})
+
+	t.Run("log volume of a metric query returns an error", func(t *testing.T) {
+		_, err := logsVolumeExpr(`rate({job="grafana"}[1m])`, time.Second)
+		require.Error(t, err)
+
+		expr, err := logsVolumeExpr(` {job="grafana"} |= "error"`, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, `sum by (level) (count_over_time( {job="grafana"} |= "error"[1000ms]))`, expr)
+	})
+
+	t.Run("log levels are normalized like in the logs visualization", func(t *testing.T) {
+		require.Equal(t, "critical", normalizeLogLevel("emerg"))
+		require.Equal(t, "error", normalizeLogLevel("ERR"))
+		require.Equal(t, "info", normalizeLogLevel("notice"))
+		require.Equal(t, "unknown", normalizeLogLevel("verbose"))
+		require.Equal(t, "unknown", normalizeLogLevel(""))
+	})
+}
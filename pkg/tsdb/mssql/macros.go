@@ -85,6 +85,14 @@ func (m *msSQLMacroEngine) evaluateMacro(timeRange backend.TimeRange, query *bac
 			}
 		}
 		return fmt.Sprintf("FLOOR(DATEDIFF(second, '1970-01-01', %s)/%.0f)*%.0f", args[0], interval.Seconds(), interval.Seconds()), nil
+	case "__timeBucket":
+		bucket, err := sqleng.TimeBucketArgs(timeRange, args)
+		if err != nil {
+			return "", err
+		}
+		return bucket.Expr(fmt.Sprintf("DATEDIFF(second, '1970-01-01', %s)", args[0]), func(expr string) string {
+			return fmt.Sprintf("FLOOR(%s/%.0f)*%.0f", expr, bucket.Interval.Seconds(), bucket.Interval.Seconds())
+		}), nil
 	case "__timeGroupAlias":
 		tg, err := m.evaluateMacro(timeRange, query, "__timeGroup", args)
 		if err == nil {
@@ -24,6 +24,12 @@ import (
 
 var logger = log.New("tsdb.mssql")
 
+// dialect is the syntax of SQL Server statements.
+var dialect = sqleng.SQLDialect{
+	NestedComments:     true,
+	BracketIdentifiers: true,
+}
+
 type Service struct {
 	im instancemgmt.InstanceManager
 }
@@ -88,6 +94,10 @@ func newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFactoryFunc {
 			DSInfo:            dsInfo,
 			MetricColumnTypes: []string{"VARCHAR", "CHAR", "NVARCHAR", "NCHAR"},
 			RowLimit:          cfg.DataProxyRowLimit,
+			RowBytesLimit:     cfg.DataProxyRowBytesLimit,
+			// the driver supports neither read-only transactions nor a server-side statement timeout, a query which
+			// times out is cancelled by the driver
+			Dialect: dialect,
 		}
 
 		queryResultTransformer := mssqlQueryResultTransformer{
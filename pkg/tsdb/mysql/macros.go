@@ -91,6 +91,14 @@ func (m *mySQLMacroEngine) evaluateMacro(timeRange backend.TimeRange, query *bac
 			}
 		}
 		return fmt.Sprintf("UNIX_TIMESTAMP(%s) DIV %.0f * %.0f", args[0], interval.Seconds(), interval.Seconds()), nil
+	case "__timeBucket":
+		bucket, err := sqleng.TimeBucketArgs(timeRange, args)
+		if err != nil {
+			return "", err
+		}
+		return bucket.Expr(fmt.Sprintf("UNIX_TIMESTAMP(%s)", args[0]), func(expr string) string {
+			return fmt.Sprintf("%s DIV %.0f * %.0f", expr, bucket.Interval.Seconds(), bucket.Interval.Seconds())
+		}), nil
 	case "__timeGroupAlias":
 		tg, err := m.evaluateMacro(timeRange, query, "__timeGroup", args)
 		if err == nil {
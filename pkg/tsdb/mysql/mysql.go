@@ -32,6 +32,16 @@ const (
 
 var logger = log.New("tsdb.mysql")
 
+// dialect is the syntax of MySQL statements, whose string literals can be quoted with double quotes, and where
+// backslashes escape characters unless the NO_BACKSLASH_ESCAPES mode is set.
+var dialect = sqleng.SQLDialect{
+	BackslashEscapes:      true,
+	ExecutableComments:    true,
+	HashComments:          true,
+	DashCommentsNeedSpace: true,
+	BacktickIdentifiers:   true,
+}
+
 type Service struct {
 	Cfg *setting.Cfg
 	im  instancemgmt.InstanceManager
@@ -110,12 +120,21 @@ func newInstanceSettings(cfg *setting.Cfg, httpClientProvider httpclient.Provide
 		}
 
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        "mysql",
-			ConnectionString:  cnnstr,
-			DSInfo:            dsInfo,
-			TimeColumnNames:   []string{"time", "time_sec"},
-			MetricColumnTypes: []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
-			RowLimit:          cfg.DataProxyRowLimit,
+			DriverName:           "mysql",
+			ConnectionString:     cnnstr,
+			DSInfo:               dsInfo,
+			TimeColumnNames:      []string{"time", "time_sec"},
+			MetricColumnTypes:    []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
+			RowLimit:             cfg.DataProxyRowLimit,
+			RowBytesLimit:        cfg.DataProxyRowBytesLimit,
+			Dialect:              dialect,
+			ReadOnlyTransactions: true,
+			StatementTimeoutQuery: func(timeout time.Duration) string {
+				// only SELECT statements are limited, MySQL 5.7.8 or later
+				return fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds())
+			},
+			// the timeout is a setting of the session, which isn't reset by the end of the transaction
+			StatementTimeoutResetQuery: "SET SESSION max_execution_time = DEFAULT",
 		}
 
 		rowTransformer := mysqlQueryResultTransformer{
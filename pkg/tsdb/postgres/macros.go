@@ -113,6 +113,14 @@ func (m *postgresMacroEngine) evaluateMacro(timeRange backend.TimeRange, query *
 			interval.Seconds(),
 			interval.Seconds(),
 		), nil
+	case "__timeBucket":
+		bucket, err := sqleng.TimeBucketArgs(timeRange, args)
+		if err != nil {
+			return "", err
+		}
+		return bucket.Expr(fmt.Sprintf("extract(epoch from %s)", args[0]), func(expr string) string {
+			return fmt.Sprintf("floor(%s/%v)*%v", expr, bucket.Interval.Seconds(), bucket.Interval.Seconds())
+		}), nil
 	case "__timeGroupAlias":
 		tg, err := m.evaluateMacro(timeRange, query, "__timeGroup", args)
 		if err == nil {
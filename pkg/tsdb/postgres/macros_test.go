@@ -78,6 +78,23 @@ func TestMacroEngine(t *testing.T) {
 			require.Equal(t, sql2, sql+" AS \"time\"")
 		})
 
+		t.Run("interpolate __timeBucket function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeBucket(time_column, '1d')")
+			require.NoError(t, err)
+			require.Equal(t, "GROUP BY floor((extract(epoch from time_column) + 0)/86400)*86400 - 0", sql)
+		})
+
+		t.Run("interpolate __timeBucket function with timezone", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeBucket(time_column, '1d', 'Europe/Berlin')")
+			require.NoError(t, err)
+			require.Equal(t, "GROUP BY floor((extract(epoch from time_column) + 7200)/86400)*86400 - 7200", sql)
+		})
+
+		t.Run("interpolate __timeBucket function with an unknown timezone", func(t *testing.T) {
+			_, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeBucket(time_column, '1d', 'Mars/Olympus')")
+			require.Error(t, err)
+		})
+
 		t.Run("interpolate __timeGroup function with spaces between args", func(t *testing.T) {
 			sql, err := engine.Interpolate(query, timeRange, "$__timeGroup(time_column , '5m')")
 			require.NoError(t, err)
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
@@ -20,6 +21,15 @@ import (
 
 var logger = log.New("tsdb.postgres")
 
+// dialect is the syntax of PostgreSQL statements, where backslashes escape characters in string literals unless
+// standard_conforming_strings is on.
+var dialect = sqleng.SQLDialect{
+	BackslashEscapes: true,
+	EscapeStrings:    true,
+	DollarQuotes:     true,
+	NestedComments:   true,
+}
+
 func ProvideService(cfg *setting.Cfg) *Service {
 	s := &Service{
 		tlsManager: newTLSManager(logger, cfg.DataPath),
@@ -86,11 +96,17 @@ func (s *Service) newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFacto
 		}
 
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        "postgres",
-			ConnectionString:  cnnstr,
-			DSInfo:            dsInfo,
-			MetricColumnTypes: []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
-			RowLimit:          cfg.DataProxyRowLimit,
+			DriverName:           "postgres",
+			ConnectionString:     cnnstr,
+			DSInfo:               dsInfo,
+			MetricColumnTypes:    []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
+			RowLimit:             cfg.DataProxyRowLimit,
+			RowBytesLimit:        cfg.DataProxyRowBytesLimit,
+			Dialect:              dialect,
+			ReadOnlyTransactions: true,
+			StatementTimeoutQuery: func(timeout time.Duration) string {
+				return fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+			},
 		}
 
 		queryResultTransformer := postgresQueryResultTransformer{
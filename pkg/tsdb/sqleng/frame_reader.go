@@ -0,0 +1,148 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+)
+
+// rowChunkSize is the number of rows read from the database between two checks of the context of a query.
+const rowChunkSize = 1000
+
+// rowLimits are the limits of the rows read from the result of a query, a limit of 0 means no limit.
+type rowLimits struct {
+	rows  int64
+	bytes int64
+}
+
+// newRowLimits returns the limits of a data source, the limits of the data source settings can only
+// lower the limits of the server.
+func newRowLimits(config DataPluginConfiguration) rowLimits {
+	return rowLimits{
+		rows:  lowerLimit(config.RowLimit, config.DSInfo.JsonData.RowLimit),
+		bytes: lowerLimit(config.RowBytesLimit, config.DSInfo.JsonData.RowBytesLimit),
+	}
+}
+
+func lowerLimit(limit int64, dsLimit int64) int64 {
+	if dsLimit > 0 && (limit <= 0 || dsLimit < limit) {
+		return dsLimit
+	}
+	return limit
+}
+
+// readFrame reads the rows of a query result into a single frame. The whole result is buffered in the frame:
+// reading stops with a notice on the frame when the row or byte limit is reached, so memory use is only bounded
+// when one of the limits is set, and with an error when the query is cancelled, which is checked every
+// rowChunkSize rows.
+func readFrame(ctx context.Context, rows *sql.Rows, limits rowLimits, converters ...sqlutil.Converter) (*data.Frame, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	next := rows.Next()
+	if next {
+		// some drivers, like SQLite, only know the types of the columns once a row is read
+		if types, err = rows.ColumnTypes(); err != nil {
+			return nil, err
+		}
+	}
+
+	scanner, converters, err := sqlutil.MakeScanRow(types, names, append(converters, unknownTypeConverters(types)...)...)
+	if err != nil {
+		return nil, err
+	}
+	frame := sqlutil.NewFrame(names, converters...)
+
+	var count, size int64
+	for ; next; next = rows.Next() {
+		if count%rowChunkSize == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		if limits.rows > 0 && count == limits.rows {
+			frame.AppendNotices(data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("Results have been limited to %v because the SQL row limit was reached", limits.rows),
+			})
+			break
+		}
+
+		r := scanner.NewScannableRow()
+		if err := rows.Scan(r...); err != nil {
+			return nil, err
+		}
+		if err := sqlutil.Append(frame, r, converters...); err != nil {
+			return nil, err
+		}
+		count++
+
+		size += rowSize(frame, frame.Rows()-1)
+		if limits.bytes > 0 && size > limits.bytes {
+			// the row which exceeds the limit is not part of the result
+			for _, field := range frame.Fields {
+				field.Delete(field.Len() - 1)
+			}
+			frame.AppendNotices(data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("Results have been limited to %v rows because the SQL byte limit of %v bytes was reached", count-1, limits.bytes),
+			})
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return frame, err
+	}
+	return frame, nil
+}
+
+// unknownTypeConverters returns converters reading the columns whose type is not known as strings, like the
+// columns of SQLite expressions which are NULL in the first row.
+func unknownTypeConverters(types []*sql.ColumnType) []sqlutil.Converter {
+	var converters []sqlutil.Converter
+	for _, t := range types {
+		if t.ScanType() != nil {
+			continue
+		}
+		converter := sqlutil.NullStringConverter
+		converter.InputColumnName = t.Name()
+		converters = append(converters, converter)
+	}
+	return converters
+}
+
+// rowSize estimates the memory used by the values of a row of a frame.
+func rowSize(frame *data.Frame, idx int) int64 {
+	var size int64
+	for _, field := range frame.Fields {
+		v := reflect.ValueOf(field.At(idx))
+		if !v.IsValid() {
+			continue
+		}
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.String, reflect.Slice:
+			size += int64(v.Len())
+		default:
+			size += int64(v.Type().Size())
+		}
+	}
+	return size
+}
@@ -0,0 +1,115 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFrame(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	_, err = db.Exec("CREATE TABLE metrics (name TEXT, value INTEGER)")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = db.Exec("INSERT INTO metrics VALUES (?, ?)", fmt.Sprintf("metric-%d", i), i)
+		require.NoError(t, err)
+	}
+
+	// the SQLite driver only knows the scan types of the columns once a row is read
+	converters := []sqlutil.Converter{
+		{
+			InputScanType: reflect.TypeOf(sql.NullString{}),
+			InputTypeName: "TEXT",
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableString,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullString)
+					if !v.Valid {
+						return (*string)(nil), nil
+					}
+					return &v.String, nil
+				},
+			},
+		},
+		{
+			InputScanType: reflect.TypeOf(sql.NullInt64{}),
+			InputTypeName: "INTEGER",
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableInt64,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullInt64)
+					if !v.Valid {
+						return (*int64)(nil), nil
+					}
+					return &v.Int64, nil
+				},
+			},
+		},
+	}
+
+	query := func(t *testing.T) *sql.Rows {
+		t.Helper()
+		rows, err := db.Query("SELECT name, value FROM metrics ORDER BY value")
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, rows.Close()) })
+		return rows
+	}
+
+	t.Run("reads all rows without limits", func(t *testing.T) {
+		frame, err := readFrame(context.Background(), query(t), rowLimits{}, converters...)
+		require.NoError(t, err)
+		require.Equal(t, 10, frame.Rows())
+		require.Empty(t, frame.Meta)
+	})
+
+	t.Run("stops at the row limit", func(t *testing.T) {
+		frame, err := readFrame(context.Background(), query(t), rowLimits{rows: 3}, converters...)
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, frame.Meta.Notices[0].Severity)
+	})
+
+	t.Run("stops at the byte limit", func(t *testing.T) {
+		// a row is a string of 8 bytes and an integer of 8 bytes
+		frame, err := readFrame(context.Background(), query(t), rowLimits{bytes: 40}, converters...)
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "metric-1", *frame.Fields[0].At(1).(*string))
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Contains(t, frame.Meta.Notices[0].Text, "byte limit")
+	})
+
+	t.Run("returns an error when the query is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		rows := query(t)
+		cancel()
+		_, err := readFrame(ctx, rows, rowLimits{}, converters...)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("data source limits lower the server limits", func(t *testing.T) {
+		limits := newRowLimits(DataPluginConfiguration{
+			RowLimit: 1000000,
+			DSInfo:   DataSourceInfo{JsonData: JsonData{RowLimit: 100, RowBytesLimit: 1024}},
+		})
+		require.Equal(t, rowLimits{rows: 100, bytes: 1024}, limits)
+
+		limits = newRowLimits(DataPluginConfiguration{
+			RowLimit:      100,
+			RowBytesLimit: 1024,
+			DSInfo:        DataSourceInfo{JsonData: JsonData{RowLimit: 1000000}},
+		})
+		require.Equal(t, rowLimits{rows: 100, bytes: 1024}, limits)
+	})
+}
@@ -0,0 +1,232 @@
+package sqleng
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+)
+
+// maxMacroExpansions is the maximum number of times macros are expanded in a query, macros can use other macros.
+const maxMacroExpansions = 10
+
+var (
+	macroNameRegexp = regexp.MustCompile(`^[a-zA-Z][_a-zA-Z0-9]*$`)
+	macroRegexp     = regexp.MustCompile(`\$([_a-zA-Z0-9]+)\(([^\)]*)\)`)
+)
+
+// MacroFunc evaluates a macro with its arguments. The result can use other macros.
+type MacroFunc func(query *backend.DataQuery, timeRange backend.TimeRange, args []string) (string, error)
+
+// CustomMacro is a macro defined in the settings of a data source, a templated SQL snippet with arguments.
+// The arguments are used in the template as ${name}, for example the macro
+// `{"name": "tenantFilter", "args": ["column"], "template": "${column} = 'acme' AND $__timeFilter(time)"}`
+// is used in a query as `$tenantFilter(tenant)`.
+type CustomMacro struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Template string   `json:"template"`
+}
+
+// MacroRegistry holds the macros which are the same in all SQL data sources, and the custom macros of a data source.
+// The macros of the registry are expanded before the global substitutions and the macros of the data source, so
+// they can use them.
+type MacroRegistry struct {
+	*SQLMacroEngineBase
+	macros map[string]MacroFunc
+}
+
+// NewMacroRegistry returns a registry with the built-in macros of all SQL data sources.
+func NewMacroRegistry() *MacroRegistry {
+	r := &MacroRegistry{
+		SQLMacroEngineBase: NewSQLMacroEngineBase(),
+		macros:             make(map[string]MacroFunc),
+	}
+	r.macros["__inRange"] = inRangeMacro
+	return r
+}
+
+// Register adds a macro to the registry, the name of the macro is used in queries without the $ prefix.
+func (r *MacroRegistry) Register(name string, fn MacroFunc) error {
+	if _, exists := r.macros[name]; exists {
+		return fmt.Errorf("macro %v is already defined", name)
+	}
+	r.macros[name] = fn
+	return nil
+}
+
+// RegisterCustomMacros adds the custom macros of a data source to the registry. The names of custom macros
+// cannot start with an underscore, which is reserved for the built-in macros.
+func (r *MacroRegistry) RegisterCustomMacros(macros []CustomMacro) error {
+	for _, m := range macros {
+		if !macroNameRegexp.MatchString(m.Name) {
+			return fmt.Errorf("invalid custom macro name %q", m.Name)
+		}
+		m := m
+		if err := r.Register(m.Name, func(query *backend.DataQuery, timeRange backend.TimeRange, args []string) (string, error) {
+			return m.evaluate(args)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CustomMacro) evaluate(args []string) (string, error) {
+	// a macro without arguments is used as $name()
+	if len(args) == 1 && args[0] == "" && len(m.Args) == 0 {
+		args = nil
+	}
+	if len(args) != len(m.Args) {
+		return "", fmt.Errorf("macro %v needs %d arguments (%s)", m.Name, len(m.Args), strings.Join(m.Args, ", "))
+	}
+
+	replacements := make([]string, 0, len(args)*2)
+	for i, name := range m.Args {
+		replacements = append(replacements, "${"+name+"}", args[i])
+	}
+	return strings.NewReplacer(replacements...).Replace(m.Template), nil
+}
+
+// Interpolate expands the macros of the registry in a query. Other macros are left for the data source.
+func (r *MacroRegistry) Interpolate(query *backend.DataQuery, timeRange backend.TimeRange, sql string) (string, error) {
+	if r == nil {
+		return sql, nil
+	}
+
+	for i := 0; i < maxMacroExpansions; i++ {
+		expanded := false
+		var macroError error
+
+		sql = r.ReplaceAllStringSubmatchFunc(macroRegexp, sql, func(groups []string) string {
+			fn, ok := r.macros[groups[1]]
+			if !ok {
+				return groups[0]
+			}
+			expanded = true
+
+			args := strings.Split(groups[2], ",")
+			for i, arg := range args {
+				args[i] = strings.Trim(arg, " ")
+			}
+			res, err := fn(query, timeRange, args)
+			if err != nil && macroError == nil {
+				macroError = err
+				return "macro_error()"
+			}
+			return res
+		})
+
+		if macroError != nil {
+			return "", macroError
+		}
+		if !expanded {
+			return sql, nil
+		}
+	}
+
+	return "", fmt.Errorf("macros are expanded more than %d times, a macro probably uses itself", maxMacroExpansions)
+}
+
+// inRangeMacro returns a filter for the values of a column in the time range, including the start of the time
+// range and excluding its end, so that consecutive time ranges don't overlap.
+func inRangeMacro(query *backend.DataQuery, timeRange backend.TimeRange, args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", fmt.Errorf("missing time column argument for macro %v", "__inRange")
+	}
+	return fmt.Sprintf("%s >= $__timeFrom() AND %s < $__timeTo()", args[0], args[0]), nil
+}
+
+// TimeBucket is the interval and the timezone of the buckets of the $__timeBucket(column, interval, timezone) macro
+// of SQL data sources. Buckets are aligned on the timezone, for example daily buckets start at midnight in the
+// timezone, also when the offset of the timezone changes in the time range for daylight saving time.
+type TimeBucket struct {
+	Interval time.Duration
+	// offsets of the timezone in the time range, in seconds, ordered by time
+	offsets []timezoneOffset
+}
+
+// timezoneOffset is the offset of a timezone, in seconds, from a unix timestamp until the next offset.
+type timezoneOffset struct {
+	from   int64
+	offset int
+}
+
+// TimeBucketArgs parses the arguments of the $__timeBucket(column, interval, timezone) macro of SQL data sources.
+func TimeBucketArgs(timeRange backend.TimeRange, args []string) (TimeBucket, error) {
+	if len(args) < 2 {
+		return TimeBucket{}, fmt.Errorf("macro %v needs time column, interval and optional timezone", "__timeBucket")
+	}
+
+	interval, err := gtime.ParseInterval(strings.Trim(args[1], `'"`))
+	if err != nil {
+		return TimeBucket{}, fmt.Errorf("error parsing interval %v", args[1])
+	}
+
+	loc := time.UTC
+	if len(args) > 2 {
+		loc, err = time.LoadLocation(strings.Trim(args[2], `'"`))
+		if err != nil {
+			return TimeBucket{}, fmt.Errorf("error parsing timezone %v", args[2])
+		}
+	}
+	// the first bucket starts up to an interval before the time range
+	return TimeBucket{Interval: interval, offsets: timezoneOffsets(loc, timeRange.From.Add(-interval), timeRange.To)}, nil
+}
+
+// timezoneOffsets returns the offsets of a timezone between two times.
+func timezoneOffsets(loc *time.Location, from time.Time, to time.Time) []timezoneOffset {
+	offsetAt := func(t time.Time) int {
+		_, offset := t.In(loc).Zone()
+		return offset
+	}
+
+	offsets := []timezoneOffset{{from: from.Unix(), offset: offsetAt(from)}}
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if next.After(to) {
+			next = to
+		}
+		if offsetAt(next) == offsetAt(day) {
+			continue
+		}
+		// the offset changes once a day at most, the change is searched to the second
+		before, after := day, next
+		for after.Sub(before) > time.Second {
+			middle := before.Add(after.Sub(before) / 2).Truncate(time.Second)
+			if offsetAt(middle) == offsetAt(before) {
+				before = middle
+			} else {
+				after = middle
+			}
+		}
+		offsets = append(offsets, timezoneOffset{from: after.Unix(), offset: offsetAt(after)})
+	}
+	return offsets
+}
+
+// Expr returns the SQL expression of the start of the bucket of a time column, as a unix timestamp. epoch is the SQL
+// expression of the unix timestamp of the column, and floor returns the SQL expression rounding down a unix timestamp
+// to the interval. When the offset of the timezone changes in the time range, the offset of each timestamp is
+// computed in SQL, and the start of its bucket uses the offset at that time.
+func (b TimeBucket) Expr(epoch string, floor func(expr string) string) string {
+	if len(b.offsets) == 1 {
+		offset := b.offsets[0].offset
+		return fmt.Sprintf("%s - %d", floor(fmt.Sprintf("(%s + %d)", epoch, offset)), offset)
+	}
+
+	// the offset of a timestamp, and the offset of the start of a bucket in the local time of the timezone
+	offsetCases := make([]string, 0, len(b.offsets))
+	bucketOffsetCases := make([]string, 0, len(b.offsets))
+	for i := 1; i < len(b.offsets); i++ {
+		offsetCases = append(offsetCases, fmt.Sprintf("WHEN %s < %d THEN %d", epoch, b.offsets[i].from, b.offsets[i-1].offset))
+	}
+	localBucket := floor(fmt.Sprintf("(%s + CASE %s ELSE %d END)", epoch, strings.Join(offsetCases, " "), b.offsets[len(b.offsets)-1].offset))
+	for i := 1; i < len(b.offsets); i++ {
+		bucketOffsetCases = append(bucketOffsetCases, fmt.Sprintf("WHEN %s < %d THEN %d", localBucket, b.offsets[i].from+int64(b.offsets[i-1].offset), b.offsets[i-1].offset))
+	}
+	return fmt.Sprintf("%s - CASE %s ELSE %d END", localBucket, strings.Join(bucketOffsetCases, " "), b.offsets[len(b.offsets)-1].offset)
+}
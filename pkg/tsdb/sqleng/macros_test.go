@@ -0,0 +1,64 @@
+package sqleng
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacroRegistry(t *testing.T) {
+	from := time.Date(2018, 4, 12, 18, 0, 0, 0, time.UTC)
+	timeRange := backend.TimeRange{From: from, To: from.Add(5 * time.Minute)}
+	query := &backend.DataQuery{JSON: []byte("{}")}
+
+	t.Run("interpolate __inRange function", func(t *testing.T) {
+		sql, err := NewMacroRegistry().Interpolate(query, timeRange, "WHERE $__inRange(time_column)")
+		require.NoError(t, err)
+		require.Equal(t, "WHERE time_column >= $__timeFrom() AND time_column < $__timeTo()", sql)
+	})
+
+	t.Run("leaves the macros of the data source", func(t *testing.T) {
+		sql, err := NewMacroRegistry().Interpolate(query, timeRange, "SELECT $__timeGroup(time_column, '5m') WHERE $__timeFilter(time_column)")
+		require.NoError(t, err)
+		require.Equal(t, "SELECT $__timeGroup(time_column, '5m') WHERE $__timeFilter(time_column)", sql)
+	})
+
+	t.Run("interpolate custom macros", func(t *testing.T) {
+		registry := NewMacroRegistry()
+		err := registry.RegisterCustomMacros([]CustomMacro{
+			{Name: "tenantFilter", Args: []string{"column", "tenant"}, Template: "${column} = '${tenant}' AND $__inRange(time)"},
+			{Name: "activeOnly", Template: "deleted_at IS NULL"},
+		})
+		require.NoError(t, err)
+
+		sql, err := registry.Interpolate(query, timeRange, "WHERE $tenantFilter(tenant_id, acme) AND $activeOnly()")
+		require.NoError(t, err)
+		require.Equal(t, "WHERE tenant_id = 'acme' AND time >= $__timeFrom() AND time < $__timeTo() AND deleted_at IS NULL", sql)
+	})
+
+	t.Run("returns an error for a wrong number of arguments", func(t *testing.T) {
+		registry := NewMacroRegistry()
+		err := registry.RegisterCustomMacros([]CustomMacro{{Name: "tenantFilter", Args: []string{"column", "tenant"}, Template: "${column} = '${tenant}'"}})
+		require.NoError(t, err)
+
+		_, err = registry.Interpolate(query, timeRange, "WHERE $tenantFilter(tenant_id)")
+		require.Error(t, err)
+	})
+
+	t.Run("returns an error for recursive macros", func(t *testing.T) {
+		registry := NewMacroRegistry()
+		err := registry.RegisterCustomMacros([]CustomMacro{{Name: "loop", Template: "$loop()"}})
+		require.NoError(t, err)
+
+		_, err = registry.Interpolate(query, timeRange, "SELECT $loop()")
+		require.Error(t, err)
+	})
+
+	t.Run("rejects invalid custom macros", func(t *testing.T) {
+		require.Error(t, NewMacroRegistry().RegisterCustomMacros([]CustomMacro{{Name: "__inRange", Template: "1 = 1"}}))
+		require.Error(t, NewMacroRegistry().RegisterCustomMacros([]CustomMacro{{Name: "tenant filter", Template: "1 = 1"}}))
+		require.Error(t, NewMacroRegistry().RegisterCustomMacros([]CustomMacro{{Name: "tenant", Template: "1 = 1"}, {Name: "tenant", Template: "1 = 2"}}))
+	})
+}
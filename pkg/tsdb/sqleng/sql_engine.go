@@ -41,6 +41,12 @@ type SqlQueryResultTransformer interface {
 	GetConverterList() []sqlutil.StringConverter
 }
 
+// SqlQueryResultConverterProvider is implemented by the result transformers of data sources which need converters
+// scanning other types than strings. Its converters are used before the string converters.
+type SqlQueryResultConverterProvider interface {
+	GetConverters() []sqlutil.Converter
+}
+
 var sqlIntervalCalculator = intervalv2.NewCalculator()
 
 // NewXormEngine is an xorm.Engine factory, that can be stubbed by tests.
@@ -51,20 +57,25 @@ var NewXormEngine = func(driverName string, connectionString string) (*xorm.Engi
 }
 
 type JsonData struct {
-	MaxOpenConns        int    `json:"maxOpenConns"`
-	MaxIdleConns        int    `json:"maxIdleConns"`
-	ConnMaxLifetime     int    `json:"connMaxLifetime"`
-	Timescaledb         bool   `json:"timescaledb"`
-	Mode                string `json:"sslmode"`
-	ConfigurationMethod string `json:"tlsConfigurationMethod"`
-	TlsSkipVerify       bool   `json:"tlsSkipVerify"`
-	RootCertFile        string `json:"sslRootCertFile"`
-	CertFile            string `json:"sslCertFile"`
-	CertKeyFile         string `json:"sslKeyFile"`
-	Timezone            string `json:"timezone"`
-	Encrypt             string `json:"encrypt"`
-	Servername          string `json:"servername"`
-	TimeInterval        string `json:"timeInterval"`
+	MaxOpenConns        int           `json:"maxOpenConns"`
+	MaxIdleConns        int           `json:"maxIdleConns"`
+	ConnMaxLifetime     int           `json:"connMaxLifetime"`
+	Timescaledb         bool          `json:"timescaledb"`
+	Mode                string        `json:"sslmode"`
+	ConfigurationMethod string        `json:"tlsConfigurationMethod"`
+	TlsSkipVerify       bool          `json:"tlsSkipVerify"`
+	RootCertFile        string        `json:"sslRootCertFile"`
+	CertFile            string        `json:"sslCertFile"`
+	CertKeyFile         string        `json:"sslKeyFile"`
+	Timezone            string        `json:"timezone"`
+	Encrypt             string        `json:"encrypt"`
+	Servername          string        `json:"servername"`
+	TimeInterval        string        `json:"timeInterval"`
+	CustomMacros        []CustomMacro `json:"customMacros"`
+	RowLimit            int64         `json:"rowLimit"`
+	RowBytesLimit       int64         `json:"rowBytesLimit"`
+	ReadOnly            bool          `json:"readOnly"`
+	StatementTimeout    int           `json:"statementTimeout"`
 }
 
 type DataSourceInfo struct {
@@ -85,16 +96,33 @@ type DataPluginConfiguration struct {
 	TimeColumnNames   []string
 	MetricColumnTypes []string
 	RowLimit          int64
+	RowBytesLimit     int64
+	// Dialect is the syntax the statements of read-only data sources are checked with.
+	Dialect SQLDialect
+	// ReadOnlyTransactions is set when the driver supports read-only transactions.
+	ReadOnlyTransactions bool
+	// StatementTimeoutQuery returns the statement setting the server-side timeout of the statements of a
+	// transaction, it's nil when the database has no such setting and the timeout is only enforced by the client.
+	StatementTimeoutQuery func(timeout time.Duration) string
+	// StatementTimeoutResetQuery is the statement resetting the timeout at the end of the transaction, when the
+	// timeout is a setting of the session which outlives the transaction, and would apply to the next queries of
+	// the connection.
+	StatementTimeoutResetQuery string
 }
 type DataSourceHandler struct {
 	macroEngine            SQLMacroEngine
+	macroRegistry          *MacroRegistry
 	queryResultTransformer SqlQueryResultTransformer
 	engine                 *xorm.Engine
 	timeColumnNames        []string
 	metricColumnTypes      []string
 	log                    log.Logger
 	dsInfo                 DataSourceInfo
-	rowLimit               int64
+	rowLimits              rowLimits
+	dialect                SQLDialect
+	readOnlyTransactions   bool
+	statementTimeoutQuery  func(timeout time.Duration) string
+	statementTimeoutReset  string
 }
 type QueryJson struct {
 	RawSql       string  `json:"rawSql"`
@@ -132,7 +160,11 @@ func NewQueryDataHandler(config DataPluginConfiguration, queryResultTransformer
 		timeColumnNames:        []string{"time"},
 		log:                    log,
 		dsInfo:                 config.DSInfo,
-		rowLimit:               config.RowLimit,
+		rowLimits:              newRowLimits(config),
+		dialect:                config.Dialect,
+		readOnlyTransactions:   config.ReadOnlyTransactions,
+		statementTimeoutQuery:  config.StatementTimeoutQuery,
+		statementTimeoutReset:  config.StatementTimeoutResetQuery,
 	}
 
 	if len(config.TimeColumnNames) > 0 {
@@ -143,6 +175,11 @@ func NewQueryDataHandler(config DataPluginConfiguration, queryResultTransformer
 		queryDataHandler.metricColumnTypes = config.MetricColumnTypes
 	}
 
+	queryDataHandler.macroRegistry = NewMacroRegistry()
+	if err := queryDataHandler.macroRegistry.RegisterCustomMacros(config.DSInfo.JsonData.CustomMacros); err != nil {
+		return nil, err
+	}
+
 	engine, err := NewXormEngine(config.DriverName, config.ConnectionString)
 	if err != nil {
 		return nil, err
@@ -243,8 +280,15 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 		ch <- queryResult
 	}
 
+	// built-in and custom macros of the data source
+	interpolatedQuery, err := e.macroRegistry.Interpolate(&query, timeRange, queryJson.RawSql)
+	if err != nil {
+		errAppendDebug("interpolation failed", e.transformQueryError(err), interpolatedQuery)
+		return
+	}
+
 	// global substitutions
-	interpolatedQuery, err := Interpolate(query, timeRange, e.dsInfo.JsonData.TimeInterval, queryJson.RawSql)
+	interpolatedQuery, err = Interpolate(query, timeRange, e.dsInfo.JsonData.TimeInterval, interpolatedQuery)
 	if err != nil {
 		errAppendDebug("interpolation failed", e.transformQueryError(err), interpolatedQuery)
 		return
@@ -257,15 +301,28 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 		return
 	}
 
+	if e.dsInfo.JsonData.ReadOnly {
+		if err := CheckReadOnlyStatement(e.dialect, interpolatedQuery); err != nil {
+			errAppendDebug("query not allowed", err, interpolatedQuery)
+			return
+		}
+	}
+
+	if e.dsInfo.JsonData.StatementTimeout > 0 {
+		var cancel context.CancelFunc
+		queryContext, cancel = context.WithTimeout(queryContext, time.Duration(e.dsInfo.JsonData.StatementTimeout)*time.Second)
+		defer cancel()
+	}
+
 	session := e.engine.NewSession()
 	defer session.Close()
-	db := session.DB()
 
-	rows, err := db.QueryContext(queryContext, interpolatedQuery)
+	rows, endTx, err := e.query(queryContext, session.DB(), interpolatedQuery)
 	if err != nil {
 		errAppendDebug("db query error", e.transformQueryError(err), interpolatedQuery)
 		return
 	}
+	defer endTx()
 	defer func() {
 		if err := rows.Close(); err != nil {
 			e.log.Warn("Failed to close rows", "err", err)
@@ -279,8 +336,13 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 	}
 
 	// Convert row.Rows to dataframe
+	var converters []sqlutil.Converter
+	if provider, ok := e.queryResultTransformer.(SqlQueryResultConverterProvider); ok {
+		converters = provider.GetConverters()
+	}
 	stringConverters := e.queryResultTransformer.GetConverterList()
-	frame, err := sqlutil.FrameFromRows(rows.Rows, e.rowLimit, sqlutil.ToConverters(stringConverters...)...)
+	converters = append(converters, sqlutil.ToConverters(stringConverters...)...)
+	frame, err := readFrame(queryContext, rows.Rows, e.rowLimits, converters...)
 	if err != nil {
 		errAppendDebug("convert frame from rows error", err, interpolatedQuery)
 		return
@@ -377,6 +439,18 @@ var Interpolate = func(query backend.DataQuery, timeRange backend.TimeRange, tim
 	}
 	interval := sqlIntervalCalculator.Calculate(timeRange, minInterval, query.MaxDataPoints)
 
+	var intervalErr error
+	sql = intervalArithmeticRegexp.ReplaceAllStringFunc(sql, func(expr string) string {
+		groups := intervalArithmeticRegexp.FindStringSubmatch(expr)
+		ms, err := intervalArithmetic(interval.Milliseconds(), groups[1], groups[2])
+		if err != nil && intervalErr == nil {
+			intervalErr = err
+		}
+		return strconv.FormatInt(ms, 10)
+	})
+	if intervalErr != nil {
+		return "", intervalErr
+	}
 	sql = strings.ReplaceAll(sql, "$__interval_ms", strconv.FormatInt(interval.Milliseconds(), 10))
 	sql = strings.ReplaceAll(sql, "$__interval", interval.Text)
 	sql = strings.ReplaceAll(sql, "$__unixEpochFrom()", fmt.Sprintf("%d", timeRange.From.UTC().Unix()))
@@ -385,6 +459,75 @@ var Interpolate = func(query backend.DataQuery, timeRange backend.TimeRange, tim
 	return sql, nil
 }
 
+// intervalArithmeticRegexp matches $__interval_ms with an operation, like $__interval_ms(*2).
+var intervalArithmeticRegexp = regexp.MustCompile(`\$__interval_ms\(\s*([*/+-])\s*(\d+(?:\.\d+)?)\s*\)`)
+
+func intervalArithmetic(ms int64, operator string, operand string) (int64, error) {
+	value, err := strconv.ParseFloat(operand, 64)
+	if err != nil {
+		return 0, err
+	}
+	res := float64(ms)
+	switch operator {
+	case "*":
+		res *= value
+	case "/":
+		if value == 0 {
+			return 0, fmt.Errorf("division by zero in $__interval_ms(%s%s)", operator, operand)
+		}
+		res /= value
+	case "+":
+		res += value
+	case "-":
+		res -= value
+	}
+	return int64(res), nil
+}
+
+// query executes a query. Queries of read-only data sources are executed in read-only transactions when the driver
+// supports them, and queries with a statement timeout in a transaction setting the server-side timeout when the
+// database has such a setting. The returned function ends the transaction, once the rows are read.
+func (e *DataSourceHandler) query(ctx context.Context, db *core.DB, query string) (*core.Rows, func(), error) {
+	readOnly := e.dsInfo.JsonData.ReadOnly && e.readOnlyTransactions
+	timeout := time.Duration(e.dsInfo.JsonData.StatementTimeout) * time.Second
+	setTimeout := timeout > 0 && e.statementTimeoutQuery != nil
+	if !readOnly && !setTimeout {
+		rows, err := db.QueryContext(ctx, query)
+		return rows, func() {}, err
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	endTx := func() {
+		if setTimeout && e.statementTimeoutReset != "" {
+			// the query context may be done, the timeout of the session is reset anyway
+			if _, err := tx.ExecContext(context.Background(), e.statementTimeoutReset); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				e.log.Warn("Failed to reset statement timeout", "err", err)
+			}
+		}
+		// nothing is written in the transaction
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			e.log.Warn("Failed to end transaction", "err", err)
+		}
+	}
+
+	if setTimeout {
+		if _, err := tx.ExecContext(ctx, e.statementTimeoutQuery(timeout)); err != nil {
+			endTx()
+			return nil, nil, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		endTx()
+		return nil, nil, err
+	}
+	return rows, endTx, nil
+}
+
 func (e *DataSourceHandler) newProcessCfg(query backend.DataQuery, queryContext context.Context,
 	rows *core.Rows, interpolatedQuery string) (*dataQueryModel, error) {
 	columnNames, err := rows.Columns()
@@ -42,6 +42,17 @@ func TestSQLEngine(t *testing.T) {
 			require.Equal(t, "select 60000 ", sql)
 		})
 
+		t.Run("interpolate $__interval_ms with arithmetic", func(t *testing.T) {
+			sql, err := Interpolate(query, timeRange, "", "select $__interval_ms(*2), $__interval_ms( / 4 ), $__interval_ms(-500), $__interval_ms")
+			require.NoError(t, err)
+			require.Equal(t, "select 120000, 15000, 59500, 60000", sql)
+		})
+
+		t.Run("interpolate $__interval_ms with a division by zero", func(t *testing.T) {
+			_, err := Interpolate(query, timeRange, "", "select $__interval_ms(/0)")
+			require.Error(t, err)
+		})
+
 		t.Run("interpolate __unixEpochFrom function", func(t *testing.T) {
 			sql, err := Interpolate(query, timeRange, "", "select $__unixEpochFrom()")
 			require.NoError(t, err)
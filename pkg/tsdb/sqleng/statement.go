@@ -0,0 +1,264 @@
+package sqleng
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrStatementNotAllowed is returned for the queries of read-only data sources which aren't a single SELECT statement.
+var ErrStatementNotAllowed = errors.New("only SELECT statements are allowed")
+
+// SQLDialect is the lexical syntax of the statements of a database, which the statements of read-only data sources
+// are checked with. The syntax must be the syntax of the database, a statement is read differently otherwise, and
+// could hide a statement modifying the database in what looks like a string literal or a comment.
+type SQLDialect struct {
+	// BackslashEscapes is set when backslashes may escape characters in string literals, depending on the settings
+	// of the server. Statements are checked both with and without backslash escapes.
+	BackslashEscapes bool
+	// EscapeStrings is set when backslashes always escape characters in E'' string literals.
+	EscapeStrings bool
+	// DollarQuotes is set when strings can be quoted with $tag$ delimiters.
+	DollarQuotes bool
+	// NestedComments is set when block comments can be nested.
+	NestedComments bool
+	// ExecutableComments is set when the content of /*! */ comments is executed.
+	ExecutableComments bool
+	// HashComments is set when # starts a comment to the end of the line.
+	HashComments bool
+	// DashCommentsNeedSpace is set when -- only starts a comment when followed by a space or a control character.
+	DashCommentsNeedSpace bool
+	// BracketIdentifiers is set when identifiers can be quoted with [].
+	BracketIdentifiers bool
+	// BacktickIdentifiers is set when identifiers can be quoted with backticks.
+	BacktickIdentifiers bool
+}
+
+// forbiddenKeywords are the keywords which can't be in a single SELECT statement, except in data-modifying common
+// table expressions and in SELECT INTO, which aren't allowed. Other statements need to be separated by a semicolon,
+// except in SQL Server, where these keywords start the statements modifying the database or the session.
+var forbiddenKeywords = map[string]bool{
+	"ALTER": true, "BACKUP": true, "BULK": true, "COMMIT": true, "CREATE": true, "DBCC": true, "DEALLOCATE": true,
+	"DELETE": true, "DENY": true, "DROP": true, "EXEC": true, "EXECUTE": true, "GRANT": true, "INSERT": true,
+	"INTO": true, "KILL": true, "MERGE": true, "OPENDATASOURCE": true, "OPENQUERY": true, "OPENROWSET": true,
+	"RESTORE": true, "REVOKE": true, "ROLLBACK": true, "SET": true, "SHUTDOWN": true, "TRUNCATE": true,
+	"UPDATE": true, "UPDATETEXT": true, "WRITETEXT": true,
+}
+
+// forbiddenFunctions are the functions with side effects which read-only transactions don't prevent, like ending
+// the sessions of other users, running statements over another connection, changing the settings of the session or
+// the server, holding locks past the query, or reading the files of the server. The functions running the statements
+// of their string arguments, like dblink or query_to_xml, are forbidden too since the content of string literals is
+// not checked. The list is best-effort: it doesn't replace a database user with read-only grants.
+var forbiddenFunctions = map[string]bool{
+	// PostgreSQL
+	"DBLINK": true, "DBLINK_CONNECT": true, "DBLINK_CONNECT_U": true, "DBLINK_EXEC": true, "DBLINK_OPEN": true,
+	"DBLINK_SEND_QUERY": true, "LO_EXPORT": true, "LO_IMPORT": true, "PG_ADVISORY_LOCK": true,
+	"PG_ADVISORY_LOCK_SHARED": true, "PG_BACKUP_START": true, "PG_BACKUP_STOP": true, "PG_CANCEL_BACKEND": true,
+	"PG_CREATE_LOGICAL_REPLICATION_SLOT": true, "PG_CREATE_PHYSICAL_REPLICATION_SLOT": true,
+	"PG_CREATE_RESTORE_POINT": true, "PG_DROP_REPLICATION_SLOT": true, "PG_FILE_WRITE": true,
+	"PG_LOGICAL_EMIT_MESSAGE": true, "PG_LS_DIR": true, "PG_NOTIFY": true, "PG_PROMOTE": true,
+	"PG_READ_BINARY_FILE": true, "PG_READ_FILE": true, "PG_RELOAD_CONF": true, "PG_ROTATE_LOGFILE": true,
+	"PG_START_BACKUP": true, "PG_STAT_FILE": true, "PG_STOP_BACKUP": true, "PG_SWITCH_WAL": true,
+	"PG_TERMINATE_BACKEND": true, "PG_TRY_ADVISORY_LOCK": true, "PG_TRY_ADVISORY_LOCK_SHARED": true,
+	"PG_WAL_REPLAY_PAUSE": true, "PG_WAL_REPLAY_RESUME": true, "SET_CONFIG": true,
+	"CURSOR_TO_XML": true, "CURSOR_TO_XMLSCHEMA": true, "DATABASE_TO_XML": true, "DATABASE_TO_XML_AND_XMLSCHEMA": true,
+	"DATABASE_TO_XMLSCHEMA": true, "QUERY_TO_XML": true, "QUERY_TO_XML_AND_XMLSCHEMA": true, "QUERY_TO_XMLSCHEMA": true,
+	"SCHEMA_TO_XML": true, "SCHEMA_TO_XML_AND_XMLSCHEMA": true, "SCHEMA_TO_XMLSCHEMA": true, "TABLE_TO_XML": true,
+	"TABLE_TO_XML_AND_XMLSCHEMA": true, "TABLE_TO_XMLSCHEMA": true,
+	// MySQL
+	"GET_LOCK": true, "LOAD_FILE": true, "RELEASE_ALL_LOCKS": true, "RELEASE_LOCK": true,
+}
+
+var dollarQuoteRegexp = regexp.MustCompile(`^\$([a-zA-Z_][a-zA-Z0-9_]*)?\$`)
+
+// CheckReadOnlyStatement returns an error unless the query is a single SELECT statement, optionally with common
+// table expressions, which doesn't contain any of the keywords of statements modifying the database, nor calls any
+// of the functions with side effects.
+func CheckReadOnlyStatement(dialect SQLDialect, query string) error {
+	if err := checkStatement(dialect, query, false); err != nil {
+		return err
+	}
+	if dialect.BackslashEscapes {
+		return checkStatement(dialect, query, true)
+	}
+	return nil
+}
+
+func checkStatement(dialect SQLDialect, query string, backslashEscapes bool) error {
+	words, err := statementWords(dialect, query, backslashEscapes)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStatementNotAllowed, err)
+	}
+
+	for len(words) > 0 && words[len(words)-1] == ";" {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return ErrStatementNotAllowed
+	}
+	for _, word := range words {
+		if word == ";" {
+			return fmt.Errorf("%w: multiple statements are not allowed", ErrStatementNotAllowed)
+		}
+		if forbiddenKeywords[word] {
+			return fmt.Errorf("%w: %s is not allowed", ErrStatementNotAllowed, word)
+		}
+		// functions can be called with quoted names
+		if name := strings.TrimPrefix(word, `"`); forbiddenFunctions[name] {
+			return fmt.Errorf("%w: %s is not allowed", ErrStatementNotAllowed, strings.ToLower(name))
+		}
+	}
+	return nil
+}
+
+// statementWords returns the unquoted words of a statement in upper case, without string literals and comments.
+// Quoted identifiers are returned in upper case and prefixed with a double quote, so that they aren't read as
+// keywords. Statement separators are returned as ";".
+func statementWords(dialect SQLDialect, query string, backslashEscapes bool) ([]string, error) {
+	var words []string
+	// the first word can only be preceded by opening parentheses
+	leading := true
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		rest := query[i:]
+
+		switch {
+		case isSpace(c):
+			i++
+		case c == ';':
+			words = append(words, ";")
+			i++
+		case strings.HasPrefix(rest, "--") && (!dialect.DashCommentsNeedSpace || len(rest) == 2 || rest[2] <= ' '):
+			i += lineCommentLength(rest)
+		case c == '#' && dialect.HashComments:
+			i += lineCommentLength(rest)
+		case strings.HasPrefix(rest, "/*!") && dialect.ExecutableComments:
+			// the content of the comment is read as statement, the end of the comment is read as operators
+			i += 3
+		case strings.HasPrefix(rest, "/*"):
+			n, err := blockCommentLength(rest, dialect.NestedComments)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		case c == '\'':
+			n, err := quotedLength(rest, '\'', backslashEscapes)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		case c == '"':
+			n, err := quotedLength(rest, '"', backslashEscapes)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, quotedIdentifier(rest[:n]))
+			i += n
+		case c == '`' && dialect.BacktickIdentifiers:
+			n, err := quotedLength(rest, '`', false)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, quotedIdentifier(rest[:n]))
+			i += n
+		case c == '[' && dialect.BracketIdentifiers:
+			n, err := quotedLength(rest, ']', false)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, quotedIdentifier(rest[:n]))
+			i += n
+		case c == '$' && dialect.DollarQuotes && dollarQuoteRegexp.MatchString(rest):
+			tag := dollarQuoteRegexp.FindString(rest)
+			end := strings.Index(rest[len(tag):], tag)
+			if end == -1 {
+				return nil, errors.New("unterminated dollar-quoted string")
+			}
+			i += len(tag) + end + len(tag)
+		case isWordChar(c):
+			n := 1
+			for n < len(rest) && isWordChar(rest[n]) {
+				n++
+			}
+			word := strings.ToUpper(rest[:n])
+			i += n
+			if dialect.EscapeStrings && word == "E" && i < len(query) && query[i] == '\'' {
+				n, err := quotedLength(query[i:], '\'', true)
+				if err != nil {
+					return nil, err
+				}
+				i += n
+				continue
+			}
+			leading = false
+			words = append(words, word)
+		default:
+			if c != '(' && leading {
+				// the statement starts with an operator
+				return []string{string(c)}, nil
+			}
+			i++
+		}
+	}
+	return words, nil
+}
+
+// quotedIdentifier returns the word of a quoted identifier, without its quotes.
+func quotedIdentifier(quoted string) string {
+	return `"` + strings.ToUpper(quoted[1:len(quoted)-1])
+}
+
+func lineCommentLength(s string) int {
+	if n := strings.IndexByte(s, '\n'); n != -1 {
+		return n + 1
+	}
+	return len(s)
+}
+
+func blockCommentLength(s string, nested bool) (int, error) {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*' && (nested || depth == 0):
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, errors.New("unterminated comment")
+}
+
+// quotedLength returns the length of a string literal or a quoted identifier, where a doubled closing quote is an
+// escaped quote.
+func quotedLength(s string, closing byte, backslashEscapes bool) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && backslashEscapes:
+			i++
+		case s[i] == closing:
+			if i+1 < len(s) && s[i+1] == closing {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated quoted string")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= 0x80
+}
@@ -0,0 +1,172 @@
+package sqleng
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestCheckReadOnlyStatement(t *testing.T) {
+	mysql := SQLDialect{
+		BackslashEscapes:      true,
+		ExecutableComments:    true,
+		HashComments:          true,
+		DashCommentsNeedSpace: true,
+		BacktickIdentifiers:   true,
+	}
+	postgres := SQLDialect{BackslashEscapes: true, EscapeStrings: true, DollarQuotes: true, NestedComments: true}
+	mssql := SQLDialect{NestedComments: true, BracketIdentifiers: true}
+
+	t.Run("allows single SELECT statements", func(t *testing.T) {
+		for _, query := range []string{
+			"SELECT 1",
+			"select value from metrics where name = 'delete; drop table metrics'",
+			"  (SELECT 1) UNION (SELECT 2);",
+			"WITH t AS (SELECT 1 AS updated) SELECT updated FROM t",
+			"SELECT 1; -- trailing comment",
+			"SELECT 1 /* ; DELETE FROM metrics */",
+			`SELECT "insert" FROM metrics`,
+		} {
+			for _, dialect := range []SQLDialect{mysql, postgres, mssql} {
+				require.NoError(t, CheckReadOnlyStatement(dialect, query), query)
+			}
+		}
+
+		require.NoError(t, CheckReadOnlyStatement(mysql, "SELECT `delete` FROM metrics # ; DELETE"))
+		require.NoError(t, CheckReadOnlyStatement(postgres, "SELECT $$; DELETE FROM metrics$$, $tag$'$tag$"))
+		require.NoError(t, CheckReadOnlyStatement(postgres, "SELECT /* /* */ DELETE */ 1"))
+		require.NoError(t, CheckReadOnlyStatement(postgres, `SELECT E'\' ; DELETE FROM metrics; -- '`))
+		require.NoError(t, CheckReadOnlyStatement(mssql, "SELECT [drop] FROM metrics"))
+		require.NoError(t, CheckReadOnlyStatement(mssql, `SELECT 'C:\' AS path`))
+	})
+
+	t.Run("rejects other statements", func(t *testing.T) {
+		for _, query := range []string{
+			"",
+			"-- SELECT",
+			"DELETE FROM metrics",
+			"SELECT 1; DELETE FROM metrics",
+			"SELECT 1;; SELECT 2",
+			"SELECT * INTO copy FROM metrics",
+			"WITH t AS (DELETE FROM metrics RETURNING *) SELECT * FROM t",
+			"SELECT 1 'unterminated",
+			"SELECT 1 /* unterminated",
+			"/* comment */ update metrics set value = 1",
+			"+ SELECT 1",
+		} {
+			for _, dialect := range []SQLDialect{mysql, postgres, mssql} {
+				err := CheckReadOnlyStatement(dialect, query)
+				require.ErrorIs(t, err, ErrStatementNotAllowed, query)
+			}
+		}
+	})
+
+	t.Run("rejects functions with side effects", func(t *testing.T) {
+		for _, tc := range []struct {
+			dialect SQLDialect
+			query   string
+		}{
+			{postgres, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity"},
+			{postgres, "SELECT pg_catalog.pg_cancel_backend(1234)"},
+			{postgres, `SELECT "pg_terminate_backend"(1234)`},
+			{postgres, "SELECT dblink_exec('dbname=grafana', 'DELETE FROM metrics')"},
+			{postgres, "SELECT * FROM dblink('dbname=grafana', 'SELECT 1') AS t(a int)"},
+			{postgres, "SELECT set_config('statement_timeout', '0', false)"},
+			{postgres, "SELECT pg_read_file('/etc/passwd')"},
+			{postgres, "SELECT query_to_xml('SELECT pg_terminate_backend(1234)', true, false, '')"},
+			{postgres, "SELECT query_to_xml_and_xmlschema('SELECT pg_terminate_backend(1234)', true, false, '')"},
+			{postgres, "SELECT cursor_to_xml('cursor', 10, true, false, '')"},
+			{postgres, "SELECT table_to_xml('metrics', true, false, '')"},
+			{postgres, "SELECT database_to_xml(true, false, '')"},
+			{postgres, "SELECT schema_to_xml('public', true, false, '')"},
+			{mysql, "SELECT LOAD_FILE('/etc/passwd')"},
+			{mysql, "SELECT `get_lock`('lock', 10)"},
+		} {
+			err := CheckReadOnlyStatement(tc.dialect, tc.query)
+			require.ErrorIs(t, err, ErrStatementNotAllowed, tc.query)
+		}
+
+		require.NoError(t, CheckReadOnlyStatement(postgres, "SELECT 'pg_terminate_backend(1234)'"))
+	})
+
+	t.Run("rejects statements hidden by the syntax of the database", func(t *testing.T) {
+		for _, tc := range []struct {
+			dialect SQLDialect
+			query   string
+		}{
+			{mysql, `SELECT 'a\' , ' ; DELETE FROM metrics; -- '`},
+			{mysql, "SELECT 1 # '\n; DELETE FROM metrics; -- '"},
+			{mysql, "SELECT 2 --1; DELETE FROM metrics"},
+			{mysql, "SELECT 1 /*! ; DELETE FROM metrics */"},
+			{postgres, `SELECT 'a\' , ' ; DELETE FROM metrics; -- '`},
+			{postgres, "SELECT /* /* */ ' */ ; DELETE FROM metrics; -- '"},
+			{mssql, "SELECT 1 DELETE FROM metrics"},
+			{mssql, "SELECT 1 EXEC xp_cmdshell 'dir'"},
+			{mssql, "SELECT /* /* */ ' */ DELETE FROM metrics -- '"},
+		} {
+			err := CheckReadOnlyStatement(tc.dialect, tc.query)
+			require.ErrorIs(t, err, ErrStatementNotAllowed, tc.query)
+		}
+	})
+}
+
+func TestReadOnlyQuery(t *testing.T) {
+	engine, err := xorm.NewEngine("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, engine.Close()) })
+	// the queries use the same connection
+	engine.DB().SetMaxOpenConns(1)
+
+	var timeoutQueries []string
+	handler := &DataSourceHandler{
+		engine:               engine,
+		log:                  log.New("test"),
+		readOnlyTransactions: true,
+		statementTimeoutQuery: func(timeout time.Duration) string {
+			query := fmt.Sprintf("PRAGMA busy_timeout = %d", timeout.Milliseconds())
+			timeoutQueries = append(timeoutQueries, query)
+			return query
+		},
+		statementTimeoutReset: "PRAGMA busy_timeout = 1000",
+	}
+
+	query := func(t *testing.T) int {
+		t.Helper()
+		rows, endTx, err := handler.query(context.Background(), engine.DB(), "SELECT 1")
+		require.NoError(t, err)
+		defer endTx()
+		defer func() { require.NoError(t, rows.Close()) }()
+
+		require.True(t, rows.Next())
+		var value int
+		require.NoError(t, rows.Scan(&value))
+		return value
+	}
+
+	t.Run("queries without a statement timeout aren't in a transaction", func(t *testing.T) {
+		require.Equal(t, 1, query(t))
+		require.Empty(t, timeoutQueries)
+	})
+
+	t.Run("queries with a statement timeout set the timeout in a transaction", func(t *testing.T) {
+		handler.dsInfo.JsonData = JsonData{ReadOnly: true, StatementTimeout: 30}
+		require.Equal(t, 1, query(t))
+		require.Equal(t, []string{"PRAGMA busy_timeout = 30000"}, timeoutQueries)
+	})
+
+	t.Run("the statement timeout is reset at the end of the transaction", func(t *testing.T) {
+		handler.dsInfo.JsonData = JsonData{ReadOnly: true, StatementTimeout: 30}
+		require.Equal(t, 1, query(t))
+
+		var timeout int
+		require.NoError(t, engine.DB().QueryRow("PRAGMA busy_timeout").Scan(&timeout))
+		require.Equal(t, 1000, timeout)
+	})
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacroEngine(t *testing.T) {
+	engine := newSQLiteMacroEngine()
+	query := &backend.DataQuery{}
+
+	t.Run("Given a time range between 2018-04-12 18:00 and 2018-04-12 18:05", func(t *testing.T) {
+		from := time.Date(2018, 4, 12, 18, 0, 0, 0, time.UTC)
+		to := from.Add(5 * time.Minute)
+		timeRange := backend.TimeRange{From: from, To: to}
+
+		t.Run("interpolate __time function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "select $__time(time_column)")
+			require.Nil(t, err)
+
+			require.Equal(t, "select time_column AS \"time\"", sql)
+		})
+
+		t.Run("interpolate __timeEpoch function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "select $__timeEpoch(time_column)")
+			require.Nil(t, err)
+
+			require.Equal(t, "select CAST(strftime('%s', time_column) AS INTEGER) AS \"time\"", sql)
+		})
+
+		t.Run("interpolate __timeGroup function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column,'5m')")
+			require.Nil(t, err)
+			sql2, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroupAlias(time_column,'5m')")
+			require.Nil(t, err)
+
+			require.Equal(t, "GROUP BY CAST(strftime('%s', time_column) AS INTEGER) / 300 * 300", sql)
+			require.Equal(t, sql+" AS \"time\"", sql2)
+		})
+
+		t.Run("interpolate __timeBucket function with timezone", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeBucket(time_column, '1d', 'Europe/Berlin')")
+			require.Nil(t, err)
+			require.Equal(t, "GROUP BY (CAST(strftime('%s', time_column) AS INTEGER) + 7200) / 86400 * 86400 - 7200", sql)
+		})
+
+		t.Run("interpolate __timeFilter function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "WHERE $__timeFilter(time_column)")
+			require.Nil(t, err)
+
+			require.Equal(t, "WHERE time_column BETWEEN '2018-04-12 18:00:00' AND '2018-04-12 18:05:00'", sql)
+		})
+
+		t.Run("interpolate __timeFrom function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "select $__timeFrom()")
+			require.Nil(t, err)
+
+			require.Equal(t, "select '2018-04-12 18:00:00'", sql)
+		})
+
+		t.Run("interpolate __timeTo function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "select $__timeTo()")
+			require.Nil(t, err)
+
+			require.Equal(t, "select '2018-04-12 18:05:00'", sql)
+		})
+
+		t.Run("interpolate __unixEpochFilter function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochFilter(time)")
+			require.Nil(t, err)
+
+			require.Equal(t, fmt.Sprintf("select time >= %d AND time <= %d", from.Unix(), to.Unix()), sql)
+		})
+
+		t.Run("interpolate __unixEpochGroup function", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "SELECT $__unixEpochGroup(time_column,'5m')")
+			require.Nil(t, err)
+			sql2, err := engine.Interpolate(query, timeRange, "SELECT $__unixEpochGroupAlias(time_column,'5m')")
+			require.Nil(t, err)
+
+			require.Equal(t, "SELECT time_column / 300 * 300", sql)
+			require.Equal(t, sql+" AS \"time\"", sql2)
+		})
+	})
+
+	t.Run("Given a time range in a time zone other than UTC", func(t *testing.T) {
+		from := time.Date(2018, 4, 12, 20, 0, 0, 0, time.FixedZone("CEST", 7200))
+		timeRange := backend.TimeRange{From: from, To: from.Add(time.Hour)}
+
+		t.Run("interpolate __timeFilter function in UTC", func(t *testing.T) {
+			sql, err := engine.Interpolate(query, timeRange, "WHERE $__timeFilter(time_column)")
+			require.Nil(t, err)
+
+			require.Equal(t, "WHERE time_column BETWEEN '2018-04-12 18:00:00' AND '2018-04-12 19:00:00'", sql)
+		})
+	})
+
+	t.Run("Given a time range over a daylight saving time change", func(t *testing.T) {
+		from := time.Date(2022, 3, 25, 12, 0, 0, 0, time.UTC)
+		timeRange := backend.TimeRange{From: from, To: from.Add(4 * 24 * time.Hour)}
+
+		db, err := sql.Open("sqlite3", ":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+		for value, bucket := range map[string]time.Time{
+			"2022-03-26 12:00:00": time.Date(2022, 3, 25, 23, 0, 0, 0, time.UTC),
+			"2022-03-27 00:30:00": time.Date(2022, 3, 26, 23, 0, 0, 0, time.UTC),
+			"2022-03-27 12:00:00": time.Date(2022, 3, 26, 23, 0, 0, 0, time.UTC),
+			"2022-03-27 22:30:00": time.Date(2022, 3, 27, 22, 0, 0, 0, time.UTC),
+			"2022-03-28 12:00:00": time.Date(2022, 3, 27, 22, 0, 0, 0, time.UTC),
+		} {
+			t.Run(fmt.Sprintf("interpolate __timeBucket function with the offset of the bucket of %s", value), func(t *testing.T) {
+				query, err := engine.Interpolate(query, timeRange, fmt.Sprintf("SELECT $__timeBucket('%s', '1d', 'Europe/Berlin')", value))
+				require.NoError(t, err)
+
+				var start int64
+				require.NoError(t, db.QueryRow(query).Scan(&start))
+				require.Equal(t, bucket, time.Unix(start, 0).UTC())
+			})
+		}
+	})
+
+	t.Run("unknown macros return an error", func(t *testing.T) {
+		_, err := engine.Interpolate(query, backend.TimeRange{}, "select $__unknown(time_column)")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,292 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/mattn/go-sqlite3"
+	"xorm.io/core"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+)
+
+var logger = log.New("tsdb.sqlite")
+
+// dialect is the syntax of SQLite statements.
+var dialect = sqleng.SQLDialect{
+	BracketIdentifiers:  true,
+	BacktickIdentifiers: true,
+}
+
+// driverName is the driver of the SQLite data sources, the sqlite3 driver with an authorizer denying the statements
+// which could read files out of the allowed paths.
+const driverName = "sqlite3_datasource"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.RegisterAuthorizer(authorize)
+			return nil
+		},
+	})
+	core.RegisterDriver(driverName, core.QueryDriver("sqlite3"))
+}
+
+// authorize denies attaching databases, which could be out of the allowed paths, and loading extensions.
+func authorize(action int, arg1 string, arg2 string, arg3 string) int {
+	switch {
+	case action == sqlite3.SQLITE_ATTACH:
+		return sqlite3.SQLITE_DENY
+	case action == sqlite3.SQLITE_FUNCTION && strings.EqualFold(arg2, "load_extension"):
+		return sqlite3.SQLITE_DENY
+	default:
+		return sqlite3.SQLITE_OK
+	}
+}
+
+var (
+	errNoAllowedPaths = errors.New("no SQLite database files can be read, the allowed paths are not configured")
+	errPathNotAllowed = errors.New("the SQLite database file is not in an allowed path")
+)
+
+type Service struct {
+	im instancemgmt.InstanceManager
+}
+
+func ProvideService(cfg *setting.Cfg) *Service {
+	return &Service{
+		im: datasource.NewInstanceManager(newInstanceSettings(cfg)),
+	}
+}
+
+func newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFactoryFunc {
+	return func(settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+		jsonData := sqleng.JsonData{
+			MaxOpenConns:    0,
+			MaxIdleConns:    2,
+			ConnMaxLifetime: 14400,
+		}
+
+		err := json.Unmarshal(settings.JSONData, &jsonData)
+		if err != nil {
+			return nil, fmt.Errorf("error reading settings: %w", err)
+		}
+		dsInfo := sqleng.DataSourceInfo{
+			JsonData:                jsonData,
+			URL:                     settings.URL,
+			User:                    settings.User,
+			Database:                settings.Database,
+			ID:                      settings.ID,
+			Updated:                 settings.Updated,
+			UID:                     settings.UID,
+			DecryptedSecureJSONData: settings.DecryptedSecureJSONData,
+		}
+
+		path, err := resolvePath(cfg.DataSourceSQLiteAllowedPaths, dsInfo.Database)
+		if err != nil {
+			return nil, err
+		}
+
+		// the database files are opened read-only, Grafana never writes to them
+		cnnstr := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro&_busy_timeout=5000"
+		if cfg.Env == setting.Dev {
+			logger.Debug("getEngine", "connection", cnnstr)
+		}
+
+		config := sqleng.DataPluginConfiguration{
+			DriverName:        driverName,
+			ConnectionString:  cnnstr,
+			DSInfo:            dsInfo,
+			MetricColumnTypes: []string{"TEXT", "VARCHAR", "CHAR", "CLOB", "text", "varchar", "char", "clob"},
+			RowLimit:          cfg.DataProxyRowLimit,
+			RowBytesLimit:     cfg.DataProxyRowBytesLimit,
+			// the database files are read-only, the driver interrupts a query which times out
+			Dialect: dialect,
+		}
+
+		queryResultTransformer := sqliteQueryResultTransformer{
+			log: logger,
+		}
+
+		return sqleng.NewQueryDataHandler(config, &queryResultTransformer, newSQLiteMacroEngine(), logger)
+	}
+}
+
+// resolvePath returns the absolute path of a database file, which must be in one of the allowed paths. A relative
+// path is relative to the first allowed path. Symbolic links are resolved, so that they can't lead out of the
+// allowed paths.
+func resolvePath(allowedPaths []string, path string) (string, error) {
+	if len(allowedPaths) == 0 {
+		return "", errNoAllowedPaths
+	}
+	if path == "" {
+		return "", errors.New("missing SQLite database file path")
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(allowedPaths[0], path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SQLite database file: %w", err)
+	}
+
+	for _, allowed := range allowedPaths {
+		allowed, err := filepath.EvalSymlinks(allowed)
+		if err != nil {
+			logger.Warn("Failed to resolve allowed SQLite path", "path", allowed, "err", err)
+			continue
+		}
+		rel, err := filepath.Rel(allowed, resolved)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", errPathNotAllowed
+}
+
+func (s *Service) getDataSourceHandler(pluginCtx backend.PluginContext) (*sqleng.DataSourceHandler, error) {
+	i, err := s.im.Get(pluginCtx)
+	if err != nil {
+		return nil, err
+	}
+	instance := i.(*sqleng.DataSourceHandler)
+	return instance, nil
+}
+
+func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	dsHandler, err := s.getDataSourceHandler(req.PluginContext)
+	if err != nil {
+		return nil, err
+	}
+	return dsHandler.QueryData(ctx, req)
+}
+
+type sqliteQueryResultTransformer struct {
+	log log.Logger
+}
+
+func (t *sqliteQueryResultTransformer) TransformQueryError(err error) error {
+	var driverErr sqlite3.Error
+	// errors of the statements, like syntax errors or statements denied by the authorizer, are returned to the user
+	if errors.As(err, &driverErr) && driverErr.Code != sqlite3.ErrError && driverErr.Code != sqlite3.ErrAuth {
+		t.log.Error("query error", "err", err)
+		return errQueryFailed
+	}
+
+	return err
+}
+
+var errQueryFailed = errors.New("query failed - please inspect Grafana server log for details")
+
+func (t *sqliteQueryResultTransformer) GetConverterList() []sqlutil.StringConverter {
+	return nil
+}
+
+// GetConverters returns the converters of the declared types of the columns, following the type affinity rules of
+// SQLite (https://www.sqlite.org/datatype3.html). The type of the values of columns without a declared type, like
+// expressions, is the type of their values in the first row. The type names are set so that the converters don't
+// match the empty type name of such columns.
+func (t *sqliteQueryResultTransformer) GetConverters() []sqlutil.Converter {
+	return []sqlutil.Converter{
+		{
+			Name:           "handle DATE, DATETIME and TIMESTAMP",
+			InputScanType:  reflect.TypeOf(sql.NullTime{}),
+			InputTypeName:  "DATETIME",
+			InputTypeRegex: regexp.MustCompile(`(?i)^(date|datetime|timestamp)$`),
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableTime,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullTime)
+					if !v.Valid {
+						return (*time.Time)(nil), nil
+					}
+					return &v.Time, nil
+				},
+			},
+		},
+		{
+			Name:           "handle BOOLEAN",
+			InputScanType:  reflect.TypeOf(sql.NullBool{}),
+			InputTypeName:  "BOOLEAN",
+			InputTypeRegex: regexp.MustCompile(`(?i)^boolean$`),
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableBool,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullBool)
+					if !v.Valid {
+						return (*bool)(nil), nil
+					}
+					return &v.Bool, nil
+				},
+			},
+		},
+		{
+			Name:           "handle INTEGER affinity",
+			InputScanType:  reflect.TypeOf(sql.NullInt64{}),
+			InputTypeName:  "INTEGER",
+			InputTypeRegex: regexp.MustCompile(`(?i)int`),
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableInt64,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullInt64)
+					if !v.Valid {
+						return (*int64)(nil), nil
+					}
+					return &v.Int64, nil
+				},
+			},
+		},
+		{
+			Name:           "handle TEXT affinity",
+			InputScanType:  reflect.TypeOf(sql.NullString{}),
+			InputTypeName:  "TEXT",
+			InputTypeRegex: regexp.MustCompile(`(?i)char|clob|text`),
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableString,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullString)
+					if !v.Valid {
+						return (*string)(nil), nil
+					}
+					return &v.String, nil
+				},
+			},
+		},
+		{
+			Name:           "handle REAL and NUMERIC affinity",
+			InputScanType:  reflect.TypeOf(sql.NullFloat64{}),
+			InputTypeName:  "REAL",
+			InputTypeRegex: regexp.MustCompile(`(?i)real|floa|doub|numeric|decimal`),
+			FrameConverter: sqlutil.FrameConverter{
+				FieldType: data.FieldTypeNullableFloat64,
+				ConverterFunc: func(in interface{}) (interface{}, error) {
+					v := in.(*sql.NullFloat64)
+					if !v.Valid {
+						return (*float64)(nil), nil
+					}
+					return &v.Float64, nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+)
+
+func TestSQLite(t *testing.T) {
+	dir := t.TempDir()
+	createDatabase(t, filepath.Join(dir, "metrics.db"))
+
+	cfg := setting.NewCfg()
+	cfg.DataSourceSQLiteAllowedPaths = []string{dir}
+
+	instance, err := newInstanceSettings(cfg)(backend.DataSourceInstanceSettings{
+		Database: "metrics.db",
+		JSONData: []byte("{}"),
+	})
+	require.NoError(t, err)
+	handler := instance.(*sqleng.DataSourceHandler)
+	t.Cleanup(handler.Dispose)
+
+	from := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	timeRange := backend.TimeRange{From: from, To: from.Add(time.Hour)}
+
+	query := func(t *testing.T, rawSQL string, format string) backend.DataResponse {
+		t.Helper()
+		resp, err := handler.QueryData(context.Background(), &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					RefID:     "A",
+					JSON:      []byte(`{"rawSql": "` + rawSQL + `", "format": "` + format + `"}`),
+					TimeRange: timeRange,
+					Interval:  time.Minute,
+				},
+			},
+		})
+		require.NoError(t, err)
+		return resp.Responses["A"]
+	}
+
+	t.Run("table query returns the types of the declared columns", func(t *testing.T) {
+		res := query(t, "SELECT time, host, value, up FROM metrics WHERE $__timeFilter(time) ORDER BY time LIMIT 1", "table")
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+
+		frame := res.Frames[0]
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, data.FieldTypeNullableTime, frame.Fields[0].Type())
+		require.Equal(t, data.FieldTypeNullableString, frame.Fields[1].Type())
+		require.Equal(t, data.FieldTypeNullableFloat64, frame.Fields[2].Type())
+		require.Equal(t, data.FieldTypeNullableBool, frame.Fields[3].Type())
+		require.Equal(t, from, frame.Fields[0].At(0).(*time.Time).UTC())
+	})
+
+	t.Run("time series query groups expressions by time", func(t *testing.T) {
+		res := query(t, "SELECT $__timeGroupAlias(time, '10m'), host AS metric, avg(value) AS value FROM metrics WHERE $__timeFilter(time) GROUP BY 1, 2 ORDER BY 1", "time_series")
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+
+		frame := res.Frames[0]
+		require.Len(t, frame.Fields, 2)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, from, frame.Fields[0].At(0).(time.Time).UTC())
+		require.Equal(t, "server-a", frame.Fields[1].Name)
+		require.Equal(t, 1.5, *frame.Fields[1].At(0).(*float64))
+		require.Equal(t, 3.5, *frame.Fields[1].At(1).(*float64))
+	})
+
+	t.Run("expression columns which are NULL in the first row are read as strings", func(t *testing.T) {
+		res := query(t, "SELECT NULL AS missing, 1 AS one", "table")
+		require.NoError(t, res.Error)
+		require.Equal(t, data.FieldTypeNullableString, res.Frames[0].Fields[0].Type())
+	})
+
+	t.Run("database file is read-only", func(t *testing.T) {
+		res := query(t, "DELETE FROM metrics", "table")
+		require.Error(t, res.Error)
+	})
+
+	t.Run("databases can't be attached", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "other.db")
+		createDatabase(t, other)
+
+		res := query(t, "ATTACH DATABASE '"+other+"' AS other", "table")
+		require.Error(t, res.Error)
+		require.Contains(t, res.Error.Error(), "not authorized")
+	})
+
+	t.Run("extensions can't be loaded", func(t *testing.T) {
+		res := query(t, "SELECT load_extension('extension.so')", "table")
+		require.Error(t, res.Error)
+		require.Contains(t, res.Error.Error(), "not authorized")
+	})
+
+	t.Run("read-only data sources only allow SELECT statements", func(t *testing.T) {
+		instance, err := newInstanceSettings(cfg)(backend.DataSourceInstanceSettings{
+			Database: "metrics.db",
+			JSONData: []byte(`{"readOnly": true, "statementTimeout": 10}`),
+		})
+		require.NoError(t, err)
+		handler := instance.(*sqleng.DataSourceHandler)
+		t.Cleanup(handler.Dispose)
+
+		resp, err := handler.QueryData(context.Background(), &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{RefID: "A", JSON: []byte(`{"rawSql": "SELECT count(*) FROM metrics", "format": "table"}`), TimeRange: timeRange},
+				{RefID: "B", JSON: []byte(`{"rawSql": "SELECT 1; DELETE FROM metrics", "format": "table"}`), TimeRange: timeRange},
+			},
+		})
+		require.NoError(t, err)
+		require.NoError(t, resp.Responses["A"].Error)
+		require.ErrorIs(t, resp.Responses["B"].Error, sqleng.ErrStatementNotAllowed)
+	})
+}
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	require.NoError(t, os.Mkdir(allowed, 0750))
+	createDatabase(t, filepath.Join(allowed, "metrics.db"))
+	createDatabase(t, filepath.Join(dir, "secret.db"))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.db"), filepath.Join(allowed, "link.db")))
+
+	t.Run("relative paths are relative to the first allowed path", func(t *testing.T) {
+		path, err := resolvePath([]string{allowed}, "metrics.db")
+		require.NoError(t, err)
+		require.Equal(t, "metrics.db", filepath.Base(path))
+	})
+
+	t.Run("absolute paths must be in an allowed path", func(t *testing.T) {
+		_, err := resolvePath([]string{allowed}, filepath.Join(allowed, "metrics.db"))
+		require.NoError(t, err)
+
+		_, err = resolvePath([]string{allowed}, filepath.Join(dir, "secret.db"))
+		require.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("paths can't lead out of the allowed paths", func(t *testing.T) {
+		_, err := resolvePath([]string{allowed}, "../secret.db")
+		require.ErrorIs(t, err, errPathNotAllowed)
+
+		_, err = resolvePath([]string{allowed}, "link.db")
+		require.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("no files can be read without allowed paths", func(t *testing.T) {
+		_, err := resolvePath(nil, filepath.Join(allowed, "metrics.db"))
+		require.ErrorIs(t, err, errNoAllowedPaths)
+	})
+}
+
+func createDatabase(t *testing.T, path string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	_, err = db.Exec("CREATE TABLE metrics (time DATETIME, host TEXT, value REAL, up BOOLEAN)")
+	require.NoError(t, err)
+	for i, row := range []struct {
+		time  string
+		host  string
+		value float64
+	}{
+		{"2022-06-01 10:00:00", "server-a", 1},
+		{"2022-06-01 10:05:00", "server-a", 2},
+		{"2022-06-01 10:10:00", "server-a", 3},
+		{"2022-06-01 10:15:00", "server-a", 4},
+		{"2022-06-01 12:00:00", "server-a", 100},
+	} {
+		_, err = db.Exec("INSERT INTO metrics VALUES (?, ?, ?, ?)", row.time, row.host, row.value, i%2 == 0)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,210 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultSearchLimit is the number of traces returned by a search which does not set a limit, like in the query editor.
+const defaultSearchLimit = 20
+
+// SearchResponse is the response of the search API of Tempo, for both native searches and TraceQL queries.
+type SearchResponse struct {
+	Traces []*TraceSearchMetadata `json:"traces"`
+}
+
+// TraceSearchMetadata is a trace found by a search.
+type TraceSearchMetadata struct {
+	TraceID           string `json:"traceID"`
+	RootServiceName   string `json:"rootServiceName"`
+	RootTraceName     string `json:"rootTraceName"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	DurationMs        int64  `json:"durationMs"`
+	// SpanSet is the set of spans matching a TraceQL query, replaced by SpanSets in later versions of Tempo.
+	SpanSet  *SpanSet   `json:"spanSet"`
+	SpanSets []*SpanSet `json:"spanSets"`
+}
+
+// SpanSet is a set of spans of a trace matching a TraceQL query.
+type SpanSet struct {
+	Spans   []json.RawMessage `json:"spans"`
+	Matched int64             `json:"matched"`
+}
+
+// startTime returns the start time of the trace.
+func (t *TraceSearchMetadata) startTime() time.Time {
+	nanos, err := strconv.ParseInt(t.StartTimeUnixNano, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// matchedSpans returns the number of spans of the trace matching a TraceQL query, or nil for a native search.
+func (t *TraceSearchMetadata) matchedSpans() *int64 {
+	spanSets := t.SpanSets
+	if len(spanSets) == 0 && t.SpanSet != nil {
+		spanSets = []*SpanSet{t.SpanSet}
+	}
+	if len(spanSets) == 0 {
+		return nil
+	}
+
+	var matched int64
+	for _, spanSet := range spanSets {
+		if spanSet.Matched > 0 {
+			matched += spanSet.Matched
+		} else {
+			matched += int64(len(spanSet.Spans))
+		}
+	}
+	return &matched
+}
+
+// querySearch searches traces with tags or a TraceQL query, and returns a table of the traces found.
+func (s *Service) querySearch(ctx context.Context, dsInfo *datasourceInfo, query backend.DataQuery, model *QueryModel) (backend.DataResponse, error) {
+	queryRes := backend.DataResponse{}
+
+	traces, err := s.search(ctx, dsInfo, query, model, model.QueryType)
+	if err != nil {
+		queryRes.Error = err
+		return queryRes, nil
+	}
+
+	frame := searchResultToFrame(traces)
+	frame.RefID = query.RefID
+	queryRes.Frames = data.Frames{frame}
+	return queryRes, nil
+}
+
+// search returns the traces found by a native search or a TraceQL query in the time range of the query.
+func (s *Service) search(ctx context.Context, dsInfo *datasourceInfo, query backend.DataQuery, model *QueryModel, searchType string) ([]*TraceSearchMetadata, error) {
+	params, err := searchParams(query, model, searchType)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dsInfo.URL+"/api/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	s.tlog.Debug("Tempo search request", "url", req.URL.String())
+	resp, err := dsInfo.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed get to tempo: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.tlog.Warn("failed to close response body", "err", err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search traces Status: %s Body: %s", resp.Status, string(body))
+	}
+
+	var searchResp SearchResponse
+	if err := json.Unmarshal(body, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode tempo search response: %w", err)
+	}
+	return searchResp.Traces, nil
+}
+
+// searchParams returns the parameters of the search API for a query, the same as the query editor sends.
+func searchParams(query backend.DataQuery, model *QueryModel, searchType string) (url.Values, error) {
+	params := url.Values{}
+
+	limit := model.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+	params.Set("limit", strconv.FormatInt(limit, 10))
+	params.Set("start", strconv.FormatInt(query.TimeRange.From.Unix(), 10))
+	params.Set("end", strconv.FormatInt(query.TimeRange.To.Unix(), 10))
+
+	if searchType == queryTypeTraceQL {
+		if strings.TrimSpace(model.TraceID) == "" {
+			return nil, fmt.Errorf("TraceQL query is empty")
+		}
+		params.Set("q", model.TraceID)
+		return params, nil
+	}
+
+	tags := strings.TrimSpace(model.Search)
+	if model.ServiceName != "" {
+		tags += fmt.Sprintf(" service.name=%q", model.ServiceName)
+	}
+	if model.SpanName != "" {
+		tags += fmt.Sprintf(" name=%q", model.SpanName)
+	}
+	if tags = strings.TrimSpace(tags); tags != "" {
+		params.Set("tags", tags)
+	}
+
+	for name, duration := range map[string]string{"minDuration": model.MinDuration, "maxDuration": model.MaxDuration} {
+		duration = strings.ReplaceAll(duration, " ", "")
+		if duration == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(duration); err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", name, duration)
+		}
+		params.Set(name, duration)
+	}
+	return params, nil
+}
+
+// searchResultToFrame returns a table of the traces found by a search, the most recent traces first.
+func searchResultToFrame(traces []*TraceSearchMetadata) *data.Frame {
+	sort.SliceStable(traces, func(i, j int) bool {
+		return traces[i].startTime().After(traces[j].startTime())
+	})
+
+	traceIDs := make([]string, 0, len(traces))
+	services := make([]string, 0, len(traces))
+	names := make([]string, 0, len(traces))
+	startTimes := make([]time.Time, 0, len(traces))
+	durations := make([]float64, 0, len(traces))
+	spans := make([]*int64, 0, len(traces))
+	for _, trace := range traces {
+		traceIDs = append(traceIDs, trace.TraceID)
+		services = append(services, trace.RootServiceName)
+		names = append(names, trace.RootTraceName)
+		startTimes = append(startTimes, trace.startTime())
+		durations = append(durations, float64(trace.DurationMs))
+		spans = append(spans, trace.matchedSpans())
+	}
+
+	frame := data.NewFrame("Traces",
+		data.NewField("traceID", nil, traceIDs).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Trace ID"}),
+		data.NewField("traceService", nil, services).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Service"}),
+		data.NewField("traceName", nil, names).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Name"}),
+		data.NewField("startTime", nil, startTimes).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Start time"}),
+		data.NewField("duration", nil, durations).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Duration", Unit: "ms"}),
+		data.NewField("spanCount", nil, spans).SetConfig(&data.FieldConfig{DisplayNameFromDS: "Matched spans"}),
+	)
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	return frame
+}
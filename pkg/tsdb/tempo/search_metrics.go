@@ -0,0 +1,156 @@
+package tempo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultSearchMetricsLimit is the number of traces searched by a metrics query which does not set a limit.
+const defaultSearchMetricsLimit = 1000
+
+// maxSearchMetricsPoints is the maximum number of intervals of the series of a metrics query, the interval is
+// increased for longer time ranges.
+const maxSearchMetricsPoints = 11000
+
+// Functions applied by metrics queries to the traces found in each interval.
+const (
+	metricCount       = "count"
+	metricAvgDuration = "avgDuration"
+	metricMaxDuration = "maxDuration"
+	metricSpanCount   = "spanCount"
+)
+
+// querySearchMetrics searches traces like a native search or TraceQL query, and returns a time series of the traces
+// found in each interval of the time range, which can be used by alert rules.
+func (s *Service) querySearchMetrics(ctx context.Context, dsInfo *datasourceInfo, query backend.DataQuery, model *QueryModel) (backend.DataResponse, error) {
+	queryRes := backend.DataResponse{}
+
+	metric := model.Metric
+	if metric == "" {
+		metric = metricCount
+	}
+	switch metric {
+	case metricCount, metricAvgDuration, metricMaxDuration, metricSpanCount:
+	default:
+		queryRes.Error = fmt.Errorf("unsupported metric: %q", metric)
+		return queryRes, nil
+	}
+	if !query.TimeRange.To.After(query.TimeRange.From) {
+		queryRes.Error = fmt.Errorf("invalid time range: %s to %s", query.TimeRange.From, query.TimeRange.To)
+		return queryRes, nil
+	}
+
+	searchType := model.SearchType
+	if searchType == "" {
+		searchType = queryTypeNativeSearch
+		if model.TraceID != "" {
+			searchType = queryTypeTraceQL
+		}
+	}
+
+	searchModel := *model
+	if searchModel.Limit == 0 {
+		searchModel.Limit = defaultSearchMetricsLimit
+	}
+	traces, err := s.search(ctx, dsInfo, query, &searchModel, searchType)
+	if err != nil {
+		queryRes.Error = err
+		return queryRes, nil
+	}
+
+	frame := searchMetricsFrame(traces, metric, query.TimeRange, searchMetricsStep(query))
+	frame.RefID = query.RefID
+	if int64(len(traces)) >= searchModel.Limit {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("The search returned the maximum number of traces (%d), the series may be incomplete.", searchModel.Limit),
+		})
+	}
+	queryRes.Frames = data.Frames{frame}
+	return queryRes, nil
+}
+
+// searchMetricsStep returns the interval of the series of a metrics query.
+func searchMetricsStep(query backend.DataQuery) time.Duration {
+	step := query.Interval
+	if step <= 0 && query.MaxDataPoints > 0 {
+		step = query.TimeRange.Duration() / time.Duration(query.MaxDataPoints)
+	}
+	if step < time.Second {
+		step = time.Second
+	}
+	// the series has an interval more than the time range when the start of the range is not aligned on the step
+	if minStep := query.TimeRange.Duration() / (maxSearchMetricsPoints - 1); step <= minStep {
+		step = minStep.Truncate(time.Second) + time.Second
+	}
+	return step
+}
+
+// searchMetricsFrame returns the value of a metric for the traces starting in each interval of the time range.
+// Intervals without traces have a count of zero and no duration. The end of the time range must be after its start.
+func searchMetricsFrame(traces []*TraceSearchMetadata, metric string, timeRange backend.TimeRange, step time.Duration) *data.Frame {
+	start := timeRange.From.Truncate(step)
+	buckets := int(timeRange.To.Sub(start)/step) + 1
+
+	times := make([]time.Time, buckets)
+	counts := make([]int64, buckets)
+	values := make([]*float64, buckets)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * step).UTC()
+	}
+
+	for _, trace := range traces {
+		t := trace.startTime()
+		if t.Before(start) || t.After(timeRange.To) {
+			continue
+		}
+		i := int(t.Sub(start) / step)
+		counts[i]++
+
+		var value float64
+		switch metric {
+		case metricCount:
+			value = 1
+		case metricSpanCount:
+			if matched := trace.matchedSpans(); matched != nil {
+				value = float64(*matched)
+			}
+		default:
+			value = float64(trace.DurationMs)
+		}
+
+		switch {
+		case values[i] == nil:
+			values[i] = &value
+		case metric == metricMaxDuration:
+			if value > *values[i] {
+				*values[i] = value
+			}
+		default:
+			*values[i] += value
+		}
+	}
+
+	for i, value := range values {
+		switch {
+		case value == nil && (metric == metricCount || metric == metricSpanCount):
+			zero := float64(0)
+			values[i] = &zero
+		case value != nil && metric == metricAvgDuration:
+			*value /= float64(counts[i])
+		}
+	}
+
+	config := &data.FieldConfig{DisplayNameFromDS: metric}
+	if metric == metricAvgDuration || metric == metricMaxDuration {
+		config.Unit = "ms"
+	}
+	return data.NewFrame(metric,
+		data.NewField(data.TimeSeriesTimeFieldName, nil, times),
+		data.NewField(data.TimeSeriesValueFieldName, nil, values).SetConfig(config),
+	)
+}
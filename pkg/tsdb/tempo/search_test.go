@@ -0,0 +1,169 @@
+package tempo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const searchResponse = `{
+	"traces": [
+		{
+			"traceID": "1",
+			"rootServiceName": "loki-all",
+			"rootTraceName": "HTTP GET",
+			"startTimeUnixNano": "1616072924000000000",
+			"durationMs": 10,
+			"spanSets": [{ "spans": [{ "spanID": "a" }, { "spanID": "b" }], "matched": 3 }]
+		},
+		{
+			"traceID": "2",
+			"rootServiceName": "loki-all",
+			"rootTraceName": "HTTP POST",
+			"startTimeUnixNano": "1616072984000000000",
+			"durationMs": 30,
+			"spanSet": { "spans": [{ "spanID": "c" }] }
+		},
+		{
+			"traceID": "3",
+			"rootServiceName": "loki-all",
+			"rootTraceName": "HTTP GET",
+			"startTimeUnixNano": "1616072930000000000",
+			"durationMs": 20
+		}
+	]
+}`
+
+func TestSearch(t *testing.T) {
+	from := time.Unix(1616072900, 0)
+	to := time.Unix(1616073000, 0)
+	timeRange := backend.TimeRange{From: from, To: to}
+
+	var requested url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/search", r.URL.Path)
+		requested = r.URL.Query()
+		_, err := rw.Write([]byte(searchResponse))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(ts.Close)
+
+	service := &Service{tlog: log.New("tempo-test")}
+	dsInfo := &datasourceInfo{HTTPClient: ts.Client(), URL: ts.URL}
+
+	t.Run("native search sends tags and durations", func(t *testing.T) {
+		model := &QueryModel{
+			QueryType:   queryTypeNativeSearch,
+			Search:      `http.status_code=500`,
+			ServiceName: "loki-all",
+			MinDuration: "10 ms",
+		}
+		res, err := service.querySearch(context.Background(), dsInfo, backend.DataQuery{RefID: "A", TimeRange: timeRange}, model)
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+
+		assert.Equal(t, `http.status_code=500 service.name="loki-all"`, requested.Get("tags"))
+		assert.Equal(t, "10ms", requested.Get("minDuration"))
+		assert.Equal(t, "20", requested.Get("limit"))
+		assert.Equal(t, "1616072900", requested.Get("start"))
+		assert.Equal(t, "1616073000", requested.Get("end"))
+		assert.Empty(t, requested.Get("q"))
+
+		require.Len(t, res.Frames, 1)
+		frame := res.Frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 3, frame.Rows())
+		// most recent traces first
+		assert.Equal(t, "2", frame.Fields[0].At(0))
+		assert.Equal(t, "3", frame.Fields[0].At(1))
+		assert.Equal(t, "1", frame.Fields[0].At(2))
+		assert.Equal(t, time.Unix(1616072984, 0).UTC(), frame.Fields[3].At(0))
+		assert.Equal(t, 30., frame.Fields[4].At(0))
+		assert.Equal(t, int64(1), *frame.Fields[5].At(0).(*int64))
+		assert.Nil(t, frame.Fields[5].At(1))
+		assert.Equal(t, int64(3), *frame.Fields[5].At(2).(*int64))
+	})
+
+	t.Run("TraceQL search sends the query", func(t *testing.T) {
+		model := &QueryModel{QueryType: queryTypeTraceQL, TraceID: `{ .http.status_code = 500 }`, Limit: 5}
+		res, err := service.querySearch(context.Background(), dsInfo, backend.DataQuery{RefID: "A", TimeRange: timeRange}, model)
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+
+		assert.Equal(t, `{ .http.status_code = 500 }`, requested.Get("q"))
+		assert.Equal(t, "5", requested.Get("limit"))
+		assert.Empty(t, requested.Get("tags"))
+	})
+
+	t.Run("invalid duration returns an error", func(t *testing.T) {
+		model := &QueryModel{QueryType: queryTypeNativeSearch, MaxDuration: "10 parsecs"}
+		res, err := service.querySearch(context.Background(), dsInfo, backend.DataQuery{RefID: "A", TimeRange: timeRange}, model)
+		require.NoError(t, err)
+		require.Error(t, res.Error)
+	})
+
+	t.Run("metrics query returns the traces of each interval", func(t *testing.T) {
+		query := backend.DataQuery{RefID: "A", TimeRange: timeRange, Interval: time.Minute}
+
+		res, err := service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics})
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+		assert.Equal(t, "1000", requested.Get("limit"))
+
+		require.Len(t, res.Frames, 1)
+		frame := res.Frames[0]
+		require.Equal(t, 3, frame.Rows())
+		assert.Equal(t, time.Unix(1616072880, 0).UTC(), frame.Fields[0].At(0))
+		assert.Equal(t, 2., *frame.Fields[1].At(0).(*float64))
+		assert.Equal(t, 1., *frame.Fields[1].At(1).(*float64))
+		assert.Equal(t, 0., *frame.Fields[1].At(2).(*float64))
+
+		res, err = service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics, Metric: metricAvgDuration})
+		require.NoError(t, err)
+		frame = res.Frames[0]
+		assert.Equal(t, 15., *frame.Fields[1].At(0).(*float64))
+		assert.Equal(t, 30., *frame.Fields[1].At(1).(*float64))
+		assert.Nil(t, frame.Fields[1].At(2))
+		assert.Equal(t, "ms", frame.Fields[1].Config.Unit)
+	})
+
+	t.Run("metrics query warns when the search is truncated", func(t *testing.T) {
+		query := backend.DataQuery{RefID: "A", TimeRange: timeRange, Interval: time.Minute}
+		res, err := service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics, Limit: 3})
+		require.NoError(t, err)
+		require.Len(t, res.Frames[0].Meta.Notices, 1)
+	})
+
+	t.Run("metrics query over a long time range is limited in intervals", func(t *testing.T) {
+		query := backend.DataQuery{RefID: "A", TimeRange: backend.TimeRange{From: to.AddDate(-1, 0, 0), To: to}, Interval: time.Second}
+		res, err := service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics})
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+		assert.LessOrEqual(t, res.Frames[0].Rows(), maxSearchMetricsPoints)
+	})
+
+	t.Run("metrics query with unknown metric returns an error", func(t *testing.T) {
+		query := backend.DataQuery{RefID: "A", TimeRange: timeRange}
+		res, err := service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics, Metric: "p99"})
+		require.NoError(t, err)
+		require.Error(t, res.Error)
+	})
+
+	t.Run("metrics query with an inverted or empty time range returns an error", func(t *testing.T) {
+		for _, tr := range []backend.TimeRange{{From: to, To: from}, {From: from, To: from}} {
+			query := backend.DataQuery{RefID: "A", TimeRange: tr, Interval: time.Minute}
+			res, err := service.querySearchMetrics(context.Background(), dsInfo, query, &QueryModel{QueryType: queryTypeMetrics})
+			require.NoError(t, err)
+			require.Error(t, res.Error)
+			require.Empty(t, res.Frames)
+		}
+	})
+}
@@ -33,8 +33,31 @@ type datasourceInfo struct {
 	URL        string
 }
 
+// Query types of the Tempo data source, a query without query type fetches a trace by its ID.
+const (
+	queryTypeTraceID      = "traceId"
+	queryTypeNativeSearch = "nativeSearch"
+	queryTypeTraceQL      = "traceql"
+	queryTypeMetrics      = "metrics"
+)
+
 type QueryModel struct {
+	QueryType string `json:"queryType"`
+	// TraceID is the ID of the trace of trace ID queries, and the TraceQL query of TraceQL queries.
 	TraceID string `json:"query"`
+
+	// Search is the tag filter of native search queries, in logfmt.
+	Search      string `json:"search"`
+	ServiceName string `json:"serviceName"`
+	SpanName    string `json:"spanName"`
+	MinDuration string `json:"minDuration"`
+	MaxDuration string `json:"maxDuration"`
+	Limit       int64  `json:"limit"`
+
+	// Metric is the function applied to the traces found in each interval by metrics queries, see searchMetrics.
+	Metric string `json:"metric"`
+	// SearchType is the type of search of metrics queries, traceql or nativeSearch.
+	SearchType string `json:"searchType"`
 }
 
 func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.InstanceFactoryFunc {
@@ -59,28 +82,50 @@ func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.Inst
 
 func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	result := backend.NewQueryDataResponse()
-	queryRes := backend.DataResponse{}
-	refID := req.Queries[0].RefID
-
-	model := &QueryModel{}
-	err := json.Unmarshal(req.Queries[0].JSON, model)
-	if err != nil {
-		return result, err
-	}
 
 	dsInfo, err := s.getDSInfo(req.PluginContext)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, q := range req.Queries {
+		model := &QueryModel{}
+		err := json.Unmarshal(q.JSON, model)
+		if err != nil {
+			return result, err
+		}
+
+		var queryRes backend.DataResponse
+		switch model.QueryType {
+		case "", queryTypeTraceID:
+			queryRes, err = s.queryTrace(ctx, dsInfo, q, model)
+		case queryTypeNativeSearch, queryTypeTraceQL:
+			queryRes, err = s.querySearch(ctx, dsInfo, q, model)
+		case queryTypeMetrics:
+			queryRes, err = s.querySearchMetrics(ctx, dsInfo, q, model)
+		default:
+			queryRes = backend.DataResponse{Error: fmt.Errorf("unsupported query type: %q", model.QueryType)}
+		}
+		if err != nil {
+			return &backend.QueryDataResponse{}, err
+		}
+		result.Responses[q.RefID] = queryRes
+	}
+
+	return result, nil
+}
+
+func (s *Service) queryTrace(ctx context.Context, dsInfo *datasourceInfo, query backend.DataQuery, model *QueryModel) (backend.DataResponse, error) {
+	queryRes := backend.DataResponse{}
+
 	request, err := s.createRequest(ctx, dsInfo, model.TraceID)
 	if err != nil {
-		return result, err
+		return queryRes, err
 	}
 
 	resp, err := dsInfo.HTTPClient.Do(request)
 	if err != nil {
-		return result, fmt.Errorf("failed get to tempo: %w", err)
+		return queryRes, fmt.Errorf("failed get to tempo: %w", err)
 	}
 
 	defer func() {
@@ -91,30 +136,27 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &backend.QueryDataResponse{}, err
+		return queryRes, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		queryRes.Error = fmt.Errorf("failed to get trace with id: %s Status: %s Body: %s", model.TraceID, resp.Status, string(body))
-		result.Responses[refID] = queryRes
-		return result, nil
+		return queryRes, nil
 	}
 
 	otTrace, err := otlp.NewProtobufTracesUnmarshaler().UnmarshalTraces(body)
 
 	if err != nil {
-		return &backend.QueryDataResponse{}, fmt.Errorf("failed to convert tempo response to Otlp: %w", err)
+		return queryRes, fmt.Errorf("failed to convert tempo response to Otlp: %w", err)
 	}
 
 	frame, err := TraceToFrame(otTrace)
 	if err != nil {
-		return &backend.QueryDataResponse{}, fmt.Errorf("failed to transform trace %v to data frame: %w", model.TraceID, err)
+		return queryRes, fmt.Errorf("failed to transform trace %v to data frame: %w", model.TraceID, err)
 	}
-	frame.RefID = refID
-	frames := []*data.Frame{frame}
-	queryRes.Frames = frames
-	result.Responses[refID] = queryRes
-	return result, nil
+	frame.RefID = query.RefID
+	queryRes.Frames = []*data.Frame{frame}
+	return queryRes, nil
 }
 
 func (s *Service) createRequest(ctx context.Context, dsInfo *datasourceInfo, traceID string) (*http.Request, error) {
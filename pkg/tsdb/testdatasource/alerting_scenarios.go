@@ -0,0 +1,363 @@
+package testdatasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// alertingOptions are the options of the alerting scenarios. Time is divided in windows of period seconds, aligned on
+// the epoch like the predictable pulse, so that the same time range always returns the same data. The windows cycle
+// through onCount windows with data and offCount windows without data.
+type alertingOptions struct {
+	TimeStep    int64   `json:"timeStep"`
+	Period      int64   `json:"period"`
+	OnCount     int64   `json:"onCount"`
+	OffCount    int64   `json:"offCount"`
+	SeriesCount int     `json:"seriesCount"`
+	Value       float64 `json:"value"`
+}
+
+func parseAlertingOptions(model *simplejson.Json, defaultSeriesCount int) (alertingOptions, error) {
+	options := alertingOptions{
+		TimeStep:    60,
+		Period:      300,
+		OnCount:     1,
+		OffCount:    1,
+		SeriesCount: defaultSeriesCount,
+		Value:       1,
+	}
+
+	raw, err := model.Get("alerting").Encode()
+	if err != nil {
+		return options, err
+	}
+	if string(raw) != "null" {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return options, fmt.Errorf("failed to parse alerting options: %w", err)
+		}
+	}
+
+	if options.TimeStep <= 0 || options.Period <= 0 {
+		return options, fmt.Errorf("timeStep and period must be positive")
+	}
+	if options.OnCount < 0 || options.OffCount < 0 || options.OnCount+options.OffCount == 0 {
+		return options, fmt.Errorf("onCount and offCount can't be negative and can't both be 0")
+	}
+	if options.SeriesCount < 1 {
+		return options, fmt.Errorf("seriesCount must be at least 1")
+	}
+	return options, nil
+}
+
+// hasData returns whether a window of the cycle has data, the cycle of a series is shifted by its shift windows.
+func (o alertingOptions) hasData(window int64, shift int64) bool {
+	cycle := o.OnCount + o.OffCount
+	return (window+shift)%cycle < o.OnCount
+}
+
+// points calls fn for the time of every point in the time range, with the window of the point.
+func (o alertingOptions) points(timeRange backend.TimeRange, fn func(t time.Time, window int64)) {
+	step := o.TimeStep * 1000
+	period := o.Period * 1000
+	from := timeRange.From.UnixNano() / int64(time.Millisecond)
+	to := timeRange.To.UnixNano() / int64(time.Millisecond)
+
+	maxPoints := 10000 // Don't return too many points
+	for cursor, i := from-(from%step), 0; cursor < to && i < maxPoints; cursor, i = cursor+step, i+1 {
+		if cursor < from {
+			continue
+		}
+		fn(time.Unix(cursor/1000, (cursor%1000)*int64(time.Millisecond)), cursor/period)
+	}
+}
+
+func newAlertingFrame(query backend.DataQuery, model *simplejson.Json, index int, labels data.Labels) *data.Frame {
+	frame := newSeriesForQuery(query, model, index)
+	frame.Fields = data.Fields{
+		data.NewField(data.TimeSeriesTimeFieldName, nil, []time.Time{}),
+		data.NewField(data.TimeSeriesValueFieldName, labels, []float64{}),
+	}
+	return frame
+}
+
+func seriesLabels(model *simplejson.Json, series int) data.Labels {
+	labels := parseLabels(model)
+	labels["series"] = strconv.Itoa(series)
+	return labels
+}
+
+// missingSeries returns series which appear and disappear over time, the cycle of every series is shifted by one
+// window. Series without data in the time range aren't returned.
+func missingSeries(query backend.DataQuery, model *simplejson.Json) (data.Frames, error) {
+	options, err := parseAlertingOptions(model, 3)
+	if err != nil {
+		return nil, err
+	}
+	return windowedSeries(query, model, options, true), nil
+}
+
+// labelChurn returns series whose labels change in every window, every window of a series is a new series with a
+// generation label.
+func labelChurn(query backend.DataQuery, model *simplejson.Json) (data.Frames, error) {
+	options, err := parseAlertingOptions(model, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames data.Frames
+	for i := 0; i < options.SeriesCount; i++ {
+		var frame *data.Frame
+		generation := int64(-1)
+		options.points(query.TimeRange, func(t time.Time, window int64) {
+			if frame == nil || window != generation {
+				generation = window
+				labels := seriesLabels(model, i)
+				labels["generation"] = strconv.FormatInt(window, 10)
+				frame = newAlertingFrame(query, model, i, labels)
+				frames = append(frames, frame)
+			}
+			frame.AppendRow(t, options.Value)
+		})
+	}
+	return frames, nil
+}
+
+// noDataWindows returns series which all have no data in the same windows. No series are returned when there is no
+// data in the time range.
+func noDataWindows(query backend.DataQuery, model *simplejson.Json) (data.Frames, error) {
+	options, err := parseAlertingOptions(model, 1)
+	if err != nil {
+		return nil, err
+	}
+	return windowedSeries(query, model, options, false), nil
+}
+
+// windowedSeries returns the series with data in the time range, optionally shifting the cycle of every series.
+func windowedSeries(query backend.DataQuery, model *simplejson.Json, options alertingOptions, shift bool) data.Frames {
+	frames := make(data.Frames, 0, options.SeriesCount)
+	for i := 0; i < options.SeriesCount; i++ {
+		var seriesShift int64
+		if shift {
+			seriesShift = int64(i)
+		}
+
+		frame := newAlertingFrame(query, model, i, seriesLabels(model, i))
+		options.points(query.TimeRange, func(t time.Time, window int64) {
+			if options.hasData(window, seriesShift) {
+				frame.AppendRow(t, options.Value)
+			}
+		})
+		if frame.Rows() > 0 {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+type replayOptions struct {
+	FileName string `json:"fileName"`
+	Content  string `json:"content"`
+}
+
+// replay plays back recorded frames, the times of the frames are shifted so that the recording ends at the end of
+// the time range. Points outside of the time range are dropped.
+func (s *Service) replay(query backend.DataQuery, model *simplejson.Json) (data.Frames, error) {
+	var options replayOptions
+	raw, err := model.Get("replay").Encode()
+	if err != nil {
+		return nil, err
+	}
+	if string(raw) != "null" {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return nil, fmt.Errorf("failed to parse replay options: %w", err)
+		}
+	}
+
+	content := []byte(options.Content)
+	if options.FileName != "" {
+		if content, err = s.loadRecordingFile(options.FileName); err != nil {
+			return nil, err
+		}
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	frames, err := parseRecording(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var end time.Time
+	for _, frame := range frames {
+		forEachTime(frame, func(t time.Time) {
+			if t.After(end) {
+				end = t
+			}
+		})
+	}
+	if end.IsZero() {
+		return frames, nil
+	}
+
+	shift := query.TimeRange.To.Sub(end)
+	replayed := make(data.Frames, 0, len(frames))
+	for _, frame := range frames {
+		if frame, err = shiftFrame(frame, shift, query.TimeRange); err != nil {
+			return nil, err
+		}
+		replayed = append(replayed, frame)
+	}
+	return replayed, nil
+}
+
+var validRecordingFileName = regexp.MustCompile(`^\w+\.json$`)
+
+func (s *Service) loadRecordingFile(fileName string) ([]byte, error) {
+	if !validRecordingFileName.MatchString(fileName) {
+		return nil, fmt.Errorf("invalid recording file name: %q", fileName)
+	}
+
+	filePath := filepath.Join(s.cfg.StaticRootPath, "testdata", filepath.Clean(filepath.Join("/", fileName)))
+
+	// Can ignore gosec G304 here, because we check the file pattern above
+	// nolint:gosec
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording file: %v", err)
+	}
+	return content, nil
+}
+
+// parseRecording reads a recording of frames, which is either a frame, a list of frames, or a query response of
+// the query API, as shown in the query inspector.
+func parseRecording(content []byte) (data.Frames, error) {
+	content = bytes.TrimSpace(content)
+
+	if content[0] == '[' {
+		var frames data.Frames
+		if err := json.Unmarshal(content, &frames); err != nil {
+			return nil, fmt.Errorf("failed to parse recorded frames: %w", err)
+		}
+		return frames, nil
+	}
+
+	var response struct {
+		Results map[string]struct {
+			Frames data.Frames `json:"frames"`
+		} `json:"results"`
+		Schema json.RawMessage `json:"schema"`
+	}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse recording: %w", err)
+	}
+	if response.Schema != nil {
+		frame := &data.Frame{}
+		if err := json.Unmarshal(content, frame); err != nil {
+			return nil, fmt.Errorf("failed to parse recorded frame: %w", err)
+		}
+		return data.Frames{frame}, nil
+	}
+
+	refIDs := make([]string, 0, len(response.Results))
+	for refID := range response.Results {
+		refIDs = append(refIDs, refID)
+	}
+	sort.Strings(refIDs)
+
+	var frames data.Frames
+	for _, refID := range refIDs {
+		frames = append(frames, response.Results[refID].Frames...)
+	}
+	return frames, nil
+}
+
+func forEachTime(frame *data.Frame, fn func(t time.Time)) {
+	for _, field := range frame.Fields {
+		if field.Type() != data.FieldTypeTime && field.Type() != data.FieldTypeNullableTime {
+			continue
+		}
+		for i := 0; i < field.Len(); i++ {
+			if v, ok := field.ConcreteAt(i); ok {
+				fn(v.(time.Time))
+			}
+		}
+	}
+}
+
+// shiftFrame shifts the times of a frame, and drops the rows whose time is outside of the time range.
+func shiftFrame(frame *data.Frame, shift time.Duration, timeRange backend.TimeRange) (*data.Frame, error) {
+	timeIndex := -1
+	for i, field := range frame.Fields {
+		switch field.Type() {
+		case data.FieldTypeTime:
+			for j := 0; j < field.Len(); j++ {
+				field.Set(j, field.At(j).(time.Time).Add(shift))
+			}
+		case data.FieldTypeNullableTime:
+			for j := 0; j < field.Len(); j++ {
+				if t := field.At(j).(*time.Time); t != nil {
+					shifted := t.Add(shift)
+					field.Set(j, &shifted)
+				}
+			}
+		default:
+			continue
+		}
+		if timeIndex == -1 {
+			timeIndex = i
+		}
+	}
+	if timeIndex == -1 {
+		return frame, nil
+	}
+
+	return frame.FilterRowsByField(timeIndex, func(v interface{}) (bool, error) {
+		var t time.Time
+		switch v := v.(type) {
+		case time.Time:
+			t = v
+		case *time.Time:
+			if v == nil {
+				return false, nil
+			}
+			t = *v
+		}
+		return !t.Before(timeRange.From) && !t.After(timeRange.To), nil
+	})
+}
+
+func (s *Service) handleAlertingScenario(generate func(query backend.DataQuery, model *simplejson.Json) (data.Frames, error)) backend.QueryDataHandlerFunc {
+	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+		resp := backend.NewQueryDataResponse()
+
+		for _, q := range req.Queries {
+			model, err := simplejson.NewJson(q.JSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse query json: %v", err)
+			}
+
+			respD := resp.Responses[q.RefID]
+			frames, err := generate(q, model)
+			if err != nil {
+				respD.Error = err
+			}
+			respD.Frames = append(respD.Frames, frames...)
+			resp.Responses[q.RefID] = respD
+		}
+
+		return resp, nil
+	}
+}
@@ -0,0 +1,146 @@
+package testdatasource
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestAlertingScenarios(t *testing.T) {
+	s := &Service{cfg: setting.NewCfg()}
+
+	// the windows are 5 minutes, starting at 10:00
+	from := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	query := func(t *testing.T, handler backend.QueryDataHandlerFunc, model string, timeRange backend.TimeRange) backend.DataResponse {
+		t.Helper()
+		resp, err := handler(context.Background(), &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{{RefID: "A", TimeRange: timeRange, JSON: []byte(model)}},
+		})
+		require.NoError(t, err)
+		return resp.Responses["A"]
+	}
+
+	seriesLabels := func(frames data.Frames) []string {
+		var labels []string
+		for _, frame := range frames {
+			labels = append(labels, frame.Fields[1].Labels.String())
+		}
+		return labels
+	}
+
+	t.Run("missing series appear and disappear in turns", func(t *testing.T) {
+		handler := s.handleAlertingScenario(missingSeries)
+		model := `{"alerting": {"timeStep": 60, "period": 300, "onCount": 1, "offCount": 2}}`
+
+		res := query(t, handler, model, backend.TimeRange{From: from, To: from.Add(5 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Equal(t, []string{"series=0"}, seriesLabels(res.Frames))
+		require.Equal(t, 5, res.Frames[0].Rows())
+
+		res = query(t, handler, model, backend.TimeRange{From: from.Add(5 * time.Minute), To: from.Add(10 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Equal(t, []string{"series=2"}, seriesLabels(res.Frames))
+
+		res = query(t, handler, model, backend.TimeRange{From: from, To: from.Add(15 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 3)
+	})
+
+	t.Run("label churn returns a new series in every window", func(t *testing.T) {
+		handler := s.handleAlertingScenario(labelChurn)
+
+		res := query(t, handler, `{"labels": "{job=\"churn\"}"}`, backend.TimeRange{From: from, To: from.Add(10 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Equal(t, []string{
+			"generation=5513592, job=churn, series=0",
+			"generation=5513593, job=churn, series=0",
+		}, seriesLabels(res.Frames))
+	})
+
+	t.Run("no data windows return no series without data", func(t *testing.T) {
+		handler := s.handleAlertingScenario(noDataWindows)
+		model := `{"alerting": {"seriesCount": 2, "value": 5}}`
+
+		res := query(t, handler, model, backend.TimeRange{From: from, To: from.Add(5 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 2)
+		require.Equal(t, 5.0, res.Frames[0].Fields[1].At(0))
+
+		res = query(t, handler, model, backend.TimeRange{From: from.Add(5 * time.Minute), To: from.Add(10 * time.Minute)})
+		require.NoError(t, res.Error)
+		require.Empty(t, res.Frames)
+	})
+
+	t.Run("invalid options return an error", func(t *testing.T) {
+		res := query(t, s.handleAlertingScenario(noDataWindows), `{"alerting": {"onCount": 0, "offCount": 0}}`, backend.TimeRange{From: from, To: from.Add(time.Hour)})
+		require.Error(t, res.Error)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		recorded := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		frame := data.NewFrame("recording",
+			data.NewField("time", nil, []time.Time{recorded, recorded.Add(time.Minute), recorded.Add(2 * time.Minute)}),
+			data.NewField("value", data.Labels{"host": "a"}, []float64{1, 2, 3}),
+		)
+		frames, err := json.Marshal(data.Frames{frame})
+		require.NoError(t, err)
+		handler := s.handleAlertingScenario(s.replay)
+
+		model := func(t *testing.T, options replayOptions) string {
+			t.Helper()
+			b, err := json.Marshal(map[string]interface{}{"replay": options})
+			require.NoError(t, err)
+			return string(b)
+		}
+
+		t.Run("shifts the recording to the end of the time range", func(t *testing.T) {
+			res := query(t, handler, model(t, replayOptions{Content: string(frames)}), backend.TimeRange{From: from, To: from.Add(time.Hour)})
+			require.NoError(t, res.Error)
+			require.Len(t, res.Frames, 1)
+			require.Equal(t, 3, res.Frames[0].Rows())
+			require.Equal(t, from.Add(58*time.Minute), res.Frames[0].Fields[0].At(0))
+			require.Equal(t, from.Add(time.Hour), res.Frames[0].Fields[0].At(2))
+			require.Equal(t, "a", res.Frames[0].Fields[1].Labels["host"])
+		})
+
+		t.Run("drops points outside of the time range", func(t *testing.T) {
+			res := query(t, handler, model(t, replayOptions{Content: string(frames)}), backend.TimeRange{From: from, To: from.Add(90 * time.Second)})
+			require.NoError(t, res.Error)
+			require.Equal(t, 2, res.Frames[0].Rows())
+			require.Equal(t, []float64{2, 3}, []float64{res.Frames[0].Fields[1].At(0).(float64), res.Frames[0].Fields[1].At(1).(float64)})
+		})
+
+		t.Run("reads query responses from the query inspector", func(t *testing.T) {
+			response := `{"results": {"A": {"frames": ` + string(frames) + `}}}`
+			res := query(t, handler, model(t, replayOptions{Content: response}), backend.TimeRange{From: from, To: from.Add(time.Hour)})
+			require.NoError(t, res.Error)
+			require.Len(t, res.Frames, 1)
+			require.Equal(t, 3, res.Frames[0].Rows())
+		})
+
+		t.Run("reads recording files", func(t *testing.T) {
+			s := &Service{cfg: setting.NewCfg()}
+			s.cfg.StaticRootPath = t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(s.cfg.StaticRootPath, "testdata"), 0750))
+			require.NoError(t, os.WriteFile(filepath.Join(s.cfg.StaticRootPath, "testdata", "recording.json"), frames, 0600))
+			handler := s.handleAlertingScenario(s.replay)
+
+			res := query(t, handler, model(t, replayOptions{FileName: "recording.json"}), backend.TimeRange{From: from, To: from.Add(time.Hour)})
+			require.NoError(t, res.Error)
+			require.Len(t, res.Frames, 1)
+
+			res = query(t, handler, model(t, replayOptions{FileName: "../recording.json"}), backend.TimeRange{From: from, To: from.Add(time.Hour)})
+			require.Error(t, res.Error)
+		})
+	})
+}
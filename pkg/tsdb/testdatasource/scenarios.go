@@ -42,6 +42,10 @@ const (
 	rawFrameQuery                     queryType = "raw_frame"
 	csvFileQueryType                  queryType = "csv_file"
 	csvContentQueryType               queryType = "csv_content"
+	missingSeriesQuery                queryType = "missing_series"
+	labelChurnQuery                   queryType = "label_churn"
+	noDataWindowsQuery                queryType = "no_data_windows"
+	replayQuery                       queryType = "replay"
 )
 
 type queryType string
@@ -212,6 +216,40 @@ Timestamps will line up evenly on timeStepSeconds (For example, 60 seconds means
 		handler: s.handleCsvContentScenario,
 	})
 
+	s.registerScenario(&Scenario{
+		ID:      string(missingSeriesQuery),
+		Name:    "Missing Series",
+		handler: s.handleAlertingScenario(missingSeries),
+		Description: `Missing Series returns series which appear and disappear over time, to test alerting on missing series.
+Time is divided in windows of period seconds, which cycle through onCount windows with data and offCount windows without data.
+The cycle of every series is shifted by one window, and series without data in the time range aren't returned.`,
+	})
+
+	s.registerScenario(&Scenario{
+		ID:      string(labelChurnQuery),
+		Name:    "Label Churn",
+		handler: s.handleAlertingScenario(labelChurn),
+		Description: `Label Churn returns series whose labels change in every window of period seconds.
+The data of every window is a new series with a generation label.`,
+	})
+
+	s.registerScenario(&Scenario{
+		ID:      string(noDataWindowsQuery),
+		Name:    "No Data Windows",
+		handler: s.handleAlertingScenario(noDataWindows),
+		Description: `No Data Windows returns series which have no data at the same time, to test alerting on no data.
+Time is divided in windows of period seconds, which cycle through onCount windows with data and offCount windows without data.
+No series are returned when there is no data in the time range.`,
+	})
+
+	s.registerScenario(&Scenario{
+		ID:      string(replayQuery),
+		Name:    "Replay",
+		handler: s.handleAlertingScenario(s.replay),
+		Description: `Replay plays back recorded data frames, for example the response shown in the query inspector.
+The recording is shifted to end at the end of the time range, and points outside of the time range are dropped.`,
+	})
+
 	s.queryMux.HandleFunc("", s.handleFallbackScenario)
 }
 
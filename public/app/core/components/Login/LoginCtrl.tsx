@@ -15,8 +15,17 @@ export interface FormModel {
   email: string;
 }
 
+export type TOTPStep = 'verify' | 'enroll';
+
+export interface TOTPEnrollment {
+  secret: string;
+  url: string;
+}
+
 interface Props {
   resetCode?: string;
+  // totpStep continues the login of a user who signed up or accepted an invite with the second step
+  totpStep?: TOTPStep;
 
   children: (props: {
     isLoggingIn: boolean;
@@ -29,16 +38,25 @@ interface Props {
     isOauthEnabled: boolean;
     loginHint: string;
     passwordHint: string;
+    totpStep?: TOTPStep;
+    totpEnrollment?: TOTPEnrollment;
+    verifyTOTP: (code: string) => void;
+    recoveryCodes?: string[];
+    continueLogin: () => void;
   }) => JSX.Element;
 }
 
 interface State {
   isLoggingIn: boolean;
   isChangingPassword: boolean;
+  totpStep?: TOTPStep;
+  totpEnrollment?: TOTPEnrollment;
+  recoveryCodes?: string[];
 }
 
 export class LoginCtrl extends PureComponent<Props, State> {
   result: any = {};
+  isDefaultPassword = false;
 
   constructor(props: Props) {
     super(props);
@@ -52,6 +70,13 @@ export class LoginCtrl extends PureComponent<Props, State> {
     }
   }
 
+  componentDidMount() {
+    const { totpStep } = this.props;
+    if (totpStep === 'verify' || totpStep === 'enroll') {
+      this.startTOTP(totpStep);
+    }
+  }
+
   changePassword = (password: string) => {
     const pw = {
       newPassword: password,
@@ -90,12 +115,12 @@ export class LoginCtrl extends PureComponent<Props, State> {
       .post('/login', formModel)
       .then((result) => {
         this.result = result;
-        if (formModel.password !== 'admin' || config.ldapEnabled || config.authProxyEnabled) {
-          this.toGrafana();
+        this.isDefaultPassword = formModel.password === 'admin';
+        if (result.totp) {
+          this.startTOTP(result.totp);
           return;
-        } else {
-          this.changeView();
         }
+        this.continueLogin();
       })
       .catch(() => {
         this.setState({
@@ -104,6 +129,54 @@ export class LoginCtrl extends PureComponent<Props, State> {
       });
   };
 
+  startTOTP = (step: TOTPStep) => {
+    if (step === 'verify') {
+      this.setState({ isLoggingIn: false, totpStep: step });
+      return;
+    }
+
+    // users who have to enroll get the secret for their authenticator app first
+    getBackendSrv()
+      .post('/login/totp/enroll')
+      .then((enrollment: TOTPEnrollment) => {
+        this.setState({ isLoggingIn: false, totpStep: step, totpEnrollment: enrollment });
+      })
+      .catch(() => {
+        this.setState({ isLoggingIn: false });
+      });
+  };
+
+  verifyTOTP = (code: string) => {
+    this.setState({
+      isLoggingIn: true,
+    });
+
+    getBackendSrv()
+      .post('/login/totp', { code })
+      .then((result) => {
+        this.result = result;
+        if (result.recoveryCodes) {
+          this.setState({ isLoggingIn: false, totpStep: undefined, recoveryCodes: result.recoveryCodes });
+          return;
+        }
+        this.continueLogin();
+      })
+      .catch(() => {
+        this.setState({
+          isLoggingIn: false,
+        });
+      });
+  };
+
+  continueLogin = () => {
+    if (!this.isDefaultPassword || config.ldapEnabled || config.authProxyEnabled) {
+      this.toGrafana();
+      return;
+    }
+    this.setState({ totpStep: undefined, recoveryCodes: undefined });
+    this.changeView();
+  };
+
   changeView = () => {
     this.setState({
       isChangingPassword: true,
@@ -125,8 +198,8 @@ export class LoginCtrl extends PureComponent<Props, State> {
 
   render() {
     const { children } = this.props;
-    const { isLoggingIn, isChangingPassword } = this.state;
-    const { login, toGrafana, changePassword } = this;
+    const { isLoggingIn, isChangingPassword, totpStep, totpEnrollment, recoveryCodes } = this.state;
+    const { login, toGrafana, changePassword, verifyTOTP, continueLogin } = this;
     const { loginHint, passwordHint, disableLoginForm, disableUserSignUp } = config;
 
     return (
@@ -142,6 +215,11 @@ export class LoginCtrl extends PureComponent<Props, State> {
           changePassword,
           skipPasswordChange: toGrafana,
           isChangingPassword,
+          totpStep,
+          totpEnrollment,
+          verifyTOTP,
+          recoveryCodes,
+          continueLogin,
         })}
       </>
     );
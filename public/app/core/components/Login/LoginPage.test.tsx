@@ -85,7 +85,14 @@ describe('Login Page', () => {
     await waitFor(() => expect(postMock).toHaveBeenCalledWith('/login', { password: 'test', user: 'admin' }));
     expect(window.location.assign).toHaveBeenCalledWith('/');
   });
-  it('renders social logins correctly', () => {
+  it('should continue with the enrollment of two-factor authentication after a sign up', async () => {
+    postMock.mockResolvedValueOnce({ secret: 'SECRET', url: 'otpauth://totp/Grafana:admin?secret=SECRET' });
+    render(<LoginPage queryParams={{ totp: 'enroll' }} />);
+
+    await waitFor(() => expect(postMock).toHaveBeenCalledWith('/login/totp/enroll'));
+    expect(screen.queryByRole('button', { name: 'Login button' })).not.toBeInTheDocument();
+  });
+    it('renders social logins correctly', () => {
     (runtimeMock as any).config.oauth = {
       okta: {
         name: 'Okta Test',
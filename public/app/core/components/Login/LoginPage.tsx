@@ -6,13 +6,15 @@ import React, { FC } from 'react';
 import { HorizontalGroup, LinkButton } from '@grafana/ui';
 import { Branding } from 'app/core/components/Branding/Branding';
 import config from 'app/core/config';
+import { GrafanaRouteComponentProps } from 'app/core/navigation/types';
 
 import { ChangePassword } from '../ForgottenPassword/ChangePassword';
 
-import LoginCtrl from './LoginCtrl';
+import LoginCtrl, { TOTPStep } from './LoginCtrl';
 import { LoginForm } from './LoginForm';
 import { LoginLayout, InnerBox } from './LoginLayout';
 import { LoginServiceButtons } from './LoginServiceButtons';
+import { RecoveryCodes, TOTPForm } from './TOTPForm';
 import { UserSignup } from './UserSignup';
 
 const forgottenPasswordStyles = css`
@@ -20,11 +22,13 @@ const forgottenPasswordStyles = css`
   margin-top: 4px;
 `;
 
-export const LoginPage: FC = () => {
+interface Props extends Partial<GrafanaRouteComponentProps<{}, { totp?: TOTPStep }>> {}
+
+export const LoginPage: FC<Props> = ({ queryParams }) => {
   document.title = Branding.AppTitle;
   return (
     <LoginLayout>
-      <LoginCtrl>
+      <LoginCtrl totpStep={queryParams?.totp}>
         {({
           loginHint,
           passwordHint,
@@ -35,9 +39,24 @@ export const LoginPage: FC = () => {
           changePassword,
           skipPasswordChange,
           isChangingPassword,
+          totpStep,
+          totpEnrollment,
+          verifyTOTP,
+          recoveryCodes,
+          continueLogin,
         }) => (
           <>
-            {!isChangingPassword && (
+            {totpStep && (
+              <InnerBox>
+                <TOTPForm step={totpStep} enrollment={totpEnrollment} onSubmit={verifyTOTP} isLoggingIn={isLoggingIn} />
+              </InnerBox>
+            )}
+            {recoveryCodes && (
+              <InnerBox>
+                <RecoveryCodes recoveryCodes={recoveryCodes} onContinue={continueLogin} />
+              </InnerBox>
+            )}
+            {!isChangingPassword && !totpStep && !recoveryCodes && (
               <InnerBox>
                 {!disableLoginForm && (
                   <LoginForm
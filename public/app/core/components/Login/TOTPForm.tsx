@@ -0,0 +1,99 @@
+import { css } from '@emotion/css';
+import React, { FC } from 'react';
+
+import { Button, Form, Input, Field, VerticalGroup } from '@grafana/ui';
+
+import { TOTPEnrollment, TOTPStep } from './LoginCtrl';
+import { submitButton } from './LoginForm';
+
+interface Props {
+  step: TOTPStep;
+  enrollment?: TOTPEnrollment;
+  onSubmit: (code: string) => void;
+  isLoggingIn: boolean;
+}
+
+interface CodeDTO {
+  code: string;
+}
+
+const wrapperStyles = css`
+  width: 100%;
+  padding-bottom: 16px;
+`;
+
+const secretStyles = css`
+  font-family: monospace;
+  word-break: break-all;
+`;
+
+export const TOTPForm: FC<Props> = ({ step, enrollment, onSubmit, isLoggingIn }) => {
+  const submit = (dto: CodeDTO) => {
+    onSubmit(dto.code);
+  };
+
+  return (
+    <div className={wrapperStyles}>
+      <Form onSubmit={submit}>
+        {({ register, errors }) => (
+          <>
+            {step === 'enroll' && enrollment && (
+              <>
+                <p>
+                  Your organization requires two-factor authentication. Add this secret to your authenticator app,
+                  or open the link on your phone, and enter the code it shows.
+                </p>
+                <p className={secretStyles}>{enrollment.secret}</p>
+                <p>
+                  <a href={enrollment.url}>Open in authenticator app</a>
+                </p>
+              </>
+            )}
+            <Field
+              label={step === 'enroll' ? 'Authentication code' : 'Authentication code or recovery code'}
+              invalid={!!errors.code}
+              error={errors.code?.message}
+            >
+              <Input
+                {...register('code', { required: 'Code is required' })}
+                id="totp-code"
+                autoFocus
+                autoComplete="one-time-code"
+                autoCapitalize="none"
+              />
+            </Field>
+            <Button type="submit" className={submitButton} disabled={isLoggingIn}>
+              {isLoggingIn ? 'Verifying...' : 'Verify'}
+            </Button>
+          </>
+        )}
+      </Form>
+    </div>
+  );
+};
+
+interface RecoveryCodesProps {
+  recoveryCodes: string[];
+  onContinue: () => void;
+}
+
+export const RecoveryCodes: FC<RecoveryCodesProps> = ({ recoveryCodes, onContinue }) => {
+  return (
+    <div className={wrapperStyles}>
+      <VerticalGroup>
+        <p>
+          Save these recovery codes in a safe place. Each code can be used once to log in if you lose access to your
+          authenticator app.
+        </p>
+        <ul className={secretStyles}>
+          {recoveryCodes.map((code) => (
+            <li key={code}>{code}</li>
+          ))}
+        </ul>
+        <Button className={submitButton} onClick={onContinue}>
+          Continue
+        </Button>
+      </VerticalGroup>
+    </div>
+  );
+};
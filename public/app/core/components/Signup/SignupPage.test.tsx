@@ -108,4 +108,22 @@ describe('Signup Page', () => {
     );
     expect(window.location.assign).toHaveBeenCalledWith('/');
   });
+  it('should navigate to the login page if the user has to enroll in two-factor authentication', async () => {
+    Object.defineProperty(window, 'location', {
+      value: {
+        assign: jest.fn(),
+      },
+    });
+    postMock.mockResolvedValueOnce({ message: 'Two-factor authentication required', totp: 'enroll' });
+    render(<SignupPage {...props} />);
+
+    await userEvent.type(screen.getByRole('textbox', { name: 'Email' }), 'test@gmail.com');
+    await userEvent.type(screen.getByLabelText('Password'), 'admin');
+    await userEvent.type(screen.getByLabelText('Confirm password'), 'admin');
+    fireEvent.click(screen.getByRole('button', { name: 'Submit' }));
+
+    await waitFor(() => expect(postMock).toHaveBeenCalled());
+    expect(window.location.assign).toHaveBeenCalledWith('/login?totp=enroll');
+    expect(window.location.assign).not.toHaveBeenCalledWith('/');
+  });
 });
@@ -48,6 +48,11 @@ export const SignupPage: FC<Props> = (props) => {
         notifyApp.warning(msg);
       });
 
+    // users of an organization which enforces two-factor authentication have to enroll before they are logged in
+    if (response.totp) {
+      window.location.assign(getConfig().appSubUrl + '/login?totp=' + response.totp);
+      return;
+    }
     if (response.code === 'redirect-to-select-org') {
       window.location.assign(getConfig().appSubUrl + '/profile/select-org?signup=1');
     }
@@ -131,4 +131,19 @@ describe('SignupInvitedPage', () => {
       });
     });
   });
+
+  describe('when the organization enforces two-factor authentication', () => {
+    it('then the user should continue with the enrollment on the login page', async () => {
+      const originalLocation = window.location;
+      Object.defineProperty(window, 'location', { configurable: true, value: { href: '' } });
+      const { postSpy } = await setupTestContext();
+      postSpy.mockResolvedValue({ message: 'Two-factor authentication required', totp: 'enroll' });
+
+      await userEvent.type(screen.getByPlaceholderText(/password/i), 'pass@word1');
+      await userEvent.click(screen.getByRole('button', { name: /sign up/i }));
+
+      await waitFor(() => expect(window.location.href).toBe('/login?totp=enroll'));
+      Object.defineProperty(window, 'location', { configurable: true, value: originalLocation });
+    });
+  });
 });
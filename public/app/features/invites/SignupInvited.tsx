@@ -49,7 +49,12 @@ export const SignupInvitedPage: FC<Props> = ({ match }) => {
   }, [code]);
 
   const onSubmit = async (formData: FormModel) => {
-    await getBackendSrv().post('/api/user/invite/complete', { ...formData, inviteCode: code });
+    const result = await getBackendSrv().post('/api/user/invite/complete', { ...formData, inviteCode: code });
+    // users of an organization which enforces two-factor authentication have to enroll before they are logged in
+    if (result?.totp) {
+      window.location.href = getConfig().appSubUrl + '/login?totp=' + result.totp;
+      return;
+    }
     window.location.href = getConfig().appSubUrl + '/';
   };
 
@@ -5,11 +5,13 @@ import { useMount } from 'react-use';
 import { VerticalGroup } from '@grafana/ui';
 import { Page } from 'app/core/components/Page/Page';
 import SharedPreferences from 'app/core/components/SharedPreferences/SharedPreferences';
+import config from 'app/core/config';
 import { StoreState } from 'app/types';
 
 import UserOrganizations from './UserOrganizations';
 import UserProfileEditForm from './UserProfileEditForm';
 import UserSessions from './UserSessions';
+import { UserTOTP } from './UserTOTP';
 import { UserTeams } from './UserTeams';
 import { changeUserOrg, initUserProfilePage, revokeUserSession, updateUserProfile } from './state/actions';
 
@@ -65,6 +67,7 @@ export function UserProfileEditPage({
           <SharedPreferences resourceUri="user" />
           <UserTeams isLoading={teamsAreLoading} teams={teams} />
           <UserOrganizations isLoading={orgsAreLoading} setUserOrg={changeUserOrg} orgs={orgs} user={user} />
+          {config.totpEnabled && <UserTOTP />}
           <UserSessions isLoading={sessionsAreLoading} revokeUserSession={revokeUserSession} sessions={sessions} />
         </VerticalGroup>
       </Page.Contents>
@@ -0,0 +1,100 @@
+import React, { useCallback, useEffect, useState } from 'react';
+
+import { getBackendSrv } from '@grafana/runtime';
+import { Button, Field, HorizontalGroup, Input, LoadingPlaceholder } from '@grafana/ui';
+import { TOTPEnrollment } from 'app/core/components/Login/LoginCtrl';
+import { RecoveryCodes } from 'app/core/components/Login/TOTPForm';
+
+interface TOTPStatus {
+  enabled: boolean;
+  enforced: boolean;
+}
+
+export const UserTOTP = () => {
+  const [status, setStatus] = useState<TOTPStatus>();
+  const [enrollment, setEnrollment] = useState<TOTPEnrollment>();
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>();
+  const [code, setCode] = useState('');
+
+  const loadStatus = useCallback(() => getBackendSrv().get('/api/user/totp').then(setStatus), []);
+
+  useEffect(() => {
+    loadStatus();
+  }, [loadStatus]);
+
+  const enroll = () => getBackendSrv().post('/api/user/totp/enroll').then(setEnrollment);
+
+  const submitCode = (url: string) =>
+    getBackendSrv()
+      .post(url, { code })
+      .then((result) => {
+        setCode('');
+        setEnrollment(undefined);
+        setRecoveryCodes(result.recoveryCodes);
+        return loadStatus();
+      });
+
+  if (!status) {
+    return <LoadingPlaceholder text="Loading two-factor authentication..." />;
+  }
+
+  const codeField = (
+    <Field label="Authentication code" description="Code of your authenticator app, or a recovery code">
+      <Input
+        id="totp-code"
+        width={30}
+        autoComplete="one-time-code"
+        value={code}
+        onChange={(event) => setCode(event.currentTarget.value)}
+      />
+    </Field>
+  );
+
+  return (
+    <div>
+      <h3 className="page-sub-heading">Two-factor authentication</h3>
+      {recoveryCodes && <RecoveryCodes recoveryCodes={recoveryCodes} onContinue={() => setRecoveryCodes(undefined)} />}
+      {!recoveryCodes && enrollment && (
+        <>
+          <p>Add this secret to your authenticator app, or open the link on your phone, and enter the code it shows.</p>
+          <p>
+            <code>{enrollment.secret}</code>
+          </p>
+          <p>
+            <a href={enrollment.url}>Open in authenticator app</a>
+          </p>
+          {codeField}
+          <Button onClick={() => submitCode('/api/user/totp/confirm')} disabled={!code}>
+            Confirm
+          </Button>
+        </>
+      )}
+      {!recoveryCodes && !enrollment && !status.enabled && (
+        <>
+          <p>
+            {status.enforced
+              ? 'Your organization requires two-factor authentication.'
+              : 'Protect your account with a code of an authenticator app when you log in.'}
+          </p>
+          <Button onClick={enroll}>Enable</Button>
+        </>
+      )}
+      {!recoveryCodes && !enrollment && status.enabled && (
+        <>
+          <p>Two-factor authentication is enabled.</p>
+          {codeField}
+          <HorizontalGroup>
+            <Button variant="secondary" onClick={() => submitCode('/api/user/totp/recovery-codes')} disabled={!code}>
+              New recovery codes
+            </Button>
+            {!status.enforced && (
+              <Button variant="destructive" onClick={() => submitCode('/api/user/totp/disable')} disabled={!code}>
+                Disable
+              </Button>
+            )}
+          </HorizontalGroup>
+        </>
+      )}
+    </div>
+  );
+};
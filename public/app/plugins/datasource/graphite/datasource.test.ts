@@ -30,6 +30,7 @@ describe('graphiteDatasource', () => {
     jest.clearAllMocks();
 
     const instanceSettings = {
+      id: 1,
       url: '/api/datasources/proxy/1',
       name: 'graphiteProd',
       jsonData: {
@@ -284,15 +285,22 @@ describe('graphiteDatasource', () => {
   });
 
   describe('when fetching Graphite function descriptions', () => {
-    // `"default": Infinity` (invalid JSON) in params passed by Graphite API in 1.1.7
-    const INVALID_JSON =
-      '{"testFunction":{"name":"function","description":"description","module":"graphite.render.functions","group":"Transform","params":[{"name":"param","type":"intOrInf","required":true,"default":Infinity}]}}';
+    // the backend replaces `"default": Infinity` (invalid JSON) passed by Graphite API in 1.1.7 with 1e9999
+    const FUNCTIONS = {
+      testFunction: {
+        name: 'function',
+        description: 'description',
+        module: 'graphite.render.functions',
+        group: 'Transform',
+        params: [{ name: 'param', type: 'intOrInf', required: true, default: Infinity }],
+      },
+    };
 
-    it('should parse the response with an invalid JSON', async () => {
-      fetchMock.mockImplementation(() => {
-        return of(createFetchResponse(INVALID_JSON));
-      });
+    it('should request the functions of the backend resource', async () => {
+      ctx.ds.getResource = jest.fn().mockResolvedValue(FUNCTIONS);
       const funcDefs = await ctx.ds.getFuncDefs();
+      expect(ctx.ds.getResource).toHaveBeenCalledWith('functions');
+      expect(fetchMock).not.toHaveBeenCalled();
       expect(funcDefs).toEqual({
         testFunction: {
           category: 'Transform',
@@ -312,6 +320,13 @@ describe('graphiteDatasource', () => {
         },
       });
     });
+
+    it('should fall back to the built-in functions when the request fails', async () => {
+      jest.spyOn(console, 'error').mockImplementation();
+      ctx.ds.getResource = jest.fn().mockRejectedValue({ status: 502 });
+      const funcDefs = await ctx.ds.getFuncDefs();
+      expect(funcDefs).toHaveProperty('sumSeries');
+    });
   });
 
   describe('building graphite params', () => {
@@ -412,89 +427,84 @@ describe('graphiteDatasource', () => {
   describe('querying for template variables', () => {
     let results: any;
     let requestOptions: any;
+    let resource: { method: string; path: string; params: any };
 
     beforeEach(() => {
       fetchMock.mockImplementation((options: any) => {
         requestOptions = options;
         return of(createFetchResponse(['backend_01', 'backend_02']));
       });
+      ctx.ds.getResource = jest.fn().mockImplementation((path: string, params?: any) => {
+        resource = { method: 'GET', path, params };
+        return Promise.resolve(['backend_01', 'backend_02']);
+      });
+      ctx.ds.postResource = jest.fn().mockImplementation((path: string, params?: any) => {
+        resource = { method: 'POST', path, params };
+        return Promise.resolve(['backend_01', 'backend_02']);
+      });
     });
 
-    it('should generate tags query', () => {
-      ctx.ds.metricFindQuery('tags()').then((data: any) => {
-        results = data;
-      });
+    it('should generate tags query', async () => {
+      results = await ctx.ds.metricFindQuery('tags()');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/tags');
-      expect(requestOptions.params.expr).toEqual([]);
-      expect(results).not.toBe(null);
+      expect(resource.path).toBe('tags/autoComplete/tags');
+      expect(resource.params.expr).toEqual([]);
+      expect(results).toEqual([{ text: 'backend_01' }, { text: 'backend_02' }]);
     });
 
-    it('should generate tags query with a filter expression', () => {
-      ctx.ds.metricFindQuery('tags(server=backend_01)').then((data: any) => {
-        results = data;
-      });
+    it('should generate tags query with a filter expression', async () => {
+      results = await ctx.ds.metricFindQuery('tags(server=backend_01)');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/tags');
-      expect(requestOptions.params.expr).toEqual(['server=backend_01']);
+      expect(resource.path).toBe('tags/autoComplete/tags');
+      expect(resource.params.expr).toEqual(['server=backend_01']);
       expect(results).not.toBe(null);
     });
 
-    it('should generate tags query for an expression with whitespace after', () => {
-      ctx.ds.metricFindQuery('tags(server=backend_01 )').then((data: any) => {
-        results = data;
-      });
+    it('should generate tags query for an expression with whitespace after', async () => {
+      results = await ctx.ds.metricFindQuery('tags(server=backend_01 )');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/tags');
-      expect(requestOptions.params.expr).toEqual(['server=backend_01']);
+      expect(resource.path).toBe('tags/autoComplete/tags');
+      expect(resource.params.expr).toEqual(['server=backend_01']);
       expect(results).not.toBe(null);
     });
 
-    it('should generate tag values query for one tag', () => {
-      ctx.ds.metricFindQuery('tag_values(server)').then((data: any) => {
-        results = data;
-      });
+    it('should generate tag values query for one tag', async () => {
+      results = await ctx.ds.metricFindQuery('tag_values(server)');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/values');
-      expect(requestOptions.params.tag).toBe('server');
-      expect(requestOptions.params.expr).toEqual([]);
+      expect(resource.path).toBe('tags/autoComplete/values');
+      expect(resource.params.tag).toBe('server');
+      expect(resource.params.expr).toEqual([]);
       expect(results).not.toBe(null);
     });
 
-    it('should generate tag values query for a tag and expression', () => {
-      ctx.ds.metricFindQuery('tag_values(server,server=~backend*)').then((data: any) => {
-        results = data;
-      });
+    it('should generate tag values query for a tag and expression', async () => {
+      results = await ctx.ds.metricFindQuery('tag_values(server,server=~backend*)');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/values');
-      expect(requestOptions.params.tag).toBe('server');
-      expect(requestOptions.params.expr).toEqual(['server=~backend*']);
+      expect(resource.path).toBe('tags/autoComplete/values');
+      expect(resource.params.tag).toBe('server');
+      expect(resource.params.expr).toEqual(['server=~backend*']);
       expect(results).not.toBe(null);
     });
 
-    it('should generate tag values query for a tag with whitespace after', () => {
-      ctx.ds.metricFindQuery('tag_values(server )').then((data: any) => {
-        results = data;
-      });
+    it('should generate tag values query for a tag with whitespace after', async () => {
+      results = await ctx.ds.metricFindQuery('tag_values(server )');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/values');
-      expect(requestOptions.params.tag).toBe('server');
-      expect(requestOptions.params.expr).toEqual([]);
+      expect(resource.path).toBe('tags/autoComplete/values');
+      expect(resource.params.tag).toBe('server');
+      expect(resource.params.expr).toEqual([]);
       expect(results).not.toBe(null);
     });
 
-    it('should generate tag values query for a tag and expression with whitespace after', () => {
-      ctx.ds.metricFindQuery('tag_values(server , server=~backend* )').then((data: any) => {
-        results = data;
-      });
+    it('should generate tag values query for a tag and expression with whitespace after', async () => {
+      results = await ctx.ds.metricFindQuery('tag_values(server , server=~backend* )');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/tags/autoComplete/values');
-      expect(requestOptions.params.tag).toBe('server');
-      expect(requestOptions.params.expr).toEqual(['server=~backend*']);
+      expect(resource.path).toBe('tags/autoComplete/values');
+      expect(resource.params.tag).toBe('server');
+      expect(resource.params.expr).toEqual(['server=~backend*']);
       expect(results).not.toBe(null);
     });
 
-    it('/metrics/find should be POST', () => {
+    it('/metrics/find should be POST', async () => {
       ctx.templateSrv.init([
         {
           type: 'query',
@@ -502,54 +512,55 @@ describe('graphiteDatasource', () => {
           current: { value: ['bar'] },
         },
       ]);
-      ctx.ds.metricFindQuery('[[foo]]').then((data: any) => {
-        results = data;
-      });
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/find');
-      expect(requestOptions.method).toEqual('POST');
-      expect(requestOptions.headers).toHaveProperty('Content-Type', 'application/x-www-form-urlencoded');
-      expect(requestOptions.data).toMatch(`query=bar`);
-      expect(requestOptions).toHaveProperty('params');
+      results = await ctx.ds.metricFindQuery('[[foo]]');
+
+      expect(resource.path).toBe('metrics/find');
+      expect(resource.method).toEqual('POST');
+      expect(resource.params).toEqual({ query: 'bar' });
+      expect(fetchMock).not.toHaveBeenCalled();
     });
 
-    it('should interpolate $__searchFilter with searchFilter', () => {
-      ctx.ds.metricFindQuery('app.$__searchFilter', { searchFilter: 'backend' }).then((data: any) => {
-        results = data;
+    it('/metrics/find should be limited to the time range', async () => {
+      results = await ctx.ds.metricFindQuery('app.*', {
+        range: { from: dateTime(1663999980000), to: dateTime(1664003580000) },
       });
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/find');
-      expect(requestOptions.params).toEqual({});
-      expect(requestOptions.data).toEqual('query=app.backend*');
+      expect(resource.path).toBe('metrics/find');
+      expect(resource.params).toEqual({ query: 'app.*', from: 1663999980, until: 1664003580 });
+    });
+
+    it('should interpolate $__searchFilter with searchFilter', async () => {
+      results = await ctx.ds.metricFindQuery('app.$__searchFilter', { searchFilter: 'backend' });
+
+      expect(resource.path).toBe('metrics/find');
+      expect(resource.params).toEqual({ query: 'app.backend*' });
       expect(results).not.toBe(null);
     });
 
-    it('should interpolate $__searchFilter with default when searchFilter is missing', () => {
-      ctx.ds.metricFindQuery('app.$__searchFilter', {}).then((data: any) => {
-        results = data;
-      });
+    it('should interpolate $__searchFilter with default when searchFilter is missing', async () => {
+      results = await ctx.ds.metricFindQuery('app.$__searchFilter', {});
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/find');
-      expect(requestOptions.params).toEqual({});
-      expect(requestOptions.data).toEqual('query=app.*');
+      expect(resource.path).toBe('metrics/find');
+      expect(resource.params).toEqual({ query: 'app.*' });
       expect(results).not.toBe(null);
     });
 
-    it('should request expanded metrics', () => {
-      ctx.ds.metricFindQuery('expand(*.servers.*)').then((data: any) => {
-        results = data;
+    it('should request expanded metrics', async () => {
+      ctx.ds.getResource = jest.fn().mockImplementation((path: string, params?: any) => {
+        resource = { method: 'GET', path, params };
+        return Promise.resolve({ results: ['a.servers.b'] });
       });
+      results = await ctx.ds.metricFindQuery('expand(*.servers.*)');
 
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/expand');
-      expect(requestOptions.params.query).toBe('*.servers.*');
-      expect(results).not.toBe(null);
+      expect(resource.path).toBe('metrics/expand');
+      expect(resource.params.query).toBe('*.servers.*');
+      expect(results).toEqual([{ text: 'a.servers.b', expandable: false }]);
     });
 
     it('should fetch from /metrics/find endpoint when queryType is default or query is string', async () => {
       const stringQuery = 'query';
-      ctx.ds.metricFindQuery(stringQuery).then((data: any) => {
-        results = data;
-      });
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/find');
+      results = await ctx.ds.metricFindQuery(stringQuery);
+      expect(resource.path).toBe('metrics/find');
       expect(results).not.toBe(null);
 
       const objectQuery = {
@@ -559,7 +570,7 @@ describe('graphiteDatasource', () => {
         datasource: ctx.ds,
       };
       const data = await ctx.ds.metricFindQuery(objectQuery);
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/find');
+      expect(resource.path).toBe('metrics/find');
       expect(data).toBeTruthy();
     });
 
@@ -621,13 +632,11 @@ describe('graphiteDatasource', () => {
     });
 
     it('should return metric names when queryType is GraphiteQueryType.MetricName', async () => {
-      fetchMock.mockImplementation((options: any) => {
-        requestOptions = options;
-        return of(
-          createFetchResponse({
-            results: ['apps.backend.backend_01', 'apps.backend.backend_02', 'apps.country.IE', 'apps.country.SE'],
-          })
-        );
+      ctx.ds.getResource = jest.fn().mockImplementation((path: string, params?: any) => {
+        resource = { method: 'GET', path, params };
+        return Promise.resolve({
+          results: ['apps.backend.backend_01', 'apps.backend.backend_02', 'apps.country.IE', 'apps.country.SE'],
+        });
       });
 
       const fq: GraphiteQuery = {
@@ -637,7 +646,7 @@ describe('graphiteDatasource', () => {
         datasource: ctx.ds,
       };
       const data = await ctx.ds.metricFindQuery(fq);
-      expect(requestOptions.url).toBe('/api/datasources/proxy/1/metrics/expand');
+      expect(resource.path).toBe('metrics/expand');
       expect(data[0].text).toBe('apps.backend.backend_01');
       expect(data[1].text).toBe('apps.backend.backend_02');
       expect(data[2].text).toBe('apps.country.IE');
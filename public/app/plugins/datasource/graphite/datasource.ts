@@ -1,5 +1,5 @@
 import { each, indexOf, isArray, isString, map as _map } from 'lodash';
-import { lastValueFrom, merge, Observable, of, OperatorFunction, pipe, throwError } from 'rxjs';
+import { lastValueFrom, merge, Observable, of, throwError } from 'rxjs';
 import { catchError, map } from 'rxjs/operators';
 
 import {
@@ -9,7 +9,6 @@ import {
   DataFrame,
   DataQueryRequest,
   DataQueryResponse,
-  DataSourceApi,
   DataSourceWithQueryExportSupport,
   dateMath,
   dateTime,
@@ -20,7 +19,7 @@ import {
   TimeZone,
   toDataFrame,
 } from '@grafana/data';
-import { getBackendSrv } from '@grafana/runtime';
+import { DataSourceWithBackend, getBackendSrv } from '@grafana/runtime';
 import { isVersionGtOrEq, SemVersion } from 'app/core/utils/version';
 import { getTemplateSrv, TemplateSrv } from 'app/features/templating/template_srv';
 import { getRollupNotice, getRuntimeConsolidationNotice } from 'app/plugins/datasource/graphite/meta';
@@ -66,7 +65,7 @@ function convertGlobToRegEx(text: string): string {
 }
 
 export class GraphiteDatasource
-  extends DataSourceApi<GraphiteQuery, GraphiteOptions, GraphiteQueryImportConfiguration>
+  extends DataSourceWithBackend<GraphiteQuery, GraphiteOptions>
   implements DataSourceWithQueryExportSupport<GraphiteQuery>
 {
   basicAuth: string;
@@ -532,9 +531,9 @@ export class GraphiteDatasource
     }
 
     if (useExpand || queryObject.queryType === GraphiteQueryType.MetricName) {
-      return this.requestMetricExpand(interpolatedQuery, options.requestId, range);
+      return this.requestMetricExpand(interpolatedQuery, range);
     } else {
-      return this.requestMetricFind(interpolatedQuery, options.requestId, range);
+      return this.requestMetricFind(interpolatedQuery, range);
     }
   }
 
@@ -589,40 +588,14 @@ export class GraphiteDatasource
    *
    * For more complex searches use requestMetricExpand
    */
-  private requestMetricFind(
-    query: string,
-    requestId: string,
-    range?: { from: any; until: any }
-  ): Promise<MetricFindValue[]> {
-    const httpOptions: any = {
-      method: 'POST',
-      url: '/metrics/find',
-      params: {},
-      data: `query=${query}`,
-      headers: {
-        'Content-Type': 'application/x-www-form-urlencoded',
-      },
-      // for cancellations
-      requestId: requestId,
-    };
-
-    if (range) {
-      httpOptions.params.from = range.from;
-      httpOptions.params.until = range.until;
-    }
-
-    return lastValueFrom(
-      this.doGraphiteRequest(httpOptions).pipe(
-        map((results: any) => {
-          return _map(results.data, (metric) => {
-            return {
-              text: metric.text,
-              expandable: metric.expandable ? true : false,
-            };
-          });
-        })
-      )
-    );
+  private async requestMetricFind(query: string, range?: { from: any; until: any }): Promise<MetricFindValue[]> {
+    const results = await this.postResource('metrics/find', { query, ...range });
+    return _map(results, (metric) => {
+      return {
+        text: metric.text,
+        expandable: metric.expandable ? true : false,
+      };
+    });
   }
 
   /**
@@ -630,39 +603,14 @@ export class GraphiteDatasource
    * The result will contain all metrics (with full name) matching provided query.
    * It's a more flexible version of /metrics/find endpoint (@see requestMetricFind)
    */
-  private requestMetricExpand(
-    query: string,
-    requestId: string,
-    range?: { from: any; until: any }
-  ): Promise<MetricFindValue[]> {
-    const httpOptions: any = {
-      method: 'GET',
-      url: '/metrics/expand',
-      params: { query },
-      headers: {
-        'Content-Type': 'application/x-www-form-urlencoded',
-      },
-      // for cancellations
-      requestId,
-    };
-
-    if (range) {
-      httpOptions.params.from = range.from;
-      httpOptions.params.until = range.until;
-    }
-
-    return lastValueFrom(
-      this.doGraphiteRequest(httpOptions).pipe(
-        map((results: any) => {
-          return _map(results.data.results, (metric) => {
-            return {
-              text: metric,
-              expandable: false,
-            };
-          });
-        })
-      )
-    );
+  private async requestMetricExpand(query: string, range?: { from: any; until: any }): Promise<MetricFindValue[]> {
+    const results = await this.getResource('metrics/expand', { query, ...range });
+    return _map(results.results, (metric) => {
+      return {
+        text: metric,
+        expandable: false,
+      };
+    });
   }
 
   getTags(optionalOptions: any) {
@@ -725,57 +673,45 @@ export class GraphiteDatasource
     );
   }
 
-  getTagsAutoComplete(expressions: any[], tagPrefix: any, optionalOptions?: any) {
+  async getTagsAutoComplete(expressions: any[], tagPrefix: any, optionalOptions?: any) {
     const options = optionalOptions || {};
 
-    const httpOptions: any = {
-      method: 'GET',
-      url: '/tags/autoComplete/tags',
-      params: {
-        expr: _map(expressions, (expression) => this.templateSrv.replace((expression || '').trim())),
-      },
-      // for cancellations
-      requestId: options.requestId,
+    const params: any = {
+      expr: _map(expressions, (expression) => this.templateSrv.replace((expression || '').trim())),
     };
 
     if (tagPrefix) {
-      httpOptions.params.tagPrefix = tagPrefix;
+      params.tagPrefix = tagPrefix;
     }
     if (options.limit) {
-      httpOptions.params.limit = options.limit;
+      params.limit = options.limit;
     }
     if (options.range) {
-      httpOptions.params.from = this.translateTime(options.range.from, false, options.timezone);
-      httpOptions.params.until = this.translateTime(options.range.to, true, options.timezone);
+      params.from = this.translateTime(options.range.from, false, options.timezone);
+      params.until = this.translateTime(options.range.to, true, options.timezone);
     }
-    return lastValueFrom(this.doGraphiteRequest(httpOptions).pipe(mapToTags()));
+    return toTags(await this.getResource('tags/autoComplete/tags', params));
   }
 
-  getTagValuesAutoComplete(expressions: any[], tag: any, valuePrefix: any, optionalOptions: any) {
+  async getTagValuesAutoComplete(expressions: any[], tag: any, valuePrefix: any, optionalOptions: any) {
     const options = optionalOptions || {};
 
-    const httpOptions: any = {
-      method: 'GET',
-      url: '/tags/autoComplete/values',
-      params: {
-        expr: _map(expressions, (expression) => this.templateSrv.replace((expression || '').trim())),
-        tag: this.templateSrv.replace((tag || '').trim()),
-      },
-      // for cancellations
-      requestId: options.requestId,
+    const params: any = {
+      expr: _map(expressions, (expression) => this.templateSrv.replace((expression || '').trim())),
+      tag: this.templateSrv.replace((tag || '').trim()),
     };
 
     if (valuePrefix) {
-      httpOptions.params.valuePrefix = valuePrefix;
+      params.valuePrefix = valuePrefix;
     }
     if (options.limit) {
-      httpOptions.params.limit = options.limit;
+      params.limit = options.limit;
     }
     if (options.range) {
-      httpOptions.params.from = this.translateTime(options.range.from, false, options.timezone);
-      httpOptions.params.until = this.translateTime(options.range.to, true, options.timezone);
+      params.from = this.translateTime(options.range.from, false, options.timezone);
+      params.until = this.translateTime(options.range.to, true, options.timezone);
     }
-    return lastValueFrom(this.doGraphiteRequest(httpOptions).pipe(mapToTags()));
+    return toTags(await this.getResource('tags/autoComplete/values', params));
   }
 
   getVersion(optionalOptions: any) {
@@ -826,33 +762,17 @@ export class GraphiteDatasource
       return this.funcDefsPromise;
     }
 
-    const httpOptions = {
-      method: 'GET',
-      url: '/functions',
-      // add responseType because if this is not defined,
-      // backend_srv defaults to json
-      responseType: 'text',
-    };
-
-    return lastValueFrom(
-      this.doGraphiteRequest(httpOptions).pipe(
-        map((results: any) => {
-          // Fix for a Graphite bug: https://github.com/graphite-project/graphite-web/issues/2609
-          // There is a fix for it https://github.com/graphite-project/graphite-web/pull/2612 but
-          // it was merged to master in July 2020 but it has never been released (the last Graphite
-          // release was 1.1.7 - March 2020). The bug was introduced in Graphite 1.1.7, in versions
-          // 1.1.0 - 1.1.6 /functions endpoint returns a valid JSON
-          const fixedData = JSON.parse(results.data.replace(/"default": ?Infinity/g, '"default": 1e9999'));
-          this.funcDefs = gfunc.parseFuncDefs(fixedData);
-          return this.funcDefs;
-        }),
-        catchError((error: any) => {
-          console.error('Fetching graphite functions error', error);
-          this.funcDefs = gfunc.getFuncDefs(this.graphiteVersion);
-          return of(this.funcDefs);
-        })
-      )
-    );
+    // the backend fixes the invalid JSON of the Graphite bug https://github.com/graphite-project/graphite-web/issues/2609
+    return this.getResource('functions')
+      .then((results: any) => {
+        this.funcDefs = gfunc.parseFuncDefs(results);
+        return this.funcDefs;
+      })
+      .catch((error: any) => {
+        console.error('Fetching graphite functions error', error);
+        this.funcDefs = gfunc.getFuncDefs(this.graphiteVersion);
+        return this.funcDefs;
+      });
   }
 
   testDatasource() {
@@ -981,16 +901,12 @@ function supportsFunctionIndex(version: string): boolean {
   return isVersionGtOrEq(version, '1.1');
 }
 
-function mapToTags(): OperatorFunction<any, Array<{ text: string }>> {
-  return pipe(
-    map((results: any) => {
-      if (results.data) {
-        return _map(results.data, (value) => {
-          return { text: value };
-        });
-      } else {
-        return [];
-      }
-    })
-  );
+function toTags(results: any): Array<{ text: string }> {
+  if (results) {
+    return _map(results, (value) => {
+      return { text: value };
+    });
+  } else {
+    return [];
+  }
 }
@@ -1,3 +1,4 @@
+import 'whatwg-fetch'; // fetch polyfill needed for Headers
 import { of } from 'rxjs';
 
 import { setBackendSrv } from '@grafana/runtime';
@@ -18,6 +19,7 @@ describe('graphiteDatasource integration with backendSrv and fetch', () => {
     jest.clearAllMocks();
 
     const instanceSettings = {
+      id: 1,
       url: '/api/datasources/proxy/1',
       name: 'graphiteProd',
       jsonData: {
@@ -29,42 +31,19 @@ describe('graphiteDatasource integration with backendSrv and fetch', () => {
   });
 
   describe('returns a list of functions', () => {
-    it('should return a list of functions with invalid JSON', async () => {
-      const INVALID_JSON =
-        '{"testFunction":{"name":"function","description":"description","module":"graphite.render.functions","group":"Transform","params":[{"name":"param","type":"intOrInf","required":true,"default":Infinity}]}}';
-
-      mockBackendSrv(INVALID_JSON);
-
-      const funcDefs = await ctx.ds.getFuncDefs();
-
-      expect(funcDefs).toEqual({
-        testFunction: {
-          category: 'Transform',
-          defaultParams: ['inf'],
-          description: 'description',
-          fake: true,
-          name: 'function',
-          params: [
-            {
-              multiple: false,
-              name: 'param',
-              optional: false,
-              options: undefined,
-              type: 'int_or_infinity',
-            },
-          ],
-        },
-      });
-    });
-
-    it('should return a list of functions with valid JSON', async () => {
+    it('should return the list of functions of the backend resource', async () => {
+      // the backend replaces `"default": Infinity` (invalid JSON) passed by Graphite API in 1.1.7 with 1e9999
       const VALID_JSON =
         '{"testFunction":{"name":"function","description":"description","module":"graphite.render.functions","group":"Transform","params":[{"name":"param","type":"intOrInf","required":true,"default":1e9999}]}}';
 
-      mockBackendSrv(VALID_JSON);
+      const fromFetchMock = mockBackendSrv(VALID_JSON);
 
       const funcDefs = await ctx.ds.getFuncDefs();
 
+      expect(fromFetchMock).toHaveBeenCalledWith(
+        'api/datasources/1/resources/functions',
+        expect.objectContaining({ method: 'GET' })
+      );
       expect(funcDefs).toEqual({
         testFunction: {
           category: 'Transform',
@@ -115,12 +94,7 @@ function mockBackendSrv(data: string) {
       redirected: false,
       type: 'basic',
       url: 'http://localhost:3000/api/some-mock',
-      headers: {
-        method: 'GET',
-        url: '/functions',
-        // to work around Graphite returning invalid JSON
-        responseType: 'text',
-      },
+      headers: new Headers(),
     };
     return of(mockedResponse);
   });
@@ -144,4 +118,5 @@ function mockBackendSrv(data: string) {
   });
 
   setBackendSrv(mockedBackendSrv);
+  return fromFetchMock;
 }
@@ -0,0 +1,10 @@
+// SQLite has a single schema for the tables of a database file.
+export const SCHEMA_NAME = 'main';
+
+export function showTables() {
+  return `SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`;
+}
+
+export function getSchema(table?: string) {
+  return `SELECT name AS "column", type FROM pragma_table_info('${(table ?? '').replace(/'/g, "''")}')`;
+}
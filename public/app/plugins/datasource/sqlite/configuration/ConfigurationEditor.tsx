@@ -0,0 +1,82 @@
+import React, { SyntheticEvent } from 'react';
+
+import {
+  DataSourcePluginOptionsEditorProps,
+  onUpdateDatasourceJsonDataOption,
+  updateDatasourcePluginJsonDataOption,
+} from '@grafana/data';
+import { Alert, FieldSet, InlineField, Input } from '@grafana/ui';
+import { ConnectionLimits } from 'app/features/plugins/sql/components/configuration/ConnectionLimits';
+
+import { SQLiteOptions } from '../types';
+
+export const ConfigurationEditor = (props: DataSourcePluginOptionsEditorProps<SQLiteOptions>) => {
+  const { options, onOptionsChange } = props;
+  const jsonData = options.jsonData;
+
+  const onDSOptionChanged = (property: keyof SQLiteOptions) => {
+    return (event: SyntheticEvent<HTMLInputElement>) => {
+      onOptionsChange({ ...options, ...{ [property]: event.currentTarget.value } });
+    };
+  };
+
+  const shortWidth = 15;
+  const longWidth = 40;
+
+  return (
+    <>
+      <FieldSet label="SQLite Database" width={400}>
+        <InlineField
+          labelWidth={shortWidth}
+          label="Path"
+          tooltip={
+            <span>
+              Path of the database file. The file must be in one of the directories of the{' '}
+              <code>sqlite_allowed_paths</code> setting of Grafana, a relative path is relative to the first directory.
+            </span>
+          }
+        >
+          <Input
+            width={longWidth}
+            name="database"
+            value={options.database || ''}
+            placeholder="analytics.db"
+            onChange={onDSOptionChanged('database')}
+          ></Input>
+        </InlineField>
+      </FieldSet>
+
+      <ConnectionLimits
+        labelWidth={shortWidth}
+        jsonData={jsonData}
+        onPropertyChanged={(property, value) => {
+          updateDatasourcePluginJsonDataOption(props, property, value);
+        }}
+      ></ConnectionLimits>
+
+      <FieldSet label="SQLite details">
+        <InlineField
+          tooltip={
+            <span>
+              A lower limit for the auto group by time interval. Recommended to be set to write frequency, for example
+              <code>1m</code> if your data is written every minute.
+            </span>
+          }
+          labelWidth={shortWidth}
+          label="Min time interval"
+        >
+          <Input
+            placeholder="1m"
+            value={jsonData.timeInterval || ''}
+            onChange={onUpdateDatasourceJsonDataOption(props, 'timeInterval')}
+          ></Input>
+        </InlineField>
+      </FieldSet>
+
+      <Alert title="Read-only access" severity="info">
+        Grafana opens the database file read-only, queries can&apos;t change the data of the file. Only the files in the
+        directories of the <code>sqlite_allowed_paths</code> setting of Grafana can be read.
+      </Alert>
+    </>
+  );
+};
@@ -0,0 +1,11 @@
+import { DataSourcePlugin } from '@grafana/data';
+import { SqlQueryEditor } from 'app/features/plugins/sql/components/QueryEditor';
+import { SQLQuery } from 'app/features/plugins/sql/types';
+
+import { ConfigurationEditor } from './configuration/ConfigurationEditor';
+import { SQLiteDatasource } from './datasource';
+import { SQLiteOptions } from './types';
+
+export const plugin = new DataSourcePlugin<SQLiteDatasource, SQLQuery, SQLiteOptions>(SQLiteDatasource)
+  .setQueryEditor(SqlQueryEditor)
+  .setConfigEditor(ConfigurationEditor);
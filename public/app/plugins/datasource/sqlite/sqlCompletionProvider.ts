@@ -0,0 +1,47 @@
+import { AGGREGATE_FNS, OPERATORS } from 'app/features/plugins/sql/constants';
+import {
+  ColumnDefinition,
+  DB,
+  LanguageCompletionProvider,
+  SQLQuery,
+  TableDefinition,
+} from 'app/features/plugins/sql/types';
+
+interface CompletionProviderGetterArgs {
+  getColumns: React.MutableRefObject<(t: SQLQuery) => Promise<ColumnDefinition[]>>;
+  getTables: React.MutableRefObject<(d?: string) => Promise<TableDefinition[]>>;
+}
+
+export const getSqlCompletionProvider: (args: CompletionProviderGetterArgs) => LanguageCompletionProvider =
+  ({ getColumns, getTables }) =>
+  () => ({
+    triggerCharacters: ['.', ' ', '$', ',', '(', "'"],
+    tables: {
+      resolve: async () => {
+        return await getTables.current();
+      },
+    },
+    columns: {
+      resolve: async (t: string) => {
+        return await getColumns.current({ table: t, refId: 'A' });
+      },
+    },
+    supportedFunctions: () => AGGREGATE_FNS,
+    supportedOperators: () => OPERATORS,
+  });
+
+export async function fetchColumns(db: DB, q: SQLQuery) {
+  const cols = await db.fields(q);
+  if (cols.length > 0) {
+    return cols.map((c) => {
+      return { name: c.value, type: c.value, description: c.value };
+    });
+  } else {
+    return [];
+  }
+}
+
+export async function fetchTables(db: DB) {
+  const tables = await db.lookup();
+  return tables;
+}
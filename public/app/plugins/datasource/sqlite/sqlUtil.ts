@@ -0,0 +1,87 @@
+import { isEmpty } from 'lodash';
+
+import { RAQBFieldTypes, SQLExpression, SQLQuery } from 'app/features/plugins/sql/types';
+import { haveColumns } from 'app/features/plugins/sql/utils/sql.utils';
+
+// The types of the columns follow the type affinity rules of SQLite: https://www.sqlite.org/datatype3.html
+export function getRAQBType(type: string): RAQBFieldTypes {
+  const t = type.toLowerCase();
+  if (t === 'date') {
+    return 'date';
+  }
+  if (t === 'datetime' || t === 'timestamp') {
+    return 'datetime';
+  }
+  if (t === 'boolean') {
+    return 'boolean';
+  }
+  if (/int|real|floa|doub|numeric|decimal/.test(t)) {
+    return 'number';
+  }
+  return 'text';
+}
+
+export function getIcon(type: string): string | undefined {
+  switch (getRAQBType(type)) {
+    case 'date':
+    case 'datetime':
+      return 'clock-nine';
+    case 'boolean':
+      return 'toggle-off';
+    case 'number':
+      return 'calculator-alt';
+    default:
+      return 'text';
+  }
+}
+
+export function toRawSql({ sql, table }: SQLQuery): string {
+  let rawQuery = '';
+
+  // Return early with empty string if there is no sql column
+  if (!sql || !haveColumns(sql.columns)) {
+    return rawQuery;
+  }
+
+  rawQuery += createSelectClause(sql.columns);
+
+  if (table) {
+    rawQuery += `FROM ${table} `;
+  }
+
+  if (sql.whereString) {
+    rawQuery += `WHERE ${sql.whereString} `;
+  }
+
+  if (sql.groupBy?.[0]?.property.name) {
+    const groupBy = sql.groupBy.map((g) => g.property.name).filter((g) => !isEmpty(g));
+    rawQuery += `GROUP BY ${groupBy.join(', ')} `;
+  }
+
+  if (sql.orderBy?.property.name) {
+    rawQuery += `ORDER BY ${sql.orderBy.property.name} `;
+  }
+
+  if (sql.orderBy?.property.name && sql.orderByDirection) {
+    rawQuery += `${sql.orderByDirection} `;
+  }
+
+  if (sql.limit !== undefined && sql.limit >= 0) {
+    rawQuery += `LIMIT ${sql.limit} `;
+  }
+
+  return rawQuery;
+}
+
+function createSelectClause(sqlColumns: NonNullable<SQLExpression['columns']>): string {
+  const columns = sqlColumns.map((c) => {
+    let rawColumn = '';
+    if (c.name) {
+      rawColumn += `${c.name}(${c.parameters?.map((p) => `${p.name}`)})`;
+    } else {
+      rawColumn += `${c.parameters?.map((p) => `${p.name}`)}`;
+    }
+    return rawColumn;
+  });
+  return `SELECT ${columns.join(', ')} `;
+}
@@ -0,0 +1,80 @@
+import React from 'react';
+
+import { SelectableValue } from '@grafana/data';
+import { InlineField, InlineFieldRow, QueryField, RadioButtonGroup, Select } from '@grafana/ui';
+
+import { TempoDatasource } from '../datasource';
+import { TempoQuery, TempoSearchMetric } from '../types';
+
+import NativeSearch from './NativeSearch';
+
+interface Props {
+  datasource: TempoDatasource;
+  query: TempoQuery;
+  onChange: (value: TempoQuery) => void;
+  onBlur?: () => void;
+  onRunQuery: () => void;
+}
+
+const metricOptions: Array<SelectableValue<TempoSearchMetric>> = [
+  { value: 'count', label: 'Count', description: 'Number of traces' },
+  { value: 'avgDuration', label: 'Average duration', description: 'Average duration of the traces, in milliseconds' },
+  { value: 'maxDuration', label: 'Maximum duration', description: 'Maximum duration of the traces, in milliseconds' },
+  { value: 'spanCount', label: 'Span count', description: 'Number of spans matching the TraceQL query' },
+];
+
+const searchTypeOptions: Array<SelectableValue<'traceql' | 'nativeSearch'>> = [
+  { value: 'nativeSearch', label: 'Search' },
+  { value: 'traceql', label: 'TraceQL' },
+];
+
+// MetricsSection edits the metrics queries, which return a time series of the traces found in each interval and can
+// be used by alert rules.
+export const MetricsSection = ({ datasource, query, onChange, onBlur, onRunQuery }: Props) => {
+  const searchType = query.searchType ?? (query.query ? 'traceql' : 'nativeSearch');
+
+  return (
+    <>
+      <InlineFieldRow>
+        <InlineField label="Metric" labelWidth={14}>
+          <Select
+            inputId="metric"
+            options={metricOptions}
+            value={query.metric ?? 'count'}
+            onChange={(v) => {
+              onChange({ ...query, metric: v?.value });
+            }}
+            width={24}
+          />
+        </InlineField>
+        <InlineField label="Search type">
+          <RadioButtonGroup<'traceql' | 'nativeSearch'>
+            options={searchTypeOptions}
+            value={searchType}
+            onChange={(v) => {
+              onChange({ ...query, searchType: v });
+            }}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      {searchType === 'traceql' ? (
+        <InlineFieldRow>
+          <InlineField label="TraceQL" labelWidth={14} grow>
+            <QueryField
+              query={query.query}
+              onChange={(val) => {
+                onChange({ ...query, query: val });
+              }}
+              onBlur={onBlur}
+              onRunQuery={onRunQuery}
+              placeholder={'Enter a TraceQL query (run with Shift+Enter)'}
+              portalOrigin="tempo"
+            />
+          </InlineField>
+        </InlineFieldRow>
+      ) : (
+        <NativeSearch datasource={datasource} query={query} onChange={onChange} onBlur={onBlur} onRunQuery={onRunQuery} />
+      )}
+    </>
+  );
+};
@@ -22,6 +22,7 @@ import { TempoDatasource } from '../datasource';
 import { QueryEditor } from '../traceql/QueryEditor';
 import { TempoQuery, TempoQueryType } from '../types';
 
+import { MetricsSection } from './MetricsSection';
 import NativeSearch from './NativeSearch';
 import { ServiceGraphSection } from './ServiceGraphSection';
 import { getDS } from './utils';
@@ -101,6 +102,10 @@ class TempoQueryFieldComponent extends React.PureComponent<Props> {
       queryTypeOptions.push({ value: 'traceql', label: 'TraceQL' });
     }
 
+    if (!datasource?.search?.hide) {
+      queryTypeOptions.push({ value: 'metrics', label: 'Metrics' });
+    }
+
     return (
       <>
         <InlineFieldRow>
@@ -179,6 +184,15 @@ class TempoQueryFieldComponent extends React.PureComponent<Props> {
         {query.queryType === 'serviceMap' && (
           <ServiceGraphSection graphDatasourceUid={graphDatasourceUid} query={query} onChange={onChange} />
         )}
+        {query.queryType === 'metrics' && (
+          <MetricsSection
+            datasource={this.props.datasource}
+            query={query}
+            onChange={onChange}
+            onBlur={this.props.onBlur}
+            onRunQuery={this.props.onRunQuery}
+          />
+        )}
         {query.queryType === 'traceql' && (
           <QueryEditor
             datasource={this.props.datasource}
@@ -177,6 +177,24 @@ describe('Tempo data source', () => {
     ]);
   });
 
+  it('runs metrics queries in the backend', async () => {
+    setupBackendSrv(
+      new MutableDataFrame({
+        fields: [
+          { name: 'Time', values: [1619712655875] },
+          { name: 'Value', values: [3] },
+        ],
+      })
+    );
+    const ds = new TempoDatasource(defaultSettings);
+    const response = await lastValueFrom(
+      ds.query({ targets: [{ refId: 'refid1', queryType: 'metrics', metric: 'count', query: '' }] } as any)
+    );
+
+    expect(response.data).toHaveLength(1);
+    expect((response.data[0] as DataFrame).fields.map((f) => f.name)).toEqual(['Time', 'Value']);
+  });
+
   it('should handle json file upload', async () => {
     const ds = new TempoDatasource(defaultSettings);
     ds.uploadedJson = JSON.stringify(mockJson);
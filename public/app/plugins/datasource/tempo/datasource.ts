@@ -231,6 +231,16 @@ export class TempoDatasource extends DataSourceWithBackend<TempoQuery, TempoJson
       }
     }
 
+    if (targets.metrics?.length > 0) {
+      reportInteraction('grafana_traces_metrics_queried', {
+        datasourceType: 'tempo',
+        app: options.app ?? '',
+        metric: targets.metrics[0].metric ?? '',
+      });
+
+      subQueries.push(super.query({ ...options, targets: targets.metrics }));
+    }
+
     if (targets.traceId?.length > 0) {
       reportInteraction('grafana_traces_traceID_queried', {
         datasourceType: 'tempo',
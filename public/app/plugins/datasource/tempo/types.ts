@@ -32,7 +32,18 @@ export interface TempoJsonData extends DataSourceJsonData {
 }
 
 // search = Loki search, nativeSearch = Tempo search for backwards compatibility
-export type TempoQueryType = 'traceql' | 'search' | 'traceId' | 'serviceMap' | 'upload' | 'nativeSearch' | 'clear';
+export type TempoQueryType =
+  | 'traceql'
+  | 'search'
+  | 'traceId'
+  | 'serviceMap'
+  | 'upload'
+  | 'nativeSearch'
+  | 'metrics'
+  | 'clear';
+
+// Functions applied by metrics queries to the traces found in each interval
+export type TempoSearchMetric = 'count' | 'avgDuration' | 'maxDuration' | 'spanCount';
 
 export interface TempoQuery extends DataQuery {
   query: string;
@@ -46,6 +57,9 @@ export interface TempoQuery extends DataQuery {
   maxDuration?: string;
   limit?: number;
   serviceMapQuery?: string;
+  // Metric and type of search of metrics queries
+  metric?: TempoSearchMetric;
+  searchType?: 'traceql' | 'nativeSearch';
 }
 
 export interface MyDataSourceOptions extends DataSourceJsonData {}
@@ -6,6 +6,7 @@ import { selectors as editorSelectors } from '@grafana/e2e-selectors';
 import { InlineField, InlineFieldRow, InlineSwitch, Input, Select, TextArea } from '@grafana/ui';
 
 import { RandomWalkEditor, StreamingClientEditor } from './components';
+import { AlertingScenarioEditor } from './components/AlertingScenarioEditor';
 import { CSVContentEditor } from './components/CSVContentEditor';
 import { CSVFileEditor } from './components/CSVFileEditor';
 import { CSVWavesEditor } from './components/CSVWaveEditor';
@@ -14,6 +15,7 @@ import { GrafanaLiveEditor } from './components/GrafanaLiveEditor';
 import { NodeGraphEditor } from './components/NodeGraphEditor';
 import { PredictablePulseEditor } from './components/PredictablePulseEditor';
 import { RawFrameEditor } from './components/RawFrameEditor';
+import { ReplayEditor } from './components/ReplayEditor';
 import { SimulationQueryEditor } from './components/SimulationQueryEditor';
 import { USAQueryEditor, usaQueryModes } from './components/USAQueryEditor';
 import { defaultCSVWaveQuery, defaultPulseQuery, defaultQuery } from './constants';
@@ -21,7 +23,8 @@ import { TestDataDataSource } from './datasource';
 import { defaultStreamQuery } from './runStreams';
 import { CSVWave, NodesQuery, TestDataQuery, USAQuery } from './types';
 
-const showLabelsFor = ['random_walk', 'predictable_pulse'];
+const showLabelsFor = ['random_walk', 'predictable_pulse', 'missing_series', 'label_churn', 'no_data_windows'];
+const alertingScenarios = ['missing_series', 'label_churn', 'no_data_windows'];
 const endpoints = [
   { value: 'datasources', label: 'Data Sources' },
   { value: 'search', label: 'Search' },
@@ -155,6 +158,7 @@ export const QueryEditor = ({ query, datasource, onChange, onRunQuery }: Props)
 
   const onStreamClientChange = onFieldChange('stream');
   const onPulseWaveChange = onFieldChange('pulseWave');
+  const onAlertingChange = onFieldChange('alerting');
   const onUSAStatsChange = (usa?: USAQuery) => {
     onUpdate({ ...query, usa });
   };
@@ -297,6 +301,10 @@ export const QueryEditor = ({ query, datasource, onChange, onRunQuery }: Props)
       {scenarioId === 'predictable_pulse' && (
         <PredictablePulseEditor onChange={onPulseWaveChange} query={query} ds={datasource} />
       )}
+      {alertingScenarios.includes(scenarioId ?? '') && (
+        <AlertingScenarioEditor onChange={onAlertingChange} query={query} ds={datasource} />
+      )}
+      {scenarioId === 'replay' && <ReplayEditor onChange={onUpdate} query={query} ds={datasource} />}
       {scenarioId === 'predictable_csv_wave' && <CSVWavesEditor onChange={onCSVWaveChange} waves={query.csvWave} />}
       {scenarioId === 'node_graph' && (
         <NodeGraphEditor onChange={(val: NodesQuery) => onChange({ ...query, nodes: val })} query={query} />
@@ -0,0 +1,59 @@
+import React, { ChangeEvent } from 'react';
+
+import { InlineField, InlineFieldRow, Input } from '@grafana/ui';
+
+import { EditorProps } from '../QueryEditor';
+import { AlertingQuery } from '../types';
+
+const fields = [
+  { label: 'Step', id: 'timeStep', placeholder: '60', tooltip: 'The number of seconds between datapoints.' },
+  {
+    label: 'Period',
+    id: 'period',
+    placeholder: '300',
+    tooltip: 'The number of seconds of a window. Every window either has data or has no data.',
+  },
+  {
+    label: 'On Count',
+    id: 'onCount',
+    placeholder: '1',
+    tooltip: 'The number of windows within a cycle, at the start of the cycle, that have data.',
+  },
+  {
+    label: 'Off Count',
+    id: 'offCount',
+    placeholder: '1',
+    tooltip: 'The number of windows within a cycle without data.',
+  },
+  { label: 'Series', id: 'seriesCount', placeholder: '1', tooltip: 'The number of series.' },
+  { label: 'Value', id: 'value', placeholder: '1', tooltip: 'The value of the datapoints.' },
+];
+
+export const AlertingScenarioEditor = ({ onChange, query }: EditorProps) => {
+  // Convert values to numbers before saving
+  const onInputChange = (e: ChangeEvent<HTMLInputElement>) => {
+    const { name, value } = e.target;
+
+    onChange({ target: { name, value: Number(value) } });
+  };
+
+  return (
+    <InlineFieldRow>
+      {fields.map(({ label, id, placeholder, tooltip }) => {
+        return (
+          <InlineField label={label} labelWidth={14} key={id} tooltip={tooltip}>
+            <Input
+              width={32}
+              type="number"
+              name={id}
+              id={`alerting.${id}-${query.refId}`}
+              value={query.alerting?.[id as keyof AlertingQuery]}
+              placeholder={placeholder}
+              onChange={onInputChange}
+            />
+          </InlineField>
+        );
+      })}
+    </InlineFieldRow>
+  );
+};
@@ -0,0 +1,43 @@
+import React, { ChangeEvent } from 'react';
+
+import { InlineField, InlineFieldRow, Input, TextArea } from '@grafana/ui';
+
+import { EditorProps } from '../QueryEditor';
+
+export const ReplayEditor = ({ onChange, query }: EditorProps) => {
+  const onInputChange = (e: ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
+    const { name, value } = e.target;
+
+    onChange({ ...query, replay: { ...query.replay, [name]: value } });
+  };
+
+  return (
+    <>
+      <InlineFieldRow>
+        <InlineField
+          label="File"
+          labelWidth={14}
+          tooltip="The name of a recording in public/testdata. The content is used when no file is set."
+        >
+          <Input
+            width={32}
+            name="fileName"
+            id={`replay.fileName-${query.refId}`}
+            value={query.replay?.fileName}
+            placeholder="recording.json"
+            onChange={onInputChange}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineField grow>
+        <TextArea
+          name="content"
+          value={query.replay?.content}
+          rows={10}
+          placeholder="Paste frames or a query response copied from the query inspector"
+          onChange={onInputChange}
+        />
+      </InlineField>
+    </>
+  );
+};
@@ -14,6 +14,8 @@ export interface TestDataQuery extends DataQuery {
   stringInput?: string;
   stream?: StreamingQuery;
   pulseWave?: PulseWaveQuery;
+  alerting?: AlertingQuery;
+  replay?: ReplayQuery;
   sim?: SimulationQuery;
   csvWave?: CSVWave[];
   labels?: string;
@@ -60,6 +62,21 @@ export interface PulseWaveQuery {
   onValue?: number;
   offValue?: number;
 }
+
+export interface AlertingQuery {
+  timeStep?: number;
+  period?: number;
+  onCount?: number;
+  offCount?: number;
+  seriesCount?: number;
+  value?: number;
+}
+
+export interface ReplayQuery {
+  fileName?: string;
+  content?: string;
+}
+
 export interface CSVWave {
   timeStep?: number;
   name?: string;